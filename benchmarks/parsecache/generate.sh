@@ -0,0 +1,32 @@
+#!/usr/bin/env bash
+# Generates a large flowchart for the parse cache benchmark.
+#
+# The program is a straight chain of N nodes. Every fifth node is a
+# condition that either skips the next node or falls through to it, so the
+# flowchart has many conditions and edges, as generated flowcharts tend to.
+# The last two nodes are never conditions, so that both branches of every
+# condition lead to a node of the chain.
+#
+# Usage: generate.sh [N] > large.mmd   (default N: 10000)
+set -euo pipefail
+
+N="${1:-10000}"
+
+echo "flowchart TD"
+echo "    Start --> N0[total = 0; i = 0]"
+after_condition=0
+for ((i = 1; i < N; i++)); do
+  prev="N$((i - 1))"
+  if ((after_condition)); then
+    echo "    ${prev} -->|Yes| N${i}[total = total + ${i}; i = i + 1]"
+    echo "    ${prev} -->|No| N$((i + 1))"
+    after_condition=0
+  elif ((i % 5 == 0 && i + 2 < N)); then
+    echo "    ${prev} --> N${i}{total % 3 == ${i} % 3?}"
+    after_condition=1
+  else
+    echo "    ${prev} --> N${i}[total = total + ${i} % 7; i = i + 1]"
+  fi
+done
+echo "    N$((N - 1)) --> P[println total; println i]"
+echo "    P --> End"
@@ -4,6 +4,8 @@ set -euo pipefail
 # --- Paths ---
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 PROGRAMS_DIR="$SCRIPT_DIR/programs"
+BUFFERING_DIR="$SCRIPT_DIR/buffering"
+PARSECACHE_DIR="$SCRIPT_DIR/parsecache"
 RESULTS_DIR="$SCRIPT_DIR/results"
 OUTPUT_FILE="$SCRIPT_DIR/README.md"
 
@@ -181,6 +183,36 @@ echo "=== Running String Concatenation benchmark (n=10000) ==="
 mapfile -t strconcat_args < <(build_hyperfine_args "strconcat")
 hyperfine "${strconcat_args[@]}"
 
+# --- Run output buffering benchmarks (merx only) ---
+run_buffering_benchmark() {
+  local name="$1"
+  local program="$2"
+
+  hyperfine --warmup "$WARMUP" --runs "$RUNS" \
+    --export-json "$RESULTS_DIR/buffering_${name}.json" \
+    -n "buffered" "${MERX_BIN} run ${program} > /dev/null" \
+    -n "unbuffered" "${MERX_BIN} run --unbuffered ${program} > /dev/null"
+}
+
+echo ""
+echo "=== Running output buffering benchmark: FizzBuzz ==="
+run_buffering_benchmark "fizzbuzz" "${PROGRAMS_DIR}/fizzbuzz/fizzbuzz.mmd"
+
+echo ""
+echo "=== Running output buffering benchmark: Print Lines (n=100000) ==="
+run_buffering_benchmark "printlines" "${BUFFERING_DIR}/printlines.mmd"
+
+# --- Run parse cache benchmark (merx only) ---
+LARGE_PROGRAM="$BUILD_DIR/large.mmd"
+"$PARSECACHE_DIR/generate.sh" 10000 >"$LARGE_PROGRAM"
+
+echo ""
+echo "=== Running parse cache benchmark: Large Flowchart (10000 nodes) ==="
+hyperfine --warmup "$WARMUP" --runs "$RUNS" \
+  --export-json "$RESULTS_DIR/parsecache_large.json" \
+  -n "uncached" "${MERX_BIN} run ${LARGE_PROGRAM} > /dev/null" \
+  -n "cached" "${MERX_BIN} run --cache-dir ${BUILD_DIR}/cache ${LARGE_PROGRAM} > /dev/null"
+
 # --- Generate Markdown report ---
 echo ""
 echo "Generating report..."
@@ -288,6 +320,34 @@ TEMP_OUTPUT="$(mktemp)"
   echo ""
   generate_table "$RESULTS_DIR/strconcat.json" "$VERSIONS_JSON"
   echo ""
+
+  echo "## Output Buffering"
+  echo ""
+  echo "\`merx run\` (buffered stdout, the default) compared with \`merx run --unbuffered\`."
+  echo ""
+  echo "### FizzBuzz (n=1..100)"
+  echo ""
+  echo "Program: [./programs/fizzbuzz/fizzbuzz.mmd](./programs/fizzbuzz/fizzbuzz.mmd)"
+  echo ""
+  generate_table "$RESULTS_DIR/buffering_fizzbuzz.json" "{}"
+  echo ""
+  echo "### Print Lines (n=100000)"
+  echo ""
+  echo "Program: [./buffering/printlines.mmd](./buffering/printlines.mmd)"
+  echo ""
+  generate_table "$RESULTS_DIR/buffering_printlines.json" "{}"
+  echo ""
+
+  echo "## Parse Cache"
+  echo ""
+  echo "\`merx run\` compared with \`merx run --cache-dir\` once the cache is filled by the warmup runs."
+  echo ""
+  echo "### Large Flowchart (10000 nodes)"
+  echo ""
+  echo "Generator: [./parsecache/generate.sh](./parsecache/generate.sh)"
+  echo ""
+  generate_table "$RESULTS_DIR/parsecache_large.json" "{}"
+  echo ""
 } >"$TEMP_OUTPUT"
 
 mv "$TEMP_OUTPUT" "$OUTPUT_FILE"
@@ -3,6 +3,8 @@
 //! Edges define the control flow between nodes in a Mermaid flowchart. They
 //! specify which node to visit next after completing the current node's execution.
 
+use super::{Expr, Statement};
+
 /// A directed connection between two nodes in the flowchart.
 ///
 /// Edges define the control flow path through the program. Each edge connects
@@ -15,13 +17,16 @@
 /// A --> B           // Unlabeled edge
 /// C -->|Yes| D      // Labeled edge (for conditions)
 /// C -->|No| E
+/// C -->|Yes / i = i + 1| F   // Labeled edge with an action
 /// ```
 ///
 /// # Labels
 ///
 /// Labels are required for edges originating from [`Condition`](super::Node::Condition)
 /// nodes. Each condition node must have exactly one `Yes` edge and one `No` edge.
-/// Labels are optional (and typically omitted) for edges from other node types.
+/// Labels are optional (and typically omitted) for edges from other node types,
+/// unless the node has several outgoing edges: then each must carry a
+/// [`Guard`](EdgeLabel::Guard), and one may be [`Else`](EdgeLabel::Else).
 ///
 #[derive(Debug, Clone, PartialEq)]
 pub struct Edge {
@@ -38,31 +43,52 @@ pub struct Edge {
     /// An optional label for conditional branching.
     ///
     /// Required for edges from condition nodes; should be [`EdgeLabel::Yes`]
-    /// or [`EdgeLabel::No`]. Custom labels are allowed but have no special
-    /// meaning to the interpreter.
+    /// or [`EdgeLabel::No`]. Custom labels name the targets of decision
+    /// tables, and are otherwise only descriptive.
     pub label: Option<EdgeLabel>,
 
     /// An optional exit code for edges leading to the `End` node.
     ///
     /// When specified via `|exit N|` or `|Yes, exit N|` / `|No, exit N|`
     /// syntax, the interpreter uses this value as the process exit code.
-    /// Only valid on edges targeting the `End` node. Defaults to `0` when
-    /// not specified.
-    pub exit_code: Option<u8>,
+    /// `N` may be any expression (e.g. `|exit errors|`); it is evaluated
+    /// when the edge is followed and must be an integer between 0 and 255.
+    /// Literal codes are range-checked at parse time. Only valid on edges
+    /// targeting the `End` node or a [`Terminal`](super::Node::Terminal).
+    /// Defaults to `0` when not specified.
+    pub exit_code: Option<Expr>,
+
+    /// An optional result value for edges leading to a terminal node.
+    ///
+    /// When specified via `|return expr|` or `|Yes, return expr|` /
+    /// `|No, return expr|` syntax, the expression is evaluated as the edge
+    /// is followed and becomes the program's result value, as if by a
+    /// [`Return`](super::Statement::Return) statement. Only valid on edges
+    /// targeting the `End` node or a [`Terminal`](super::Node::Terminal).
+    pub return_value: Option<Expr>,
+
+    /// Statements executed when the edge is followed.
+    ///
+    /// Written after a `/` in the edge label, as in `|Yes / i = i + 1|` or
+    /// `|/ count = count + 1|`. The actions run before the edge's exit code
+    /// and result value are evaluated. Empty for edges without actions.
+    pub actions: Vec<Statement>,
 }
 
 /// A label attached to an edge for conditional branching.
 ///
 /// Edge labels determine which path to follow when leaving a condition node.
 /// The interpreter recognizes `Yes` and `No` as special values for boolean
-/// branching.
+/// branching, and guards for choosing among several edges of any other node.
 ///
 /// # Mermaid Syntax
 ///
 /// ```text
 /// A -->|Yes| B      // Yes label
 /// A -->|No| C       // No label
-/// A -->|custom| D   // Custom label (not used for branching)
+/// A -->|custom| D   // Custom label (decision table target, or descriptive)
+/// E -->|[x > 10]| F // Guard
+/// E -->|[else]| G   // Fallback when no guard matches
 /// ```
 ///
 #[derive(Debug, Clone, PartialEq)]
@@ -79,10 +105,23 @@ pub enum EdgeLabel {
 
     /// A custom label string.
     ///
-    /// Custom labels are parsed but have no special meaning to the interpreter.
-    /// They may be used for documentation or visual purposes in the Mermaid
-    /// diagram.
+    /// On edges from a decision table with a target column, the label names
+    /// the target that selects the edge. Elsewhere custom labels have no
+    /// special meaning to the interpreter, and may be used for documentation
+    /// or visual purposes in the Mermaid diagram.
     Custom(String),
+
+    /// A guard expression, written `[expr]`.
+    ///
+    /// A node whose outgoing edges are guarded follows the first edge whose
+    /// guard evaluates to `true`, trying the edges in order.
+    Guard(Expr),
+
+    /// The fallback of a guarded node, written `[else]`.
+    ///
+    /// This edge is followed when no guard on the node's other edges is
+    /// `true`.
+    Else,
 }
 
 impl EdgeLabel {
@@ -108,6 +147,52 @@ impl EdgeLabel {
     pub fn is_yes_or_no(&self) -> bool {
         matches!(self, EdgeLabel::Yes | EdgeLabel::No)
     }
+
+    /// Checks whether this label is a [`Guard`](EdgeLabel::Guard) or
+    /// [`Else`](EdgeLabel::Else).
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::ast::{EdgeLabel, Expr};
+    ///
+    /// assert!(EdgeLabel::Guard(Expr::BoolLit { value: true }).is_guard());
+    /// assert!(EdgeLabel::Else.is_guard());
+    /// assert!(!EdgeLabel::Yes.is_guard());
+    /// ```
+    pub fn is_guard(&self) -> bool {
+        matches!(self, EdgeLabel::Guard(_) | EdgeLabel::Else)
+    }
+
+    /// Checks whether this label is `body`, which marks the edge a
+    /// [`Loop`](super::Node::Loop) node follows for each value.
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::ast::EdgeLabel;
+    ///
+    /// assert!(EdgeLabel::Custom("body".to_string()).is_loop_body());
+    /// assert!(!EdgeLabel::Custom("done".to_string()).is_loop_body());
+    /// ```
+    pub fn is_loop_body(&self) -> bool {
+        matches!(self, EdgeLabel::Custom(label) if label == "body")
+    }
+
+    /// Checks whether this label is `done`, which marks the edge a
+    /// [`Loop`](super::Node::Loop) node follows once its values run out.
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::ast::EdgeLabel;
+    ///
+    /// assert!(EdgeLabel::Custom("done".to_string()).is_loop_done());
+    /// assert!(!EdgeLabel::Yes.is_loop_done());
+    /// ```
+    pub fn is_loop_done(&self) -> bool {
+        matches!(self, EdgeLabel::Custom(label) if label == "done")
+    }
 }
 
 #[cfg(test)]
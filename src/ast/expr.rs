@@ -24,6 +24,8 @@ use std::fmt;
 /// | [`Unary`](Expr::Unary) | `-x`, `!b` | Unary operation |
 /// | [`Binary`](Expr::Binary) | `x + y` | Binary operation |
 /// | [`Cast`](Expr::Cast) | `x as int` | Type conversion |
+/// | [`Call`](Expr::Call) | `rand_int(1, 6)` | Built-in function call |
+/// | [`Defined`](Expr::Defined) | `exists(x)`, `defined('x')` | Whether a variable is defined |
 ///
 /// # Operator Precedence
 ///
@@ -188,6 +190,42 @@ pub enum Expr {
         /// The target type for the cast.
         target_type: TypeName,
     },
+
+    /// A call to a built-in function.
+    ///
+    /// Arguments are evaluated left to right before the function is invoked.
+    /// The function name and argument count are checked at parse time.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// rand_int(1, 6)
+    /// rand_choice('rock', 'paper', 'scissors')
+    /// ```
+    Call {
+        /// The built-in function to invoke.
+        function: Function,
+        /// The argument expressions.
+        args: Vec<Expr>,
+    },
+
+    /// Whether a variable is currently defined.
+    ///
+    /// Evaluates to `true` if the variable has been assigned and not unset.
+    /// Unlike a [`Variable`](Expr::Variable) reference, this never fails.
+    /// Both spellings name the variable directly, so the name is fixed when
+    /// the flowchart is parsed.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// exists(total)
+    /// defined('total')
+    /// ```
+    Defined {
+        /// The variable name.
+        name: String,
+    },
 }
 
 /// A unary operator.
@@ -356,3 +394,138 @@ pub enum TypeName {
     /// Represents UTF-8 strings.
     Str,
 }
+
+/// A built-in function callable from expressions.
+///
+/// Built-in functions are resolved by name at parse time, so an unknown
+/// function name or a wrong number of arguments is reported as a syntax
+/// error rather than at runtime.
+///
+/// # Mermaid Syntax
+///
+/// ```text
+/// n = rand_int(1, 6)
+/// hand = rand_choice('rock', 'paper', 'scissors')
+/// deck = shuffle('A23456789TJQK')
+/// ```
+///
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub enum Function {
+    /// `rand_int(lo, hi)`: a uniformly distributed integer in `lo..=hi`.
+    RandInt,
+
+    /// `rand_choice(a, b, ...)`: one of its arguments, chosen uniformly.
+    RandChoice,
+
+    /// `shuffle(s)`: the characters of a string in random order.
+    Shuffle,
+
+    /// `now_ms()`: the current time in milliseconds since the Unix epoch.
+    NowMs,
+
+    /// `today()`: the current UTC date as a `YYYY-MM-DD` string.
+    Today,
+
+    /// `format_date(ms, fmt)`: a timestamp formatted with a `strftime`-style pattern.
+    FormatDate,
+
+    /// `parse_date(s)`: an ISO 8601 date string converted to a timestamp.
+    ParseDate,
+}
+
+/// The number of arguments a [`Function`] accepts.
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub enum Arity {
+    /// Exactly this many arguments.
+    Exact(usize),
+
+    /// This many arguments or more.
+    AtLeast(usize),
+}
+
+impl Arity {
+    /// Checks whether `count` arguments satisfy this arity.
+    pub fn accepts(&self, count: usize) -> bool {
+        match self {
+            Arity::Exact(n) => count == *n,
+            Arity::AtLeast(n) => count >= *n,
+        }
+    }
+}
+
+impl fmt::Display for Arity {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Arity::Exact(1) => write!(f, "1 argument"),
+            Arity::Exact(n) => write!(f, "{} arguments", n),
+            Arity::AtLeast(1) => write!(f, "at least 1 argument"),
+            Arity::AtLeast(n) => write!(f, "at least {} arguments", n),
+        }
+    }
+}
+
+impl Function {
+    /// Looks up a built-in function by its source-level name.
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::ast::Function;
+    ///
+    /// assert_eq!(Function::from_name("rand_int"), Some(Function::RandInt));
+    /// assert_eq!(Function::from_name("unknown"), None);
+    /// ```
+    pub fn from_name(name: &str) -> Option<Self> {
+        match name {
+            "rand_int" => Some(Function::RandInt),
+            "rand_choice" => Some(Function::RandChoice),
+            "shuffle" => Some(Function::Shuffle),
+            "now_ms" => Some(Function::NowMs),
+            "today" => Some(Function::Today),
+            "format_date" => Some(Function::FormatDate),
+            "parse_date" => Some(Function::ParseDate),
+            _ => None,
+        }
+    }
+
+    /// Returns the source-level name of this function.
+    pub fn name(&self) -> &'static str {
+        match self {
+            Function::RandInt => "rand_int",
+            Function::RandChoice => "rand_choice",
+            Function::Shuffle => "shuffle",
+            Function::NowMs => "now_ms",
+            Function::Today => "today",
+            Function::FormatDate => "format_date",
+            Function::ParseDate => "parse_date",
+        }
+    }
+
+    /// Returns the number of arguments this function accepts.
+    pub fn arity(&self) -> Arity {
+        match self {
+            Function::RandInt => Arity::Exact(2),
+            Function::RandChoice => Arity::AtLeast(1),
+            Function::Shuffle => Arity::Exact(1),
+            Function::NowMs => Arity::Exact(0),
+            Function::Today => Arity::Exact(0),
+            Function::FormatDate => Arity::Exact(2),
+            Function::ParseDate => Arity::Exact(1),
+        }
+    }
+
+    /// Checks whether this function draws from the interpreter's random
+    /// number generator.
+    pub fn is_random(&self) -> bool {
+        matches!(
+            self,
+            Function::RandInt | Function::RandChoice | Function::Shuffle
+        )
+    }
+}
+
+impl fmt::Display for Function {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{}", self.name())
+    }
+}
@@ -9,7 +9,8 @@ use super::{Edge, Node};
 ///
 /// A flowchart consists of a layout direction, a collection of nodes, and
 /// edges that connect those nodes. The execution begins at the [`Node::Start`]
-/// node and proceeds through connected nodes until reaching [`Node::End`].
+/// node and proceeds through connected nodes until reaching a terminal node
+/// ([`Node::End`] or a [`Node::Terminal`]).
 ///
 /// # Structure
 ///
@@ -23,9 +24,9 @@ use super::{Edge, Node};
 ///
 /// A valid flowchart must contain:
 /// - Exactly one `Start` node (enforced at parse time and runtime)
-/// - Exactly one `End` node (enforced at parse time and runtime)
-///
-/// Note: Path connectivity from `Start` to `End` is not validated.
+/// - At least one terminal node, `End` or named (enforced at parse time and runtime)
+/// - A path from `Start` and from every [`Node::Entry`] to some terminal
+///   (enforced at parse time)
 ///
 /// # See Also
 ///
@@ -42,7 +43,7 @@ pub struct Flowchart {
 
     /// All nodes defined in the flowchart.
     ///
-    /// Includes `Start`, `End`, `Process`, and `Condition` nodes. Each node
+    /// Includes `Start`, `End`, `Terminal`, `Process`, and `Condition` nodes. Each node
     /// (except `Start` and `End`) has a unique identifier used by edges.
     pub nodes: Vec<Node>,
 
@@ -9,7 +9,8 @@
 //! The AST consists of several key components:
 //!
 //! - [`Flowchart`]: The root node representing an entire flowchart program
-//! - [`Node`]: Individual nodes in the flowchart (Start, End, Process, Condition)
+//! - [`Node`]: Individual nodes in the flowchart (Start, End, Process, Condition, ...)
+//! - [`DecisionTable`]: The rules of a decision table node
 //! - [`Edge`]: Connections between nodes with optional labels
 //! - [`Statement`]: Executable statements within process nodes
 //! - [`Expr`]: Expressions for computations, conditions, and values
@@ -34,9 +35,11 @@ mod expr;
 mod flowchart;
 mod node;
 mod stmt;
+mod table;
 
 pub use edge::{Edge, EdgeLabel};
-pub use expr::{BinaryOp, Expr, TypeName, UnaryOp};
+pub use expr::{Arity, BinaryOp, Expr, Function, TypeName, UnaryOp};
 pub use flowchart::{Direction, Flowchart};
-pub use node::Node;
+pub use node::{LoopIter, Node};
 pub use stmt::Statement;
+pub use table::{CellTest, DecisionTable, HitPolicy, TableInput, TableRule, TableSource};
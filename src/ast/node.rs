@@ -3,7 +3,7 @@
 //! Nodes are the fundamental building blocks of a Mermaid flowchart. Each node
 //! represents a point in the program's control flow.
 
-use super::{Expr, Statement};
+use super::{DecisionTable, Expr, Statement};
 
 /// A node in the flowchart representing a point in program execution.
 ///
@@ -14,10 +14,14 @@ use super::{Expr, Statement};
 ///
 /// | Type | Mermaid Syntax | Purpose |
 /// |------|----------------|---------|
-/// | [`Start`](Node::Start) | `Start` | Entry point (exactly one required) |
-/// | [`End`](Node::End) | `End` | Exit point (at least one required) |
+/// | [`Start`](Node::Start) | `Start` | Default entry point |
+/// | [`Entry`](Node::Entry) | `id([Start name])` | Named entry point |
+/// | [`End`](Node::End) | `End` | Exit point |
+/// | [`Terminal`](Node::Terminal) | `id([End: result])` | Named exit point |
 /// | [`Process`](Node::Process) | `id[statements]` | Execute statements |
 /// | [`Condition`](Node::Condition) | `id{expr?}` | Branch based on condition |
+/// | [`DecisionTable`](Node::DecisionTable) | `id[[table name]]` | Apply a decision table |
+/// | [`Loop`](Node::Loop) | `id{{for i in 1..n}}` | Repeat a body for each value |
 ///
 /// # Examples
 ///
@@ -35,8 +39,9 @@ pub enum Node {
     /// The entry point of the flowchart.
     ///
     /// Every flowchart must have exactly one `Start` node. Execution begins
-    /// here and follows outgoing edges. The `Start` node has a fixed identifier
-    /// `"Start"` and cannot contain statements.
+    /// here (unless an [`Entry`](Node::Entry) is selected) and follows
+    /// outgoing edges. The `Start`
+    /// node has a fixed identifier `"Start"` and cannot contain statements.
     ///
     /// # Mermaid Syntax
     ///
@@ -50,10 +55,39 @@ pub enum Node {
         label: Option<String>,
     },
 
+    /// A named entry point of the flowchart.
+    ///
+    /// A stadium node labeled `Start <name>` is an entry. Callers select it by
+    /// name, and execution then begins here instead of at `Start`, so one
+    /// diagram can describe several related operations that share subflows.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// Start_refund([Start refund]) --> A
+    /// ```
+    ///
+    /// # Fields
+    ///
+    /// - `id`: Unique identifier for referencing in edges
+    /// - `name`: The name used to select this entry
+    /// - `label`: Display label
+    Entry {
+        /// The unique identifier for this node.
+        id: String,
+
+        /// The entry name (`refund` in `Start refund`).
+        name: String,
+
+        /// Optional display label, as written in the diagram.
+        label: Option<String>,
+    },
+
     /// An exit point of the flowchart.
     ///
-    /// Execution terminates when reaching an `End` node. A flowchart must have
-    /// exactly one `End` node. The `End` node has a fixed identifier `"End"`.
+    /// Execution terminates when reaching an `End` node. The `End` node has a
+    /// fixed identifier `"End"`. A flowchart must have at least one terminal
+    /// node: either `End` or a [`Terminal`](Node::Terminal).
     ///
     /// # Mermaid Syntax
     ///
@@ -67,6 +101,49 @@ pub enum Node {
         label: Option<String>,
     },
 
+    /// A named exit point of the flowchart.
+    ///
+    /// Any stadium-shaped node other than `Start`, `End`, and entries is a
+    /// terminal.
+    /// Execution terminates when reaching it, and it must not have outgoing
+    /// edges. A flowchart may have any number of terminals, so distinct
+    /// outcomes (for example "approved" and "rejected") stay distinguishable
+    /// after the run.
+    ///
+    /// A label of the form `End: item, ...` declares the terminal's result
+    /// and default exit code; any other label is for display only.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// B -->|Yes| Approved([End: approved])
+    /// B -->|No| Rejected([End: rejected, exit 1])
+    /// C --> Done([Finished])
+    /// ```
+    ///
+    /// # Fields
+    ///
+    /// - `id`: Unique identifier for referencing in edges
+    /// - `label`: Display label
+    /// - `exit_code`: Exit code used unless the incoming edge specifies one
+    /// - `result`: Name of the outcome this terminal represents
+    Terminal {
+        /// The unique identifier for this node.
+        id: String,
+
+        /// Optional display label, as written in the diagram.
+        label: Option<String>,
+
+        /// The default exit code, declared with `exit N` in the label.
+        ///
+        /// An exit code on the incoming edge takes precedence. If neither is
+        /// given, the exit code is `0`.
+        exit_code: Option<u8>,
+
+        /// The result declared in the label (e.g. `approved`).
+        result: Option<String>,
+    },
+
     /// A processing node that executes a sequence of statements.
     ///
     /// Process nodes contain one or more statements separated by semicolons.
@@ -125,14 +202,101 @@ pub enum Node {
         /// execution follows the `Yes` edge; otherwise, the `No` edge.
         condition: Expr,
     },
+
+    /// A decision table node.
+    ///
+    /// Evaluates the table's input columns, picks a rule according to the
+    /// table's [`HitPolicy`](super::HitPolicy), assigns the rule's outputs,
+    /// and follows the outgoing edge whose label is the rule's target (or
+    /// the node's single outgoing edge if the table has no target column).
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// P[[table pricing first]]      // Inline table `%% table pricing`
+    /// Q[[table 'tiers.csv']]        // CSV file, unique hit policy
+    /// P -->|bulk| Bulk
+    /// P -->|retail| Retail
+    /// ```
+    ///
+    /// # Fields
+    ///
+    /// - `id`: Unique identifier for referencing in edges
+    /// - `table`: The table's columns and rules
+    DecisionTable {
+        /// The unique identifier for this node.
+        ///
+        /// Used by [`Edge`](super::Edge) to reference this node as a source or target.
+        id: String,
+
+        /// The table's columns and rules.
+        table: DecisionTable,
+    },
+
+    /// A counted loop node.
+    ///
+    /// Each time the node is reached from its body, the next value is
+    /// assigned to the loop variable and the `body` edge is followed. Once
+    /// the values run out, the `done` edge is followed. Reaching the node
+    /// from outside its body starts the loop over. Must have exactly two
+    /// outgoing edges, labeled `body` and `done`, and every path through the
+    /// body must be able to return to the node.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// L{{for i in 1..n}}
+    /// L -->|body| Body
+    /// L -->|done| After
+    /// Body --> L
+    /// ```
+    ///
+    /// # Fields
+    ///
+    /// - `id`: Unique identifier for referencing in edges
+    /// - `var`: The loop variable
+    /// - `iter`: The values the loop variable takes
+    Loop {
+        /// The unique identifier for this node.
+        ///
+        /// Used by [`Edge`](super::Edge) to reference this node as a source or target.
+        id: String,
+
+        /// The name of the variable assigned on each iteration.
+        var: String,
+
+        /// The values the loop variable takes, evaluated when the loop starts.
+        iter: LoopIter,
+    },
+}
+
+/// The values a [`Loop`](Node::Loop) node iterates over.
+#[derive(Debug, Clone, PartialEq)]
+pub enum LoopIter {
+    /// The integers from `start` to `end`, inclusive (`for i in 1..n`).
+    ///
+    /// The loop body does not run if `start` is greater than `end`.
+    Range {
+        /// The first value.
+        start: Expr,
+
+        /// The last value.
+        end: Expr,
+    },
+
+    /// A list of values (`for x in a, b, c`).
+    ///
+    /// A list of a single string iterates over its characters
+    /// (`for c in word`).
+    Values(Vec<Expr>),
 }
 
 impl Node {
     /// Returns the identifier of this node.
     ///
     /// For `Start` and `End` nodes, returns the fixed strings `"Start"` and
-    /// `"End"` respectively. For `Process` and `Condition` nodes, returns
-    /// the user-defined identifier.
+    /// `"End"` respectively. For all other nodes, returns the user-defined
+    /// identifier.
     ///
     /// # Examples
     ///
@@ -146,8 +310,27 @@ impl Node {
         match self {
             Node::Start { .. } => "Start",
             Node::End { .. } => "End",
+            Node::Entry { id, .. } => id,
+            Node::Terminal { id, .. } => id,
             Node::Process { id, .. } => id,
             Node::Condition { id, .. } => id,
+            Node::DecisionTable { id, .. } => id,
+            Node::Loop { id, .. } => id,
         }
     }
+
+    /// Returns `true` if execution stops at this node (`End` or a
+    /// [`Terminal`](Node::Terminal)).
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::ast::Node;
+    ///
+    /// assert!(Node::End { label: None }.is_terminal());
+    /// assert!(!Node::Start { label: None }.is_terminal());
+    /// ```
+    pub fn is_terminal(&self) -> bool {
+        matches!(self, Node::End { .. } | Node::Terminal { .. })
+    }
 }
@@ -4,7 +4,7 @@
 //! expressions which produce values, statements perform actions like outputting
 //! text, signaling errors, or storing values in variables.
 
-use super::Expr;
+use super::{BinaryOp, Expr};
 
 /// An executable statement within a process node.
 ///
@@ -16,9 +16,16 @@ use super::Expr;
 /// | Variant | Mermaid Syntax | Description |
 /// |---------|----------------|-------------|
 /// | [`Assign`](Statement::Assign) | `x = expr` | Store value in variable |
+/// | [`CompoundAssign`](Statement::CompoundAssign) | `x += expr` | Update variable with an operator |
+/// | [`ParallelAssign`](Statement::ParallelAssign) | `a, b = b, a` | Store several values at once |
+/// | [`Const`](Statement::Const) | `const X = expr` | Define a variable that cannot change |
+/// | [`Unset`](Statement::Unset) | `unset x` | Remove a variable |
 /// | [`Println`](Statement::Println) | `println expr` | Write to stdout with newline |
 /// | [`Print`](Statement::Print) | `print expr` | Write to stdout without newline |
 /// | [`Error`](Statement::Error) | `error expr` | Write to stderr and terminate |
+/// | [`Sleep`](Statement::Sleep) | `sleep expr` | Pause for a number of milliseconds |
+/// | [`Exit`](Statement::Exit) | `exit expr` | Set the program's exit code |
+/// | [`Return`](Statement::Return) | `return expr` | Set the program's result value |
 ///
 /// # Examples
 ///
@@ -57,6 +64,87 @@ pub enum Statement {
         value: Expr,
     },
 
+    /// Update a variable by applying an arithmetic operator to it.
+    ///
+    /// `x op= expr` is equivalent to `x = x op expr`: the variable must
+    /// already be defined, and the operator follows the usual typing rules
+    /// (so `+=` also concatenates strings).
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// total += price
+    /// count -= 1
+    /// label += '!'
+    /// ```
+    CompoundAssign {
+        /// The name of the variable to update.
+        variable: String,
+
+        /// The operator: one of `+`, `-`, `*`, `/`, or `%`.
+        op: BinaryOp,
+
+        /// The right-hand operand.
+        value: Expr,
+    },
+
+    /// Assign several variables at once.
+    ///
+    /// Every expression on the right-hand side is evaluated, left to right,
+    /// before any variable is assigned, so `a, b = b, a` swaps two values.
+    /// `input` expressions on the right-hand side therefore read lines in
+    /// left-to-right order.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// a, b = b, a + b
+    /// x, y = input as int, input as int
+    /// ```
+    ParallelAssign {
+        /// The names of the variables to assign to, all distinct.
+        variables: Vec<String>,
+
+        /// One expression per variable, in the same order.
+        values: Vec<Expr>,
+    },
+
+    /// Define a constant.
+    ///
+    /// Works like [`Assign`](Statement::Assign), but the variable can never
+    /// be assigned again or unset. Statements that would change a constant
+    /// are rejected during validation; the rest (such as a `const` in a loop
+    /// body that runs twice) fail at runtime.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// const TAX_RATE = 8
+    /// const GREETING = 'Hello'
+    /// ```
+    Const {
+        /// The name of the constant.
+        variable: String,
+
+        /// The expression whose value will be stored.
+        value: Expr,
+    },
+
+    /// Remove a variable.
+    ///
+    /// Afterwards the variable is undefined, as if it had never been
+    /// assigned. Unsetting a variable that is not defined does nothing.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// unset tmp
+    /// ```
+    Unset {
+        /// The name of the variable to remove.
+        variable: String,
+    },
+
     /// Print a value to standard output.
     ///
     /// Evaluates the expression and writes the result to stdout, followed
@@ -120,4 +208,68 @@ pub enum Statement {
         /// The expression to evaluate and display as an error message.
         message: Expr,
     },
+
+    /// Pause execution for a number of milliseconds.
+    ///
+    /// Evaluates the expression, which must be a non-negative integer, and
+    /// waits on the interpreter's [`Clock`](crate::runtime::Clock). With a
+    /// fake clock, the wait completes immediately and only advances the
+    /// clock's time.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// sleep 500
+    /// sleep delay * 1000
+    /// ```
+    Sleep {
+        /// The expression giving the duration in milliseconds.
+        duration: Expr,
+    },
+
+    /// Set the exit code the program ends with.
+    ///
+    /// Evaluates the expression, which must be an integer between 0 and 255
+    /// (see [`ExitCodePolicy`](crate::runtime::ExitCodePolicy) for
+    /// out-of-range values). An exit code on the edge into the terminal node
+    /// takes precedence, and a later `exit` replaces the code.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// exit errors
+    /// exit 3
+    /// ```
+    ///
+    /// # Behavior
+    ///
+    /// Like every statement, `exit` does not change control flow: execution
+    /// continues along the node's edge until a terminal node is reached.
+    Exit {
+        /// The expression giving the exit code.
+        code: Expr,
+    },
+
+    /// Set the value the program produces for its host.
+    ///
+    /// Evaluates the expression and records it as the run's result, which
+    /// embedders receive in [`RunOutcome`](crate::runtime::RunOutcome). A
+    /// later `return` replaces the value.
+    ///
+    /// # Mermaid Syntax
+    ///
+    /// ```text
+    /// return total
+    /// return 'approved'
+    /// ```
+    ///
+    /// # Behavior
+    ///
+    /// Like every statement, `return` does not change control flow: the
+    /// remaining statements run and execution continues along the node's
+    /// edge until a terminal node is reached.
+    Return {
+        /// The expression giving the result value.
+        value: Expr,
+    },
 }
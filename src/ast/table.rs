@@ -0,0 +1,108 @@
+//! Decision table definitions.
+//!
+//! A decision table replaces a tree of condition nodes with a table of
+//! rules. Each row is a rule: its input cells test values, and its output
+//! cells assign variables and optionally choose the outgoing edge to follow.
+
+use super::{BinaryOp, Expr};
+
+/// The rules of a [`DecisionTable`](super::Node::DecisionTable) node.
+///
+/// # Table Format
+///
+/// Tables are written as CSV, either in a separate file or inline in a
+/// block of `%%` comments. The first row is the header:
+///
+/// | Header | Column kind | Cells |
+/// |--------|-------------|-------|
+/// | `expr` | Input | Tests such as `>= 100`, `'gold'`, or `-` (any value) |
+/// | `name =` | Output | Expressions assigned to the variable `name` |
+/// | `->` | Target | Label of the outgoing edge to follow |
+///
+/// ```text
+/// %% table pricing first
+/// %% qty,    member, price =, ->
+/// %% >= 100, true,   80,      bulk
+/// %% >= 100, -,      90,      bulk
+/// %% -,      -,      100,     retail
+/// ```
+#[derive(Debug, Clone, PartialEq)]
+pub struct DecisionTable {
+    /// Where the table's rows are defined.
+    pub source: TableSource,
+
+    /// How a rule is chosen when several match.
+    pub hit_policy: HitPolicy,
+
+    /// The input columns, evaluated once each time the node is reached.
+    pub inputs: Vec<TableInput>,
+
+    /// The variables assigned by the output columns.
+    pub outputs: Vec<String>,
+
+    /// Whether the table has a target (`->`) column.
+    ///
+    /// If so, each rule names the outgoing edge to follow by its label.
+    /// Otherwise the node has a single outgoing edge.
+    pub has_target: bool,
+
+    /// The rules, in table order.
+    pub rules: Vec<TableRule>,
+}
+
+/// Where a decision table's rows are defined.
+#[derive(Debug, Clone, PartialEq)]
+pub enum TableSource {
+    /// An inline table declared in a `%% table <name>` comment block.
+    Inline(String),
+
+    /// A CSV file, resolved relative to the program's directory.
+    File(String),
+}
+
+/// How a decision table chooses among matching rules.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum HitPolicy {
+    /// Exactly one rule must match (the default).
+    ///
+    /// Overlapping rules are rejected when the table is parsed, where
+    /// possible, and at runtime otherwise.
+    #[default]
+    Unique,
+
+    /// The first matching rule, in table order, is used.
+    First,
+}
+
+/// An input column of a decision table.
+#[derive(Debug, Clone, PartialEq)]
+pub struct TableInput {
+    /// The header text, used in messages.
+    pub header: String,
+
+    /// The expression the column's tests are applied to.
+    pub expr: Expr,
+}
+
+/// A row of a decision table.
+#[derive(Debug, Clone, PartialEq)]
+pub struct TableRule {
+    /// One test per input column; `None` matches any value.
+    pub tests: Vec<Option<CellTest>>,
+
+    /// One value per output column; `None` leaves the variable unchanged.
+    pub values: Vec<Option<Expr>>,
+
+    /// The label of the edge to follow, if the table has a target column.
+    pub target: Option<String>,
+}
+
+/// A test in an input cell, such as `>= 100` or `'gold'` (equality).
+#[derive(Debug, Clone, PartialEq)]
+pub struct CellTest {
+    /// The comparison operator applied as `input op value`.
+    pub op: BinaryOp,
+
+    /// The value the input is compared with.
+    pub value: Expr,
+}
@@ -0,0 +1,925 @@
+//! On-disk cache of parsed programs for `--cache` and `--cache-dir`.
+//!
+//! Parsing and validating a flowchart with tens of thousands of nodes takes
+//! noticeably longer than running it. With a cache enabled, the validated
+//! [`Flowchart`] is written to the cache directory after the first
+//! successful parse, and later runs of the same program read it back
+//! instead of parsing again.
+//!
+//! # Invalidation
+//!
+//! Each entry is stored under a hash of the merx version, the encoding, the
+//! program's directory and its source text, so editing the program or
+//! upgrading merx selects a different entry. An entry also records the full source and the
+//! hash of every CSV decision table file it was built from, and is ignored
+//! if any of them differ when it is read. Unreadable or corrupt entries are
+//! ignored too: the program is simply parsed again and the entry replaced.
+//!
+//! Stale entries are never read, but are not removed either; the cache
+//! directory can be deleted at any time.
+//!
+//! # Format
+//!
+//! Entries use a compact binary encoding private to this module. Integers
+//! are little-endian, strings and lists are prefixed with their length, and
+//! enum variants with a one-byte tag.
+//!
+//! Rather than a version number that has to be bumped by hand, the encoding
+//! is identified by a hash of the source code of this module and of the AST
+//! types, computed when merx is compiled. Any change to either, such as a
+//! new [`Statement`] variant or renumbered tags, gives entries a different
+//! key, even between builds that share a version number.
+
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use merx::ast::{
+    BinaryOp, CellTest, DecisionTable, Direction, Edge, EdgeLabel, Expr, Flowchart, Function,
+    HitPolicy, LoopIter, Node, Statement, TableInput, TableRule, TableSource, TypeName, UnaryOp,
+};
+
+/// The default cache directory for `--cache`, relative to the current
+/// directory.
+pub const DEFAULT_DIR: &str = ".merx-cache";
+
+/// Identifies a cache entry file.
+const MAGIC: &[u8] = b"MERXCACHE";
+
+/// Identifies the entry encoding: a hash of the source code that defines
+/// it, namely this module and the AST it encodes.
+const SCHEMA: u64 = {
+    let sources = [
+        include_str!("cache.rs"),
+        include_str!("ast/mod.rs"),
+        include_str!("ast/edge.rs"),
+        include_str!("ast/expr.rs"),
+        include_str!("ast/flowchart.rs"),
+        include_str!("ast/node.rs"),
+        include_str!("ast/stmt.rs"),
+        include_str!("ast/table.rs"),
+    ];
+    let mut hasher = Fnv::new();
+    let mut i = 0;
+    while i < sources.len() {
+        hasher.write(sources[i].as_bytes());
+        i += 1;
+    }
+    hasher.finish()
+};
+
+/// The merx version, which entries must match.
+const VERSION: &str = env!("CARGO_PKG_VERSION");
+
+/// A directory of cached programs.
+pub struct Cache {
+    dir: PathBuf,
+}
+
+impl Cache {
+    /// Creates a cache stored in `dir`. The directory is created when the
+    /// first entry is written.
+    pub fn new(dir: impl Into<PathBuf>) -> Self {
+        Self { dir: dir.into() }
+    }
+
+    /// Returns the cached flowchart for a program, if there is an entry that
+    /// is still valid.
+    ///
+    /// # Arguments
+    ///
+    /// * `source` - The program's source text
+    /// * `dir` - The directory decision table files are resolved against
+    pub fn load(&self, source: &str, dir: &Path) -> Option<Flowchart> {
+        let bytes = fs::read(self.entry_path(source, dir)).ok()?;
+        decode_entry(&bytes, source, dir)
+    }
+
+    /// Writes the flowchart parsed from a program to the cache.
+    ///
+    /// Failures are ignored, since the cache is only an optimization. The
+    /// entry is written to a temporary file and renamed into place, so
+    /// concurrent runs never see a partly written entry.
+    pub fn store(&self, source: &str, dir: &Path, flowchart: &Flowchart) {
+        let Some(bytes) = encode_entry(source, dir, flowchart) else {
+            return;
+        };
+        let path = self.entry_path(source, dir);
+        let temp = path.with_extension(format!("tmp{}", std::process::id()));
+        if fs::create_dir_all(&self.dir).is_err() || fs::write(&temp, bytes).is_err() {
+            return;
+        }
+        if fs::rename(&temp, &path).is_err() {
+            let _ = fs::remove_file(&temp);
+        }
+    }
+
+    /// Returns the file that holds the entry for a program.
+    fn entry_path(&self, source: &str, dir: &Path) -> PathBuf {
+        let mut hasher = Fnv::new();
+        hasher.write(VERSION.as_bytes());
+        hasher.write(&[0]);
+        hasher.write(&SCHEMA.to_le_bytes());
+        hasher.write(dir.to_string_lossy().as_bytes());
+        hasher.write(&[0]);
+        hasher.write(source.as_bytes());
+        self.dir.join(format!("{:016x}.bin", hasher.finish()))
+    }
+}
+
+/// The 64-bit FNV-1a hash.
+struct Fnv(u64);
+
+impl Fnv {
+    const fn new() -> Self {
+        Fnv(0xcbf2_9ce4_8422_2325)
+    }
+
+    // A `while` loop, so that `SCHEMA` can be hashed at compile time
+    const fn write(&mut self, bytes: &[u8]) {
+        let mut i = 0;
+        while i < bytes.len() {
+            self.0 ^= bytes[i] as u64;
+            self.0 = self.0.wrapping_mul(0x0000_0100_0000_01b3);
+            i += 1;
+        }
+    }
+
+    const fn finish(&self) -> u64 {
+        self.0
+    }
+}
+
+/// Hashes the contents of a file.
+fn hash_bytes(bytes: &[u8]) -> u64 {
+    let mut hasher = Fnv::new();
+    hasher.write(bytes);
+    hasher.finish()
+}
+
+/// Returns the decision table files a flowchart was built from.
+fn table_files(flowchart: &Flowchart) -> Vec<&str> {
+    flowchart
+        .nodes
+        .iter()
+        .filter_map(|node| match node {
+            Node::DecisionTable {
+                table:
+                    DecisionTable {
+                        source: TableSource::File(path),
+                        ..
+                    },
+                ..
+            } => Some(path.as_str()),
+            _ => None,
+        })
+        .collect()
+}
+
+/// Encodes a cache entry, or returns `None` if a decision table file can no
+/// longer be read.
+fn encode_entry(source: &str, dir: &Path, flowchart: &Flowchart) -> Option<Vec<u8>> {
+    let mut enc = Encoder::default();
+    enc.buf.extend_from_slice(MAGIC);
+    enc.u64(SCHEMA);
+    enc.str(VERSION);
+    enc.str(source);
+    let files = table_files(flowchart);
+    enc.len(files.len());
+    for path in files {
+        let bytes = fs::read(dir.join(path)).ok()?;
+        enc.str(path);
+        enc.u64(hash_bytes(&bytes));
+    }
+    enc.flowchart(flowchart);
+    Some(enc.buf)
+}
+
+/// Decodes a cache entry, or returns `None` if it is corrupt or does not
+/// match the program.
+fn decode_entry(bytes: &[u8], source: &str, dir: &Path) -> Option<Flowchart> {
+    let mut dec = Decoder { bytes, pos: 0 };
+    if dec.take(MAGIC.len())? != MAGIC || dec.u64()? != SCHEMA || dec.string()? != VERSION {
+        return None;
+    }
+    if dec.string()? != source {
+        return None;
+    }
+    for _ in 0..dec.len()? {
+        let path = dec.string()?;
+        let hash = dec.u64()?;
+        if hash_bytes(&fs::read(dir.join(path)).ok()?) != hash {
+            return None;
+        }
+    }
+    let flowchart = dec.flowchart()?;
+    (dec.pos == bytes.len()).then_some(flowchart)
+}
+
+/// Writes values in the entry encoding.
+#[derive(Default)]
+struct Encoder {
+    buf: Vec<u8>,
+}
+
+impl Encoder {
+    fn u8(&mut self, n: u8) {
+        self.buf.push(n);
+    }
+
+    fn u64(&mut self, n: u64) {
+        self.buf.extend_from_slice(&n.to_le_bytes());
+    }
+
+    fn len(&mut self, n: usize) {
+        self.u64(n as u64);
+    }
+
+    fn bool(&mut self, b: bool) {
+        self.u8(u8::from(b));
+    }
+
+    fn str(&mut self, s: &str) {
+        self.len(s.len());
+        self.buf.extend_from_slice(s.as_bytes());
+    }
+
+    fn option<T>(&mut self, value: Option<&T>, mut put: impl FnMut(&mut Self, &T)) {
+        match value {
+            None => self.u8(0),
+            Some(v) => {
+                self.u8(1);
+                put(self, v);
+            }
+        }
+    }
+
+    fn list<T>(&mut self, values: &[T], mut put: impl FnMut(&mut Self, &T)) {
+        self.len(values.len());
+        for v in values {
+            put(self, v);
+        }
+    }
+
+    fn flowchart(&mut self, flowchart: &Flowchart) {
+        self.u8(match flowchart.direction {
+            Direction::Td => 0,
+            Direction::Tb => 1,
+            Direction::Lr => 2,
+            Direction::Rl => 3,
+            Direction::Bt => 4,
+        });
+        self.list(&flowchart.nodes, Self::node);
+        self.list(&flowchart.edges, Self::edge);
+    }
+
+    fn node(&mut self, node: &Node) {
+        match node {
+            Node::Start { label } => {
+                self.u8(0);
+                self.option(label.as_ref(), |e, s| e.str(s));
+            }
+            Node::Entry { id, name, label } => {
+                self.u8(1);
+                self.str(id);
+                self.str(name);
+                self.option(label.as_ref(), |e, s| e.str(s));
+            }
+            Node::End { label } => {
+                self.u8(2);
+                self.option(label.as_ref(), |e, s| e.str(s));
+            }
+            Node::Terminal {
+                id,
+                label,
+                exit_code,
+                result,
+            } => {
+                self.u8(3);
+                self.str(id);
+                self.option(label.as_ref(), |e, s| e.str(s));
+                self.option(exit_code.as_ref(), |e, &n| e.u8(n));
+                self.option(result.as_ref(), |e, s| e.str(s));
+            }
+            Node::Process { id, statements } => {
+                self.u8(4);
+                self.str(id);
+                self.list(statements, Self::statement);
+            }
+            Node::Condition { id, condition } => {
+                self.u8(5);
+                self.str(id);
+                self.expr(condition);
+            }
+            Node::DecisionTable { id, table } => {
+                self.u8(6);
+                self.str(id);
+                self.table(table);
+            }
+            Node::Loop { id, var, iter } => {
+                self.u8(7);
+                self.str(id);
+                self.str(var);
+                match iter {
+                    LoopIter::Range { start, end } => {
+                        self.u8(0);
+                        self.expr(start);
+                        self.expr(end);
+                    }
+                    LoopIter::Values(values) => {
+                        self.u8(1);
+                        self.list(values, Self::expr);
+                    }
+                }
+            }
+        }
+    }
+
+    fn table(&mut self, table: &DecisionTable) {
+        match &table.source {
+            TableSource::Inline(name) => {
+                self.u8(0);
+                self.str(name);
+            }
+            TableSource::File(path) => {
+                self.u8(1);
+                self.str(path);
+            }
+        }
+        self.u8(match table.hit_policy {
+            HitPolicy::Unique => 0,
+            HitPolicy::First => 1,
+        });
+        self.list(&table.inputs, |e, input| {
+            e.str(&input.header);
+            e.expr(&input.expr);
+        });
+        self.list(&table.outputs, |e, s| e.str(s));
+        self.bool(table.has_target);
+        self.list(&table.rules, |e, rule| {
+            e.list(&rule.tests, |e, test| {
+                e.option(test.as_ref(), |e, test| {
+                    e.binary_op(test.op);
+                    e.expr(&test.value);
+                });
+            });
+            e.list(&rule.values, |e, value| {
+                e.option(value.as_ref(), Self::expr)
+            });
+            e.option(rule.target.as_ref(), |e, s| e.str(s));
+        });
+    }
+
+    fn edge(&mut self, edge: &Edge) {
+        self.str(&edge.from);
+        self.str(&edge.to);
+        self.option(edge.label.as_ref(), |e, label| match label {
+            EdgeLabel::Yes => e.u8(0),
+            EdgeLabel::No => e.u8(1),
+            EdgeLabel::Custom(s) => {
+                e.u8(2);
+                e.str(s);
+            }
+            EdgeLabel::Guard(expr) => {
+                e.u8(3);
+                e.expr(expr);
+            }
+            EdgeLabel::Else => e.u8(4),
+        });
+        self.option(edge.exit_code.as_ref(), Self::expr);
+        self.option(edge.return_value.as_ref(), Self::expr);
+        self.list(&edge.actions, Self::statement);
+    }
+
+    fn statement(&mut self, stmt: &Statement) {
+        match stmt {
+            Statement::Assign { variable, value } => {
+                self.u8(0);
+                self.str(variable);
+                self.expr(value);
+            }
+            Statement::CompoundAssign {
+                variable,
+                op,
+                value,
+            } => {
+                self.u8(1);
+                self.str(variable);
+                self.binary_op(*op);
+                self.expr(value);
+            }
+            Statement::ParallelAssign { variables, values } => {
+                self.u8(2);
+                self.list(variables, |e, s| e.str(s));
+                self.list(values, Self::expr);
+            }
+            Statement::Const { variable, value } => {
+                self.u8(3);
+                self.str(variable);
+                self.expr(value);
+            }
+            Statement::Unset { variable } => {
+                self.u8(4);
+                self.str(variable);
+            }
+            Statement::Println { expr } => {
+                self.u8(5);
+                self.expr(expr);
+            }
+            Statement::Print { expr } => {
+                self.u8(6);
+                self.expr(expr);
+            }
+            Statement::Error { message } => {
+                self.u8(7);
+                self.expr(message);
+            }
+            Statement::Sleep { duration } => {
+                self.u8(8);
+                self.expr(duration);
+            }
+            Statement::Exit { code } => {
+                self.u8(9);
+                self.expr(code);
+            }
+            Statement::Return { value } => {
+                self.u8(10);
+                self.expr(value);
+            }
+        }
+    }
+
+    fn expr(&mut self, expr: &Expr) {
+        match expr {
+            Expr::IntLit { value } => {
+                self.u8(0);
+                self.u64(*value as u64);
+            }
+            Expr::StrLit { value } => {
+                self.u8(1);
+                self.str(value);
+            }
+            Expr::BoolLit { value } => {
+                self.u8(2);
+                self.bool(*value);
+            }
+            Expr::Variable { name } => {
+                self.u8(3);
+                self.str(name);
+            }
+            Expr::Input => self.u8(4),
+            Expr::Unary { op, operand } => {
+                self.u8(5);
+                self.u8(match op {
+                    UnaryOp::Not => 0,
+                    UnaryOp::Neg => 1,
+                });
+                self.expr(operand);
+            }
+            Expr::Binary { op, left, right } => {
+                self.u8(6);
+                self.binary_op(*op);
+                self.expr(left);
+                self.expr(right);
+            }
+            Expr::Cast { expr, target_type } => {
+                self.u8(7);
+                self.expr(expr);
+                self.u8(match target_type {
+                    TypeName::Int => 0,
+                    TypeName::Str => 1,
+                });
+            }
+            Expr::Call { function, args } => {
+                self.u8(8);
+                self.u8(match function {
+                    Function::RandInt => 0,
+                    Function::RandChoice => 1,
+                    Function::Shuffle => 2,
+                    Function::NowMs => 3,
+                    Function::Today => 4,
+                    Function::FormatDate => 5,
+                    Function::ParseDate => 6,
+                });
+                self.list(args, Self::expr);
+            }
+            Expr::Defined { name } => {
+                self.u8(9);
+                self.str(name);
+            }
+        }
+    }
+
+    fn binary_op(&mut self, op: BinaryOp) {
+        self.u8(match op {
+            BinaryOp::Add => 0,
+            BinaryOp::Sub => 1,
+            BinaryOp::Mul => 2,
+            BinaryOp::Div => 3,
+            BinaryOp::Mod => 4,
+            BinaryOp::Eq => 5,
+            BinaryOp::Ne => 6,
+            BinaryOp::Lt => 7,
+            BinaryOp::Le => 8,
+            BinaryOp::Gt => 9,
+            BinaryOp::Ge => 10,
+            BinaryOp::And => 11,
+            BinaryOp::Or => 12,
+        });
+    }
+}
+
+/// Reads values in the entry encoding. Every method returns `None` if the
+/// input is truncated or malformed.
+struct Decoder<'a> {
+    bytes: &'a [u8],
+    pos: usize,
+}
+
+impl<'a> Decoder<'a> {
+    fn take(&mut self, n: usize) -> Option<&'a [u8]> {
+        let end = self
+            .pos
+            .checked_add(n)
+            .filter(|&end| end <= self.bytes.len())?;
+        let bytes = &self.bytes[self.pos..end];
+        self.pos = end;
+        Some(bytes)
+    }
+
+    fn u8(&mut self) -> Option<u8> {
+        self.take(1).map(|b| b[0])
+    }
+
+    fn u64(&mut self) -> Option<u64> {
+        self.take(8)?.try_into().ok().map(u64::from_le_bytes)
+    }
+
+    fn len(&mut self) -> Option<usize> {
+        self.u64()?.try_into().ok()
+    }
+
+    fn bool(&mut self) -> Option<bool> {
+        match self.u8()? {
+            0 => Some(false),
+            1 => Some(true),
+            _ => None,
+        }
+    }
+
+    fn string(&mut self) -> Option<String> {
+        let len = self.len()?;
+        String::from_utf8(self.take(len)?.to_vec()).ok()
+    }
+
+    fn option<T>(&mut self, mut get: impl FnMut(&mut Self) -> Option<T>) -> Option<Option<T>> {
+        match self.u8()? {
+            0 => Some(None),
+            1 => get(self).map(Some),
+            _ => None,
+        }
+    }
+
+    fn list<T>(&mut self, mut get: impl FnMut(&mut Self) -> Option<T>) -> Option<Vec<T>> {
+        (0..self.len()?).map(|_| get(self)).collect()
+    }
+
+    fn flowchart(&mut self) -> Option<Flowchart> {
+        let direction = match self.u8()? {
+            0 => Direction::Td,
+            1 => Direction::Tb,
+            2 => Direction::Lr,
+            3 => Direction::Rl,
+            4 => Direction::Bt,
+            _ => return None,
+        };
+        Some(Flowchart {
+            direction,
+            nodes: self.list(Self::node)?,
+            edges: self.list(Self::edge)?,
+        })
+    }
+
+    fn node(&mut self) -> Option<Node> {
+        Some(match self.u8()? {
+            0 => Node::Start {
+                label: self.option(Self::string)?,
+            },
+            1 => Node::Entry {
+                id: self.string()?,
+                name: self.string()?,
+                label: self.option(Self::string)?,
+            },
+            2 => Node::End {
+                label: self.option(Self::string)?,
+            },
+            3 => Node::Terminal {
+                id: self.string()?,
+                label: self.option(Self::string)?,
+                exit_code: self.option(Self::u8)?,
+                result: self.option(Self::string)?,
+            },
+            4 => Node::Process {
+                id: self.string()?,
+                statements: self.list(Self::statement)?,
+            },
+            5 => Node::Condition {
+                id: self.string()?,
+                condition: self.expr()?,
+            },
+            6 => Node::DecisionTable {
+                id: self.string()?,
+                table: self.table()?,
+            },
+            7 => Node::Loop {
+                id: self.string()?,
+                var: self.string()?,
+                iter: match self.u8()? {
+                    0 => LoopIter::Range {
+                        start: self.expr()?,
+                        end: self.expr()?,
+                    },
+                    1 => LoopIter::Values(self.list(Self::expr)?),
+                    _ => return None,
+                },
+            },
+            _ => return None,
+        })
+    }
+
+    fn table(&mut self) -> Option<DecisionTable> {
+        let source = match self.u8()? {
+            0 => TableSource::Inline(self.string()?),
+            1 => TableSource::File(self.string()?),
+            _ => return None,
+        };
+        let hit_policy = match self.u8()? {
+            0 => HitPolicy::Unique,
+            1 => HitPolicy::First,
+            _ => return None,
+        };
+        Some(DecisionTable {
+            source,
+            hit_policy,
+            inputs: self.list(|d| {
+                Some(TableInput {
+                    header: d.string()?,
+                    expr: d.expr()?,
+                })
+            })?,
+            outputs: self.list(Self::string)?,
+            has_target: self.bool()?,
+            rules: self.list(|d| {
+                Some(TableRule {
+                    tests: d.list(|d| {
+                        d.option(|d| {
+                            Some(CellTest {
+                                op: d.binary_op()?,
+                                value: d.expr()?,
+                            })
+                        })
+                    })?,
+                    values: d.list(|d| d.option(Self::expr))?,
+                    target: d.option(Self::string)?,
+                })
+            })?,
+        })
+    }
+
+    fn edge(&mut self) -> Option<Edge> {
+        Some(Edge {
+            from: self.string()?,
+            to: self.string()?,
+            label: self.option(|d| {
+                Some(match d.u8()? {
+                    0 => EdgeLabel::Yes,
+                    1 => EdgeLabel::No,
+                    2 => EdgeLabel::Custom(d.string()?),
+                    3 => EdgeLabel::Guard(d.expr()?),
+                    4 => EdgeLabel::Else,
+                    _ => return None,
+                })
+            })?,
+            exit_code: self.option(Self::expr)?,
+            return_value: self.option(Self::expr)?,
+            actions: self.list(Self::statement)?,
+        })
+    }
+
+    fn statement(&mut self) -> Option<Statement> {
+        Some(match self.u8()? {
+            0 => Statement::Assign {
+                variable: self.string()?,
+                value: self.expr()?,
+            },
+            1 => Statement::CompoundAssign {
+                variable: self.string()?,
+                op: self.binary_op()?,
+                value: self.expr()?,
+            },
+            2 => Statement::ParallelAssign {
+                variables: self.list(Self::string)?,
+                values: self.list(Self::expr)?,
+            },
+            3 => Statement::Const {
+                variable: self.string()?,
+                value: self.expr()?,
+            },
+            4 => Statement::Unset {
+                variable: self.string()?,
+            },
+            5 => Statement::Println { expr: self.expr()? },
+            6 => Statement::Print { expr: self.expr()? },
+            7 => Statement::Error {
+                message: self.expr()?,
+            },
+            8 => Statement::Sleep {
+                duration: self.expr()?,
+            },
+            9 => Statement::Exit { code: self.expr()? },
+            10 => Statement::Return {
+                value: self.expr()?,
+            },
+            _ => return None,
+        })
+    }
+
+    fn expr(&mut self) -> Option<Expr> {
+        Some(match self.u8()? {
+            0 => Expr::IntLit {
+                value: self.u64()? as i64,
+            },
+            1 => Expr::StrLit {
+                value: self.string()?,
+            },
+            2 => Expr::BoolLit {
+                value: self.bool()?,
+            },
+            3 => Expr::Variable {
+                name: self.string()?,
+            },
+            4 => Expr::Input,
+            5 => Expr::Unary {
+                op: match self.u8()? {
+                    0 => UnaryOp::Not,
+                    1 => UnaryOp::Neg,
+                    _ => return None,
+                },
+                operand: Box::new(self.expr()?),
+            },
+            6 => Expr::Binary {
+                op: self.binary_op()?,
+                left: Box::new(self.expr()?),
+                right: Box::new(self.expr()?),
+            },
+            7 => Expr::Cast {
+                expr: Box::new(self.expr()?),
+                target_type: match self.u8()? {
+                    0 => TypeName::Int,
+                    1 => TypeName::Str,
+                    _ => return None,
+                },
+            },
+            8 => Expr::Call {
+                function: match self.u8()? {
+                    0 => Function::RandInt,
+                    1 => Function::RandChoice,
+                    2 => Function::Shuffle,
+                    3 => Function::NowMs,
+                    4 => Function::Today,
+                    5 => Function::FormatDate,
+                    6 => Function::ParseDate,
+                    _ => return None,
+                },
+                args: self.list(Self::expr)?,
+            },
+            9 => Expr::Defined {
+                name: self.string()?,
+            },
+            _ => return None,
+        })
+    }
+
+    fn binary_op(&mut self) -> Option<BinaryOp> {
+        Some(match self.u8()? {
+            0 => BinaryOp::Add,
+            1 => BinaryOp::Sub,
+            2 => BinaryOp::Mul,
+            3 => BinaryOp::Div,
+            4 => BinaryOp::Mod,
+            5 => BinaryOp::Eq,
+            6 => BinaryOp::Ne,
+            7 => BinaryOp::Lt,
+            8 => BinaryOp::Le,
+            9 => BinaryOp::Gt,
+            10 => BinaryOp::Ge,
+            11 => BinaryOp::And,
+            12 => BinaryOp::Or,
+            _ => return None,
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use merx::parser;
+
+    /// Creates a fresh directory under the system temp dir for one test.
+    fn temp_dir(name: &str) -> PathBuf {
+        let dir = std::env::temp_dir().join(format!("merx-cache-{}-{}", name, std::process::id()));
+        let _ = fs::remove_dir_all(&dir);
+        fs::create_dir_all(&dir).unwrap();
+        dir
+    }
+
+    const PROGRAM: &str = r#"flowchart TD
+    Start --> A[const LIMIT = 3; x, y = 1, -2; s = 'a'; s += x as str]
+    A --> L{{for i in 1..LIMIT}}
+    L -->|body| B[println rand_int(1, 6) + i; print exists(x)]
+    B --> L
+    L -->|done| C{!(x > 0 && input == 'q')?}
+    C -->|Yes| T[[table 'pricing.csv']]
+    C -->|No| G[unset y]
+    T -->|high| End
+    T -->|low| F([End: low, exit 1])
+    G -->|[x >= 1] / x = x * 2| E([Done])
+    G -->|[else]| End
+"#;
+
+    const CSV: &str = "x, price =, ->\n>= 2, 70, high\n< 2, 100, low\n";
+
+    #[test]
+    fn test_round_trip() {
+        let dir = temp_dir("round-trip");
+        fs::write(dir.join("pricing.csv"), CSV).unwrap();
+        let flowchart = parser::parse_in_dir(PROGRAM, &dir).unwrap();
+
+        let bytes = encode_entry(PROGRAM, &dir, &flowchart).unwrap();
+        assert_eq!(decode_entry(&bytes, PROGRAM, &dir), Some(flowchart));
+        assert_eq!(decode_entry(&bytes[..bytes.len() - 1], PROGRAM, &dir), None);
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+
+    #[test]
+    fn test_load_after_store() {
+        let dir = temp_dir("store");
+        let cache = Cache::new(dir.join("cache"));
+        let source = "flowchart TD\n    Start --> A[println 1]\n    A --> End\n";
+        let flowchart = parser::parse(source).unwrap();
+
+        assert_eq!(cache.load(source, &dir), None);
+        cache.store(source, &dir, &flowchart);
+        assert_eq!(cache.load(source, &dir), Some(flowchart));
+        assert_eq!(cache.load(&source.replace('1', "2"), &dir), None);
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+
+    #[test]
+    fn test_changed_table_file_invalidates() {
+        let dir = temp_dir("table");
+        fs::write(dir.join("pricing.csv"), CSV).unwrap();
+        let cache = Cache::new(dir.join("cache"));
+        let flowchart = parser::parse_in_dir(PROGRAM, &dir).unwrap();
+        cache.store(PROGRAM, &dir, &flowchart);
+        assert!(cache.load(PROGRAM, &dir).is_some());
+
+        fs::write(dir.join("pricing.csv"), CSV.replace("70", "75")).unwrap();
+        assert_eq!(cache.load(PROGRAM, &dir), None);
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+
+    #[test]
+    fn test_other_schema_is_ignored() {
+        let dir = temp_dir("schema");
+        let source = "flowchart TD\n    Start --> End\n";
+        let mut bytes = encode_entry(source, &dir, &parser::parse(source).unwrap()).unwrap();
+        assert!(decode_entry(&bytes, source, &dir).is_some());
+
+        // An entry written by a build whose AST or encoding differs
+        let at = MAGIC.len();
+        bytes[at..at + 8].copy_from_slice(&(SCHEMA ^ 1).to_le_bytes());
+        assert_eq!(decode_entry(&bytes, source, &dir), None);
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+
+    #[test]
+    fn test_corrupt_entry_is_ignored() {
+        let dir = temp_dir("corrupt");
+        let cache = Cache::new(dir.join("cache"));
+        let source = "flowchart TD\n    Start --> End\n";
+        cache.store(source, &dir, &parser::parse(source).unwrap());
+
+        let entry = cache.entry_path(source, &dir);
+        let mut bytes = fs::read(&entry).unwrap();
+        let last = bytes.len() - 1;
+        bytes[last] = 0xff;
+        fs::write(&entry, bytes).unwrap();
+        assert_eq!(cache.load(source, &dir), None);
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+}
@@ -0,0 +1,1223 @@
+//! Long-form explanations of error codes, printed by `merx explain`.
+
+use std::fmt;
+
+use super::ErrorCode;
+
+/// A long-form description of an [`ErrorCode`], with an example flowchart
+/// that causes the error and a corrected one.
+///
+/// The [`Display`](fmt::Display) implementation formats the explanation as
+/// `merx explain` prints it.
+///
+/// # Examples
+///
+/// ```
+/// use merx::codes::ErrorCode;
+///
+/// let explanation = ErrorCode::MissingYesEdge.explanation();
+/// assert!(explanation.wrong.unwrap().contains("B -->|No| End"));
+/// assert!(explanation.to_string().starts_with("MX0101: missing Yes edge\n"));
+/// ```
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct Explanation {
+    /// The code being explained.
+    pub code: ErrorCode,
+
+    /// What the error means and how to fix it, in one or more paragraphs.
+    pub description: &'static str,
+
+    /// A program that causes the error, if one can be shown on its own.
+    pub wrong: Option<&'static str>,
+
+    /// The same program with the error fixed.
+    pub right: Option<&'static str>,
+}
+
+impl ErrorCode {
+    /// Returns the long-form explanation of this code.
+    pub fn explanation(&self) -> Explanation {
+        let (description, wrong, right) = match self {
+            ErrorCode::Internal => (
+                "merx failed in a way that should not be possible, such as the parser producing \
+                 a syntax tree it does not expect. This is a bug in merx, not in your program.\n\n\
+                 Please report it, together with the program that triggers it.",
+                None,
+                None,
+            ),
+            ErrorCode::InvalidSyntax => (
+                "The source does not match the flowchart grammar. The error points at the line \
+                 and column where parsing failed, and lists what was expected there.\n\n\
+                 Common causes are an unfinished expression, an unclosed bracket or quote, and a \
+                 missing `flowchart TD` header. `merx check` reports every syntax error in the \
+                 file at once.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = ]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> End",
+                ),
+            ),
+            ErrorCode::IntegerOutOfRange => (
+                "An integer literal is too large to be stored. Integers are 64-bit, so a literal \
+                 must be at most 9223372036854775807.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 99999999999999999999]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 9223372036854775807]
+    A --> End",
+                ),
+            ),
+            ErrorCode::UnknownFunction => (
+                "A function is called that merx does not provide. The built-in functions are \
+                 rand_int, rand_choice, shuffle, now_ms, today, format_date, parse_date, exists \
+                 and defined.",
+                Some(
+                    "flowchart TD
+    Start --> A[roll = random(1, 6)]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[roll = rand_int(1, 6)]
+    A --> End",
+                ),
+            ),
+            ErrorCode::WrongArgumentCount => (
+                "A built-in function is called with more or fewer arguments than it takes. For \
+                 example, rand_int takes a lower and an upper bound, and exists takes a single \
+                 variable name.",
+                Some(
+                    "flowchart TD
+    Start --> A[roll = rand_int(6)]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[roll = rand_int(1, 6)]
+    A --> End",
+                ),
+            ),
+            ErrorCode::InvalidEdgeLabel => (
+                "An edge label cannot be understood. An edge may have an inline label \
+                 (`-- text -->`) or a pipe label (`-->|text|`), but not both. After a guard, only \
+                 `, exit N` or `, return expr` may follow, and `return` needs a value.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -- Yes -->|Yes| End
+    B -->|No| End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|Yes| End
+    B -->|No| End",
+                ),
+            ),
+            ErrorCode::InvalidExitCode => (
+                "An exit code written as a number must be an integer from 0 to 255, because \
+                 that is the range of process exit codes. `exit` on an edge label also needs a \
+                 value.",
+                Some(
+                    "flowchart TD
+    Start --> A[ok = false]
+    A --> B{ok?}
+    B -->|Yes| End
+    B -->|No, exit 256| End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[ok = false]
+    A --> B{ok?}
+    B -->|Yes| End
+    B -->|No, exit 1| End",
+                ),
+            ),
+            ErrorCode::AssignmentCountMismatch => (
+                "A parallel assignment has a different number of values than variables. Each \
+                 variable on the left of `=` takes the value at the same position on the right.",
+                Some(
+                    "flowchart TD
+    Start --> A[a, b = 1, 2, 3]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[a, b = 1, 2]
+    A --> End",
+                ),
+            ),
+            ErrorCode::DuplicateAssignment => (
+                "A parallel assignment names the same variable twice on the left of `=`, so it \
+                 is unclear which value the variable ends up with.",
+                Some(
+                    "flowchart TD
+    Start --> A[a, a = 1, 2]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[a, b = 1, 2]
+    A --> End",
+                ),
+            ),
+            ErrorCode::UnsupportedShape => (
+                "A node uses a shape that has no meaning in merx. Use rect or rounded for \
+                 process nodes, diamond for condition nodes, subroutine for decision tables, \
+                 hex for loops, and stadium for Start, End and other terminal nodes. `Start` \
+                 and `End` must always use a terminal shape.",
+                Some(
+                    "flowchart TD
+    Start --> A@{ shape: cyl, label: \"x = 1\" }
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A@{ shape: rect, label: \"x = 1\" }
+    A --> End",
+                ),
+            ),
+            ErrorCode::MissingLabel => (
+                "A node declared with `@{ ... }` metadata has no `label`. Except for Start and \
+                 End, a node's label holds its statements or condition, so it cannot be left \
+                 out.",
+                Some(
+                    "flowchart TD
+    Start --> A@{ shape: rect }
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A@{ shape: rect, label: \"x = 1\" }
+    A --> End",
+                ),
+            ),
+            ErrorCode::InvalidTerminalLabel => (
+                "A stadium node has a label merx cannot read. An entry node must be labeled \
+                 `Start <name>`, with a name made of letters, digits and underscores. A \
+                 terminal node labeled `End: ...` may declare one result and one `exit N`, \
+                 separated by commas, with no empty items.",
+                Some(
+                    "flowchart TD
+    Start --> A[kind = 'purchase']
+    Refund([Start refund-all]) --> B[kind = 'refund']
+    A --> End
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[kind = 'purchase']
+    Refund([Start refund_all]) --> B[kind = 'refund']
+    A --> End
+    B --> End",
+                ),
+            ),
+            ErrorCode::InvalidNameArgument => (
+                "`exists` and `defined` check whether a variable is set, so they take the \
+                 variable's name rather than a value: `exists` takes a bare name, as in \
+                 `exists(total)`, and `defined` takes the name as a string literal, as in \
+                 `defined('total')`.",
+                Some(
+                    "flowchart TD
+    Start --> A[seen = defined(total)]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[seen = defined('total')]
+    A --> End",
+                ),
+            ),
+            ErrorCode::MissingYesEdge => (
+                "A condition node has no edge labeled `Yes`. A condition node must have exactly \
+                 two outgoing edges, `Yes` and `No`, so that there is somewhere to go whichever \
+                 way the condition turns out.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|No| End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|Yes| C[println 'positive']
+    B -->|No| End
+    C --> End",
+                ),
+            ),
+            ErrorCode::MissingNoEdge => (
+                "A condition node has no edge labeled `No`. A condition node must have exactly \
+                 two outgoing edges, `Yes` and `No`, so that there is somewhere to go whichever \
+                 way the condition turns out.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|Yes| End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|Yes| End
+    B -->|No| C[println 'not positive']
+    C --> End",
+                ),
+            ),
+            ErrorCode::DuplicateConditionEdge => (
+                "A condition node has two edges labeled `Yes`, or two labeled `No`, so it is \
+                 unclear which one to follow. To do several things on one branch, chain them \
+                 after a single edge.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|Yes| C[println 'positive']
+    B -->|Yes| D[println x]
+    B -->|No| End
+    C --> End
+    D --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|Yes| C[println 'positive']
+    C --> D[println x]
+    B -->|No| End
+    D --> End",
+                ),
+            ),
+            ErrorCode::InvalidConditionEdge => (
+                "An edge from a condition node is unlabeled, or has a label other than `Yes` or \
+                 `No` (in any case). Guards such as `[x > 0]` cannot be used on a condition \
+                 node's edges; use them on a process node's edges instead.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|positive| C[println 'positive']
+    B -->|No| End
+    C --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B{x > 0?}
+    B -->|Yes| C[println 'positive']
+    B -->|No| End
+    C --> End",
+                ),
+            ),
+            ErrorCode::MissingStart => (
+                "The flowchart has no `Start` node, so there is nowhere for the program to \
+                 begin. Entry nodes (`Start <name>`) are extra entry points and do not replace \
+                 `Start`.",
+                Some(
+                    "flowchart TD
+    A[println 'hello'] --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[println 'hello']
+    A --> End",
+                ),
+            ),
+            ErrorCode::MissingEnd => (
+                "The flowchart has no `End` node and no other terminal node, so the program \
+                 can never finish. Add an `End` node, or a stadium-shaped terminal node such as \
+                 `Done([End: ok])`.",
+                Some(
+                    "flowchart TD
+    Start --> A[println 'hello']",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[println 'hello']
+    A --> End",
+                ),
+            ),
+            ErrorCode::UndefinedNode => (
+                "An edge refers to a node ID that is never given a definition. A node must be \
+                 defined with its shape and label, such as `B[println x]`, at least once; later \
+                 references can then use the bare ID.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B[println x]
+    B --> End",
+                ),
+            ),
+            ErrorCode::EdgeFromTerminal => (
+                "An edge leaves the `End` node or a terminal node. Reaching a terminal node \
+                 ends the program, so nothing after it could ever run.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> End
+    End --> B[println x]",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B[println x]
+    B --> End",
+                ),
+            ),
+            ErrorCode::MultipleOutgoingEdges => (
+                "A process, Start or entry node has more than one outgoing edge, so it is \
+                 unclear which one to follow. Chain the nodes one after another, or branch \
+                 with a condition node or guards.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B[println 'one']
+    A --> C[println 'two']
+    B --> End
+    C --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B[println 'one']
+    B --> C[println 'two']
+    C --> End",
+                ),
+            ),
+            ErrorCode::DuplicateNode => (
+                "Two definitions share a node ID but differ in shape or label. A node may be \
+                 written out more than once only if every copy is identical; otherwise give the \
+                 nodes different IDs.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B[println x]
+    B --> A[x = 2]",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B[println x]
+    B --> C[x = 2]
+    C --> End",
+                ),
+            ),
+            ErrorCode::DuplicateEntry => (
+                "Two entry nodes are labeled `Start <name>` with the same name, so `--entry \
+                 <name>` could mean either. Entry names must be unique.",
+                Some(
+                    "flowchart TD
+    Start --> A[kind = 'purchase']
+    Refund([Start refund]) --> B[kind = 'refund']
+    Return([Start refund]) --> C[kind = 'return']
+    A --> End
+    B --> End
+    C --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[kind = 'purchase']
+    Refund([Start refund]) --> B[kind = 'refund']
+    Return([Start return]) --> C[kind = 'return']
+    A --> End
+    B --> End
+    C --> End",
+                ),
+            ),
+            ErrorCode::UnreachableEnd => (
+                "There is no path from `Start`, or from an entry node, to the `End` node or \
+                 any other terminal node, so the program could never finish. This usually means \
+                 a loop without a way out, or an edge that is missing.",
+                Some(
+                    "flowchart TD
+    Start --> A[i = 0]
+    A --> B[i += 1]
+    B --> A
+    C[println i] --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[i = 0]
+    A --> B[i += 1]
+    B --> C[println i]
+    C --> End",
+                ),
+            ),
+            ErrorCode::MisplacedExitCode => (
+                "An exit code is given on an edge that does not lead to the `End` node or a \
+                 terminal node. The exit code is only used when the program ends, so it must be \
+                 on the last edge taken. To set it earlier, use the `exit` statement in a \
+                 process node.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A -->|exit 1| B[println x]
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B[println x]
+    B -->|exit 1| End",
+                ),
+            ),
+            ErrorCode::MisplacedReturn => (
+                "A `return` value is given on an edge that does not lead to the `End` node or \
+                 a terminal node. To set the result earlier, use the `return` statement in a \
+                 process node.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A -->|return x| B[println x]
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 1]
+    A --> B[println x]
+    B -->|return x| End",
+                ),
+            ),
+            ErrorCode::DuplicateElseEdge => (
+                "A node has more than one `[else]` edge. `[else]` is taken when no guard is \
+                 true, so there can only be one.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 5]
+    A -->|[x > 10]| B[println 'big']
+    A -->|[else]| C[println 'small']
+    A -->|[else]| D[println 'other']
+    B --> End
+    C --> End
+    D --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 5]
+    A -->|[x > 10]| B[println 'big']
+    A -->|[else]| C[println 'small']
+    B --> End
+    C --> End",
+                ),
+            ),
+            ErrorCode::MixedGuards => (
+                "Some edges from a node have guards and some do not. Once one edge has a guard, \
+                 every edge from the node needs one; use `[else]` for the edge to take when no \
+                 other guard is true.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 5]
+    A -->|[x > 10]| B[println 'big']
+    A --> C[println 'small']
+    B --> End
+    C --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 5]
+    A -->|[x > 10]| B[println 'big']
+    A -->|[else]| C[println 'small']
+    B --> End
+    C --> End",
+                ),
+            ),
+            ErrorCode::NonExhaustiveGuards => (
+                "The guards on a node's edges might all be false, and there is no `[else]` \
+                 edge to take in that case. merx only accepts guards without `[else]` when they \
+                 obviously cover every case, such as `[x < 10]` and `[x >= 10]`.\n\n\
+                 At runtime, the same code is reported when no guard matches in a flowchart \
+                 that was not validated.",
+                Some(
+                    "flowchart TD
+    Start --> A[x = 0]
+    A -->|[x > 0]| B[println 'positive']
+    A -->|[x < 0]| C[println 'negative']
+    B --> End
+    C --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[x = 0]
+    A -->|[x > 0]| B[println 'positive']
+    A -->|[x < 0]| C[println 'negative']
+    A -->|[else]| D[println 'zero']
+    B --> End
+    C --> End
+    D --> End",
+                ),
+            ),
+            ErrorCode::InvalidLoopEdge => (
+                "An edge from a loop node is not labeled `body` or `done`, or there are two \
+                 edges with the same label. A loop node has exactly one `body` edge, followed \
+                 for each value, and one `done` edge, followed when the values run out.",
+                Some(
+                    "flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|repeat| A[println i]
+    A --> L
+    L -->|done| End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| A[println i]
+    A --> L
+    L -->|done| End",
+                ),
+            ),
+            ErrorCode::MissingLoopEdge => (
+                "A loop node has no `body` edge, or no `done` edge. Both are needed: `body` is \
+                 followed for each value, and `done` when the values run out.\n\n\
+                 At runtime, the same code is reported when the edge is missing from a \
+                 flowchart that was not validated.",
+                Some(
+                    "flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| A[println i]
+    A --> L
+    B[println 'done'] --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| A[println i]
+    A --> L
+    L -->|done| B[println 'done']
+    B --> End",
+                ),
+            ),
+            ErrorCode::LoopNeverReturns => (
+                "The path from a loop node's `body` edge never leads back to the loop node, so \
+                 the loop could only ever run once. End the body with an edge back to the loop \
+                 node, and leave the loop through its `done` edge.",
+                Some(
+                    "flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| A[println i]
+    A --> End
+    L -->|done| End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| A[println i]
+    A --> L
+    L -->|done| End",
+                ),
+            ),
+            ErrorCode::ConstantRedefined => (
+                "A constant is defined by two `const` statements on one path: in the same place, \
+                 or in places where one can run after the other. Definitions on exclusive \
+                 branches, such as the `Yes` and `No` branches of a condition, are allowed. \
+                 Use a variable if the value needs to change.",
+                Some(
+                    "flowchart TD
+    Start --> A[const RATE = 8]
+    A --> B[const RATE = 10]
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[const RATE = 8]
+    A --> B[rate = RATE + 2]
+    B --> End",
+                ),
+            ),
+            ErrorCode::ConstantChanged => (
+                "A constant is assigned, updated, unset, used as a loop variable or written by \
+                 a decision table after its `const` definition. Constants cannot change; copy \
+                 the value into a variable instead.",
+                Some(
+                    "flowchart TD
+    Start --> A[const RATE = 8]
+    A --> B[RATE += 1]
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[const RATE = 8]
+    A --> B[rate = RATE + 1]
+    B --> End",
+                ),
+            ),
+            ErrorCode::InvalidTableLabel => (
+                "A decision table node's label is not of the form `table <name>` or \
+                 `table '<file.csv>'`, optionally followed by the hit policy `first` or \
+                 `unique`.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100",
+                ),
+            ),
+            ErrorCode::UndeclaredTable => (
+                "A decision table node names an inline table, but no `%% table <name>` comment \
+                 block declares a table with that name.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table prices first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100",
+                ),
+            ),
+            ErrorCode::DuplicateTable => (
+                "Two `%% table <name>` comment blocks declare a table with the same name. Give \
+                 every inline table its own name.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100
+
+%% table pricing
+%% qty,    discount =
+%% >= 100, 5
+%% -,      0",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100
+
+%% table discounts
+%% qty,    discount =
+%% >= 100, 5
+%% -,      0",
+                ),
+            ),
+            ErrorCode::UnreadableTable => (
+                "The CSV file named by a decision table node cannot be read. The path is \
+                 relative to the directory of the program file; check that the file exists and \
+                 is readable, or declare the table inline.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table 'missing-pricing.csv' first]]
+    P --> B[println price]
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100",
+                ),
+            ),
+            ErrorCode::InvalidTable => (
+                "A decision table's rows cannot be read. The first row is the header, naming \
+                 input expressions, `name =` outputs and an optional `->` target column; every \
+                 following row is a rule with one cell per column. Cells must be valid tests or \
+                 expressions.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80, 5
+%% -,      100",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100",
+                ),
+            ),
+            ErrorCode::InvalidTableEdge => (
+                "An edge from a decision table with a `->` column has no label, or two edges \
+                 have the same label. Each edge must be labeled with a target from the `->` \
+                 column, and each target has exactly one edge.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    P -->|retail| C[println price]
+    B --> End
+    C --> End
+
+%% table pricing
+%% qty,    price =, ->
+%% >= 100, 80,      bulk
+%% -,      100,     retail",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P -->|bulk| B[println price]
+    P -->|retail| C[println price]
+    B --> End
+    C --> End
+
+%% table pricing
+%% qty,    price =, ->
+%% >= 100, 80,      bulk
+%% -,      100,     retail",
+                ),
+            ),
+            ErrorCode::MissingTableEdge => (
+                "A target in a decision table's `->` column has no outgoing edge with that \
+                 label, so a rule choosing it would have nowhere to go.\n\n\
+                 At runtime, the same code is reported when the edge is missing from a \
+                 flowchart that was not validated.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P -->|bulk| B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =, ->
+%% >= 100, 80,      bulk
+%% -,      100,     retail",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P -->|bulk| B[println price]
+    P -->|retail| C[println price]
+    B --> End
+    C --> End
+
+%% table pricing
+%% qty,    price =, ->
+%% >= 100, 80,      bulk
+%% -,      100,     retail",
+                ),
+            ),
+            ErrorCode::TableGap => (
+                "Some inputs are matched by no rule of a decision table. When every test \
+                 compares with a literal, merx checks this before running; the error names an \
+                 input that falls through. Add a rule for it, or a final catch-all rule of `-` \
+                 cells with the `first` hit policy.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% < 100,  100",
+                ),
+            ),
+            ErrorCode::TableOverlap => (
+                "Two rules of a decision table with the `unique` hit policy (the default) can \
+                 match the same inputs. Make the rules exclusive, or use the `first` hit policy \
+                 to choose the first matching rule.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,    price =
+%% >= 100, 80
+%% -,      100",
+                ),
+            ),
+            ErrorCode::DivisionByZero => (
+                "The right-hand side of `/` or `%` was zero when the program ran. Check the \
+                 divisor before dividing.",
+                Some(
+                    "flowchart TD
+    Start --> A[total = 10; count = 0]
+    A --> B[println total / count]
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[total = 10; count = 0]
+    A --> B{count > 0?}
+    B -->|Yes| C[println total / count]
+    B -->|No| D[println 'no items']
+    C --> End
+    D --> End",
+                ),
+            ),
+            ErrorCode::UndefinedVariable => (
+                "A variable was read before any statement assigned it, or after it was unset. \
+                 Check the spelling, and that every path to the read assigns the variable \
+                 first. `exists(x)` tests whether a variable is defined.",
+                Some(
+                    "flowchart TD
+    Start --> A[total = 10]
+    A --> B[println totl]
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[total = 10]
+    A --> B[println total]
+    B --> End",
+                ),
+            ),
+            ErrorCode::ConstantReassigned => (
+                "A constant was changed while the program ran. Validation rejects most changes \
+                 to constants; this covers the rest, such as a `const` statement in a node that \
+                 runs more than once. Define the constant before the loop.",
+                Some(
+                    "flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| A[const LIMIT = 10; println i * LIMIT]
+    A --> L
+    L -->|done| End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[const LIMIT = 10]
+    A --> L{{for i in 1..3}}
+    L -->|body| B[println i * LIMIT]
+    B --> L
+    L -->|done| End",
+                ),
+            ),
+            ErrorCode::TypeMismatch => (
+                "An operation received a value of the wrong type, such as a condition that is \
+                 not a bool or arithmetic on a str. merx never converts types implicitly; use \
+                 `as int`, `as str` or a comparison to get the type the operation needs.",
+                Some(
+                    "flowchart TD
+    Start --> A[count = 3]
+    A --> B{count?}
+    B -->|Yes| C[println count]
+    B -->|No| End
+    C --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[count = 3]
+    A --> B{count > 0?}
+    B -->|Yes| C[println count]
+    B -->|No| End
+    C --> End",
+                ),
+            ),
+            ErrorCode::InvalidCast => (
+                "A value cannot be converted with `as`, such as a str that is not a number cast \
+                 to int. Input read with `input as int` fails this way when the line is not an \
+                 integer.",
+                Some(
+                    "flowchart TD
+    Start --> A[age = 'twelve' as int]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[age = '12' as int]
+    A --> End",
+                ),
+            ),
+            ErrorCode::ExitCodeOutOfRange => (
+                "An exit code computed by an expression was outside 0-255, the range of process \
+                 exit codes. Keep the value in range, or pass `--clamp-exit-code` to clamp it.",
+                Some(
+                    "flowchart TD
+    Start --> A[failures = 300]
+    A --> B[exit failures]
+    B --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[failures = 300]
+    A --> B{failures > 255?}
+    B -->|Yes| C[exit 255]
+    B -->|No| D[exit failures]
+    C --> End
+    D --> End",
+                ),
+            ),
+            ErrorCode::UnknownEntry => (
+                "The entry given with `--entry <name>` (or `Interpreter::with_entry`) does not \
+                 exist. An entry node is a stadium node labeled `Start <name>`; check that the \
+                 name matches one of them.",
+                None,
+                None,
+            ),
+            ErrorCode::NoOutgoingEdge => (
+                "The program reached a node with no edge to follow. Validation makes sure every \
+                 entry point can reach a terminal node, so this only happens for flowcharts \
+                 built or modified without validation. Add an edge from the node.",
+                None,
+                None,
+            ),
+            ErrorCode::NoMatchingRule => (
+                "No rule of a decision table matched when the program ran. Tables whose tests \
+                 all compare with literals are checked before running (see MX0208); this covers \
+                 tables whose tests use variables. Add a rule for the missing case, or a final \
+                 catch-all rule of `-` cells with the `first` hit policy.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 50; limit = 100]
+    A --> P[[table pricing]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,      price =
+%% >= limit, 80",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 50; limit = 100]
+    A --> P[[table pricing]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,      price =
+%% >= limit, 80
+%% < limit,  100",
+                ),
+            ),
+            ErrorCode::MultipleMatchingRules => (
+                "Several rules of a decision table with the `unique` hit policy (the default) \
+                 matched when the program ran. Tables whose tests all compare with literals are \
+                 checked before running (see MX0209); this covers tables whose tests use \
+                 variables. Make the rules exclusive, or use the `first` hit policy.",
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150; limit = 100]
+    A --> P[[table pricing]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,      price =
+%% >= limit, 80
+%% -,        100",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[qty = 150; limit = 100]
+    A --> P[[table pricing first]]
+    P --> B[println price]
+    B --> End
+
+%% table pricing
+%% qty,      price =
+%% >= limit, 80
+%% -,        100",
+                ),
+            ),
+            ErrorCode::InvalidArgument => (
+                "A built-in function received an argument of the right type but an unusable \
+                 value, such as rand_int with a lower bound above its upper bound, or \
+                 parse_date with text that is not a date.",
+                Some(
+                    "flowchart TD
+    Start --> A[roll = rand_int(6, 1)]
+    A --> End",
+                ),
+                Some(
+                    "flowchart TD
+    Start --> A[roll = rand_int(1, 6)]
+    A --> End",
+                ),
+            ),
+            ErrorCode::RandomDenied => (
+                "The program called rand_int, rand_choice or shuffle while randomness was \
+                 denied with `--deny-random` (or `Interpreter::with_random_denied`). Remove the \
+                 flag, or pass `--seed` instead to make the random values reproducible.",
+                None,
+                None,
+            ),
+            ErrorCode::IoError => (
+                "Reading input or writing output failed, for example because standard output \
+                 was closed. The message includes the error reported by the operating system.",
+                None,
+                None,
+            ),
+        };
+        Explanation {
+            code: *self,
+            description,
+            wrong,
+            right,
+        }
+    }
+}
+
+impl fmt::Display for Explanation {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        writeln!(f, "{}: {}", self.code, self.code.title())?;
+        writeln!(f)?;
+        writeln!(f, "{}", self.description)?;
+        for (heading, example) in [
+            ("Erroneous example:", self.wrong),
+            ("Corrected example:", self.right),
+        ] {
+            let Some(example) = example else {
+                continue;
+            };
+            writeln!(f)?;
+            writeln!(f, "{}", heading)?;
+            writeln!(f)?;
+            for line in example.lines() {
+                if line.is_empty() {
+                    writeln!(f)?;
+                } else {
+                    writeln!(f, "    {}", line)?;
+                }
+            }
+        }
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::codes::ErrorCode;
+    use crate::run_str;
+
+    #[test]
+    fn test_examples_show_their_errors() {
+        for &code in ErrorCode::ALL {
+            let explanation = code.explanation();
+            if let Some(wrong) = explanation.wrong {
+                let result = run_str(wrong, &[]);
+                assert_eq!(
+                    result.error.as_ref().map(|e| e.code()),
+                    Some(code),
+                    "{}: {:?}",
+                    code,
+                    result.error
+                );
+            }
+            if let Some(right) = explanation.right {
+                let result = run_str(right, &[]);
+                assert!(result.is_ok(), "{}: {:?}", code, result.error);
+            }
+        }
+    }
+
+    #[test]
+    fn test_display_indents_examples() {
+        let text = ErrorCode::DivisionByZero.explanation().to_string();
+        assert!(text.starts_with("MX0301: division by zero\n\n"), "{}", text);
+        assert!(
+            text.contains("Erroneous example:\n\n    flowchart TD\n"),
+            "{}",
+            text
+        );
+        assert!(text.contains("\nCorrected example:\n"), "{}", text);
+
+        let text = ErrorCode::IoError.explanation().to_string();
+        assert!(!text.contains("Erroneous example"), "{}", text);
+    }
+}
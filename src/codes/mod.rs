@@ -0,0 +1,403 @@
+//! Stable error codes.
+//!
+//! Every [`SyntaxError`](crate::parser::SyntaxError),
+//! [`ValidationError`](crate::parser::ValidationError) and
+//! [`RuntimeError`](crate::runtime::RuntimeError) has an [`ErrorCode`] such as
+//! `MX0101`, returned by its `code()` method. Unlike the error messages, the
+//! codes do not change between releases, so they can be matched on by tests
+//! and linked to from documentation.
+//!
+//! Codes are grouped by the stage that reports them:
+//!
+//! - `MX00xx` - Syntax errors
+//! - `MX01xx` - Validation errors
+//! - `MX02xx` - Decision table errors, reported while parsing or validating
+//! - `MX03xx` - Runtime errors
+//!
+//! A runtime error that is normally caught by validation, such as a missing
+//! `Yes` edge in a flowchart built by hand, shares the validation error's
+//! code. `merx explain <CODE>` prints an error's [`Explanation`].
+
+mod explain;
+
+use std::fmt;
+
+pub use explain::Explanation;
+
+/// A stable identifier for a kind of error.
+///
+/// # Examples
+///
+/// ```
+/// use merx::codes::ErrorCode;
+/// use merx::parser::parse;
+///
+/// let err = parse("flowchart TD\n    Start --> A{x > 0?}\n    A -->|No| End\n").unwrap_err();
+/// assert_eq!(err.code(), ErrorCode::MissingYesEdge);
+/// assert_eq!(err.code().to_string(), "MX0101");
+/// ```
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub enum ErrorCode {
+    /// `MX0000`: A bug in merx itself.
+    Internal,
+    /// `MX0001`: The source does not match the flowchart grammar.
+    InvalidSyntax,
+    /// `MX0002`: An integer literal does not fit in 64 bits.
+    IntegerOutOfRange,
+    /// `MX0003`: A call to a function that does not exist.
+    UnknownFunction,
+    /// `MX0004`: A function is called with the wrong number of arguments.
+    WrongArgumentCount,
+    /// `MX0005`: An edge label that cannot be understood.
+    InvalidEdgeLabel,
+    /// `MX0006`: An exit code literal that is missing or out of range.
+    InvalidExitCode,
+    /// `MX0007`: A parallel assignment with more or fewer values than
+    /// variables.
+    AssignmentCountMismatch,
+    /// `MX0008`: A parallel assignment that assigns a variable twice.
+    DuplicateAssignment,
+    /// `MX0009`: A node shape that merx does not support.
+    UnsupportedShape,
+    /// `MX0010`: A node declared with shape metadata but no label.
+    MissingLabel,
+    /// `MX0011`: An entry or terminal node with an invalid label.
+    InvalidTerminalLabel,
+    /// `MX0012`: `exists` or `defined` given something other than a
+    /// variable name.
+    InvalidNameArgument,
+
+    /// `MX0101`: A condition node without a `Yes` edge.
+    MissingYesEdge,
+    /// `MX0102`: A condition node without a `No` edge.
+    MissingNoEdge,
+    /// `MX0103`: A condition node with two `Yes` or two `No` edges.
+    DuplicateConditionEdge,
+    /// `MX0104`: An edge from a condition node not labeled `Yes` or `No`.
+    InvalidConditionEdge,
+    /// `MX0105`: A flowchart without a `Start` node.
+    MissingStart,
+    /// `MX0106`: A flowchart without an `End` or terminal node.
+    MissingEnd,
+    /// `MX0107`: An edge to or from a node that is never defined.
+    UndefinedNode,
+    /// `MX0108`: An edge leaving the `End` node or a terminal node.
+    EdgeFromTerminal,
+    /// `MX0109`: A node with more outgoing edges than it can follow.
+    MultipleOutgoingEdges,
+    /// `MX0110`: A node defined twice with different contents.
+    DuplicateNode,
+    /// `MX0111`: Two entry nodes with the same name.
+    DuplicateEntry,
+    /// `MX0112`: An entry point with no path to a terminal node.
+    UnreachableEnd,
+    /// `MX0113`: An exit code on an edge that does not end the program.
+    MisplacedExitCode,
+    /// `MX0114`: A `return` on an edge that does not end the program.
+    MisplacedReturn,
+    /// `MX0115`: A node with more than one `[else]` edge.
+    DuplicateElseEdge,
+    /// `MX0116`: A node with both guarded and unguarded outgoing edges.
+    MixedGuards,
+    /// `MX0117`: Guards that may all be false, with no `[else]` edge.
+    NonExhaustiveGuards,
+    /// `MX0118`: A loop node with duplicate or wrongly labeled edges.
+    InvalidLoopEdge,
+    /// `MX0119`: A loop node without a `body` or `done` edge.
+    MissingLoopEdge,
+    /// `MX0120`: A loop body that never returns to its loop node.
+    LoopNeverReturns,
+    /// `MX0121`: A constant defined by more than one statement.
+    ConstantRedefined,
+    /// `MX0122`: A constant written to after it is defined.
+    ConstantChanged,
+
+    /// `MX0201`: A decision table node with an invalid label.
+    InvalidTableLabel,
+    /// `MX0202`: A decision table node naming an inline table that is not
+    /// declared.
+    UndeclaredTable,
+    /// `MX0203`: Two inline tables with the same name.
+    DuplicateTable,
+    /// `MX0204`: A decision table file that cannot be read.
+    UnreadableTable,
+    /// `MX0205`: A decision table with malformed rows or cells.
+    InvalidTable,
+    /// `MX0206`: An unlabeled or duplicate edge from a decision table with
+    /// targets.
+    InvalidTableEdge,
+    /// `MX0207`: A decision table target with no outgoing edge.
+    MissingTableEdge,
+    /// `MX0208`: Inputs that no rule of a decision table matches.
+    TableGap,
+    /// `MX0209`: Two rules of a unique decision table that can both match.
+    TableOverlap,
+
+    /// `MX0301`: Division or modulo by zero.
+    DivisionByZero,
+    /// `MX0302`: A variable read before it is assigned.
+    UndefinedVariable,
+    /// `MX0303`: A constant changed while the program runs.
+    ConstantReassigned,
+    /// `MX0304`: An operation applied to a value of the wrong type.
+    TypeMismatch,
+    /// `MX0305`: A value that cannot be cast to the requested type.
+    InvalidCast,
+    /// `MX0306`: A computed exit code outside 0-255.
+    ExitCodeOutOfRange,
+    /// `MX0307`: An entry name that no entry node declares.
+    UnknownEntry,
+    /// `MX0308`: A node with no edge to follow.
+    NoOutgoingEdge,
+    /// `MX0309`: Inputs that no rule of a decision table matches at
+    /// runtime.
+    NoMatchingRule,
+    /// `MX0310`: Several rules of a unique decision table matching at
+    /// runtime.
+    MultipleMatchingRules,
+    /// `MX0311`: A built-in function given an unusable argument.
+    InvalidArgument,
+    /// `MX0312`: A random function called while randomness is denied.
+    RandomDenied,
+    /// `MX0313`: Reading input or writing output failed.
+    IoError,
+}
+
+impl ErrorCode {
+    /// Every error code, in numeric order.
+    pub const ALL: &[ErrorCode] = &[
+        ErrorCode::Internal,
+        ErrorCode::InvalidSyntax,
+        ErrorCode::IntegerOutOfRange,
+        ErrorCode::UnknownFunction,
+        ErrorCode::WrongArgumentCount,
+        ErrorCode::InvalidEdgeLabel,
+        ErrorCode::InvalidExitCode,
+        ErrorCode::AssignmentCountMismatch,
+        ErrorCode::DuplicateAssignment,
+        ErrorCode::UnsupportedShape,
+        ErrorCode::MissingLabel,
+        ErrorCode::InvalidTerminalLabel,
+        ErrorCode::InvalidNameArgument,
+        ErrorCode::MissingYesEdge,
+        ErrorCode::MissingNoEdge,
+        ErrorCode::DuplicateConditionEdge,
+        ErrorCode::InvalidConditionEdge,
+        ErrorCode::MissingStart,
+        ErrorCode::MissingEnd,
+        ErrorCode::UndefinedNode,
+        ErrorCode::EdgeFromTerminal,
+        ErrorCode::MultipleOutgoingEdges,
+        ErrorCode::DuplicateNode,
+        ErrorCode::DuplicateEntry,
+        ErrorCode::UnreachableEnd,
+        ErrorCode::MisplacedExitCode,
+        ErrorCode::MisplacedReturn,
+        ErrorCode::DuplicateElseEdge,
+        ErrorCode::MixedGuards,
+        ErrorCode::NonExhaustiveGuards,
+        ErrorCode::InvalidLoopEdge,
+        ErrorCode::MissingLoopEdge,
+        ErrorCode::LoopNeverReturns,
+        ErrorCode::ConstantRedefined,
+        ErrorCode::ConstantChanged,
+        ErrorCode::InvalidTableLabel,
+        ErrorCode::UndeclaredTable,
+        ErrorCode::DuplicateTable,
+        ErrorCode::UnreadableTable,
+        ErrorCode::InvalidTable,
+        ErrorCode::InvalidTableEdge,
+        ErrorCode::MissingTableEdge,
+        ErrorCode::TableGap,
+        ErrorCode::TableOverlap,
+        ErrorCode::DivisionByZero,
+        ErrorCode::UndefinedVariable,
+        ErrorCode::ConstantReassigned,
+        ErrorCode::TypeMismatch,
+        ErrorCode::InvalidCast,
+        ErrorCode::ExitCodeOutOfRange,
+        ErrorCode::UnknownEntry,
+        ErrorCode::NoOutgoingEdge,
+        ErrorCode::NoMatchingRule,
+        ErrorCode::MultipleMatchingRules,
+        ErrorCode::InvalidArgument,
+        ErrorCode::RandomDenied,
+        ErrorCode::IoError,
+    ];
+
+    /// Looks up an error code by its identifier, ignoring case.
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::codes::ErrorCode;
+    ///
+    /// assert_eq!(ErrorCode::from_code("MX0301"), Some(ErrorCode::DivisionByZero));
+    /// assert_eq!(ErrorCode::from_code("mx0301"), Some(ErrorCode::DivisionByZero));
+    /// assert_eq!(ErrorCode::from_code("MX9999"), None);
+    /// ```
+    pub fn from_code(code: &str) -> Option<Self> {
+        Self::ALL
+            .iter()
+            .copied()
+            .find(|c| c.as_str().eq_ignore_ascii_case(code.trim()))
+    }
+
+    /// Returns the code's identifier, such as `MX0101`.
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            ErrorCode::Internal => "MX0000",
+            ErrorCode::InvalidSyntax => "MX0001",
+            ErrorCode::IntegerOutOfRange => "MX0002",
+            ErrorCode::UnknownFunction => "MX0003",
+            ErrorCode::WrongArgumentCount => "MX0004",
+            ErrorCode::InvalidEdgeLabel => "MX0005",
+            ErrorCode::InvalidExitCode => "MX0006",
+            ErrorCode::AssignmentCountMismatch => "MX0007",
+            ErrorCode::DuplicateAssignment => "MX0008",
+            ErrorCode::UnsupportedShape => "MX0009",
+            ErrorCode::MissingLabel => "MX0010",
+            ErrorCode::InvalidTerminalLabel => "MX0011",
+            ErrorCode::InvalidNameArgument => "MX0012",
+            ErrorCode::MissingYesEdge => "MX0101",
+            ErrorCode::MissingNoEdge => "MX0102",
+            ErrorCode::DuplicateConditionEdge => "MX0103",
+            ErrorCode::InvalidConditionEdge => "MX0104",
+            ErrorCode::MissingStart => "MX0105",
+            ErrorCode::MissingEnd => "MX0106",
+            ErrorCode::UndefinedNode => "MX0107",
+            ErrorCode::EdgeFromTerminal => "MX0108",
+            ErrorCode::MultipleOutgoingEdges => "MX0109",
+            ErrorCode::DuplicateNode => "MX0110",
+            ErrorCode::DuplicateEntry => "MX0111",
+            ErrorCode::UnreachableEnd => "MX0112",
+            ErrorCode::MisplacedExitCode => "MX0113",
+            ErrorCode::MisplacedReturn => "MX0114",
+            ErrorCode::DuplicateElseEdge => "MX0115",
+            ErrorCode::MixedGuards => "MX0116",
+            ErrorCode::NonExhaustiveGuards => "MX0117",
+            ErrorCode::InvalidLoopEdge => "MX0118",
+            ErrorCode::MissingLoopEdge => "MX0119",
+            ErrorCode::LoopNeverReturns => "MX0120",
+            ErrorCode::ConstantRedefined => "MX0121",
+            ErrorCode::ConstantChanged => "MX0122",
+            ErrorCode::InvalidTableLabel => "MX0201",
+            ErrorCode::UndeclaredTable => "MX0202",
+            ErrorCode::DuplicateTable => "MX0203",
+            ErrorCode::UnreadableTable => "MX0204",
+            ErrorCode::InvalidTable => "MX0205",
+            ErrorCode::InvalidTableEdge => "MX0206",
+            ErrorCode::MissingTableEdge => "MX0207",
+            ErrorCode::TableGap => "MX0208",
+            ErrorCode::TableOverlap => "MX0209",
+            ErrorCode::DivisionByZero => "MX0301",
+            ErrorCode::UndefinedVariable => "MX0302",
+            ErrorCode::ConstantReassigned => "MX0303",
+            ErrorCode::TypeMismatch => "MX0304",
+            ErrorCode::InvalidCast => "MX0305",
+            ErrorCode::ExitCodeOutOfRange => "MX0306",
+            ErrorCode::UnknownEntry => "MX0307",
+            ErrorCode::NoOutgoingEdge => "MX0308",
+            ErrorCode::NoMatchingRule => "MX0309",
+            ErrorCode::MultipleMatchingRules => "MX0310",
+            ErrorCode::InvalidArgument => "MX0311",
+            ErrorCode::RandomDenied => "MX0312",
+            ErrorCode::IoError => "MX0313",
+        }
+    }
+
+    /// Returns a short description of the error, such as `missing Yes edge`.
+    pub fn title(&self) -> &'static str {
+        match self {
+            ErrorCode::Internal => "internal error",
+            ErrorCode::InvalidSyntax => "invalid syntax",
+            ErrorCode::IntegerOutOfRange => "integer literal out of range",
+            ErrorCode::UnknownFunction => "unknown function",
+            ErrorCode::WrongArgumentCount => "wrong number of arguments",
+            ErrorCode::InvalidEdgeLabel => "invalid edge label",
+            ErrorCode::InvalidExitCode => "invalid exit code",
+            ErrorCode::AssignmentCountMismatch => "mismatched parallel assignment",
+            ErrorCode::DuplicateAssignment => "variable assigned twice in parallel assignment",
+            ErrorCode::UnsupportedShape => "unsupported node shape",
+            ErrorCode::MissingLabel => "missing node label",
+            ErrorCode::InvalidTerminalLabel => "invalid entry or terminal label",
+            ErrorCode::InvalidNameArgument => "argument is not a variable name",
+            ErrorCode::MissingYesEdge => "missing Yes edge",
+            ErrorCode::MissingNoEdge => "missing No edge",
+            ErrorCode::DuplicateConditionEdge => "duplicate Yes or No edge",
+            ErrorCode::InvalidConditionEdge => "condition edge not labeled Yes or No",
+            ErrorCode::MissingStart => "missing Start node",
+            ErrorCode::MissingEnd => "missing End node",
+            ErrorCode::UndefinedNode => "undefined node",
+            ErrorCode::EdgeFromTerminal => "edge from End or terminal node",
+            ErrorCode::MultipleOutgoingEdges => "multiple outgoing edges",
+            ErrorCode::DuplicateNode => "node defined multiple times",
+            ErrorCode::DuplicateEntry => "duplicate entry name",
+            ErrorCode::UnreachableEnd => "End node unreachable",
+            ErrorCode::MisplacedExitCode => "exit code on non-terminal edge",
+            ErrorCode::MisplacedReturn => "return on non-terminal edge",
+            ErrorCode::DuplicateElseEdge => "multiple else edges",
+            ErrorCode::MixedGuards => "guarded and unguarded edges mixed",
+            ErrorCode::NonExhaustiveGuards => "guards may not cover every case",
+            ErrorCode::InvalidLoopEdge => "invalid loop edge",
+            ErrorCode::MissingLoopEdge => "missing body or done edge",
+            ErrorCode::LoopNeverReturns => "loop body never returns",
+            ErrorCode::ConstantRedefined => "constant defined more than once",
+            ErrorCode::ConstantChanged => "constant changed",
+            ErrorCode::InvalidTableLabel => "invalid decision table label",
+            ErrorCode::UndeclaredTable => "decision table not declared",
+            ErrorCode::DuplicateTable => "decision table declared twice",
+            ErrorCode::UnreadableTable => "decision table file unreadable",
+            ErrorCode::InvalidTable => "invalid decision table",
+            ErrorCode::InvalidTableEdge => "invalid decision table edge",
+            ErrorCode::MissingTableEdge => "missing decision table edge",
+            ErrorCode::TableGap => "decision table has no rule for some inputs",
+            ErrorCode::TableOverlap => "decision table rules overlap",
+            ErrorCode::DivisionByZero => "division by zero",
+            ErrorCode::UndefinedVariable => "undefined variable",
+            ErrorCode::ConstantReassigned => "constant reassigned at runtime",
+            ErrorCode::TypeMismatch => "type error",
+            ErrorCode::InvalidCast => "invalid cast",
+            ErrorCode::ExitCodeOutOfRange => "computed exit code out of range",
+            ErrorCode::UnknownEntry => "unknown entry",
+            ErrorCode::NoOutgoingEdge => "no outgoing edge",
+            ErrorCode::NoMatchingRule => "no decision table rule matched",
+            ErrorCode::MultipleMatchingRules => "several decision table rules matched",
+            ErrorCode::InvalidArgument => "invalid argument to built-in function",
+            ErrorCode::RandomDenied => "randomness denied",
+            ErrorCode::IoError => "I/O error",
+        }
+    }
+}
+
+impl fmt::Display for ErrorCode {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{}", self.as_str())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_codes_are_unique_and_sorted() {
+        let codes: Vec<&str> = ErrorCode::ALL.iter().map(ErrorCode::as_str).collect();
+        let mut sorted = codes.clone();
+        sorted.sort();
+        sorted.dedup();
+        assert_eq!(codes, sorted);
+    }
+
+    #[test]
+    fn test_from_code_round_trips() {
+        for &code in ErrorCode::ALL {
+            assert_eq!(ErrorCode::from_code(code.as_str()), Some(code));
+        }
+        assert_eq!(
+            ErrorCode::from_code(" mx0101 "),
+            Some(ErrorCode::MissingYesEdge)
+        );
+        assert_eq!(ErrorCode::from_code("MX01"), None);
+    }
+}
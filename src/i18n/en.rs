@@ -0,0 +1,627 @@
+//! English messages.
+
+use std::fmt;
+
+use crate::codes::ErrorCode;
+use crate::parser::{
+    AnalysisError, ExprRole, Place, SyntaxError, TableError, TableValue, ValidationError,
+};
+use crate::run::RunError;
+use crate::runtime::RuntimeError;
+
+use super::{ReadFileError, WatchError, WatchSummary};
+
+pub(super) fn analysis_error(e: &AnalysisError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        AnalysisError::Syntax(e) => {
+            write!(f, "Syntax error: ")?;
+            syntax_error(e, f)
+        }
+        AnalysisError::Validation(e) => {
+            write!(f, "Validation error: ")?;
+            validation_error(e, f)
+        }
+    }
+}
+
+pub(super) fn run_error(e: &RunError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        RunError::Analysis(e) => analysis_error(e, f),
+        RunError::Runtime(e) => {
+            write!(f, "Runtime error: ")?;
+            runtime_error(e, f)
+        }
+    }
+}
+
+pub(super) fn syntax_error(e: &SyntaxError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        SyntaxError::Grammar { message } => write!(f, "{}", message),
+        SyntaxError::Internal { message } => write!(f, "internal: {}", message),
+        SyntaxError::Line { line, error } => {
+            write!(f, "line {}: ", line)?;
+            syntax_error(error, f)
+        }
+        SyntaxError::ConflictingEdgeLabels { from } => {
+            write!(
+                f,
+                "edge from '{}' cannot have both an inline label (--text-->) and a pipe label (|text|)",
+                from
+            )
+        }
+        SyntaxError::InvalidEdgeAction { text, message } => {
+            write!(f, "invalid edge action '{}': {}", text, message)
+        }
+        SyntaxError::TextAfterGuard { text } => {
+            write!(
+                f,
+                "unexpected '{}' after guard (only ', exit N' or ', return expr' may follow)",
+                text
+            )
+        }
+        SyntaxError::MissingReturnValue => {
+            write!(f, "return requires a value (e.g., 'return total')")
+        }
+        SyntaxError::MissingExitCode => {
+            write!(f, "exit code requires a numeric value (e.g., 'exit 1')")
+        }
+        SyntaxError::ExitCodeOutOfRange { text } => {
+            write!(
+                f,
+                "invalid exit code '{}': must be an integer between 0 and 255",
+                text
+            )
+        }
+        SyntaxError::InvalidExpression {
+            role,
+            text,
+            message,
+        } => {
+            write!(f, "invalid {} '{}': {}", expr_role(*role), text, message)
+        }
+        SyntaxError::InvalidNodeLabel { node_id, message } => {
+            write!(f, "invalid label of node '{}': {}", node_id, message)
+        }
+        SyntaxError::DuplicateAssignment { name } => {
+            write!(f, "variable '{}' is assigned more than once", name)
+        }
+        SyntaxError::AssignmentCountMismatch { variables, values } => {
+            write!(
+                f,
+                "expected {} values for {} variables, but got {}",
+                variables, variables, values
+            )
+        }
+        SyntaxError::IntegerOutOfRange { literal } => {
+            write!(f, "integer literal '{}' is out of range", literal)
+        }
+        SyntaxError::UnknownFunction { name } => write!(f, "unknown function '{}'", name),
+        SyntaxError::WrongArgumentCount {
+            function,
+            expected,
+            got,
+        } => {
+            write!(
+                f,
+                "function '{}' expects {}, but got {}",
+                function, expected, got
+            )
+        }
+        SyntaxError::ExistsArgument => {
+            write!(f, "function 'exists' expects a variable name argument")
+        }
+        SyntaxError::DefinedArgument => {
+            write!(f, "function 'defined' expects a string literal argument")
+        }
+        SyntaxError::UnsupportedShape { node_id, shape } => {
+            write!(
+                f,
+                "shape '{}' of node '{}' is not supported (use rect or rounded for process nodes, diamond for condition nodes, subroutine for decision tables, hex for loops, or stadium for Start/End)",
+                shape, node_id
+            )
+        }
+        SyntaxError::NonTerminalShape { node_id, shape } => {
+            write!(
+                f,
+                "node '{}' must use a terminal shape such as stadium, but got '{}'",
+                node_id, shape
+            )
+        }
+        SyntaxError::MissingLabel { node_id } => {
+            write!(f, "node '{}' requires a label", node_id)
+        }
+        SyntaxError::InvalidEntryName { node_id } => {
+            write!(
+                f,
+                "entry node '{}' must be labeled 'Start <name>' with a name made of letters, digits, and underscores",
+                node_id
+            )
+        }
+        SyntaxError::EmptyTerminalItem { node_id } => {
+            write!(
+                f,
+                "terminal node '{}' has an empty item in its label",
+                node_id
+            )
+        }
+        SyntaxError::MultipleTerminalExitCodes { node_id } => {
+            write!(
+                f,
+                "terminal node '{}' declares more than one exit code",
+                node_id
+            )
+        }
+        SyntaxError::MultipleTerminalResults { node_id } => {
+            write!(
+                f,
+                "terminal node '{}' declares more than one result",
+                node_id
+            )
+        }
+        SyntaxError::InvalidTableLabel { node_id } => {
+            write!(
+                f,
+                "decision table node '{}' must be labeled 'table <name>' or 'table '<file.csv>'', optionally followed by 'first' or 'unique'",
+                node_id
+            )
+        }
+        SyntaxError::UndeclaredTable { node_id, name } => {
+            write!(
+                f,
+                "decision table '{}' of node '{}' is not declared (expected a '%% table {}' comment block)",
+                name, node_id, name
+            )
+        }
+        SyntaxError::UnreadableTable {
+            node_id,
+            path,
+            message,
+        } => {
+            write!(
+                f,
+                "cannot read decision table '{}' of node '{}': {}",
+                path, node_id, message
+            )
+        }
+        SyntaxError::InvalidTable { node_id, error } => {
+            write!(f, "invalid decision table of node '{}': ", node_id)?;
+            table_error(error, f)
+        }
+        SyntaxError::DuplicateTable { name } => {
+            write!(f, "decision table '{}' is declared more than once", name)
+        }
+    }
+}
+
+pub(super) fn table_error(e: &TableError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        TableError::Empty => write!(f, "the table is empty"),
+        TableError::MultipleTargetColumns => {
+            write!(f, "the header has more than one '->' column")
+        }
+        TableError::InvalidColumn(error) => syntax_error(error, f),
+        TableError::RuleLength {
+            rule,
+            cells,
+            columns,
+        } => {
+            write!(
+                f,
+                "rule {} has {} cells, but the header has {}",
+                rule, cells, columns
+            )
+        }
+        TableError::InvalidCell { rule, error } => {
+            write!(f, "rule {}: ", rule)?;
+            syntax_error(error, f)
+        }
+        TableError::MissingTarget { rule } => {
+            write!(f, "rule {} has no target edge label", rule)
+        }
+        TableError::NoRules => write!(f, "the table has no rules"),
+        TableError::UnclosedQuote { line } => write!(f, "line {} has an unclosed quote", line),
+        TableError::TextAfterQuote { line } => {
+            write!(f, "line {} has text after a closing quote", line)
+        }
+    }
+}
+
+pub(super) fn validation_error(e: &ValidationError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        ValidationError::DuplicateNode { node_id } => {
+            write!(f, "Node '{}' is defined multiple times", node_id)
+        }
+        ValidationError::MultipleConditionEdges { node_id, yes } => {
+            write!(
+                f,
+                "Condition node '{}' has multiple '{}' edges",
+                node_id,
+                yes_no(*yes)
+            )
+        }
+        ValidationError::InvalidConditionLabel { node_id, label } => {
+            write!(
+                f,
+                "Condition node '{}' must have 'Yes' or 'No' label, but got '{}'",
+                node_id, label
+            )
+        }
+        ValidationError::GuardOnConditionEdge { node_id } => {
+            write!(
+                f,
+                "Condition node '{}' must have 'Yes' or 'No' label, but got a guard",
+                node_id
+            )
+        }
+        ValidationError::UnlabeledConditionEdge { node_id } => {
+            write!(
+                f,
+                "Edge from condition node '{}' must have 'Yes' or 'No' label",
+                node_id
+            )
+        }
+        ValidationError::MissingConditionEdge { node_id, yes } => {
+            write!(
+                f,
+                "Condition node '{}' is missing '{}' edge",
+                node_id,
+                yes_no(*yes)
+            )
+        }
+        ValidationError::UnlabeledTableEdge { node_id } => {
+            write!(
+                f,
+                "Edges from decision table '{}' must be labeled with a target from its '->' column",
+                node_id
+            )
+        }
+        ValidationError::MultipleTableEdges { node_id, label } => {
+            write!(
+                f,
+                "Decision table '{}' has multiple '{}' edges",
+                node_id, label
+            )
+        }
+        ValidationError::MissingTableEdge { node_id, label } => {
+            write!(
+                f,
+                "Decision table '{}' is missing '{}' edge",
+                node_id, label
+            )
+        }
+        ValidationError::TableGap { node_id, inputs } => {
+            write!(f, "Decision table '{}' has no rule for ", node_id)?;
+            table_inputs(inputs, f)
+        }
+        ValidationError::TableOverlap {
+            node_id,
+            first,
+            second,
+            inputs,
+        } => {
+            write!(
+                f,
+                "Rules {} and {} of decision table '{}' overlap (both match ",
+                first, second, node_id
+            )?;
+            table_inputs(inputs, f)?;
+            write!(f, ")")
+        }
+        ValidationError::MissingStartNode => write!(f, "Missing 'Start' node"),
+        ValidationError::MissingEndNode => write!(f, "Missing 'End' node"),
+        ValidationError::UndefinedNode { node_id, from, to } => {
+            write!(
+                f,
+                "Undefined node '{}' referenced in edge from '{}' to '{}'",
+                node_id, from, to
+            )
+        }
+        ValidationError::EdgeFromEnd => write!(f, "End node cannot have outgoing edges"),
+        ValidationError::EdgeFromTerminal { node_id } => {
+            write!(f, "Terminal node '{}' cannot have outgoing edges", node_id)
+        }
+        ValidationError::MultipleOutgoingEdges { node_id } => {
+            write!(
+                f,
+                "Node '{}' has multiple outgoing edges (expected at most 1)",
+                node_id
+            )
+        }
+        ValidationError::DuplicateEntry {
+            name,
+            first,
+            second,
+        } => {
+            write!(
+                f,
+                "Entry '{}' is declared by both '{}' and '{}'",
+                name, first, second
+            )
+        }
+        ValidationError::UnreachableEnd { entry: None } => {
+            write!(f, "'Start' node cannot reach an End node")
+        }
+        ValidationError::UnreachableEnd { entry: Some(name) } => {
+            write!(f, "Entry '{}' cannot reach an End node", name)
+        }
+        ValidationError::MisplacedExitCode { from, to } => {
+            write!(
+                f,
+                "Exit code can only be specified on edges to 'End' node or other terminal nodes, but found on edge from '{}' to '{}'",
+                from, to
+            )
+        }
+        ValidationError::MisplacedReturn { from, to } => {
+            write!(
+                f,
+                "Return value can only be specified on edges to 'End' node or other terminal nodes, but found on edge from '{}' to '{}'",
+                from, to
+            )
+        }
+        ValidationError::MultipleElseEdges { node_id } => {
+            write!(f, "Node '{}' has multiple [else] edges", node_id)
+        }
+        ValidationError::MixedGuards { node_id } => {
+            write!(
+                f,
+                "Node '{}' mixes guarded and unguarded outgoing edges",
+                node_id
+            )
+        }
+        ValidationError::NonExhaustiveGuards { node_id } => {
+            write!(
+                f,
+                "Guards on edges from node '{}' may not cover every case; add an [else] edge",
+                node_id
+            )
+        }
+        ValidationError::MultipleLoopEdges { node_id, body } => {
+            write!(
+                f,
+                "Loop node '{}' has multiple '{}' edges",
+                node_id,
+                body_done(*body)
+            )
+        }
+        ValidationError::InvalidLoopLabel { node_id } => {
+            write!(
+                f,
+                "Edges from loop node '{}' must be labeled 'body' or 'done'",
+                node_id
+            )
+        }
+        ValidationError::MissingLoopEdge { node_id, body } => {
+            write!(
+                f,
+                "Loop node '{}' is missing '{}' edge",
+                node_id,
+                body_done(*body)
+            )
+        }
+        ValidationError::LoopNeverReturns { node_id } => {
+            write!(f, "The body of loop node '{}' never returns to it", node_id)
+        }
+        ValidationError::ConstantRedefined {
+            name,
+            first,
+            second,
+        } => {
+            if first == second {
+                write!(f, "Constant '{}' is defined more than once in ", name)?;
+                place(first, f)
+            } else {
+                write!(f, "Constant '{}' is defined in both ", name)?;
+                place(first, f)?;
+                write!(f, " and ")?;
+                place(second, f)
+            }
+        }
+        ValidationError::ConstantChanged {
+            name,
+            defined,
+            changed,
+        } => {
+            write!(f, "Constant '{}' (defined in ", name)?;
+            place(defined, f)?;
+            write!(f, ") is changed in ")?;
+            place(changed, f)
+        }
+    }
+}
+
+pub(super) fn runtime_error(e: &RuntimeError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        RuntimeError::UndefinedVariable { name } => {
+            write!(f, "Undefined variable: '{}'", name)
+        }
+        RuntimeError::ConstantReassigned { name } => {
+            write!(f, "Cannot change constant '{}'", name)
+        }
+        RuntimeError::TypeError {
+            expected,
+            actual,
+            operation,
+        } => {
+            write!(
+                f,
+                "Type error in {}: expected {}, got {}",
+                operation, expected, actual
+            )
+        }
+        RuntimeError::CastError {
+            from_type,
+            to_type,
+            value,
+        } => {
+            write!(f, "Cannot cast {} '{}' to {}", from_type, value, to_type)
+        }
+        RuntimeError::DivisionByZero => {
+            write!(f, "Division by zero")
+        }
+        RuntimeError::InvalidExitCode { value } => {
+            write!(
+                f,
+                "Invalid exit code {}: must be an integer between 0 and 255",
+                value
+            )
+        }
+        RuntimeError::MissingStartNode => {
+            write!(f, "Missing 'Start' node")
+        }
+        RuntimeError::MissingEndNode => {
+            write!(f, "Missing 'End' node")
+        }
+        RuntimeError::UnknownEntry { name } => {
+            write!(f, "Unknown entry '{}'", name)
+        }
+        RuntimeError::NoOutgoingEdge { node_id } => {
+            write!(f, "No outgoing edge from node '{}'", node_id)
+        }
+        RuntimeError::NoMatchingConditionEdge {
+            node_id,
+            condition_result,
+        } => {
+            write!(
+                f,
+                "No '{}' edge from condition node '{}'",
+                yes_no(*condition_result),
+                node_id
+            )
+        }
+        RuntimeError::NoMatchingGuard { node_id } => {
+            write!(f, "No guard matched on edges from node '{}'", node_id)
+        }
+        RuntimeError::NoMatchingRule { node_id } => {
+            write!(f, "No rule matched in decision table '{}'", node_id)
+        }
+        RuntimeError::MultipleMatchingRules {
+            node_id,
+            first,
+            second,
+        } => {
+            write!(
+                f,
+                "Rules {} and {} both matched in decision table '{}'",
+                first, second, node_id
+            )
+        }
+        RuntimeError::NoMatchingTableEdge { node_id, label } => {
+            write!(f, "No '{}' edge from decision table '{}'", label, node_id)
+        }
+        RuntimeError::NoMatchingLoopEdge { node_id, body } => {
+            write!(
+                f,
+                "No '{}' edge from loop node '{}'",
+                body_done(*body),
+                node_id
+            )
+        }
+        RuntimeError::NodeNotFound { node_id } => {
+            write!(f, "Node '{}' not found", node_id)
+        }
+        RuntimeError::InvalidArgument { function, message } => {
+            write!(f, "Invalid argument to {}: {}", function, message)
+        }
+        RuntimeError::RandomDenied { function } => {
+            write!(f, "Randomness is denied: cannot call {}", function)
+        }
+        RuntimeError::IoError { message } => {
+            write!(f, "I/O error: {}", message)
+        }
+    }
+}
+
+pub(super) fn explain_hint(codes: &[ErrorCode], f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match codes {
+        [] => Ok(()),
+        [code] => write!(
+            f,
+            "For more information about this error, try `merx explain {}`.",
+            code
+        ),
+        [first, ..] => {
+            let list: Vec<&str> = codes.iter().map(|c| c.as_str()).collect();
+            writeln!(
+                f,
+                "Some errors have detailed explanations: {}.",
+                list.join(", ")
+            )?;
+            write!(
+                f,
+                "For more information about an error, try `merx explain {}`.",
+                first
+            )
+        }
+    }
+}
+
+pub(super) fn read_file_error(e: &ReadFileError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    write!(f, "Error reading file '{}': {}", e.path.display(), e.error)
+}
+
+pub(super) fn watch_summary(s: &WatchSummary, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    write!(
+        f,
+        "[merx] exit {} in {:.2?} - watching {} for changes (Ctrl+C to quit)",
+        s.exit_code,
+        s.elapsed,
+        s.path.display()
+    )
+}
+
+pub(super) fn watch_error(e: &WatchError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    write!(f, "Error watching '{}': {}", e.path.display(), e.error)
+}
+
+pub(super) fn unknown_code(code: &str, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    write!(
+        f,
+        "Unknown error code '{}' (run `merx explain` to list every code)",
+        code
+    )
+}
+
+fn yes_no(yes: bool) -> &'static str {
+    if yes { "Yes" } else { "No" }
+}
+
+fn body_done(body: bool) -> &'static str {
+    if body { "body" } else { "done" }
+}
+
+/// Names what an expression is for, such as `exit code`.
+fn expr_role(role: ExprRole) -> &'static str {
+    match role {
+        ExprRole::ReturnValue => "return value",
+        ExprRole::Guard => "guard",
+        ExprRole::ExitCode => "exit code",
+        ExprRole::InputColumn => "input column",
+        ExprRole::Test => "test",
+        ExprRole::Output => "output",
+    }
+}
+
+/// Writes where a constant is written, such as `node 'A'`.
+fn place(place: &Place, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match place {
+        Place::Node(id) => write!(f, "node '{}'", id),
+        Place::Edge { from, to } => write!(f, "the edge from '{}' to '{}'", from, to),
+    }
+}
+
+/// Writes an input combination, such as `qty = 99, member = true`.
+fn table_inputs(inputs: &[(String, TableValue)], f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    if inputs.is_empty() {
+        return write!(f, "any input");
+    }
+    for (i, (header, value)) in inputs.iter().enumerate() {
+        if i > 0 {
+            write!(f, ", ")?;
+        }
+        match value {
+            TableValue::Literal(value) => write!(f, "{} = {}", header, value)?,
+            TableValue::OtherString => write!(f, "{} = any other string", header)?,
+        }
+    }
+    Ok(())
+}
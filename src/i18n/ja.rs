@@ -0,0 +1,650 @@
+//! Japanese messages.
+
+use std::fmt;
+
+use crate::ast::Arity;
+use crate::codes::ErrorCode;
+use crate::parser::{
+    AnalysisError, ExprRole, Place, SyntaxError, TableError, TableValue, ValidationError,
+};
+use crate::run::RunError;
+use crate::runtime::RuntimeError;
+
+use super::{ReadFileError, WatchError, WatchSummary};
+
+pub(super) fn analysis_error(e: &AnalysisError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        AnalysisError::Syntax(e) => {
+            write!(f, "構文エラー: ")?;
+            syntax_error(e, f)
+        }
+        AnalysisError::Validation(e) => {
+            write!(f, "検証エラー: ")?;
+            validation_error(e, f)
+        }
+    }
+}
+
+pub(super) fn run_error(e: &RunError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        RunError::Analysis(e) => analysis_error(e, f),
+        RunError::Runtime(e) => {
+            write!(f, "実行時エラー: ")?;
+            runtime_error(e, f)
+        }
+    }
+}
+
+pub(super) fn syntax_error(e: &SyntaxError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        SyntaxError::Grammar { message } => write!(f, "{}", message),
+        SyntaxError::Internal { message } => write!(f, "内部エラー: {}", message),
+        SyntaxError::Line { line, error } => {
+            write!(f, "{} 行目: ", line)?;
+            syntax_error(error, f)
+        }
+        SyntaxError::ConflictingEdgeLabels { from } => {
+            write!(
+                f,
+                "'{}' からのエッジにインラインラベル（--text-->）とパイプラベル（|text|）の両方は指定できません",
+                from
+            )
+        }
+        SyntaxError::InvalidEdgeAction { text, message } => {
+            write!(f, "不正なエッジアクション '{}': {}", text, message)
+        }
+        SyntaxError::TextAfterGuard { text } => {
+            write!(
+                f,
+                "ガードの後に予期しない '{}' があります（続けられるのは ', exit N' か ', return expr' だけです）",
+                text
+            )
+        }
+        SyntaxError::MissingReturnValue => {
+            write!(f, "return には値が必要です（例: 'return total'）")
+        }
+        SyntaxError::MissingExitCode => {
+            write!(f, "終了コードには数値が必要です（例: 'exit 1'）")
+        }
+        SyntaxError::ExitCodeOutOfRange { text } => {
+            write!(
+                f,
+                "不正な終了コード '{}': 0 から 255 までの整数である必要があります",
+                text
+            )
+        }
+        SyntaxError::InvalidExpression {
+            role,
+            text,
+            message,
+        } => {
+            write!(f, "不正な{} '{}': {}", expr_role(*role), text, message)
+        }
+        SyntaxError::InvalidNodeLabel { node_id, message } => {
+            write!(f, "ノード '{}' のラベルが不正です: {}", node_id, message)
+        }
+        SyntaxError::DuplicateAssignment { name } => {
+            write!(f, "変数 '{}' に複数回代入されています", name)
+        }
+        SyntaxError::AssignmentCountMismatch { variables, values } => {
+            write!(
+                f,
+                "{} 個の変数に {} 個の値が必要ですが、{} 個が指定されています",
+                variables, variables, values
+            )
+        }
+        SyntaxError::IntegerOutOfRange { literal } => {
+            write!(f, "整数リテラル '{}' が範囲外です", literal)
+        }
+        SyntaxError::UnknownFunction { name } => write!(f, "不明な関数 '{}'", name),
+        SyntaxError::WrongArgumentCount {
+            function,
+            expected,
+            got,
+        } => {
+            write!(f, "関数 '{}' の引数は ", function)?;
+            arity(expected, f)?;
+            write!(f, "ですが、{} 個が渡されました", got)
+        }
+        SyntaxError::ExistsArgument => {
+            write!(f, "関数 'exists' の引数には変数名が必要です")
+        }
+        SyntaxError::DefinedArgument => {
+            write!(f, "関数 'defined' の引数には文字列リテラルが必要です")
+        }
+        SyntaxError::UnsupportedShape { node_id, shape } => {
+            write!(
+                f,
+                "ノード '{}' のシェイプ '{}' はサポートされていません（処理ノードには rect か rounded、条件ノードには diamond、デシジョンテーブルには subroutine、ループには hex、Start/End には stadium を使ってください）",
+                node_id, shape
+            )
+        }
+        SyntaxError::NonTerminalShape { node_id, shape } => {
+            write!(
+                f,
+                "ノード '{}' には stadium などの終端シェイプが必要ですが、'{}' が指定されています",
+                node_id, shape
+            )
+        }
+        SyntaxError::MissingLabel { node_id } => {
+            write!(f, "ノード '{}' にはラベルが必要です", node_id)
+        }
+        SyntaxError::InvalidEntryName { node_id } => {
+            write!(
+                f,
+                "エントリノード '{}' には、英数字とアンダースコアからなる名前を付けた 'Start <name>' のラベルが必要です",
+                node_id
+            )
+        }
+        SyntaxError::EmptyTerminalItem { node_id } => {
+            write!(f, "終端ノード '{}' のラベルに空の項目があります", node_id)
+        }
+        SyntaxError::MultipleTerminalExitCodes { node_id } => {
+            write!(
+                f,
+                "終端ノード '{}' で終了コードが複数宣言されています",
+                node_id
+            )
+        }
+        SyntaxError::MultipleTerminalResults { node_id } => {
+            write!(f, "終端ノード '{}' で結果が複数宣言されています", node_id)
+        }
+        SyntaxError::InvalidTableLabel { node_id } => {
+            write!(
+                f,
+                "デシジョンテーブルノード '{}' には 'table <name>' か 'table '<file.csv>'' のラベル（後に 'first' か 'unique' を付けられます）が必要です",
+                node_id
+            )
+        }
+        SyntaxError::UndeclaredTable { node_id, name } => {
+            write!(
+                f,
+                "ノード '{}' のデシジョンテーブル '{}' が宣言されていません（'%% table {}' のコメントブロックが必要です）",
+                node_id, name, name
+            )
+        }
+        SyntaxError::UnreadableTable {
+            node_id,
+            path,
+            message,
+        } => {
+            write!(
+                f,
+                "ノード '{}' のデシジョンテーブル '{}' を読み込めません: {}",
+                node_id, path, message
+            )
+        }
+        SyntaxError::InvalidTable { node_id, error } => {
+            write!(f, "ノード '{}' のデシジョンテーブルが不正です: ", node_id)?;
+            table_error(error, f)
+        }
+        SyntaxError::DuplicateTable { name } => {
+            write!(f, "デシジョンテーブル '{}' が複数回宣言されています", name)
+        }
+    }
+}
+
+pub(super) fn table_error(e: &TableError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        TableError::Empty => write!(f, "テーブルが空です"),
+        TableError::MultipleTargetColumns => write!(f, "ヘッダーに '->' 列が複数あります"),
+        TableError::InvalidColumn(error) => syntax_error(error, f),
+        TableError::RuleLength {
+            rule,
+            cells,
+            columns,
+        } => {
+            write!(
+                f,
+                "ルール {} のセルは {} 個ですが、ヘッダーの列は {} 個です",
+                rule, cells, columns
+            )
+        }
+        TableError::InvalidCell { rule, error } => {
+            write!(f, "ルール {}: ", rule)?;
+            syntax_error(error, f)
+        }
+        TableError::MissingTarget { rule } => {
+            write!(f, "ルール {} にターゲットのエッジラベルがありません", rule)
+        }
+        TableError::NoRules => write!(f, "テーブルにルールがありません"),
+        TableError::UnclosedQuote { line } => {
+            write!(f, "{} 行目のクォートが閉じられていません", line)
+        }
+        TableError::TextAfterQuote { line } => {
+            write!(f, "{} 行目の閉じクォートの後にテキストがあります", line)
+        }
+    }
+}
+
+pub(super) fn validation_error(e: &ValidationError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        ValidationError::DuplicateNode { node_id } => {
+            write!(f, "ノード '{}' が複数回定義されています", node_id)
+        }
+        ValidationError::MultipleConditionEdges { node_id, yes } => {
+            write!(
+                f,
+                "条件ノード '{}' に '{}' エッジが複数あります",
+                node_id,
+                yes_no(*yes)
+            )
+        }
+        ValidationError::InvalidConditionLabel { node_id, label } => {
+            write!(
+                f,
+                "条件ノード '{}' のエッジには 'Yes' か 'No' のラベルが必要ですが、'{}' が指定されています",
+                node_id, label
+            )
+        }
+        ValidationError::GuardOnConditionEdge { node_id } => {
+            write!(
+                f,
+                "条件ノード '{}' のエッジには 'Yes' か 'No' のラベルが必要ですが、ガードが指定されています",
+                node_id
+            )
+        }
+        ValidationError::UnlabeledConditionEdge { node_id } => {
+            write!(
+                f,
+                "条件ノード '{}' からのエッジには 'Yes' か 'No' のラベルが必要です",
+                node_id
+            )
+        }
+        ValidationError::MissingConditionEdge { node_id, yes } => {
+            write!(
+                f,
+                "条件ノード '{}' に '{}' エッジがありません",
+                node_id,
+                yes_no(*yes)
+            )
+        }
+        ValidationError::UnlabeledTableEdge { node_id } => {
+            write!(
+                f,
+                "デシジョンテーブル '{}' からのエッジには '->' 列のターゲットをラベルとして付ける必要があります",
+                node_id
+            )
+        }
+        ValidationError::MultipleTableEdges { node_id, label } => {
+            write!(
+                f,
+                "デシジョンテーブル '{}' に '{}' エッジが複数あります",
+                node_id, label
+            )
+        }
+        ValidationError::MissingTableEdge { node_id, label } => {
+            write!(
+                f,
+                "デシジョンテーブル '{}' に '{}' エッジがありません",
+                node_id, label
+            )
+        }
+        ValidationError::TableGap { node_id, inputs } => {
+            write!(f, "デシジョンテーブル '{}' に ", node_id)?;
+            table_inputs(inputs, f)?;
+            write!(f, " に対するルールがありません")
+        }
+        ValidationError::TableOverlap {
+            node_id,
+            first,
+            second,
+            inputs,
+        } => {
+            write!(
+                f,
+                "デシジョンテーブル '{}' のルール {} と {} が重複しています（どちらも ",
+                node_id, first, second
+            )?;
+            table_inputs(inputs, f)?;
+            write!(f, " に一致します）")
+        }
+        ValidationError::MissingStartNode => write!(f, "'Start' ノードがありません"),
+        ValidationError::MissingEndNode => write!(f, "'End' ノードがありません"),
+        ValidationError::UndefinedNode { node_id, from, to } => {
+            write!(
+                f,
+                "'{}' から '{}' へのエッジで未定義のノード '{}' が参照されています",
+                from, to, node_id
+            )
+        }
+        ValidationError::EdgeFromEnd => write!(f, "End ノードから出るエッジは指定できません"),
+        ValidationError::EdgeFromTerminal { node_id } => {
+            write!(f, "終端ノード '{}' から出るエッジは指定できません", node_id)
+        }
+        ValidationError::MultipleOutgoingEdges { node_id } => {
+            write!(
+                f,
+                "ノード '{}' から出るエッジが複数あります（1 本までです）",
+                node_id
+            )
+        }
+        ValidationError::DuplicateEntry {
+            name,
+            first,
+            second,
+        } => {
+            write!(
+                f,
+                "エントリ '{}' が '{}' と '{}' の両方で宣言されています",
+                name, first, second
+            )
+        }
+        ValidationError::UnreachableEnd { entry: None } => {
+            write!(f, "'Start' ノードから End ノードに到達できません")
+        }
+        ValidationError::UnreachableEnd { entry: Some(name) } => {
+            write!(f, "エントリ '{}' から End ノードに到達できません", name)
+        }
+        ValidationError::MisplacedExitCode { from, to } => {
+            write!(
+                f,
+                "終了コードは 'End' ノードなどの終端ノードへのエッジにのみ指定できますが、'{}' から '{}' へのエッジに指定されています",
+                from, to
+            )
+        }
+        ValidationError::MisplacedReturn { from, to } => {
+            write!(
+                f,
+                "戻り値は 'End' ノードなどの終端ノードへのエッジにのみ指定できますが、'{}' から '{}' へのエッジに指定されています",
+                from, to
+            )
+        }
+        ValidationError::MultipleElseEdges { node_id } => {
+            write!(f, "ノード '{}' に [else] エッジが複数あります", node_id)
+        }
+        ValidationError::MixedGuards { node_id } => {
+            write!(
+                f,
+                "ノード '{}' から出るエッジに、ガードのあるものとないものが混在しています",
+                node_id
+            )
+        }
+        ValidationError::NonExhaustiveGuards { node_id } => {
+            write!(
+                f,
+                "ノード '{}' からのエッジのガードがすべての場合を網羅していない可能性があります。[else] エッジを追加してください",
+                node_id
+            )
+        }
+        ValidationError::MultipleLoopEdges { node_id, body } => {
+            write!(
+                f,
+                "ループノード '{}' に '{}' エッジが複数あります",
+                node_id,
+                body_done(*body)
+            )
+        }
+        ValidationError::InvalidLoopLabel { node_id } => {
+            write!(
+                f,
+                "ループノード '{}' からのエッジには 'body' か 'done' のラベルが必要です",
+                node_id
+            )
+        }
+        ValidationError::MissingLoopEdge { node_id, body } => {
+            write!(
+                f,
+                "ループノード '{}' に '{}' エッジがありません",
+                node_id,
+                body_done(*body)
+            )
+        }
+        ValidationError::LoopNeverReturns { node_id } => {
+            write!(
+                f,
+                "ループノード '{}' の本体からループノードに戻る経路がありません",
+                node_id
+            )
+        }
+        ValidationError::ConstantRedefined {
+            name,
+            first,
+            second,
+        } => {
+            write!(f, "定数 '{}' が", name)?;
+            place(first, f)?;
+            if first == second {
+                write!(f, " で複数回定義されています")
+            } else {
+                write!(f, " と")?;
+                place(second, f)?;
+                write!(f, " の両方で定義されています")
+            }
+        }
+        ValidationError::ConstantChanged {
+            name,
+            defined,
+            changed,
+        } => {
+            write!(f, "定数 '{}'（", name)?;
+            place(defined, f)?;
+            write!(f, " で定義）が")?;
+            place(changed, f)?;
+            write!(f, " で変更されています")
+        }
+    }
+}
+
+pub(super) fn runtime_error(e: &RuntimeError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match e {
+        RuntimeError::UndefinedVariable { name } => {
+            write!(f, "未定義の変数: '{}'", name)
+        }
+        RuntimeError::ConstantReassigned { name } => {
+            write!(f, "定数 '{}' は変更できません", name)
+        }
+        RuntimeError::TypeError {
+            expected,
+            actual,
+            operation,
+        } => {
+            write!(
+                f,
+                "{} で型エラー: {} が必要ですが {} が渡されました",
+                operation, expected, actual
+            )
+        }
+        RuntimeError::CastError {
+            from_type,
+            to_type,
+            value,
+        } => {
+            write!(
+                f,
+                "{} '{}' を {} に変換できません",
+                from_type, value, to_type
+            )
+        }
+        RuntimeError::DivisionByZero => {
+            write!(f, "ゼロ除算")
+        }
+        RuntimeError::InvalidExitCode { value } => {
+            write!(
+                f,
+                "不正な終了コード {}: 0 から 255 までの整数である必要があります",
+                value
+            )
+        }
+        RuntimeError::MissingStartNode => {
+            write!(f, "'Start' ノードがありません")
+        }
+        RuntimeError::MissingEndNode => {
+            write!(f, "'End' ノードがありません")
+        }
+        RuntimeError::UnknownEntry { name } => {
+            write!(f, "不明なエントリ '{}'", name)
+        }
+        RuntimeError::NoOutgoingEdge { node_id } => {
+            write!(f, "ノード '{}' から出るエッジがありません", node_id)
+        }
+        RuntimeError::NoMatchingConditionEdge {
+            node_id,
+            condition_result,
+        } => {
+            write!(
+                f,
+                "条件ノード '{}' に '{}' エッジがありません",
+                node_id,
+                yes_no(*condition_result)
+            )
+        }
+        RuntimeError::NoMatchingGuard { node_id } => {
+            write!(
+                f,
+                "ノード '{}' からのエッジに一致するガードがありません",
+                node_id
+            )
+        }
+        RuntimeError::NoMatchingRule { node_id } => {
+            write!(
+                f,
+                "デシジョンテーブル '{}' に一致するルールがありません",
+                node_id
+            )
+        }
+        RuntimeError::MultipleMatchingRules {
+            node_id,
+            first,
+            second,
+        } => {
+            write!(
+                f,
+                "デシジョンテーブル '{}' でルール {} と {} の両方が一致しました",
+                node_id, first, second
+            )
+        }
+        RuntimeError::NoMatchingTableEdge { node_id, label } => {
+            write!(
+                f,
+                "デシジョンテーブル '{}' に '{}' エッジがありません",
+                node_id, label
+            )
+        }
+        RuntimeError::NoMatchingLoopEdge { node_id, body } => {
+            write!(
+                f,
+                "ループノード '{}' に '{}' エッジがありません",
+                node_id,
+                body_done(*body)
+            )
+        }
+        RuntimeError::NodeNotFound { node_id } => {
+            write!(f, "ノード '{}' が見つかりません", node_id)
+        }
+        RuntimeError::InvalidArgument { function, message } => {
+            write!(f, "{} の引数が不正です: {}", function, message)
+        }
+        RuntimeError::RandomDenied { function } => {
+            write!(f, "乱数は禁止されています: {} は呼び出せません", function)
+        }
+        RuntimeError::IoError { message } => {
+            write!(f, "入出力エラー: {}", message)
+        }
+    }
+}
+
+pub(super) fn explain_hint(codes: &[ErrorCode], f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match codes {
+        [] => Ok(()),
+        [code] => write!(
+            f,
+            "このエラーの詳細は `merx explain {}` を実行してください。",
+            code
+        ),
+        [first, ..] => {
+            let list: Vec<&str> = codes.iter().map(|c| c.as_str()).collect();
+            writeln!(f, "詳しい説明があるエラー: {}", list.join(", "))?;
+            write!(
+                f,
+                "エラーの詳細は `merx explain {}` を実行してください。",
+                first
+            )
+        }
+    }
+}
+
+pub(super) fn read_file_error(e: &ReadFileError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    write!(
+        f,
+        "ファイル '{}' を読み込めません: {}",
+        e.path.display(),
+        e.error
+    )
+}
+
+pub(super) fn watch_summary(s: &WatchSummary, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    write!(
+        f,
+        "[merx] 終了コード {}（{:.2?}）- {} の変更を監視しています（Ctrl+C で終了）",
+        s.exit_code,
+        s.elapsed,
+        s.path.display()
+    )
+}
+
+pub(super) fn watch_error(e: &WatchError, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    write!(f, "'{}' を監視できません: {}", e.path.display(), e.error)
+}
+
+pub(super) fn unknown_code(code: &str, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    write!(
+        f,
+        "不明なエラーコード '{}'（`merx explain` ですべてのコードを一覧できます）",
+        code
+    )
+}
+
+fn yes_no(yes: bool) -> &'static str {
+    if yes { "Yes" } else { "No" }
+}
+
+fn body_done(body: bool) -> &'static str {
+    if body { "body" } else { "done" }
+}
+
+/// Names what an expression is for, such as `終了コード`.
+fn expr_role(role: ExprRole) -> &'static str {
+    match role {
+        ExprRole::ReturnValue => "戻り値",
+        ExprRole::Guard => "ガード",
+        ExprRole::ExitCode => "終了コード",
+        ExprRole::InputColumn => "入力列",
+        ExprRole::Test => "入力セル",
+        ExprRole::Output => "出力セル",
+    }
+}
+
+/// Writes a number of arguments, such as `1 個以上`.
+fn arity(arity: &Arity, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match arity {
+        Arity::Exact(n) => write!(f, "{} 個", n),
+        Arity::AtLeast(n) => write!(f, "{} 個以上", n),
+    }
+}
+
+/// Writes where a constant is written, such as `ノード 'A'` or
+/// `エッジ 'A' --> 'B'`.
+fn place(place: &Place, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    match place {
+        Place::Node(id) => write!(f, "ノード '{}'", id),
+        Place::Edge { from, to } => write!(f, "エッジ '{}' --> '{}'", from, to),
+    }
+}
+
+/// Writes an input combination, such as `qty = 99, member = true`.
+fn table_inputs(inputs: &[(String, TableValue)], f: &mut fmt::Formatter<'_>) -> fmt::Result {
+    if inputs.is_empty() {
+        return write!(f, "任意の入力");
+    }
+    for (i, (header, value)) in inputs.iter().enumerate() {
+        if i > 0 {
+            write!(f, ", ")?;
+        }
+        match value {
+            TableValue::Literal(value) => write!(f, "{} = {}", header, value)?,
+            TableValue::OtherString => write!(f, "{} = その他の文字列", header)?,
+        }
+    }
+    Ok(())
+}
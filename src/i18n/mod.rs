@@ -0,0 +1,497 @@
+//! Localized diagnostic messages.
+//!
+//! Errors keep the details of a problem in structured fields, and are put
+//! into words only when they are displayed. Their
+//! [`Display`](std::fmt::Display) implementations use English; the
+//! [`Localize`] trait renders them in any supported [`Lang`], from the
+//! message catalog of that language.
+//!
+//! Details that come from elsewhere are included as they are, in English:
+//! the PEG parser's descriptions of grammar errors, the operation of a
+//! [`TypeError`](crate::runtime::RuntimeError::TypeError), the reason for an
+//! [`InvalidArgument`](crate::runtime::RuntimeError::InvalidArgument), and
+//! I/O error messages.
+//!
+//! # Examples
+//!
+//! ```
+//! use merx::i18n::{Lang, Localize};
+//! use merx::runtime::RuntimeError;
+//!
+//! let err = RuntimeError::DivisionByZero;
+//! assert_eq!(err.to_string(), "Division by zero");
+//! assert_eq!(err.in_lang(Lang::Ja).to_string(), "ゼロ除算");
+//! ```
+
+mod en;
+mod ja;
+
+use std::fmt;
+use std::io;
+use std::path::Path;
+use std::time::Duration;
+
+use crate::codes::ErrorCode;
+use crate::parser::{AnalysisError, SyntaxError, TableError, ValidationError};
+use crate::run::RunError;
+use crate::runtime::RuntimeError;
+
+/// A language that diagnostic messages can be displayed in.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Hash)]
+pub enum Lang {
+    /// English, the default.
+    #[default]
+    En,
+    /// Japanese.
+    Ja,
+}
+
+impl Lang {
+    /// Returns the language of a language tag or locale name, such as `ja`,
+    /// `en-US` or `ja_JP.UTF-8`, or `None` if it is not supported.
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::i18n::Lang;
+    ///
+    /// assert_eq!(Lang::from_tag("ja_JP.UTF-8"), Some(Lang::Ja));
+    /// assert_eq!(Lang::from_tag("EN"), Some(Lang::En));
+    /// assert_eq!(Lang::from_tag("fr"), None);
+    /// ```
+    pub fn from_tag(tag: &str) -> Option<Lang> {
+        let language = tag.split(['-', '_', '.', '@']).next().unwrap_or("");
+        match language.to_ascii_lowercase().as_str() {
+            "en" => Some(Lang::En),
+            "ja" => Some(Lang::Ja),
+            _ => None,
+        }
+    }
+
+    /// Returns the language of the messages locale, from the first of the
+    /// `LC_ALL`, `LC_MESSAGES` and `LANG` environment variables that is set.
+    ///
+    /// Falls back to English if none is set, or the locale's language is not
+    /// supported.
+    pub fn from_env() -> Lang {
+        from_locale(|name| std::env::var(name).ok())
+    }
+}
+
+/// Returns the language of the messages locale, reading environment
+/// variables with `var`.
+fn from_locale(var: impl Fn(&str) -> Option<String>) -> Lang {
+    ["LC_ALL", "LC_MESSAGES", "LANG"]
+        .into_iter()
+        .filter_map(var)
+        .find(|value| !value.is_empty())
+        .and_then(|value| Lang::from_tag(&value))
+        .unwrap_or_default()
+}
+
+/// A message that can be displayed in any supported [`Lang`].
+pub trait Localize {
+    /// Writes the message in `lang`.
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result;
+
+    /// Returns a value whose [`Display`](fmt::Display) implementation writes
+    /// the message in `lang`.
+    fn in_lang(&self, lang: Lang) -> InLang<'_, Self> {
+        InLang {
+            message: self,
+            lang,
+        }
+    }
+}
+
+/// A message together with the language to display it in, returned by
+/// [`Localize::in_lang`].
+#[derive(Debug, Clone, Copy)]
+pub struct InLang<'a, T: ?Sized> {
+    message: &'a T,
+    lang: Lang,
+}
+
+impl<T: Localize + ?Sized> fmt::Display for InLang<'_, T> {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.message.localize(self.lang, f)
+    }
+}
+
+/// The hint printed after errors, pointing to `merx explain` for their
+/// codes.
+///
+/// The codes should be sorted and free of duplicates. The hint may span
+/// several lines, and is empty if there are no codes.
+#[derive(Debug, Clone, Copy)]
+pub struct ExplainHint<'a>(pub &'a [ErrorCode]);
+
+impl fmt::Display for ExplainHint<'_> {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.localize(Lang::En, f)
+    }
+}
+
+impl Localize for ExplainHint<'_> {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::explain_hint(self.0, f),
+            Lang::Ja => ja::explain_hint(self.0, f),
+        }
+    }
+}
+
+/// The error printed when a program file cannot be read.
+#[derive(Debug, Clone, Copy)]
+pub struct ReadFileError<'a> {
+    /// The file that was to be read.
+    pub path: &'a Path,
+    /// Why it could not be read. Its description is not localized.
+    pub error: &'a io::Error,
+}
+
+impl fmt::Display for ReadFileError<'_> {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.localize(Lang::En, f)
+    }
+}
+
+impl Localize for ReadFileError<'_> {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::read_file_error(self, f),
+            Lang::Ja => ja::read_file_error(self, f),
+        }
+    }
+}
+
+/// The line printed after each run in watch mode.
+#[derive(Debug, Clone, Copy)]
+pub struct WatchSummary<'a> {
+    /// The exit code of the run.
+    pub exit_code: u8,
+    /// How long the run took.
+    pub elapsed: Duration,
+    /// The program file being watched.
+    pub path: &'a Path,
+}
+
+impl fmt::Display for WatchSummary<'_> {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.localize(Lang::En, f)
+    }
+}
+
+impl Localize for WatchSummary<'_> {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::watch_summary(self, f),
+            Lang::Ja => ja::watch_summary(self, f),
+        }
+    }
+}
+
+/// The error printed when watch mode can no longer watch a program.
+#[derive(Debug, Clone, Copy)]
+pub struct WatchError<'a> {
+    /// The program file being watched.
+    pub path: &'a Path,
+    /// Why it can no longer be watched. Its description is not localized.
+    pub error: &'a io::Error,
+}
+
+impl fmt::Display for WatchError<'_> {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.localize(Lang::En, f)
+    }
+}
+
+impl Localize for WatchError<'_> {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::watch_error(self, f),
+            Lang::Ja => ja::watch_error(self, f),
+        }
+    }
+}
+
+/// The error printed when `merx explain` is given a code that does not
+/// exist.
+#[derive(Debug, Clone, Copy)]
+pub struct UnknownCode<'a>(pub &'a str);
+
+impl fmt::Display for UnknownCode<'_> {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.localize(Lang::En, f)
+    }
+}
+
+impl Localize for UnknownCode<'_> {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::unknown_code(self.0, f),
+            Lang::Ja => ja::unknown_code(self.0, f),
+        }
+    }
+}
+
+impl Localize for SyntaxError {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::syntax_error(self, f),
+            Lang::Ja => ja::syntax_error(self, f),
+        }
+    }
+}
+
+impl Localize for TableError {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::table_error(self, f),
+            Lang::Ja => ja::table_error(self, f),
+        }
+    }
+}
+
+impl Localize for ValidationError {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::validation_error(self, f),
+            Lang::Ja => ja::validation_error(self, f),
+        }
+    }
+}
+
+impl Localize for AnalysisError {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::analysis_error(self, f),
+            Lang::Ja => ja::analysis_error(self, f),
+        }
+    }
+}
+
+impl Localize for RuntimeError {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::runtime_error(self, f),
+            Lang::Ja => ja::runtime_error(self, f),
+        }
+    }
+}
+
+impl Localize for RunError {
+    fn localize(&self, lang: Lang, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match lang {
+            Lang::En => en::run_error(self, f),
+            Lang::Ja => ja::run_error(self, f),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ast::Arity;
+    use crate::parser::{ExprRole, Place, TableValue};
+
+    #[test]
+    fn test_from_tag() {
+        assert_eq!(Lang::from_tag("ja"), Some(Lang::Ja));
+        assert_eq!(Lang::from_tag("ja-JP"), Some(Lang::Ja));
+        assert_eq!(Lang::from_tag("en_GB.UTF-8"), Some(Lang::En));
+        assert_eq!(Lang::from_tag("C"), None);
+        assert_eq!(Lang::from_tag(""), None);
+    }
+
+    #[test]
+    fn test_from_locale_uses_the_first_variable_set() {
+        let env = |vars: &'static [(&'static str, &'static str)]| {
+            move |name: &str| {
+                vars.iter()
+                    .find(|(n, _)| *n == name)
+                    .map(|(_, v)| v.to_string())
+            }
+        };
+        assert_eq!(from_locale(env(&[("LANG", "ja_JP.UTF-8")])), Lang::Ja);
+        assert_eq!(
+            from_locale(env(&[("LC_ALL", ""), ("LANG", "ja_JP.UTF-8")])),
+            Lang::Ja
+        );
+        assert_eq!(
+            from_locale(env(&[("LC_MESSAGES", "C"), ("LANG", "ja_JP.UTF-8")])),
+            Lang::En
+        );
+        assert_eq!(from_locale(env(&[("LANG", "fr_FR.UTF-8")])), Lang::En);
+        assert_eq!(from_locale(env(&[])), Lang::En);
+    }
+
+    #[test]
+    fn test_display_is_english() {
+        let err = ValidationError::NonExhaustiveGuards {
+            node_id: "A".to_string(),
+        };
+        assert_eq!(err.to_string(), err.in_lang(Lang::En).to_string());
+    }
+
+    #[test]
+    fn test_japanese_validation_errors() {
+        let err = ValidationError::UndefinedNode {
+            node_id: "C".to_string(),
+            from: "A".to_string(),
+            to: "C".to_string(),
+        };
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "'A' から 'C' へのエッジで未定義のノード 'C' が参照されています"
+        );
+
+        let err = ValidationError::ConstantChanged {
+            name: "RATE".to_string(),
+            defined: Place::Node("A".to_string()),
+            changed: Place::Edge {
+                from: "A".to_string(),
+                to: "B".to_string(),
+            },
+        };
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "定数 'RATE'（ノード 'A' で定義）がエッジ 'A' --> 'B' で変更されています"
+        );
+
+        let err = ValidationError::TableGap {
+            node_id: "T".to_string(),
+            inputs: vec![
+                ("qty".to_string(), TableValue::Literal("10".to_string())),
+                ("tier".to_string(), TableValue::OtherString),
+            ],
+        };
+        assert_eq!(
+            err.to_string(),
+            "Decision table 'T' has no rule for qty = 10, tier = any other string"
+        );
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "デシジョンテーブル 'T' に qty = 10, tier = その他の文字列 に対するルールがありません"
+        );
+    }
+
+    #[test]
+    fn test_japanese_syntax_errors() {
+        let err = AnalysisError::from(SyntaxError::Line {
+            line: 3,
+            error: Box::new(SyntaxError::UnknownFunction {
+                name: "nope".to_string(),
+            }),
+        });
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "構文エラー: 3 行目: 不明な関数 'nope'"
+        );
+
+        let err = SyntaxError::WrongArgumentCount {
+            function: "max".to_string(),
+            expected: Arity::AtLeast(1),
+            got: 0,
+        };
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "関数 'max' の引数は 1 個以上ですが、0 個が渡されました"
+        );
+
+        let err = SyntaxError::InvalidTable {
+            node_id: "T".to_string(),
+            error: TableError::InvalidCell {
+                rule: 2,
+                error: Box::new(SyntaxError::InvalidExpression {
+                    role: ExprRole::Output,
+                    text: "1 +".to_string(),
+                    message: "expected primary".to_string(),
+                }),
+            },
+        };
+        assert_eq!(
+            err.to_string(),
+            "invalid decision table of node 'T': rule 2: invalid output '1 +': expected primary"
+        );
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "ノード 'T' のデシジョンテーブルが不正です: ルール 2: 不正な出力セル '1 +': expected primary"
+        );
+    }
+
+    #[test]
+    fn test_japanese_run_errors() {
+        let err = RunError::Runtime(RuntimeError::UndefinedVariable {
+            name: "x".to_string(),
+        });
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "実行時エラー: 未定義の変数: 'x'"
+        );
+    }
+
+    #[test]
+    fn test_explain_hint() {
+        let one = [ErrorCode::DivisionByZero];
+        assert_eq!(
+            ExplainHint(&one).to_string(),
+            "For more information about this error, try `merx explain MX0301`."
+        );
+        let two = [ErrorCode::MissingYesEdge, ErrorCode::DivisionByZero];
+        assert_eq!(
+            ExplainHint(&two).in_lang(Lang::Ja).to_string(),
+            "詳しい説明があるエラー: MX0101, MX0301\nエラーの詳細は `merx explain MX0101` を実行してください。"
+        );
+        assert_eq!(ExplainHint(&[]).to_string(), "");
+    }
+
+    #[test]
+    fn test_cli_errors() {
+        let error = io::Error::new(io::ErrorKind::NotFound, "not found");
+        let err = ReadFileError {
+            path: Path::new("main.mmd"),
+            error: &error,
+        };
+        assert_eq!(err.to_string(), "Error reading file 'main.mmd': not found");
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "ファイル 'main.mmd' を読み込めません: not found"
+        );
+        assert_eq!(
+            UnknownCode("MX9999").in_lang(Lang::Ja).to_string(),
+            "不明なエラーコード 'MX9999'（`merx explain` ですべてのコードを一覧できます）"
+        );
+    }
+
+    #[test]
+    fn test_watch_messages() {
+        let summary = WatchSummary {
+            exit_code: 1,
+            elapsed: Duration::from_millis(5),
+            path: Path::new("main.mmd"),
+        };
+        assert_eq!(
+            summary.to_string(),
+            "[merx] exit 1 in 5.00ms - watching main.mmd for changes (Ctrl+C to quit)"
+        );
+        assert_eq!(
+            summary.in_lang(Lang::Ja).to_string(),
+            "[merx] 終了コード 1（5.00ms）- main.mmd の変更を監視しています（Ctrl+C で終了）"
+        );
+
+        let error = io::Error::other("too many watches");
+        let err = WatchError {
+            path: Path::new("main.mmd"),
+            error: &error,
+        };
+        assert_eq!(
+            err.to_string(),
+            "Error watching 'main.mmd': too many watches"
+        );
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "'main.mmd' を監視できません: too many watches"
+        );
+    }
+}
@@ -1,3 +1,8 @@
 pub mod ast;
+pub mod codes;
+pub mod i18n;
 pub mod parser;
+mod run;
 pub mod runtime;
+
+pub use run::{RunError, RunResult, run_str};
@@ -1,17 +1,33 @@
+mod cache;
+mod watch;
+
 use std::fs;
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
 use std::process::ExitCode;
 
-use clap::{Parser, Subcommand};
+use clap::{Args, Parser, Subcommand};
+
+use cache::Cache;
 
-use merx::parser;
-use merx::runtime::Interpreter;
+use merx::RunError;
+use merx::ast::Flowchart;
+use merx::codes::ErrorCode;
+use merx::i18n::{ExplainHint, Lang, Localize, ReadFileError, UnknownCode};
+use merx::parser::{self, AnalysisError, Document, PartialParse};
+use merx::runtime::{
+    BufferedStdioWriter, ExitCodePolicy, Interpreter, OutputWriter, RuntimeError, StdinReader,
+    StdioWriter,
+};
 
 #[derive(Parser)]
 #[command(name = "merx", about = "Mermaid flowchart executor", version)]
 struct Cli {
     #[command(subcommand)]
     command: Commands,
+
+    /// Language of error messages (defaults to the language of $LANG)
+    #[arg(long, global = true, value_name = "LANG", value_parser = ["en", "ja"])]
+    lang: Option<String>,
 }
 
 #[derive(Subcommand)]
@@ -20,45 +36,350 @@ enum Commands {
     Run {
         /// Path to the .mmd file
         file: PathBuf,
+
+        /// Begin at the entry node labeled `Start <NAME>` instead of `Start`
+        #[arg(long, value_name = "NAME")]
+        entry: Option<String>,
+
+        /// Seed for the random number generator (makes random functions reproducible)
+        #[arg(long)]
+        seed: Option<u64>,
+
+        /// Make random functions (rand_int, rand_choice, shuffle) a runtime error
+        #[arg(long, conflicts_with = "seed")]
+        deny_random: bool,
+
+        /// Clamp computed exit codes into 0-255 instead of failing when out of range
+        #[arg(long)]
+        clamp_exit_code: bool,
+
+        /// Write each line of output immediately instead of buffering stdout
+        #[arg(long)]
+        unbuffered: bool,
+
+        /// Re-run the program whenever the file changes
+        #[arg(long)]
+        watch: bool,
+
+        #[command(flatten)]
+        cache: CacheArgs,
+    },
+
+    /// Parse and validate a Mermaid flowchart program without running it
+    Check {
+        /// Path to the .mmd file
+        file: PathBuf,
+
+        /// Re-check the program whenever the file changes
+        #[arg(long)]
+        watch: bool,
+
+        #[command(flatten)]
+        cache: CacheArgs,
     },
+
+    /// Explain an error code, such as MX0101
+    Explain {
+        /// The error code to explain (lists every code if omitted)
+        code: Option<String>,
+    },
+}
+
+/// Options for caching parsed programs.
+///
+/// Not available in watch mode, which keeps the last parse in memory
+/// instead.
+#[derive(Args)]
+struct CacheArgs {
+    /// Cache the parsed program in .merx-cache/ to skip parsing on later runs
+    #[arg(long, conflicts_with = "watch")]
+    cache: bool,
+
+    /// Cache the parsed program in DIR instead of .merx-cache/ (implies --cache)
+    #[arg(long, value_name = "DIR", conflicts_with = "watch")]
+    cache_dir: Option<PathBuf>,
+}
+
+impl CacheArgs {
+    /// Returns the cache to use, if caching is enabled.
+    fn cache(self) -> Option<Cache> {
+        match self.cache_dir {
+            Some(dir) => Some(Cache::new(dir)),
+            None if self.cache => Some(Cache::new(cache::DEFAULT_DIR)),
+            None => None,
+        }
+    }
+}
+
+/// Options for a single `run` invocation.
+struct RunOptions {
+    lang: Lang,
+    entry: Option<String>,
+    seed: Option<u64>,
+    deny_random: bool,
+    clamp_exit_code: bool,
+    unbuffered: bool,
 }
 
 fn main() -> ExitCode {
     let cli = Cli::parse();
+    let lang = cli
+        .lang
+        .as_deref()
+        .and_then(Lang::from_tag)
+        .unwrap_or_else(Lang::from_env);
 
     match cli.command {
-        Commands::Run { file } => {
-            let content = match fs::read_to_string(&file) {
-                Ok(c) => c,
-                Err(e) => {
-                    eprintln!("Error reading file '{}': {}", file.display(), e);
-                    return ExitCode::from(2);
-                }
+        Commands::Run {
+            file,
+            entry,
+            seed,
+            deny_random,
+            clamp_exit_code,
+            unbuffered,
+            watch,
+            cache,
+        } => {
+            let options = RunOptions {
+                lang,
+                entry,
+                seed,
+                deny_random,
+                clamp_exit_code,
+                unbuffered,
             };
+            if watch {
+                let mut loader = Loader::Incremental(None);
+                watch::watch(&file, lang, || {
+                    let code = run_file(&file, &mut loader, &options);
+                    (code, loader.table_files())
+                })
+            } else {
+                let mut loader = Loader::Full(cache.cache());
+                ExitCode::from(run_file(&file, &mut loader, &options))
+            }
+        }
+        Commands::Check { file, watch, cache } => {
+            if watch {
+                let mut loader = Loader::Incremental(None);
+                watch::watch(&file, lang, || {
+                    let code = check_file(&file, &mut loader, lang);
+                    (code, loader.table_files())
+                })
+            } else {
+                let mut loader = Loader::Full(cache.cache());
+                ExitCode::from(check_file(&file, &mut loader, lang))
+            }
+        }
+        Commands::Explain { code } => ExitCode::from(explain(code.as_deref(), lang)),
+    }
+}
 
-            let flowchart = match parser::parse(&content) {
-                Ok(f) => f,
-                Err(e) => {
-                    eprintln!("{}", e);
-                    return ExitCode::from(2);
-                }
-            };
+/// How program files are parsed.
+enum Loader {
+    /// Parse the whole file each time, using the cache if there is one.
+    Full(Option<Cache>),
 
-            let mut interpreter = match Interpreter::new(flowchart) {
-                Ok(i) => i,
-                Err(e) => {
-                    eprintln!("Runtime error: {}", e);
-                    return ExitCode::from(1);
-                }
-            };
+    /// Keep the last parse, and re-parse only the lines that changed since.
+    /// Used in watch mode, which does not allow caching.
+    Incremental(Option<Document>),
+}
+
+impl Loader {
+    /// Returns the decision table files of the last program loaded
+    /// incrementally, which watch mode also watches.
+    fn table_files(&self) -> Vec<PathBuf> {
+        match self {
+            Loader::Incremental(Some(document)) => document.table_files(),
+            _ => Vec::new(),
+        }
+    }
+
+    /// Reads and parses a program file, printing any error in `lang`.
+    ///
+    /// Returns the exit code to use on failure.
+    fn load(&mut self, file: &Path, lang: Lang) -> Result<Flowchart, u8> {
+        let content = match fs::read_to_string(file) {
+            Ok(c) => c,
+            Err(e) => {
+                let error = ReadFileError {
+                    path: file,
+                    error: &e,
+                };
+                eprintln!("{}", error.in_lang(lang));
+                return Err(2);
+            }
+        };
 
-            match interpreter.run() {
-                Ok(exit_code) => ExitCode::from(exit_code),
-                Err(e) => {
-                    eprintln!("Runtime error: {}", e);
-                    ExitCode::from(1)
+        // Decision table files are resolved relative to the program
+        let dir = file.parent().unwrap_or(Path::new(""));
+        match self {
+            Loader::Full(cache) => {
+                if let Some(flowchart) = cache.as_ref().and_then(|c| c.load(&content, dir)) {
+                    return Ok(flowchart);
                 }
+                let flowchart = parser::parse_in_dir(&content, dir).map_err(|e| {
+                    report_error(e, || parser::parse_partial_in_dir(&content, dir), lang);
+                    2
+                })?;
+                if let Some(cache) = cache {
+                    cache.store(&content, dir, &flowchart);
+                }
+                Ok(flowchart)
+            }
+            Loader::Incremental(document) => {
+                let document = match document {
+                    Some(document) => {
+                        document.update(&content);
+                        document
+                    }
+                    None => document.insert(Document::in_dir(content, dir)),
+                };
+                document.flowchart().map_err(|e| {
+                    report_error(e, || document.partial(), lang);
+                    2
+                })
+            }
+        }
+    }
+}
+
+/// Prints an analysis error. After a syntax error, the rest of the program
+/// is parsed too, so that every syntax error is reported at once.
+fn report_error(error: AnalysisError, partial: impl FnOnce() -> PartialParse, lang: Lang) {
+    if matches!(error, AnalysisError::Syntax(_)) {
+        let errors = partial().errors;
+        if !errors.is_empty() {
+            let codes: Vec<ErrorCode> = errors.iter().map(|e| e.code()).collect();
+            for e in errors {
+                eprintln!("{}", AnalysisError::from(e).in_lang(lang));
+            }
+            print_explain_hint(codes, lang);
+            return;
+        }
+    }
+    eprintln!("{}", error.in_lang(lang));
+    print_explain_hint(vec![error.code()], lang);
+}
+
+/// Prints a runtime error.
+fn report_runtime_error(error: RuntimeError, lang: Lang) {
+    let code = error.code();
+    eprintln!("{}", RunError::Runtime(error).in_lang(lang));
+    print_explain_hint(vec![code], lang);
+}
+
+/// Points to `merx explain` for the codes of the errors just printed.
+fn print_explain_hint(mut codes: Vec<ErrorCode>, lang: Lang) {
+    codes.sort_by_key(|c| c.as_str());
+    codes.dedup();
+    if !codes.is_empty() {
+        eprintln!("{}", ExplainHint(&codes).in_lang(lang));
+    }
+}
+
+/// Prints the explanation of an error code, or lists every code if none is
+/// given, returning the process exit code. An unknown code is reported in
+/// `lang`.
+fn explain(code: Option<&str>, lang: Lang) -> u8 {
+    let Some(code) = code else {
+        for code in ErrorCode::ALL {
+            println!("{}  {}", code, code.title());
+        }
+        return 0;
+    };
+    match ErrorCode::from_code(code) {
+        Some(code) => {
+            print!("{}", code.explanation());
+            0
+        }
+        None => {
+            eprintln!("{}", UnknownCode(code).in_lang(lang));
+            2
+        }
+    }
+}
+
+/// Parses and validates a program file, returning the process exit code.
+fn check_file(file: &Path, loader: &mut Loader, lang: Lang) -> u8 {
+    match loader.load(file, lang) {
+        Ok(_) => {
+            println!("{}: OK", file.display());
+            0
+        }
+        Err(exit_code) => exit_code,
+    }
+}
+
+/// Loads and executes a program file, returning the process exit code.
+fn run_file(file: &Path, loader: &mut Loader, options: &RunOptions) -> u8 {
+    let flowchart = match loader.load(file, options.lang) {
+        Ok(f) => f,
+        Err(exit_code) => return exit_code,
+    };
+
+    if options.unbuffered {
+        run(flowchart, StdioWriter::new(), options)
+    } else {
+        run(flowchart, BufferedStdioWriter::new(), options)
+    }
+}
+
+/// Executes a flowchart with the given output writer, returning the process exit code.
+fn run<W: OutputWriter>(flowchart: Flowchart, output_writer: W, options: &RunOptions) -> u8 {
+    let mut interpreter = match Interpreter::with_io(flowchart, StdinReader::new(), output_writer) {
+        Ok(i) => i,
+        Err(e) => {
+            report_runtime_error(e, options.lang);
+            return 1;
+        }
+    };
+    if let Some(entry) = &options.entry {
+        interpreter = match interpreter.with_entry(entry) {
+            Ok(i) => i,
+            Err(e) => {
+                report_runtime_error(e, options.lang);
+                return 1;
+            }
+        };
+    }
+    if let Some(seed) = options.seed {
+        interpreter = interpreter.with_seed(seed);
+    }
+    if options.deny_random {
+        interpreter = interpreter.with_random_denied();
+    }
+    if options.clamp_exit_code {
+        interpreter = interpreter.with_exit_code_policy(ExitCodePolicy::Clamp);
+    }
+
+    match interpreter.run() {
+        Ok(exit_code) => exit_code,
+        Err(e) => {
+            report_runtime_error(e, options.lang);
+            1
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_cache_conflicts_with_watch() {
+        for command in ["run", "check"] {
+            for flags in [&["--cache"][..], &["--cache-dir", "dir"]] {
+                let args = [&["merx", command, "--watch"][..], flags, &["a.mmd"]].concat();
+                let err = Cli::try_parse_from(&args).err();
+                assert_eq!(
+                    err.map(|e| e.kind()),
+                    Some(clap::error::ErrorKind::ArgumentConflict),
+                    "{:?}",
+                    args
+                );
             }
+            assert!(Cli::try_parse_from(["merx", command, "--cache", "a.mmd"]).is_ok());
         }
     }
 }
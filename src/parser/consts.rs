@@ -0,0 +1,232 @@
+//! Static checks for constants (`const NAME = expr`).
+//!
+//! A constant may be defined by more than one statement only if no path
+//! leads from one definition to another, as on the `Yes` and `No` branches
+//! of a condition. No other statement, loop node or decision table may
+//! write to it. Constants defined in a node that runs more than once are
+//! left to the runtime check, since whether that happens depends on the
+//! path taken.
+
+use rustc_hash::{FxHashMap, FxHashSet};
+
+use crate::ast::{Edge, Node, Statement};
+
+use super::error::{Place, ValidationError};
+
+/// Checks that no constant can be defined twice on one path, and that none
+/// is ever changed.
+///
+/// # Errors
+///
+/// Returns [`ValidationError`] if a constant is defined twice in the same
+/// place or in two places on one path, or if a statement, loop variable or
+/// decision table output writes to it.
+pub(super) fn validate_constants(
+    nodes: &FxHashMap<String, Node>,
+    edges: &[Edge],
+) -> Result<(), ValidationError> {
+    // Every place a variable is written, in a stable order
+    let mut node_ids: Vec<&String> = nodes.keys().collect();
+    node_ids.sort();
+    let mut writes: Vec<(Place, &str, bool)> = Vec::new();
+    for id in node_ids {
+        let place = Place::Node(id.clone());
+        match &nodes[id] {
+            Node::Process { statements, .. } => {
+                for stmt in statements {
+                    push_writes(&mut writes, &place, stmt);
+                }
+            }
+            Node::Loop { var, .. } => writes.push((place, var, false)),
+            Node::DecisionTable { table, .. } => {
+                for output in &table.outputs {
+                    writes.push((place.clone(), output, false));
+                }
+            }
+            _ => {}
+        }
+    }
+    for edge in edges {
+        let place = Place::Edge {
+            from: edge.from.clone(),
+            to: edge.to.clone(),
+        };
+        for stmt in &edge.actions {
+            push_writes(&mut writes, &place, stmt);
+        }
+    }
+
+    let mut outgoing: FxHashMap<&str, Vec<&str>> = FxHashMap::default();
+    for edge in edges {
+        outgoing.entry(&edge.from).or_default().push(&edge.to);
+    }
+
+    // Every place each constant is defined, in the order of `writes`
+    let mut constants: FxHashMap<&str, Vec<&Place>> = FxHashMap::default();
+    for (place, name, is_const) in &writes {
+        if !is_const {
+            continue;
+        }
+        let defined = constants.entry(name).or_default();
+        if let Some(first) = defined.iter().find(|first| {
+            **first == place
+                || runs_after(first, place, &outgoing)
+                || runs_after(place, first, &outgoing)
+        }) {
+            return Err(ValidationError::ConstantRedefined {
+                name: name.to_string(),
+                first: (*first).clone(),
+                second: place.clone(),
+            });
+        }
+        defined.push(place);
+    }
+    for (place, name, is_const) in &writes {
+        if let Some(defined) = constants.get(name).and_then(|places| places.first())
+            && !is_const
+        {
+            return Err(ValidationError::ConstantChanged {
+                name: name.to_string(),
+                defined: (*defined).clone(),
+                changed: place.clone(),
+            });
+        }
+    }
+    Ok(())
+}
+
+/// Returns `true` if `later` can be reached by following edges after
+/// `earlier` runs.
+fn runs_after(earlier: &Place, later: &Place, outgoing: &FxHashMap<&str, Vec<&str>>) -> bool {
+    let target = match later {
+        Place::Node(id) => id,
+        Place::Edge { from, .. } => {
+            // A node runs before each of its outgoing edges
+            if *earlier == Place::Node(from.clone()) {
+                return true;
+            }
+            from
+        }
+    };
+    let mut stack: Vec<&str> = match earlier {
+        Place::Node(id) => outgoing.get(id.as_str()).cloned().unwrap_or_default(),
+        Place::Edge { to, .. } => vec![to],
+    };
+    let mut seen: FxHashSet<&str> = FxHashSet::default();
+    while let Some(id) = stack.pop() {
+        if id == target {
+            return true;
+        }
+        if seen.insert(id) {
+            stack.extend(outgoing.get(id).into_iter().flatten());
+        }
+    }
+    false
+}
+
+/// Records the variables a statement writes, and whether it defines them as
+/// constants.
+fn push_writes<'a>(writes: &mut Vec<(Place, &'a str, bool)>, place: &Place, stmt: &'a Statement) {
+    match stmt {
+        Statement::Const { variable, .. } => writes.push((place.clone(), variable, true)),
+        Statement::Assign { variable, .. }
+        | Statement::CompoundAssign { variable, .. }
+        | Statement::Unset { variable } => writes.push((place.clone(), variable, false)),
+        Statement::ParallelAssign { variables, .. } => {
+            for variable in variables {
+                writes.push((place.clone(), variable, false));
+            }
+        }
+        _ => {}
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::parser::parse;
+
+    fn validate(body: &str) -> Result<(), String> {
+        parse(&format!("flowchart TD\n{}", body))
+            .map(|_| ())
+            .map_err(|e| e.to_string())
+    }
+
+    #[test]
+    fn test_constant_used_but_not_changed() {
+        assert!(validate("    Start --> A[const RATE = 8; x = RATE * 2]\n    A --> End\n").is_ok());
+    }
+
+    #[test]
+    fn test_constant_assigned() {
+        let err =
+            validate("    Start --> A[const RATE = 8]\n    A --> B[RATE += 1]\n    B --> End\n")
+                .unwrap_err();
+        assert!(
+            err.contains("Constant 'RATE' (defined in node 'A') is changed in node 'B'"),
+            "{}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_constant_unset_on_edge() {
+        let err =
+            validate("    Start --> A[const RATE = 8]\n    A -->|/ unset RATE| End\n").unwrap_err();
+        assert!(
+            err.contains("is changed in the edge from 'A' to 'End'"),
+            "{}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_constant_defined_twice() {
+        let err =
+            validate("    Start --> A[const X = 1; const X = 2]\n    A --> End\n").unwrap_err();
+        assert!(
+            err.contains("Constant 'X' is defined more than once in node 'A'"),
+            "{}",
+            err
+        );
+
+        let err =
+            validate("    Start --> A[const X = 1]\n    A --> B[const X = 2]\n    B --> End\n")
+                .unwrap_err();
+        assert!(
+            err.contains("Constant 'X' is defined in both node 'A' and node 'B'"),
+            "{}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_constant_defined_on_exclusive_branches() {
+        assert!(
+            validate(
+                "    Start --> A[x = 1]\n    A --> B{x > 0?}\n    B -->|Yes| C[const RATE = 10]\n    B -->|No| D[const RATE = 8]\n    C --> E[println RATE]\n    D --> E\n    E --> End\n",
+            )
+            .is_ok()
+        );
+
+        let err = validate(
+            "    Start --> A[x = 1]\n    A --> B{x > 0?}\n    B -->|Yes / const RATE = 10| C[println RATE]\n    B -->|No| C\n    C --> D[const RATE = 8]\n    D --> End\n",
+        )
+        .unwrap_err();
+        assert!(
+            err.contains(
+                "Constant 'RATE' is defined in both node 'D' and the edge from 'B' to 'C'"
+            ),
+            "{}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_constant_as_loop_variable() {
+        let err = validate(
+            "    Start --> A[const i = 0]\n    A --> L{{for i in 1..3}}\n    L -->|body| L\n    L -->|done| End\n",
+        )
+        .unwrap_err();
+        assert!(err.contains("is changed in node 'L'"), "{}", err);
+    }
+}
@@ -0,0 +1,320 @@
+//! Static checks for missing and overlapping decision table rules.
+//!
+//! When every test in a table compares with a literal, the set of inputs
+//! that can make a difference is small: for each input column it is enough
+//! to try the literals themselves, their neighbours, and (for strings) one
+//! value that equals none of them. Every combination of these samples is
+//! checked against the rules, which finds any input that no rule matches,
+//! and, under the unique hit policy, any input that two rules match.
+//!
+//! Tables whose tests use other expressions (such as variables) are left to
+//! the runtime checks, as are tables with too many combinations to try.
+
+use crate::ast::{BinaryOp, DecisionTable, Expr, HitPolicy, UnaryOp};
+
+use super::error::{TableValue, ValidationError};
+
+/// The largest number of input combinations the check will try.
+const MAX_COMBINATIONS: usize = 1_000_000;
+
+/// A literal that a cell test compares with.
+#[derive(Debug, Clone, PartialEq)]
+enum Literal {
+    Int(i64),
+    Str(String),
+    Bool(bool),
+}
+
+/// A sample value of an input column.
+#[derive(Debug, Clone, PartialEq)]
+enum Sample {
+    Int(i64),
+    Str(String),
+    Bool(bool),
+    /// A string different from every literal in the column.
+    OtherStr,
+    /// Any value, for a column that no rule tests.
+    Any,
+}
+
+/// Checks that every input is matched by some rule, and by at most one rule
+/// under the unique hit policy.
+///
+/// Tables that cannot be checked statically are accepted.
+///
+/// # Errors
+///
+/// Returns [`ValidationError`] naming an input that no rule matches, or two
+/// rules that both match the same input.
+pub(super) fn check_coverage(node_id: &str, table: &DecisionTable) -> Result<(), ValidationError> {
+    let Some(domains) = sample_domains(table) else {
+        return Ok(());
+    };
+    let combinations = domains
+        .iter()
+        .try_fold(1usize, |total, domain| total.checked_mul(domain.len()));
+    if combinations.is_none_or(|total| total > MAX_COMBINATIONS) {
+        return Ok(());
+    }
+
+    let mut indices = vec![0; domains.len()];
+    loop {
+        let samples: Vec<&Sample> = indices.iter().zip(&domains).map(|(&i, d)| &d[i]).collect();
+
+        let mut matching = table.rules.iter().enumerate().filter(|(_, rule)| {
+            rule.tests.iter().zip(&samples).all(|(test, sample)| {
+                test.as_ref().is_none_or(|test| {
+                    literal(&test.value).is_some_and(|lit| holds(sample, test.op, &lit))
+                })
+            })
+        });
+        match (matching.next(), matching.next()) {
+            (None, _) => {
+                return Err(ValidationError::TableGap {
+                    node_id: node_id.to_string(),
+                    inputs: describe(table, &samples),
+                });
+            }
+            (Some((first, _)), Some((second, _))) if table.hit_policy == HitPolicy::Unique => {
+                return Err(ValidationError::TableOverlap {
+                    node_id: node_id.to_string(),
+                    first: first + 1,
+                    second: second + 1,
+                    inputs: describe(table, &samples),
+                });
+            }
+            _ => {}
+        }
+
+        // Advance to the next combination, like an odometer
+        let mut column = 0;
+        loop {
+            if column == indices.len() {
+                return Ok(());
+            }
+            indices[column] += 1;
+            if indices[column] < domains[column].len() {
+                break;
+            }
+            indices[column] = 0;
+            column += 1;
+        }
+    }
+}
+
+/// Returns the sample values of each input column, or `None` if some test
+/// does not compare with a literal of the column's type.
+fn sample_domains(table: &DecisionTable) -> Option<Vec<Vec<Sample>>> {
+    let mut domains = Vec::with_capacity(table.inputs.len());
+    for column in 0..table.inputs.len() {
+        let mut literals = Vec::new();
+        for rule in &table.rules {
+            let Some(test) = &rule.tests[column] else {
+                continue;
+            };
+            let lit = literal(&test.value)?;
+            let is_equality = matches!(test.op, BinaryOp::Eq | BinaryOp::Ne);
+            match (&lit, literals.first()) {
+                (Literal::Str(_) | Literal::Bool(_), _) if !is_equality => return None,
+                (Literal::Int(_), Some(Literal::Int(_)))
+                | (Literal::Str(_), Some(Literal::Str(_)))
+                | (Literal::Bool(_), Some(Literal::Bool(_)))
+                | (_, None) => {}
+                _ => return None,
+            }
+            literals.push(lit);
+        }
+
+        let mut domain = Vec::new();
+        for lit in &literals {
+            match lit {
+                Literal::Int(n) => {
+                    domain.extend(n.checked_sub(1).map(Sample::Int));
+                    domain.push(Sample::Int(*n));
+                    domain.extend(n.checked_add(1).map(Sample::Int));
+                }
+                Literal::Str(s) => {
+                    let sample = Sample::Str(s.clone());
+                    if !domain.contains(&sample) {
+                        domain.push(sample);
+                    }
+                }
+                Literal::Bool(_) => {}
+            }
+        }
+        match literals.first() {
+            None => domain.push(Sample::Any),
+            Some(Literal::Int(_)) => {}
+            Some(Literal::Str(_)) => domain.push(Sample::OtherStr),
+            Some(Literal::Bool(_)) => domain.extend([Sample::Bool(false), Sample::Bool(true)]),
+        }
+        if matches!(literals.first(), Some(Literal::Int(_))) {
+            // Report the smallest uncovered value first
+            domain.sort_by_key(|sample| match sample {
+                Sample::Int(n) => *n,
+                _ => 0,
+            });
+            domain.dedup();
+        }
+        domains.push(domain);
+    }
+    Some(domains)
+}
+
+/// Returns the literal value of a test, if it is one (including negative
+/// integers).
+fn literal(expr: &Expr) -> Option<Literal> {
+    match expr {
+        Expr::IntLit { value } => Some(Literal::Int(*value)),
+        Expr::StrLit { value } => Some(Literal::Str(value.clone())),
+        Expr::BoolLit { value } => Some(Literal::Bool(*value)),
+        Expr::Unary {
+            op: UnaryOp::Neg,
+            operand,
+        } => match **operand {
+            Expr::IntLit { value } => value.checked_neg().map(Literal::Int),
+            _ => None,
+        },
+        _ => None,
+    }
+}
+
+/// Returns `true` if `sample op lit` holds.
+fn holds(sample: &Sample, op: BinaryOp, lit: &Literal) -> bool {
+    let ordering = match (sample, lit) {
+        (Sample::Int(a), Literal::Int(b)) => a.cmp(b),
+        (Sample::Str(a), Literal::Str(b)) => a.as_str().cmp(b.as_str()),
+        (Sample::Bool(a), Literal::Bool(b)) => a.cmp(b),
+        (Sample::OtherStr, Literal::Str(_)) => return op == BinaryOp::Ne,
+        _ => return false,
+    };
+    match op {
+        BinaryOp::Eq => ordering.is_eq(),
+        BinaryOp::Ne => ordering.is_ne(),
+        BinaryOp::Lt => ordering.is_lt(),
+        BinaryOp::Le => ordering.is_le(),
+        BinaryOp::Gt => ordering.is_gt(),
+        BinaryOp::Ge => ordering.is_ge(),
+        _ => false,
+    }
+}
+
+/// Describes an input combination as the header and value of each column
+/// that matters, such as `qty = 99, member = true`.
+fn describe(table: &DecisionTable, samples: &[&Sample]) -> Vec<(String, TableValue)> {
+    table
+        .inputs
+        .iter()
+        .zip(samples)
+        .filter_map(|(input, sample)| {
+            let value = match sample {
+                Sample::Int(n) => TableValue::Literal(n.to_string()),
+                Sample::Str(s) => TableValue::Literal(format!("'{}'", s)),
+                Sample::Bool(b) => TableValue::Literal(b.to_string()),
+                Sample::OtherStr => TableValue::OtherString,
+                Sample::Any => return None,
+            };
+            Some((input.header.clone(), value))
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ast::{CellTest, TableInput, TableRule, TableSource};
+
+    fn table(hit_policy: HitPolicy, rules: &[&[Option<(BinaryOp, i64)>]]) -> DecisionTable {
+        DecisionTable {
+            source: TableSource::Inline("t".to_string()),
+            hit_policy,
+            inputs: (0..rules[0].len())
+                .map(|i| TableInput {
+                    header: format!("x{}", i),
+                    expr: Expr::Variable {
+                        name: format!("x{}", i),
+                    },
+                })
+                .collect(),
+            outputs: Vec::new(),
+            has_target: false,
+            rules: rules
+                .iter()
+                .map(|tests| TableRule {
+                    tests: tests
+                        .iter()
+                        .map(|test| {
+                            test.map(|(op, value)| CellTest {
+                                op,
+                                value: Expr::IntLit { value },
+                            })
+                        })
+                        .collect(),
+                    values: Vec::new(),
+                    target: None,
+                })
+                .collect(),
+        }
+    }
+
+    #[test]
+    fn test_complete_ranges() {
+        let t = table(
+            HitPolicy::Unique,
+            &[&[Some((BinaryOp::Lt, 10))], &[Some((BinaryOp::Ge, 10))]],
+        );
+        assert!(check_coverage("T", &t).is_ok());
+    }
+
+    #[test]
+    fn test_gap() {
+        let t = table(
+            HitPolicy::Unique,
+            &[&[Some((BinaryOp::Lt, 10))], &[Some((BinaryOp::Gt, 10))]],
+        );
+        let err = check_coverage("T", &t).unwrap_err();
+        assert_eq!(
+            err.to_string(),
+            "Decision table 'T' has no rule for x0 = 10"
+        );
+    }
+
+    #[test]
+    fn test_overlap_depends_on_hit_policy() {
+        let rules: &[&[Option<(BinaryOp, i64)>]] = &[&[Some((BinaryOp::Ge, 100))], &[None]];
+        let err = check_coverage("T", &table(HitPolicy::Unique, rules)).unwrap_err();
+        assert_eq!(
+            err.to_string(),
+            "Rules 1 and 2 of decision table 'T' overlap (both match x0 = 100)"
+        );
+        assert!(check_coverage("T", &table(HitPolicy::First, rules)).is_ok());
+    }
+
+    #[test]
+    fn test_strings_need_a_catch_all() {
+        let mut t = table(HitPolicy::Unique, &[&[None]]);
+        t.rules[0].tests[0] = Some(CellTest {
+            op: BinaryOp::Eq,
+            value: Expr::StrLit {
+                value: "gold".to_string(),
+            },
+        });
+        let err = check_coverage("T", &t).unwrap_err();
+        assert_eq!(
+            err.to_string(),
+            "Decision table 'T' has no rule for x0 = any other string"
+        );
+    }
+
+    #[test]
+    fn test_non_literal_tests_are_skipped() {
+        let mut t = table(HitPolicy::Unique, &[&[None]]);
+        t.rules[0].tests[0] = Some(CellTest {
+            op: BinaryOp::Eq,
+            value: Expr::Variable {
+                name: "limit".to_string(),
+            },
+        });
+        assert!(check_coverage("T", &t).is_ok());
+    }
+}
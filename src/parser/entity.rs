@@ -0,0 +1,83 @@
+//! Decoding of Mermaid entity codes in labels.
+//!
+//! Mermaid labels cannot contain some characters literally (most notably
+//! `"` inside a quoted label), so Mermaid provides entity codes of the form
+//! `#name;` or `#NNN;` (a decimal code point). Labels are decoded before
+//! they are parsed, so a program executes exactly what the rendered diagram
+//! shows.
+
+use std::borrow::Cow;
+
+/// Named entity codes recognized in labels.
+const NAMED: &[(&str, char)] = &[
+    ("quot", '"'),
+    ("amp", '&'),
+    ("lt", '<'),
+    ("gt", '>'),
+    ("apos", '\''),
+    ("nbsp", '\u{a0}'),
+];
+
+/// Replaces entity codes such as `#quot;`, `#lt;`, and `#35;` with the
+/// characters they stand for.
+///
+/// Text that merely looks similar (an unknown name, a code point that is
+/// out of range, or a `#` without a closing `;`) is left unchanged.
+pub(super) fn decode_entities(text: &str) -> Cow<'_, str> {
+    if !text.contains('#') {
+        return Cow::Borrowed(text);
+    }
+
+    let mut out = String::with_capacity(text.len());
+    let mut rest = text;
+    while let Some(hash) = rest.find('#') {
+        out.push_str(&rest[..hash]);
+        let after = &rest[hash + 1..];
+        match after
+            .find(';')
+            .and_then(|semi| Some((lookup(&after[..semi])?, semi)))
+        {
+            Some((c, semi)) => {
+                out.push(c);
+                rest = &after[semi + 1..];
+            }
+            None => {
+                out.push('#');
+                rest = after;
+            }
+        }
+    }
+    out.push_str(rest);
+    Cow::Owned(out)
+}
+
+/// Resolves the body of an entity code (the part between `#` and `;`).
+fn lookup(name: &str) -> Option<char> {
+    if !name.is_empty() && name.bytes().all(|b| b.is_ascii_digit()) {
+        return name.parse().ok().and_then(char::from_u32);
+    }
+    NAMED.iter().find(|(n, _)| *n == name).map(|(_, c)| *c)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_decode_named_and_numeric() {
+        assert_eq!(decode_entities("say #quot;hi#quot;"), "say \"hi\"");
+        assert_eq!(
+            decode_entities("x #lt; 5 #amp;#amp; y #gt; 1"),
+            "x < 5 && y > 1"
+        );
+        assert_eq!(decode_entities("#35;1"), "#1");
+        assert_eq!(decode_entities("#9829;"), "\u{2665}");
+    }
+
+    #[test]
+    fn test_decode_leaves_unknown_codes() {
+        assert_eq!(decode_entities("#unknown; #1"), "#unknown; #1");
+        assert_eq!(decode_entities("#99999999;"), "#99999999;");
+        assert_eq!(decode_entities("no entities"), "no entities");
+    }
+}
@@ -11,6 +11,9 @@ use std::fmt;
 
 use pest::error::Error as PestError;
 
+use crate::ast::Arity;
+use crate::codes::ErrorCode;
+use crate::i18n::{Lang, Localize};
 use crate::parser::Rule;
 
 /// An error that occurred during syntactic parsing of Mermaid flowchart syntax.
@@ -19,34 +22,197 @@ use crate::parser::Rule;
 /// delimiters, malformed expressions, or other issues detected by the PEG
 /// parser or during AST construction.
 ///
+/// Like [`ValidationError`], each variant keeps the details of the problem,
+/// and is put into words only when it is displayed. The descriptions the
+/// PEG parser gives of grammar errors are included as they are, in English.
+///
 /// # Examples
 ///
 /// ```
+/// use merx::i18n::{Lang, Localize};
 /// use merx::parser::SyntaxError;
 ///
-/// let error = SyntaxError::new("integer literal '99999999999999999999' is out of range");
+/// let error = SyntaxError::IntegerOutOfRange {
+///     literal: "99999999999999999999".to_string(),
+/// };
+/// assert_eq!(error.code().to_string(), "MX0002");
 /// assert_eq!(
 ///     error.to_string(),
 ///     "integer literal '99999999999999999999' is out of range"
 /// );
+/// assert_eq!(
+///     error.in_lang(Lang::Ja).to_string(),
+///     "整数リテラル '99999999999999999999' が範囲外です"
+/// );
 /// ```
-#[derive(Debug)]
-pub struct SyntaxError {
-    message: String,
+#[derive(Debug, Clone, PartialEq)]
+pub enum SyntaxError {
+    /// The input does not match the grammar. `message` is the PEG parser's
+    /// description of the error, which includes where it is.
+    Grammar { message: String },
+
+    /// The parser met a parse tree the grammar should never produce.
+    Internal { message: String },
+
+    /// An error on the given 1-based line, reported when every syntax error
+    /// of a program is collected.
+    Line {
+        line: usize,
+        error: Box<SyntaxError>,
+    },
+
+    /// An edge from `from` has both an inline label and a pipe label.
+    ConflictingEdgeLabels { from: String },
+
+    /// The actions of an edge label (`text`) do not match the grammar.
+    InvalidEdgeAction { text: String, message: String },
+
+    /// A guard label is followed by `text`, which is neither an exit code
+    /// nor a return value.
+    TextAfterGuard { text: String },
+
+    /// A `return` edge label has no value.
+    MissingReturnValue,
+
+    /// An `exit` edge label or terminal label item has no value.
+    MissingExitCode,
+
+    /// An exit code literal (`text`) is not between 0 and 255.
+    ExitCodeOutOfRange { text: String },
+
+    /// An expression in an edge label or decision table (`text`) does not
+    /// match the grammar.
+    InvalidExpression {
+        role: ExprRole,
+        text: String,
+        message: String,
+    },
+
+    /// The label of a node does not match the grammar.
+    InvalidNodeLabel { node_id: String, message: String },
+
+    /// A parallel assignment assigns a variable more than once.
+    DuplicateAssignment { name: String },
+
+    /// A parallel assignment has a different number of variables and
+    /// values.
+    AssignmentCountMismatch { variables: usize, values: usize },
+
+    /// An integer literal does not fit in 64 bits.
+    IntegerOutOfRange { literal: String },
+
+    /// A call names a function that does not exist.
+    UnknownFunction { name: String },
+
+    /// A function is called with a number of arguments its arity does not
+    /// accept.
+    WrongArgumentCount {
+        function: String,
+        expected: Arity,
+        got: usize,
+    },
+
+    /// `exists` is given something other than a bare variable name.
+    ExistsArgument,
+
+    /// `defined` is given something other than a string literal.
+    DefinedArgument,
+
+    /// A metadata node uses a shape merx does not support.
+    UnsupportedShape { node_id: String, shape: String },
+
+    /// `Start` or `End` uses a shape other than a terminal one.
+    NonTerminalShape { node_id: String, shape: String },
+
+    /// A metadata node that needs a label has none.
+    MissingLabel { node_id: String },
+
+    /// An entry node's name is not an identifier.
+    InvalidEntryName { node_id: String },
+
+    /// A terminal node's `End:` label has an empty item.
+    EmptyTerminalItem { node_id: String },
+
+    /// A terminal node's label declares more than one exit code.
+    MultipleTerminalExitCodes { node_id: String },
+
+    /// A terminal node's label declares more than one result.
+    MultipleTerminalResults { node_id: String },
+
+    /// A decision table node's label is not `table <name>` or
+    /// `table '<file.csv>'`, with an optional hit policy.
+    InvalidTableLabel { node_id: String },
+
+    /// A decision table node names an inline table that is not declared.
+    UndeclaredTable { node_id: String, name: String },
+
+    /// A decision table node's CSV file cannot be read. `message` is the
+    /// I/O error's description.
+    UnreadableTable {
+        node_id: String,
+        path: String,
+        message: String,
+    },
+
+    /// A decision table node's rows are malformed.
+    InvalidTable { node_id: String, error: TableError },
+
+    /// An inline table is declared more than once.
+    DuplicateTable { name: String },
 }
 
 impl SyntaxError {
-    /// Creates a new `SyntaxError` with the given message.
-    pub fn new(message: impl Into<String>) -> Self {
-        Self {
-            message: message.into(),
+    /// Creates a `SyntaxError` for a parse tree the grammar should never
+    /// produce.
+    pub(crate) fn internal(message: &str) -> Self {
+        SyntaxError::Internal {
+            message: message.to_string(),
+        }
+    }
+
+    /// Returns the error's code.
+    pub fn code(&self) -> ErrorCode {
+        match self {
+            SyntaxError::Grammar { .. }
+            | SyntaxError::InvalidEdgeAction { .. }
+            | SyntaxError::InvalidExpression { .. }
+            | SyntaxError::InvalidNodeLabel { .. } => ErrorCode::InvalidSyntax,
+            SyntaxError::Internal { .. } => ErrorCode::Internal,
+            SyntaxError::Line { error, .. } => error.code(),
+            SyntaxError::ConflictingEdgeLabels { .. }
+            | SyntaxError::TextAfterGuard { .. }
+            | SyntaxError::MissingReturnValue => ErrorCode::InvalidEdgeLabel,
+            SyntaxError::MissingExitCode | SyntaxError::ExitCodeOutOfRange { .. } => {
+                ErrorCode::InvalidExitCode
+            }
+            SyntaxError::DuplicateAssignment { .. } => ErrorCode::DuplicateAssignment,
+            SyntaxError::AssignmentCountMismatch { .. } => ErrorCode::AssignmentCountMismatch,
+            SyntaxError::IntegerOutOfRange { .. } => ErrorCode::IntegerOutOfRange,
+            SyntaxError::UnknownFunction { .. } => ErrorCode::UnknownFunction,
+            SyntaxError::WrongArgumentCount { .. } => ErrorCode::WrongArgumentCount,
+            SyntaxError::ExistsArgument | SyntaxError::DefinedArgument => {
+                ErrorCode::InvalidNameArgument
+            }
+            SyntaxError::UnsupportedShape { .. } | SyntaxError::NonTerminalShape { .. } => {
+                ErrorCode::UnsupportedShape
+            }
+            SyntaxError::MissingLabel { .. } => ErrorCode::MissingLabel,
+            SyntaxError::InvalidEntryName { .. }
+            | SyntaxError::EmptyTerminalItem { .. }
+            | SyntaxError::MultipleTerminalExitCodes { .. }
+            | SyntaxError::MultipleTerminalResults { .. } => ErrorCode::InvalidTerminalLabel,
+            SyntaxError::InvalidTableLabel { .. } => ErrorCode::InvalidTableLabel,
+            SyntaxError::UndeclaredTable { .. } => ErrorCode::UndeclaredTable,
+            SyntaxError::UnreadableTable { .. } => ErrorCode::UnreadableTable,
+            SyntaxError::InvalidTable { .. } => ErrorCode::InvalidTable,
+            SyntaxError::DuplicateTable { .. } => ErrorCode::DuplicateTable,
         }
     }
 }
 
 impl fmt::Display for SyntaxError {
     fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
-        write!(f, "{}", self.message)
+        self.localize(Lang::En, f)
     }
 }
 
@@ -54,7 +220,74 @@ impl std::error::Error for SyntaxError {}
 
 impl From<PestError<Rule>> for SyntaxError {
     fn from(err: PestError<Rule>) -> Self {
-        Self::new(err.to_string())
+        SyntaxError::Grammar {
+            message: err.to_string(),
+        }
+    }
+}
+
+/// What an expression parsed on its own is for, in a
+/// [`SyntaxError::InvalidExpression`].
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ExprRole {
+    /// The value of a `return` edge label.
+    ReturnValue,
+    /// The condition of a guard edge label.
+    Guard,
+    /// The value of an `exit` edge label.
+    ExitCode,
+    /// The header of a decision table input column.
+    InputColumn,
+    /// The test of a decision table input cell.
+    Test,
+    /// The value of a decision table output cell.
+    Output,
+}
+
+/// A problem with the rows of a decision table, in a
+/// [`SyntaxError::InvalidTable`].
+#[derive(Debug, Clone, PartialEq)]
+pub enum TableError {
+    /// The table has no header row.
+    Empty,
+
+    /// The header has more than one `->` column.
+    MultipleTargetColumns,
+
+    /// An input column header is not a valid expression.
+    InvalidColumn(Box<SyntaxError>),
+
+    /// A rule has `cells` cells, but the header has `columns`. Rules are
+    /// numbered from 1.
+    RuleLength {
+        rule: usize,
+        cells: usize,
+        columns: usize,
+    },
+
+    /// A cell of a rule is not a valid expression.
+    InvalidCell {
+        rule: usize,
+        error: Box<SyntaxError>,
+    },
+
+    /// A rule leaves its target cell empty.
+    MissingTarget { rule: usize },
+
+    /// The table has a header but no rules.
+    NoRules,
+
+    /// A quoted cell on the given 1-based line of CSV text is not closed.
+    UnclosedQuote { line: usize },
+
+    /// A closing quote on the given 1-based line of CSV text is followed by
+    /// more text before the next comma.
+    TextAfterQuote { line: usize },
+}
+
+impl fmt::Display for TableError {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.localize(Lang::En, f)
     }
 }
 
@@ -64,39 +297,231 @@ impl From<PestError<Rule>> for SyntaxError {
 /// valid but violates semantic rules, such as missing Start/End nodes,
 /// duplicate node definitions, or invalid condition node edge configurations.
 ///
+/// Each variant keeps the details of the problem, and is put into words only
+/// when it is displayed: [`Display`](fmt::Display) uses English, and
+/// [`Localize::in_lang`] any other [`Lang`].
+///
 /// # Examples
 ///
 /// ```
+/// use merx::i18n::{Lang, Localize};
 /// use merx::parser::ValidationError;
 ///
-/// let error = ValidationError::new("Condition node 'A' is missing 'Yes' edge");
+/// let error = ValidationError::MissingConditionEdge {
+///     node_id: "A".to_string(),
+///     yes: true,
+/// };
+/// assert_eq!(error.code().to_string(), "MX0101");
 /// assert_eq!(
 ///     error.to_string(),
 ///     "Condition node 'A' is missing 'Yes' edge"
 /// );
+/// assert_eq!(
+///     error.in_lang(Lang::Ja).to_string(),
+///     "条件ノード 'A' に 'Yes' エッジがありません"
+/// );
 /// ```
-#[derive(Debug)]
-pub struct ValidationError {
-    message: String,
+#[derive(Debug, Clone, PartialEq)]
+pub enum ValidationError {
+    /// A node is defined more than once, with different contents.
+    DuplicateNode { node_id: String },
+
+    /// A condition node has more than one `Yes` edge (`yes` is `true`) or
+    /// `No` edge (`yes` is `false`).
+    MultipleConditionEdges { node_id: String, yes: bool },
+
+    /// An edge from a condition node has a label other than `Yes` or `No`.
+    InvalidConditionLabel { node_id: String, label: String },
+
+    /// An edge from a condition node has a guard.
+    GuardOnConditionEdge { node_id: String },
+
+    /// An edge from a condition node has no label.
+    UnlabeledConditionEdge { node_id: String },
+
+    /// A condition node has no `Yes` edge (`yes` is `true`) or `No` edge
+    /// (`yes` is `false`).
+    MissingConditionEdge { node_id: String, yes: bool },
+
+    /// An edge from a decision table with a target column is not labeled
+    /// with a target.
+    UnlabeledTableEdge { node_id: String },
+
+    /// A decision table has more than one edge with the same label.
+    MultipleTableEdges { node_id: String, label: String },
+
+    /// A rule of a decision table targets a label the node has no edge for.
+    MissingTableEdge { node_id: String, label: String },
+
+    /// No rule of a decision table matches an input.
+    ///
+    /// `inputs` pairs the header of each column that matters with its
+    /// value; it is empty if no rule matches any input at all.
+    TableGap {
+        node_id: String,
+        inputs: Vec<(String, TableValue)>,
+    },
+
+    /// Two rules of a decision table with the unique hit policy match the
+    /// same input.
+    ///
+    /// `first` and `second` are 1-based rule numbers, and `inputs` is as
+    /// for [`TableGap`](ValidationError::TableGap).
+    TableOverlap {
+        node_id: String,
+        first: usize,
+        second: usize,
+        inputs: Vec<(String, TableValue)>,
+    },
+
+    /// The flowchart has no `Start` node.
+    MissingStartNode,
+
+    /// The flowchart has no `End` node or other terminal node.
+    MissingEndNode,
+
+    /// The edge from `from` to `to` references `node_id`, which is not
+    /// defined.
+    UndefinedNode {
+        node_id: String,
+        from: String,
+        to: String,
+    },
+
+    /// The `End` node has an outgoing edge.
+    EdgeFromEnd,
+
+    /// A named terminal node has an outgoing edge.
+    EdgeFromTerminal { node_id: String },
+
+    /// A node without guards has more than one outgoing edge.
+    MultipleOutgoingEdges { node_id: String },
+
+    /// Two entry nodes declare the same name.
+    DuplicateEntry {
+        name: String,
+        first: String,
+        second: String,
+    },
+
+    /// No terminal node can be reached from the `Start` node (`entry` is
+    /// `None`) or from the named entry.
+    UnreachableEnd { entry: Option<String> },
+
+    /// An exit code is given on an edge that does not lead to a terminal
+    /// node.
+    MisplacedExitCode { from: String, to: String },
+
+    /// A return value is given on an edge that does not lead to a terminal
+    /// node.
+    MisplacedReturn { from: String, to: String },
+
+    /// A node has more than one `[else]` edge.
+    MultipleElseEdges { node_id: String },
+
+    /// A node has both guarded and unguarded outgoing edges.
+    MixedGuards { node_id: String },
+
+    /// A node's guards may all be `false`, and it has no `[else]` edge.
+    NonExhaustiveGuards { node_id: String },
+
+    /// A loop node has more than one `body` edge (`body` is `true`) or
+    /// `done` edge (`body` is `false`).
+    MultipleLoopEdges { node_id: String, body: bool },
+
+    /// An edge from a loop node is not labeled `body` or `done`.
+    InvalidLoopLabel { node_id: String },
+
+    /// A loop node has no `body` edge (`body` is `true`) or `done` edge
+    /// (`body` is `false`).
+    MissingLoopEdge { node_id: String, body: bool },
+
+    /// No path from a loop node's `body` edge leads back to it.
+    LoopNeverReturns { node_id: String },
+
+    /// A constant is defined in two places on one path, which may be the
+    /// same place.
+    ConstantRedefined {
+        name: String,
+        first: Place,
+        second: Place,
+    },
+
+    /// A constant defined in `defined` is written in `changed`.
+    ConstantChanged {
+        name: String,
+        defined: Place,
+        changed: Place,
+    },
 }
 
 impl ValidationError {
-    /// Creates a new `ValidationError` with the given message.
-    pub fn new(message: impl Into<String>) -> Self {
-        Self {
-            message: message.into(),
+    /// Returns the error's code.
+    pub fn code(&self) -> ErrorCode {
+        match self {
+            ValidationError::DuplicateNode { .. } => ErrorCode::DuplicateNode,
+            ValidationError::MultipleConditionEdges { .. } => ErrorCode::DuplicateConditionEdge,
+            ValidationError::InvalidConditionLabel { .. }
+            | ValidationError::GuardOnConditionEdge { .. }
+            | ValidationError::UnlabeledConditionEdge { .. } => ErrorCode::InvalidConditionEdge,
+            ValidationError::MissingConditionEdge { yes: true, .. } => ErrorCode::MissingYesEdge,
+            ValidationError::MissingConditionEdge { yes: false, .. } => ErrorCode::MissingNoEdge,
+            ValidationError::UnlabeledTableEdge { .. }
+            | ValidationError::MultipleTableEdges { .. } => ErrorCode::InvalidTableEdge,
+            ValidationError::MissingTableEdge { .. } => ErrorCode::MissingTableEdge,
+            ValidationError::TableGap { .. } => ErrorCode::TableGap,
+            ValidationError::TableOverlap { .. } => ErrorCode::TableOverlap,
+            ValidationError::MissingStartNode => ErrorCode::MissingStart,
+            ValidationError::MissingEndNode => ErrorCode::MissingEnd,
+            ValidationError::UndefinedNode { .. } => ErrorCode::UndefinedNode,
+            ValidationError::EdgeFromEnd | ValidationError::EdgeFromTerminal { .. } => {
+                ErrorCode::EdgeFromTerminal
+            }
+            ValidationError::MultipleOutgoingEdges { .. } => ErrorCode::MultipleOutgoingEdges,
+            ValidationError::DuplicateEntry { .. } => ErrorCode::DuplicateEntry,
+            ValidationError::UnreachableEnd { .. } => ErrorCode::UnreachableEnd,
+            ValidationError::MisplacedExitCode { .. } => ErrorCode::MisplacedExitCode,
+            ValidationError::MisplacedReturn { .. } => ErrorCode::MisplacedReturn,
+            ValidationError::MultipleElseEdges { .. } => ErrorCode::DuplicateElseEdge,
+            ValidationError::MixedGuards { .. } => ErrorCode::MixedGuards,
+            ValidationError::NonExhaustiveGuards { .. } => ErrorCode::NonExhaustiveGuards,
+            ValidationError::MultipleLoopEdges { .. }
+            | ValidationError::InvalidLoopLabel { .. } => ErrorCode::InvalidLoopEdge,
+            ValidationError::MissingLoopEdge { .. } => ErrorCode::MissingLoopEdge,
+            ValidationError::LoopNeverReturns { .. } => ErrorCode::LoopNeverReturns,
+            ValidationError::ConstantRedefined { .. } => ErrorCode::ConstantRedefined,
+            ValidationError::ConstantChanged { .. } => ErrorCode::ConstantChanged,
         }
     }
 }
 
 impl fmt::Display for ValidationError {
     fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
-        write!(f, "{}", self.message)
+        self.localize(Lang::En, f)
     }
 }
 
 impl std::error::Error for ValidationError {}
 
+/// Where a variable is written, in a [`ValidationError`] about a constant.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum Place {
+    /// A node, by its identifier.
+    Node(String),
+    /// An edge's actions, by the identifiers of the nodes it joins.
+    Edge { from: String, to: String },
+}
+
+/// A value of a decision table input column, in a [`ValidationError`]
+/// naming an input.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum TableValue {
+    /// A literal, as it would be written in the table (strings quoted).
+    Literal(String),
+    /// A string different from every literal in the column.
+    OtherString,
+}
+
 /// An error that occurred during analysis of Mermaid flowchart syntax.
 ///
 /// `AnalysisError` is returned by [`parse`](super::parse) when the input
@@ -120,24 +545,31 @@ impl std::error::Error for ValidationError {}
 pub enum AnalysisError {
     /// A syntax error from the PEG parser or AST construction.
     Syntax(SyntaxError),
-    /// A semantic validation error.
-    Validation(ValidationError),
+    /// A semantic validation error, boxed to keep `AnalysisError` small.
+    Validation(Box<ValidationError>),
 }
 
-impl fmt::Display for AnalysisError {
-    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+impl AnalysisError {
+    /// Returns the code of the wrapped error.
+    pub fn code(&self) -> ErrorCode {
         match self {
-            AnalysisError::Syntax(e) => write!(f, "Syntax error: {}", e),
-            AnalysisError::Validation(e) => write!(f, "Validation error: {}", e),
+            AnalysisError::Syntax(e) => e.code(),
+            AnalysisError::Validation(e) => e.code(),
         }
     }
 }
 
+impl fmt::Display for AnalysisError {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.localize(Lang::En, f)
+    }
+}
+
 impl std::error::Error for AnalysisError {
     fn source(&self) -> Option<&(dyn std::error::Error + 'static)> {
         match self {
             AnalysisError::Syntax(e) => Some(e),
-            AnalysisError::Validation(e) => Some(e),
+            AnalysisError::Validation(e) => Some(e.as_ref()),
         }
     }
 }
@@ -150,7 +582,7 @@ impl From<SyntaxError> for AnalysisError {
 
 impl From<ValidationError> for AnalysisError {
     fn from(err: ValidationError) -> Self {
-        AnalysisError::Validation(err)
+        AnalysisError::Validation(Box::new(err))
     }
 }
 
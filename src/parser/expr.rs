@@ -1,6 +1,6 @@
-use pest::iterators::Pair;
+use pest::iterators::{Pair, Pairs};
 
-use crate::ast::{BinaryOp, Expr, TypeName, UnaryOp};
+use crate::ast::{Arity, BinaryOp, Expr, Function, TypeName, UnaryOp};
 
 use super::Rule;
 use super::error::SyntaxError;
@@ -194,7 +194,7 @@ fn parse_unary_expr(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
     }
 
     let mut expr =
-        cast_expr.ok_or_else(|| SyntaxError::new("internal: expected cast_expr in unary_expr"))?;
+        cast_expr.ok_or_else(|| SyntaxError::internal("expected cast_expr in unary_expr"))?;
 
     // Apply unary operators in reverse order
     for op in unary_ops.into_iter().rev() {
@@ -252,8 +252,7 @@ fn parse_cast_expr(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
         }
     }
 
-    let mut result =
-        expr.ok_or_else(|| SyntaxError::new("internal: expected expr in cast_expr"))?;
+    let mut result = expr.ok_or_else(|| SyntaxError::internal("expected expr in cast_expr"))?;
 
     if let Some(t) = target_type {
         result = Expr::Cast {
@@ -273,6 +272,7 @@ fn parse_cast_expr(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
 /// - Boolean literals: `true`, `false`
 /// - Integer literals: sequences of digits
 /// - String literals: single-quoted strings (e.g., `'hello'`)
+/// - Function calls: built-in functions (e.g., `rand_int(1, 6)`)
 /// - Variables: identifiers referring to stored values
 ///
 /// # Arguments
@@ -285,12 +285,13 @@ fn parse_cast_expr(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
 ///
 /// # Errors
 ///
-/// Returns [`SyntaxError`] if a nested expression cannot be parsed.
+/// Returns [`SyntaxError`] if a nested expression cannot be parsed, or if a
+/// function call names an unknown function or has the wrong argument count.
 fn parse_primary(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
     let inner = pair
         .into_inner()
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected inner in primary"))?;
+        .ok_or_else(|| SyntaxError::internal("expected inner in primary"))?;
 
     match inner.as_rule() {
         Rule::expression => parse_expression(inner),
@@ -301,9 +302,11 @@ fn parse_primary(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
         Rule::int_lit => {
             let s = inner.as_str();
             Ok(Expr::IntLit {
-                value: s.parse::<i64>().map_err(|_| {
-                    SyntaxError::new(format!("integer literal '{}' is out of range", s))
-                })?,
+                value: s
+                    .parse::<i64>()
+                    .map_err(|_| SyntaxError::IntegerOutOfRange {
+                        literal: s.to_string(),
+                    })?,
             })
         }
         Rule::string_lit => {
@@ -314,6 +317,7 @@ fn parse_primary(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
                 value: unescape_string(content)?,
             })
         }
+        Rule::call_expr => parse_call_expr(inner),
         Rule::identifier => Ok(Expr::Variable {
             name: inner.as_str().to_string(),
         }),
@@ -321,6 +325,77 @@ fn parse_primary(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
     }
 }
 
+/// Parses a built-in function call such as `rand_int(1, 6)`.
+///
+/// The function name is resolved to a [`Function`] and the argument count
+/// is checked against its arity, so calls that could never succeed are
+/// rejected before execution starts.
+///
+/// # Arguments
+///
+/// * `pair` - A pest [`Pair`] matching the `call_expr` rule
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if the function is unknown, the argument count
+/// does not match, or an argument expression cannot be parsed.
+fn parse_call_expr(pair: Pair<Rule>) -> Result<Expr, SyntaxError> {
+    let mut parts = pair.into_inner();
+    let name = parts
+        .next()
+        .ok_or_else(|| SyntaxError::internal("expected identifier in call_expr"))?
+        .as_str();
+    if matches!(name, "exists" | "defined") {
+        return parse_defined(name, parts);
+    }
+    let function = Function::from_name(name).ok_or_else(|| SyntaxError::UnknownFunction {
+        name: name.to_string(),
+    })?;
+
+    let args = parts
+        .filter(|p| p.as_rule() == Rule::expression)
+        .map(parse_expression)
+        .collect::<Result<Vec<_>, _>>()?;
+
+    if !function.arity().accepts(args.len()) {
+        return Err(SyntaxError::WrongArgumentCount {
+            function: function.to_string(),
+            expected: function.arity(),
+            got: args.len(),
+        });
+    }
+
+    Ok(Expr::Call { function, args })
+}
+
+/// Parses `exists(x)` or `defined('x')` into [`Expr::Defined`].
+///
+/// `exists` takes a bare variable name and `defined` takes the name as a
+/// string literal.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if the call does not have exactly one argument,
+/// or the argument is not of the right form.
+fn parse_defined(name: &str, args: Pairs<Rule>) -> Result<Expr, SyntaxError> {
+    let args = args
+        .filter(|p| p.as_rule() == Rule::expression)
+        .map(parse_expression)
+        .collect::<Result<Vec<_>, _>>()?;
+    match (name, args.as_slice()) {
+        ("exists", [Expr::Variable { name }]) | ("defined", [Expr::StrLit { value: name }]) => {
+            Ok(Expr::Defined { name: name.clone() })
+        }
+        ("exists", [_]) => Err(SyntaxError::ExistsArgument),
+        (_, [_]) => Err(SyntaxError::DefinedArgument),
+        _ => Err(SyntaxError::WrongArgumentCount {
+            function: name.to_string(),
+            expected: Arity::Exact(1),
+            got: args.len(),
+        }),
+    }
+}
+
 /// Processes escape sequences in a raw string extracted from between quotes.
 ///
 /// Supports: `\\'`, `\\\\`, `\\n`, `\\t`, `\\r`, `\\0`, `\\xHH`.
@@ -333,16 +408,16 @@ pub(super) fn unescape_string(raw: &str) -> Result<String, SyntaxError> {
         if c == '\\' {
             chars
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: truncated escape sequence"))?;
+                .ok_or_else(|| SyntaxError::internal("truncated escape sequence"))?;
             let specifier = chars
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: truncated escape sequence"))?;
+                .ok_or_else(|| SyntaxError::internal("truncated escape sequence"))?;
             match specifier {
                 '\'' => result.push('\''),
                 '\\' => {
                     chars
                         .next()
-                        .ok_or_else(|| SyntaxError::new("internal: truncated backslash escape"))?;
+                        .ok_or_else(|| SyntaxError::internal("truncated backslash escape"))?;
                     result.push('\\');
                     result.push('\\');
                 }
@@ -354,18 +429,18 @@ pub(super) fn unescape_string(raw: &str) -> Result<String, SyntaxError> {
                     let h1 = chars
                         .next()
                         .and_then(|c| c.to_digit(16))
-                        .ok_or_else(|| SyntaxError::new("internal: invalid hex escape"))?
+                        .ok_or_else(|| SyntaxError::internal("invalid hex escape"))?
                         as u8;
                     let h2 = chars
                         .next()
                         .and_then(|c| c.to_digit(16))
-                        .ok_or_else(|| SyntaxError::new("internal: invalid hex escape"))?
+                        .ok_or_else(|| SyntaxError::internal("invalid hex escape"))?
                         as u8;
                     result.push((h1 * 16 + h2) as char);
                 }
                 _ => {
-                    return Err(SyntaxError::new(
-                        "internal: unexpected escape specifier in string",
+                    return Err(SyntaxError::internal(
+                        "unexpected escape specifier in string",
                     ));
                 }
             }
@@ -0,0 +1,236 @@
+//! Guarded edges (`A -->|[x > 10]| B` and `A -->|[else]| C`).
+//!
+//! Instead of branching through a condition node, any node may have several
+//! outgoing edges whose labels are guard expressions. At runtime the guards
+//! are evaluated in order and the first one that is `true` is followed; the
+//! `[else]` edge is followed if none is.
+//!
+//! Validation requires such a node to have exactly one `[else]` edge,
+//! unless its guards can be shown to cover every case. The proof is
+//! deliberately simple: a guard that is the literal `true`, or two guards
+//! that are negations of each other (`[ok]` and `[!ok]`, `[x < 10]` and
+//! `[x >= 10]`). Guards that read input or call functions are never used in
+//! the proof, because evaluating them twice may give different results.
+
+use crate::ast::{BinaryOp, Edge, EdgeLabel, Expr, UnaryOp};
+
+use super::error::ValidationError;
+
+/// Splits a guard label into the text between the brackets and the text
+/// after the closing bracket.
+///
+/// Returns `None` if `text` does not start with `[` or has no closing `]`.
+/// A `]` inside a string literal does not close the guard.
+pub(super) fn split_guard(text: &str) -> Option<(&str, &str)> {
+    let inner = text.strip_prefix('[')?;
+    let mut in_string = false;
+    let mut escaped = false;
+    for (i, c) in inner.char_indices() {
+        match c {
+            _ if escaped => escaped = false,
+            '\\' if in_string => escaped = true,
+            '\'' => in_string = !in_string,
+            ']' if !in_string => return Some((inner[..i].trim(), inner[i + 1..].trim())),
+            _ => {}
+        }
+    }
+    None
+}
+
+/// Checks the guarded outgoing edges of a non-condition node.
+///
+/// # Errors
+///
+/// Returns [`ValidationError`] if:
+/// - Some, but not all, of the edges have a guard
+/// - More than one edge is `[else]`
+/// - There is no `[else]` edge and the guards are not provably exhaustive
+pub(super) fn validate_guards(node_id: &str, edges: &[&Edge]) -> Result<(), ValidationError> {
+    let mut guards = Vec::new();
+    let mut has_else = false;
+    for edge in edges {
+        match &edge.label {
+            Some(EdgeLabel::Guard(expr)) => guards.push(expr),
+            Some(EdgeLabel::Else) => {
+                if has_else {
+                    return Err(ValidationError::MultipleElseEdges {
+                        node_id: node_id.to_string(),
+                    });
+                }
+                has_else = true;
+            }
+            _ => {
+                return Err(ValidationError::MixedGuards {
+                    node_id: node_id.to_string(),
+                });
+            }
+        }
+    }
+
+    if !has_else && !is_exhaustive(&guards) {
+        return Err(ValidationError::NonExhaustiveGuards {
+            node_id: node_id.to_string(),
+        });
+    }
+    Ok(())
+}
+
+/// Returns `true` if one of the guards is always true, or two of them are
+/// negations of each other.
+fn is_exhaustive(guards: &[&Expr]) -> bool {
+    let guards: Vec<&Expr> = guards.iter().copied().filter(|g| is_pure(g)).collect();
+    guards
+        .iter()
+        .any(|g| matches!(g, Expr::BoolLit { value: true }))
+        || guards.iter().any(|g| {
+            let negated = negate(g);
+            guards.iter().any(|other| equivalent(other, &negated))
+        })
+}
+
+/// Returns `true` if evaluating the expression has no side effects and
+/// always gives the same result for the same variables.
+fn is_pure(expr: &Expr) -> bool {
+    match expr {
+        Expr::Input | Expr::Call { .. } => false,
+        Expr::Unary { operand, .. } => is_pure(operand),
+        Expr::Binary { left, right, .. } => is_pure(left) && is_pure(right),
+        Expr::Cast { expr, .. } => is_pure(expr),
+        Expr::IntLit { .. }
+        | Expr::StrLit { .. }
+        | Expr::BoolLit { .. }
+        | Expr::Variable { .. }
+        | Expr::Defined { .. } => true,
+    }
+}
+
+/// Returns the logical negation of a guard, simplifying `!e` and
+/// comparisons.
+fn negate(expr: &Expr) -> Expr {
+    match expr {
+        Expr::Unary {
+            op: UnaryOp::Not,
+            operand,
+        } => (**operand).clone(),
+        Expr::Binary { op, left, right } => match complement(*op) {
+            Some(op) => Expr::Binary {
+                op,
+                left: left.clone(),
+                right: right.clone(),
+            },
+            None => not(expr),
+        },
+        _ => not(expr),
+    }
+}
+
+fn not(expr: &Expr) -> Expr {
+    Expr::Unary {
+        op: UnaryOp::Not,
+        operand: Box::new(expr.clone()),
+    }
+}
+
+/// Returns the comparison that is true exactly when `op` is false.
+fn complement(op: BinaryOp) -> Option<BinaryOp> {
+    match op {
+        BinaryOp::Eq => Some(BinaryOp::Ne),
+        BinaryOp::Ne => Some(BinaryOp::Eq),
+        BinaryOp::Lt => Some(BinaryOp::Ge),
+        BinaryOp::Le => Some(BinaryOp::Gt),
+        BinaryOp::Gt => Some(BinaryOp::Le),
+        BinaryOp::Ge => Some(BinaryOp::Lt),
+        _ => None,
+    }
+}
+
+/// Returns the comparison with its operands swapped (`a < b` is `b > a`).
+fn mirror(op: BinaryOp) -> Option<BinaryOp> {
+    match op {
+        BinaryOp::Eq | BinaryOp::Ne => Some(op),
+        BinaryOp::Lt => Some(BinaryOp::Gt),
+        BinaryOp::Le => Some(BinaryOp::Ge),
+        BinaryOp::Gt => Some(BinaryOp::Lt),
+        BinaryOp::Ge => Some(BinaryOp::Le),
+        _ => None,
+    }
+}
+
+/// Returns `true` if the expressions are identical, or the same comparison
+/// written with its operands swapped.
+fn equivalent(a: &Expr, b: &Expr) -> bool {
+    if a == b {
+        return true;
+    }
+    match (a, b) {
+        (
+            Expr::Binary {
+                op: op_a,
+                left: left_a,
+                right: right_a,
+            },
+            Expr::Binary {
+                op: op_b,
+                left: left_b,
+                right: right_b,
+            },
+        ) => mirror(*op_a) == Some(*op_b) && left_a == right_b && right_a == left_b,
+        _ => false,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn var(name: &str) -> Box<Expr> {
+        Box::new(Expr::Variable {
+            name: name.to_string(),
+        })
+    }
+
+    fn compare(op: BinaryOp, left: Box<Expr>, right: Box<Expr>) -> Expr {
+        Expr::Binary { op, left, right }
+    }
+
+    #[test]
+    fn test_split_guard() {
+        assert_eq!(split_guard("[x > 1]"), Some(("x > 1", "")));
+        assert_eq!(split_guard("[ else ], exit 1"), Some(("else", ", exit 1")));
+        assert_eq!(split_guard("[s == ']']"), Some(("s == ']'", "")));
+        assert_eq!(split_guard("[draft"), None);
+        assert_eq!(split_guard("draft]"), None);
+    }
+
+    #[test]
+    fn test_exhaustive_complementary_comparisons() {
+        let lt = compare(BinaryOp::Lt, var("x"), Box::new(Expr::IntLit { value: 10 }));
+        let ge = compare(BinaryOp::Ge, var("x"), Box::new(Expr::IntLit { value: 10 }));
+        let mirrored = compare(BinaryOp::Le, Box::new(Expr::IntLit { value: 10 }), var("x"));
+        assert!(is_exhaustive(&[&lt, &ge]));
+        assert!(is_exhaustive(&[&lt, &mirrored]));
+        assert!(!is_exhaustive(&[&lt]));
+    }
+
+    #[test]
+    fn test_exhaustive_negation_and_true() {
+        let ok = Expr::Variable {
+            name: "ok".to_string(),
+        };
+        assert!(is_exhaustive(&[&ok, &not(&ok)]));
+        assert!(is_exhaustive(&[&Expr::BoolLit { value: true }]));
+        assert!(!is_exhaustive(&[&ok]));
+    }
+
+    #[test]
+    fn test_impure_guards_are_not_exhaustive() {
+        let input = compare(
+            BinaryOp::Eq,
+            Box::new(Expr::Input),
+            Box::new(Expr::StrLit {
+                value: "y".to_string(),
+            }),
+        );
+        assert!(!is_exhaustive(&[&input, &negate(&input)]));
+    }
+}
@@ -0,0 +1,376 @@
+//! Incremental parsing for editors and watch mode.
+//!
+//! A [`Document`] keeps the result of parsing each line of a program on its
+//! own (see [`parse_partial`](super::parse_partial)). When the text is
+//! edited, only the lines the edit touches are parsed again: parsing
+//! restarts at the first line whose text the edit may have changed, and
+//! stops as soon as it reaches the start of a line after the edit, whose
+//! earlier result is reused. Validation always runs on the whole flowchart.
+
+use std::ops::Range;
+use std::path::{Path, PathBuf};
+
+use rustc_hash::FxHashMap;
+
+use crate::ast::{DecisionTable, Edge, Flowchart, Node, TableSource};
+
+use super::add_line_decl;
+use super::error::AnalysisError;
+use super::recover::{Header, PartialParse, Segment, assemble, parse_header_at, parse_segment};
+use super::table::load_tables;
+use super::validate::validate_flowchart;
+
+/// A program's source text together with its parsed lines, which can be
+/// edited and re-parsed incrementally.
+///
+/// # Examples
+///
+/// ```
+/// use merx::parser::Document;
+///
+/// let mut doc = Document::new("flowchart TD\n    Start --> A[x = 1]\n    A --> End\n");
+/// assert!(doc.flowchart().is_ok());
+///
+/// // Replace `1` with `1 +`, which is a syntax error
+/// let at = doc.source().find('1').unwrap();
+/// doc.edit(at..at + 1, "1 +");
+/// assert_eq!(doc.partial().errors.len(), 1);
+///
+/// doc.edit(at..at + 3, "2");
+/// assert!(doc.flowchart().is_ok());
+/// ```
+#[derive(Debug, Clone)]
+pub struct Document {
+    source: String,
+    dir: PathBuf,
+    header: Header,
+    segments: Vec<Segment>,
+}
+
+impl Document {
+    /// Parses a program. Decision tables stored in CSV files are read
+    /// relative to the current directory.
+    pub fn new(source: impl Into<String>) -> Self {
+        Self::in_dir(source, "")
+    }
+
+    /// Parses a program, reading CSV decision tables relative to `dir`.
+    pub fn in_dir(source: impl Into<String>, dir: impl Into<PathBuf>) -> Self {
+        let source = source.into();
+        let header = parse_header_at(&source);
+        let mut segments = Vec::new();
+        let mut pos = header.end;
+        while let Some(segment) = parse_segment(&source, pos) {
+            pos = segment.end;
+            segments.push(segment);
+        }
+        Self {
+            source,
+            dir: dir.into(),
+            header,
+            segments,
+        }
+    }
+
+    /// Returns the current source text.
+    pub fn source(&self) -> &str {
+        &self.source
+    }
+
+    /// Returns the directory decision table files are read from.
+    pub fn dir(&self) -> &Path {
+        &self.dir
+    }
+
+    /// Returns the CSV files the program's decision tables are read from,
+    /// resolved against [`dir`](Document::dir).
+    ///
+    /// Files named on lines with syntax errors are not included, but files
+    /// that cannot be read or are malformed are.
+    pub fn table_files(&self) -> Vec<PathBuf> {
+        let nodes = self
+            .segments
+            .iter()
+            .filter_map(|segment| segment.decl.as_ref().ok())
+            .flat_map(|decl| &decl.nodes);
+        let mut files: Vec<PathBuf> = nodes
+            .filter_map(|node| match node {
+                Node::DecisionTable {
+                    table:
+                        DecisionTable {
+                            source: TableSource::File(path),
+                            ..
+                        },
+                    ..
+                } => Some(self.dir.join(path)),
+                _ => None,
+            })
+            .collect();
+        files.sort();
+        files.dedup();
+        files
+    }
+
+    /// Replaces the text in `range`, given as byte offsets into the current
+    /// source, with `text`, and re-parses the lines the edit touches.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the range is out of bounds or does not lie on character
+    /// boundaries, like [`String::replace_range`].
+    pub fn edit(&mut self, range: Range<usize>, text: &str) {
+        let Range { start, end } = range;
+        self.source.replace_range(start..end, text);
+        if start <= self.header.reach {
+            *self = Self::in_dir(
+                std::mem::take(&mut self.source),
+                std::mem::take(&mut self.dir),
+            );
+            return;
+        }
+
+        let (removed, added) = (end - start, text.len());
+        let first = self
+            .segments
+            .iter()
+            .position(|s| s.reach >= start)
+            .unwrap_or(self.segments.len());
+        let mut pos = match first {
+            0 => self.header.end,
+            _ => self.segments[first - 1].end,
+        };
+
+        let mut old = self.segments.split_off(first).into_iter().peekable();
+        loop {
+            // Lines that start after the edit are unchanged, and can be
+            // reused once parsing reaches the start of one of them
+            while old
+                .peek()
+                .is_some_and(|s| s.start < end || s.start - removed + added < pos)
+            {
+                old.next();
+            }
+            if let Some(next) = old.peek()
+                && next.start - removed + added == pos
+            {
+                self.segments.extend(old.map(|mut s| {
+                    s.shift(removed, added);
+                    s
+                }));
+                return;
+            }
+
+            let Some(segment) = parse_segment(&self.source, pos) else {
+                return;
+            };
+            if old
+                .peek()
+                .is_none_or(|s| segment.start < s.start - removed + added)
+            {
+                pos = segment.end;
+                self.segments.push(segment);
+            } else {
+                // Only blank lines and comments separated `pos` from the
+                // next unchanged line
+                pos = segment.start;
+            }
+        }
+    }
+
+    /// Replaces the whole source with `source`, re-parsing only the lines
+    /// that differ from the current source.
+    ///
+    /// This suits callers that see whole files rather than individual
+    /// edits, such as a file watcher.
+    pub fn update(&mut self, source: &str) {
+        let (old, new) = (self.source.as_bytes(), source.as_bytes());
+        let on_boundary = |old_pos, new_pos| {
+            self.source.is_char_boundary(old_pos) && source.is_char_boundary(new_pos)
+        };
+
+        let mut prefix = old.iter().zip(new).take_while(|(a, b)| a == b).count();
+        while !on_boundary(prefix, prefix) {
+            prefix -= 1;
+        }
+        let mut suffix = old[prefix..]
+            .iter()
+            .rev()
+            .zip(new[prefix..].iter().rev())
+            .take_while(|(a, b)| a == b)
+            .count();
+        while !on_boundary(old.len() - suffix, new.len() - suffix) {
+            suffix -= 1;
+        }
+
+        if prefix == old.len() && prefix == new.len() {
+            return;
+        }
+        let range = prefix..old.len() - suffix;
+        self.edit(range, &source[prefix..new.len() - suffix]);
+    }
+
+    /// Returns the unvalidated flowchart and every syntax error, like
+    /// [`parse_partial`](super::parse_partial).
+    pub fn partial(&self) -> PartialParse {
+        assemble(&self.source, &self.dir, &self.header, &self.segments)
+    }
+
+    /// Returns the validated flowchart, like [`parse`](super::parse).
+    ///
+    /// # Errors
+    ///
+    /// Returns the first syntax or validation error, in the same order as
+    /// [`parse`](super::parse).
+    pub fn flowchart(&self) -> Result<Flowchart, AnalysisError> {
+        if let Some(e) = &self.header.error {
+            return Err(e.to_syntax_error(&self.source, 0).into());
+        }
+
+        let mut nodes: FxHashMap<String, Node> = FxHashMap::default();
+        let mut edges: Vec<Edge> = Vec::new();
+        for segment in &self.segments {
+            match &segment.decl {
+                Ok(decl) => add_line_decl(decl.clone(), &mut nodes, &mut edges)?,
+                Err(e) => return Err(e.to_syntax_error(&self.source, segment.start).into()),
+            }
+        }
+
+        load_tables(&mut nodes, &self.source, &self.dir)?;
+        validate_flowchart(&nodes, &edges)?;
+
+        Ok(Flowchart {
+            direction: self.header.direction,
+            nodes: nodes.into_values().collect(),
+            edges,
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::parser::parse_partial;
+
+    const PROGRAM: &str = "flowchart TD
+    %% count to three
+    Start --> A[i = 0]
+    A --> B{i < 3?}
+    B -->|Yes| C[println i; i = i + 1]
+    C --> B
+
+    B -->|No| End
+";
+
+    /// Checks that a document matches a fresh parse of its source.
+    fn assert_matches_fresh_parse(doc: &Document) {
+        let fresh = Document::new(doc.source());
+        let starts = |d: &Document| {
+            d.segments
+                .iter()
+                .map(|s| (s.start, s.end))
+                .collect::<Vec<_>>()
+        };
+        assert_eq!(starts(doc), starts(&fresh), "{:?}", doc.source());
+
+        let (partial, expected) = (doc.partial(), parse_partial(doc.source()));
+        assert_eq!(partial.flowchart, expected.flowchart, "{:?}", doc.source());
+        assert_eq!(partial.errors, expected.errors, "{:?}", doc.source());
+    }
+
+    #[test]
+    fn test_edit_within_a_line() {
+        let mut doc = Document::new(PROGRAM);
+        let at = PROGRAM.find("i < 3").unwrap() + 4;
+        doc.edit(at..at + 1, "10");
+        assert_matches_fresh_parse(&doc);
+        assert!(doc.source().contains("i < 10?"));
+        assert!(doc.flowchart().is_ok());
+    }
+
+    #[test]
+    fn test_edit_reports_moved_errors_at_their_new_lines() {
+        let mut doc = Document::new(PROGRAM.replace("i = i + 1", "i = i +"));
+        let err = doc.flowchart().unwrap_err().to_string();
+        assert!(err.contains("5:"), "{}", err);
+
+        // Insert a line before the error
+        let at = doc.source().find("    A -->").unwrap();
+        doc.edit(at..at, "    A2[x = 1]\n");
+        assert_matches_fresh_parse(&doc);
+        let err = doc.flowchart().unwrap_err().to_string();
+        assert!(err.contains("6:"), "{}", err);
+    }
+
+    #[test]
+    fn test_edits_splitting_and_joining_lines() {
+        let mut doc = Document::new(PROGRAM);
+        let at = PROGRAM.find("C --> B").unwrap();
+        // Join with the previous line, then split it again
+        doc.edit(at - 5..at, " ");
+        assert_matches_fresh_parse(&doc);
+        doc.edit(at - 5..at - 4, "\n    ");
+        assert_matches_fresh_parse(&doc);
+        assert_eq!(doc.source(), PROGRAM);
+
+        // Remove the header's direction, then restore it
+        doc.edit(10..12, "");
+        assert_matches_fresh_parse(&doc);
+        doc.edit(10..10, "LR");
+        assert_matches_fresh_parse(&doc);
+    }
+
+    #[test]
+    fn test_update_replaces_the_source() {
+        let mut doc = Document::new(PROGRAM);
+        let changed = PROGRAM.replace("println i", "println 'é' + i as str");
+        doc.update(&changed);
+        assert_eq!(doc.source(), changed);
+        assert_matches_fresh_parse(&doc);
+
+        doc.update(PROGRAM);
+        assert_eq!(doc.source(), PROGRAM);
+        assert_matches_fresh_parse(&doc);
+    }
+
+    #[test]
+    fn test_table_files() {
+        let source = "flowchart TD
+    Start --> T[[table 'rates.csv']]
+    T --> U[[table 'rates.csv' first]]
+    U --> V[[table 'more/tiers.csv']]
+    V --> W[[table inline]]
+    W --> End
+";
+        let doc = Document::in_dir(source, "prog");
+        assert_eq!(
+            doc.table_files(),
+            [
+                PathBuf::from("prog/more/tiers.csv"),
+                PathBuf::from("prog/rates.csv")
+            ]
+        );
+    }
+
+    #[test]
+    fn test_random_edits_match_fresh_parse() {
+        let pieces = [
+            "x", " ", "\n", "-->", "[", "]", "{", "?}", "|Yes|", "%%", "\"", "1",
+        ];
+        let mut doc = Document::new(PROGRAM);
+        let mut state: u64 = 7;
+        let mut next = |n: usize| {
+            state = state
+                .wrapping_mul(6364136223846793005)
+                .wrapping_add(1442695040888963407);
+            (state >> 33) as usize % n
+        };
+        for _ in 0..300 {
+            let len = doc.source().len();
+            let start = next(len + 1);
+            let end = (start + next(4)).min(len);
+            let text = pieces[next(pieces.len())];
+            doc.edit(start..end, text);
+            assert_matches_fresh_parse(&doc);
+        }
+    }
+}
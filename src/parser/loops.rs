@@ -0,0 +1,206 @@
+//! Counted loop nodes (`L{{for i in 1..n}}`).
+//!
+//! A loop node replaces the usual init / condition / increment nodes of a
+//! hand-built loop. It has a `body` edge, followed once per value, and a
+//! `done` edge, followed when the values run out:
+//!
+//! ```text
+//! flowchart TD
+//!     Start --> L{{for i in 1..3}}
+//!     L -->|body| A[println i]
+//!     A --> L
+//!     L -->|done| End
+//! ```
+
+use rustc_hash::{FxHashMap, FxHashSet};
+
+use crate::ast::{Edge, LoopIter, Node};
+
+use super::error::{SyntaxError, ValidationError};
+use super::expr::parse_expression;
+use super::{Rule, parse_label};
+
+/// Builds a [`Node::Loop`] from a loop node's id and label.
+///
+/// The label has the form `for <var> in <start>..<end>` or
+/// `for <var> in <expr>, <expr>, ...`.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if the label does not have that form.
+pub(super) fn parse_loop_node(id: String, text: &str) -> Result<Node, SyntaxError> {
+    let entry = parse_label(&id, Rule::label_loop, text.trim())?;
+    let mut var = None;
+    let mut iter = None;
+    for part in entry.into_inner() {
+        match part.as_rule() {
+            Rule::identifier => var = Some(part.as_str().to_string()),
+            Rule::loop_range => {
+                let mut bounds = part.into_inner();
+                let mut bound = || {
+                    bounds
+                        .next()
+                        .ok_or_else(|| SyntaxError::internal("expected bound in loop_range"))
+                        .and_then(parse_expression)
+                };
+                let start = bound()?;
+                let end = bound()?;
+                iter = Some(LoopIter::Range { start, end });
+            }
+            Rule::loop_values => {
+                let values = part
+                    .into_inner()
+                    .map(parse_expression)
+                    .collect::<Result<_, _>>()?;
+                iter = Some(LoopIter::Values(values));
+            }
+            _ => {}
+        }
+    }
+
+    Ok(Node::Loop {
+        id,
+        var: var.ok_or_else(|| SyntaxError::internal("expected variable in label_loop"))?,
+        iter: iter.ok_or_else(|| SyntaxError::internal("expected values in label_loop"))?,
+    })
+}
+
+/// Checks the outgoing edges of a loop node.
+///
+/// # Errors
+///
+/// Returns [`ValidationError`] if:
+/// - An edge is not labeled `body` or `done`
+/// - There is more than one `body` or `done` edge, or either is missing
+/// - No path from the `body` edge leads back to the loop node
+pub(super) fn validate_loop(
+    node_id: &str,
+    outgoing: &FxHashMap<&str, Vec<&Edge>>,
+    nodes: &FxHashMap<String, Node>,
+) -> Result<(), ValidationError> {
+    let mut body = None;
+    let mut has_done = false;
+    for edge in outgoing.get(node_id).into_iter().flatten() {
+        match &edge.label {
+            Some(label) if label.is_loop_body() => {
+                if body.is_some() {
+                    return Err(ValidationError::MultipleLoopEdges {
+                        node_id: node_id.to_string(),
+                        body: true,
+                    });
+                }
+                body = Some(edge.to.as_str());
+            }
+            Some(label) if label.is_loop_done() => {
+                if has_done {
+                    return Err(ValidationError::MultipleLoopEdges {
+                        node_id: node_id.to_string(),
+                        body: false,
+                    });
+                }
+                has_done = true;
+            }
+            _ => {
+                return Err(ValidationError::InvalidLoopLabel {
+                    node_id: node_id.to_string(),
+                });
+            }
+        }
+    }
+
+    let body = body.ok_or_else(|| ValidationError::MissingLoopEdge {
+        node_id: node_id.to_string(),
+        body: true,
+    })?;
+    if !has_done {
+        return Err(ValidationError::MissingLoopEdge {
+            node_id: node_id.to_string(),
+            body: false,
+        });
+    }
+
+    if !returns_to(node_id, body, outgoing, nodes) {
+        return Err(ValidationError::LoopNeverReturns {
+            node_id: node_id.to_string(),
+        });
+    }
+    Ok(())
+}
+
+/// Returns `true` if the loop node can be reached by following edges from
+/// `body`.
+fn returns_to(
+    loop_id: &str,
+    body: &str,
+    outgoing: &FxHashMap<&str, Vec<&Edge>>,
+    nodes: &FxHashMap<String, Node>,
+) -> bool {
+    let mut seen: FxHashSet<&str> = FxHashSet::default();
+    let mut stack = vec![body];
+    while let Some(id) = stack.pop() {
+        if id == loop_id {
+            return true;
+        }
+        if !nodes.contains_key(id) || !seen.insert(id) {
+            continue;
+        }
+        stack.extend(
+            outgoing
+                .get(id)
+                .into_iter()
+                .flatten()
+                .map(|e| e.to.as_str()),
+        );
+    }
+    false
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ast::Expr;
+
+    #[test]
+    fn test_parse_range() {
+        let node = parse_loop_node("L".to_string(), "for i in 1..n").unwrap();
+        assert_eq!(
+            node,
+            Node::Loop {
+                id: "L".to_string(),
+                var: "i".to_string(),
+                iter: LoopIter::Range {
+                    start: Expr::IntLit { value: 1 },
+                    end: Expr::Variable {
+                        name: "n".to_string()
+                    },
+                },
+            }
+        );
+    }
+
+    #[test]
+    fn test_parse_values() {
+        let node = parse_loop_node("L".to_string(), " for x in 'a', 2 ").unwrap();
+        let Node::Loop { var, iter, .. } = node else {
+            panic!("expected a loop node");
+        };
+        assert_eq!(var, "x");
+        assert_eq!(
+            iter,
+            LoopIter::Values(vec![
+                Expr::StrLit {
+                    value: "a".to_string()
+                },
+                Expr::IntLit { value: 2 },
+            ])
+        );
+    }
+
+    #[test]
+    fn test_parse_invalid_label() {
+        assert!(parse_loop_node("L".to_string(), "i in 1..3").is_err());
+        assert!(parse_loop_node("L".to_string(), "for i 1..3").is_err());
+        assert!(parse_loop_node("L".to_string(), "for i in 1..").is_err());
+        assert!(parse_loop_node("L".to_string(), "fori in 1..3").is_err());
+    }
+}
@@ -27,15 +27,25 @@
 //! let flowchart = parse(input).expect("Failed to parse");
 //! ```
 //!
+//! [`parse`] stops at the first syntax error. [`parse_partial`] keeps going
+//! past lines that cannot be parsed, returning whatever could be parsed
+//! together with every syntax error. A [`Document`] keeps such a parse up
+//! to date as its text is edited, re-parsing only the lines that change.
+//!
 //! # Grammar
 //!
 //! The parser supports the following Mermaid flowchart constructs:
 //!
 //! - **Directions**: `TD`, `TB`, `LR`, `RL`, `BT`
-//! - **Nodes**: `Start`, `End`, process nodes `id[statements]`, condition nodes `id{expr?}`
-//! - **Edges**: `-->` with optional labels `|Yes|`, `|No|`, or custom text
+//! - **Nodes**: `Start`, `End`, entry nodes `id([Start name])`, terminal nodes
+//!   `id([End: result])`, process nodes `id[statements]`, condition nodes `id{expr?}`,
+//!   decision table nodes `id[[table name]]`, loop nodes `id{{for i in 1..n}}`, and Mermaid v11 metadata nodes `id@{ shape: ..., label: "..." }`
+//! - **Edges**: `-->` with optional labels `|Yes|`, `|No|`, guards `|[expr]|` and
+//!   `|[else]|`, or custom text
 //! - **Expressions**: Arithmetic, comparison, logical operators with proper precedence
-//! - **Statements**: `println`, `print`, `error`, and assignment (`=`)
+//! - **Statements**: `println`, `print`, `error`, `sleep`, `exit`, `return`,
+//!   `const`, `unset`, assignment (`=`), compound assignment (`+=`, `-=`,
+//!   `*=`, `/=`, `%=`), and parallel assignment (`a, b = b, a`)
 //!
 //! # See Also
 //!
@@ -43,20 +53,42 @@
 //! - [`AnalysisError`] - Error type returned on analysis failures
 //! - [pest documentation](https://pest.rs/book/)
 
+mod consts;
+mod coverage;
+mod entity;
 mod error;
 mod expr;
+mod guard;
+mod incremental;
+mod loops;
+mod recover;
+mod shape;
+mod stadium;
+mod table;
 mod validate;
 
+use std::path::Path;
+
 use pest::Parser;
 use pest::iterators::Pair;
 use pest_derive::Parser;
 use rustc_hash::FxHashMap;
 
-pub use error::{AnalysisError, SyntaxError, ValidationError};
+use entity::decode_entities;
+pub use error::{
+    AnalysisError, ExprRole, Place, SyntaxError, TableError, TableValue, ValidationError,
+};
 use expr::parse_expression;
+use guard::split_guard;
+pub use incremental::Document;
+use loops::parse_loop_node;
+pub use recover::{PartialParse, parse_partial, parse_partial_in_dir};
+use shape::parse_shaped_node;
+use stadium::parse_stadium_node;
+use table::{load_tables, parse_table_node};
 use validate::{insert_node, validate_flowchart};
 
-use crate::ast::{Direction, Edge, EdgeLabel, Flowchart, Node, Statement};
+use crate::ast::{BinaryOp, Direction, Edge, EdgeLabel, Expr, Flowchart, Node, Statement};
 
 /// Internal pest parser generated from the PEG grammar.
 ///
@@ -123,9 +155,35 @@ struct MermaidParser;
 /// - All condition nodes have exactly one `Yes` edge and one `No` edge
 /// - The `End` node has no outgoing edges
 /// - Non-condition nodes have at most one outgoing edge
+/// - Loop nodes have exactly one `body` edge and one `done` edge, and their
+///   body leads back to them
+/// - Decision tables have an edge for every target, and no missing or
+///   overlapping rules where this can be checked statically
 ///
 /// This ensures the flowchart can be executed without ambiguity.
+///
+/// Decision tables stored in CSV files are read relative to the current
+/// directory; use [`parse_in_dir`] to resolve them against the program's
+/// directory instead.
 pub fn parse(input: &str) -> Result<Flowchart, AnalysisError> {
+    parse_in_dir(input, Path::new(""))
+}
+
+/// Parses Mermaid flowchart source text like [`parse`], reading CSV decision
+/// tables relative to `dir`.
+///
+/// # Errors
+///
+/// Returns the same errors as [`parse`], and a syntax error if a decision
+/// table file cannot be read.
+///
+/// # Examples
+///
+/// ```ignore
+/// let source = fs::read_to_string("rules/pricing.mmd")?;
+/// let flowchart = parse_in_dir(&source, Path::new("rules"))?;
+/// ```
+pub fn parse_in_dir(input: &str, dir: &Path) -> Result<Flowchart, AnalysisError> {
     let pairs = MermaidParser::parse(Rule::flowchart, input)?;
 
     let mut direction = Direction::Td;
@@ -136,25 +194,11 @@ pub fn parse(input: &str) -> Result<Flowchart, AnalysisError> {
         if pair.as_rule() == Rule::flowchart {
             for inner in pair.into_inner() {
                 match inner.as_rule() {
-                    Rule::direction => {
-                        direction = parse_direction(inner);
+                    Rule::header => {
+                        direction = parse_header(inner);
                     }
                     Rule::line => {
-                        let parsed = parse_line(inner)?;
-
-                        if let Some(node) = parsed.from_node {
-                            insert_node(&mut nodes, node)?;
-                        }
-                        if let Some(node) = parsed.to_node {
-                            insert_node(&mut nodes, node)?;
-                        }
-
-                        edges.push(Edge {
-                            from: parsed.from_id,
-                            to: parsed.to_id,
-                            label: parsed.label,
-                            exit_code: parsed.exit_code,
-                        });
+                        add_line_decl(parse_line_decl(inner)?, &mut nodes, &mut edges)?;
                     }
                     _ => {}
                 }
@@ -162,6 +206,7 @@ pub fn parse(input: &str) -> Result<Flowchart, AnalysisError> {
         }
     }
 
+    load_tables(&mut nodes, input, dir)?;
     validate_flowchart(&nodes, &edges)?;
 
     let nodes_vec: Vec<Node> = nodes.into_values().collect();
@@ -173,6 +218,67 @@ pub fn parse(input: &str) -> Result<Flowchart, AnalysisError> {
     })
 }
 
+/// Returns the direction declared by a `header` pair.
+fn parse_header(pair: Pair<Rule>) -> Direction {
+    pair.into_inner()
+        .find(|p| p.as_rule() == Rule::direction)
+        .map_or(Direction::Td, parse_direction)
+}
+
+/// The nodes and edge declared by a single line.
+#[derive(Debug, Clone, PartialEq)]
+struct LineDecl {
+    /// The nodes defined on the line, in order.
+    nodes: Vec<Node>,
+    /// The edge, unless the line only declares a node.
+    edge: Option<Edge>,
+}
+
+/// Parses the nodes and edge declared by a `line` pair.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if a node or label cannot be parsed.
+fn parse_line_decl(pair: Pair<Rule>) -> Result<LineDecl, SyntaxError> {
+    if let Some(node) = parse_node_decl(&pair)? {
+        return Ok(LineDecl {
+            nodes: vec![node],
+            edge: None,
+        });
+    }
+
+    let parsed = parse_line(pair)?;
+    Ok(LineDecl {
+        nodes: parsed.from_node.into_iter().chain(parsed.to_node).collect(),
+        edge: Some(Edge {
+            from: parsed.from_id,
+            to: parsed.to_id,
+            label: parsed.label,
+            exit_code: parsed.exit_code,
+            return_value: parsed.return_value,
+            actions: parsed.actions,
+        }),
+    })
+}
+
+/// Adds the nodes and edge declared by a line to a flowchart being built.
+///
+/// # Errors
+///
+/// Returns [`ValidationError`] if a node conflicts with an earlier
+/// definition.
+fn add_line_decl(
+    decl: LineDecl,
+    nodes: &mut FxHashMap<String, Node>,
+    edges: &mut Vec<Edge>,
+) -> Result<(), ValidationError> {
+    for node in decl.nodes {
+        insert_node(nodes, node)?;
+    }
+    edges.extend(decl.edge);
+    Ok(())
+}
+
 /// Converts a direction token into a [`Direction`] enum value.
 ///
 /// # Arguments
@@ -196,13 +302,18 @@ fn parse_direction(pair: Pair<Rule>) -> Direction {
 
 /// Result of parsing label text from an edge.
 ///
-/// Contains the parsed edge label and optional exit code extracted from
-/// syntax like `exit N` or `Yes, exit N`.
+/// Contains the parsed edge label, optional exit code, optional result
+/// value, and actions extracted from syntax like `exit N`, `Yes, exit N`,
+/// `return expr`, or `Yes / i = i + 1`.
 struct ParsedLabel {
     /// The edge label (Yes, No, Custom, or None for exit-code-only labels).
     edge_label: Option<EdgeLabel>,
-    /// Optional exit code (0-255) parsed from `exit N` syntax.
-    exit_code: Option<u8>,
+    /// Optional exit code parsed from `exit expr` syntax.
+    exit_code: Option<Expr>,
+    /// Optional result value parsed from `return expr` syntax.
+    return_value: Option<Expr>,
+    /// Statements parsed from the text after `/`.
+    actions: Vec<Statement>,
 }
 
 /// Result of parsing a single line (edge definition) in the flowchart.
@@ -216,7 +327,11 @@ struct ParsedLine {
     /// Optional edge label (`Yes`, `No`, or custom text).
     label: Option<EdgeLabel>,
     /// Optional exit code parsed from the edge label.
-    exit_code: Option<u8>,
+    exit_code: Option<Expr>,
+    /// Optional result value parsed from the edge label.
+    return_value: Option<Expr>,
+    /// Statements to execute when the edge is followed.
+    actions: Vec<Statement>,
     /// The source node definition, if present on this line.
     from_node: Option<Node>,
     /// The target node definition, if present on this line.
@@ -249,17 +364,17 @@ fn parse_line(pair: Pair<Rule>) -> Result<ParsedLine, SyntaxError> {
     let edge_def = pair
         .into_inner()
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected edge_def in line"))?;
+        .ok_or_else(|| SyntaxError::internal("expected edge_def in line"))?;
     let mut inner = edge_def.into_inner();
 
     let from_pair = inner
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected from_pair in edge_def"))?;
+        .ok_or_else(|| SyntaxError::internal("expected from_pair in edge_def"))?;
     let (from_id, from_node) = parse_node_ref(from_pair)?;
 
     let arrow_pair = inner
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected arrow in edge_def"))?;
+        .ok_or_else(|| SyntaxError::internal("expected arrow in edge_def"))?;
 
     let mut parsed_label: Option<ParsedLabel> = None;
     if arrow_pair.as_rule() == Rule::arrow_with_inline_label {
@@ -268,26 +383,23 @@ fn parse_line(pair: Pair<Rule>) -> Result<ParsedLine, SyntaxError> {
 
     let mut to_pair = inner
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected to_pair in edge_def"))?;
+        .ok_or_else(|| SyntaxError::internal("expected to_pair in edge_def"))?;
 
     if to_pair.as_rule() == Rule::edge_label {
         if parsed_label.is_some() {
-            return Err(SyntaxError::new(format!(
-                "edge from '{}' cannot have both an inline label (--text-->) and a pipe label (|text|)",
-                from_id
-            )));
+            return Err(SyntaxError::ConflictingEdgeLabels { from: from_id });
         }
         parsed_label = Some(parse_edge_label(to_pair)?);
         to_pair = inner
             .next()
-            .ok_or_else(|| SyntaxError::new("internal: expected to_pair after edge_label"))?;
+            .ok_or_else(|| SyntaxError::internal("expected to_pair after edge_label"))?;
     }
 
     let (to_id, to_node) = parse_node_ref(to_pair)?;
 
-    let (label, exit_code) = match parsed_label {
-        Some(pl) => (pl.edge_label, pl.exit_code),
-        None => (None, None),
+    let (label, exit_code, return_value, actions) = match parsed_label {
+        Some(pl) => (pl.edge_label, pl.exit_code, pl.return_value, pl.actions),
+        None => (None, None, None, Vec::new()),
     };
 
     Ok(ParsedLine {
@@ -295,15 +407,55 @@ fn parse_line(pair: Pair<Rule>) -> Result<ParsedLine, SyntaxError> {
         to_id,
         label,
         exit_code,
+        return_value,
+        actions,
         from_node,
         to_node,
     })
 }
 
+/// Parses a line that declares a node without an edge.
+///
+/// # Returns
+///
+/// `Some(Node)` if the line is a standalone node declaration such as
+/// `A@{ shape: rect, label: "x = 1" }`, or `None` if it is an edge definition.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if the node definition cannot be parsed.
+fn parse_node_decl(pair: &Pair<Rule>) -> Result<Option<Node>, SyntaxError> {
+    let Some(decl) = pair
+        .clone()
+        .into_inner()
+        .next()
+        .filter(|p| p.as_rule() == Rule::node_decl)
+    else {
+        return Ok(None);
+    };
+    let def = decl
+        .into_inner()
+        .next()
+        .ok_or_else(|| SyntaxError::internal("expected node_with_def in node_decl"))?;
+    parse_node_with_def(def).map(Some)
+}
+
 /// Parses an edge label enclosed in `|` delimiters.
 ///
 /// Labels are case-insensitive for `Yes` and `No`. Any other label
-/// text is preserved as a custom label.
+/// text is preserved as a custom label. Entity codes are decoded first, so
+/// an expression in `return expr` can spell `|` as `#124;`.
+///
+/// Text after a `/` is a list of statements (actions) to execute when the
+/// edge is followed, as in `Yes / i = i + 1`. The label before the `/` may
+/// be empty. Actions start at the first `/` that is followed by valid
+/// statements, so a division such as `return total / count` stays part of
+/// the label. The split happens before entity codes are decoded, so a label
+/// can contain a literal slash written as `#47;`.
+///
+/// If no `/` is followed by valid statements, but some `/` follows a label
+/// that can only select a branch, the actions after the last such `/` are
+/// reported as invalid.
 ///
 /// # Arguments
 ///
@@ -319,127 +471,259 @@ fn parse_edge_label(pair: Pair<Rule>) -> Result<ParsedLabel, SyntaxError> {
     let label_text = pair
         .into_inner()
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected label_text in edge_label"))?
-        .as_str()
-        .trim();
-    parse_label_text(label_text)
+        .ok_or_else(|| SyntaxError::internal("expected label_text in edge_label"))?
+        .as_str();
+    let mut action_error = None;
+    for (slash, _) in label_text.match_indices('/') {
+        let label = label_text[..slash].trim();
+        match parse_edge_actions(&label_text[slash + 1..]) {
+            Ok(actions) => return parse_label_with_actions(label, actions),
+            // Nothing but actions can follow an empty, Yes/No, or guard label
+            Err(e) if is_branch_only(label) => action_error = Some(e),
+            Err(_) => {}
+        }
+    }
+    match action_error {
+        Some(e) => Err(e),
+        None => parse_label_with_actions(label_text, Vec::new()),
+    }
+}
+
+/// Returns `true` if the label is empty or only selects a branch (`Yes`,
+/// `No`, `[guard]`, or `[else]`).
+fn is_branch_only(label: &str) -> bool {
+    label.is_empty()
+        || branch_label(label).is_some()
+        || split_guard(label).is_some_and(|(_, rest)| rest.is_empty())
+}
+
+/// Parses the label part of an edge label and attaches its actions.
+fn parse_label_with_actions(
+    label_text: &str,
+    actions: Vec<Statement>,
+) -> Result<ParsedLabel, SyntaxError> {
+    let mut parsed = if label_text.trim().is_empty() {
+        ParsedLabel {
+            edge_label: None,
+            exit_code: None,
+            return_value: None,
+            actions: Vec::new(),
+        }
+    } else {
+        parse_label_text(decode_entities(label_text).trim())?
+    };
+    parsed.actions = actions;
+    Ok(parsed)
+}
+
+/// Parses the actions of an edge label (the text after `/`) as statements.
+///
+/// Entity codes are decoded first. Statements may be separated by `;` or
+/// `<br>`.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if the text is not a valid list of statements.
+fn parse_edge_actions(text: &str) -> Result<Vec<Statement>, SyntaxError> {
+    let text = decode_entities(text.trim());
+    let entry = MermaidParser::parse(Rule::label_statements, &text)
+        .map_err(|e| SyntaxError::InvalidEdgeAction {
+            text: text.to_string(),
+            message: e.to_string(),
+        })?
+        .next()
+        .ok_or_else(|| SyntaxError::internal("expected label_statements"))?;
+    parse_statements(entry)
 }
 
 fn parse_inline_label(pair: Pair<Rule>) -> Result<ParsedLabel, SyntaxError> {
     let label_text = pair
         .into_inner()
         .find(|p| p.as_rule() == Rule::label_text)
-        .ok_or_else(|| {
-            SyntaxError::new("internal: expected label_text in arrow_with_inline_label")
-        })?
+        .ok_or_else(|| SyntaxError::internal("expected label_text in arrow_with_inline_label"))?
         .as_str()
         .trim();
     parse_label_text(label_text)
 }
 
-/// Parses label text into an edge label and optional exit code.
+/// Parses label text into an edge label, optional exit code, and optional
+/// result value.
 ///
 /// Recognizes the following patterns (case-insensitive):
 /// - `"yes"` / `"no"` → Yes/No label, no exit code
 /// - `"yes, exit N"` / `"no, exit N"` → Yes/No label with exit code
 /// - `"exit N"` → no label, with exit code
+/// - `"return expr"` / `"yes, return expr"` / `"no, return expr"` → result value
+/// - `"[expr]"` / `"[else]"` → guard, optionally followed by `", exit N"` or
+///   `", return expr"`
 /// - `"exit"` (no number) → Custom label
 /// - anything else → Custom label
+///
+/// The exit code may be any expression (e.g. `exit errors`); literal codes
+/// are range-checked here, computed ones at runtime.
 fn parse_label_text(text: &str) -> Result<ParsedLabel, SyntaxError> {
-    let lower = text.to_lowercase();
-    let trimmed_lower = lower.trim();
+    let trimmed = text.trim();
+    let mut parsed = ParsedLabel {
+        edge_label: None,
+        exit_code: None,
+        return_value: None,
+        actions: Vec::new(),
+    };
+
+    // Check for "yes" or "no"
+    if let Some(label) = branch_label(trimmed) {
+        parsed.edge_label = Some(label);
+        return Ok(parsed);
+    }
 
-    // Check for "yes" or "yes, exit N"
-    if let Some(rest) = trimmed_lower.strip_prefix("yes") {
-        let rest = rest.trim();
+    // Split off a leading "[guard]" or "[else]", which may only be followed
+    // by an exit code or result value, or a leading "yes," or "no,"
+    let (branch, item) = if let Some((guard, rest)) = split_guard(trimmed) {
+        let label = parse_guard(guard)?;
         if rest.is_empty() {
-            return Ok(ParsedLabel {
-                edge_label: Some(EdgeLabel::Yes),
-                exit_code: None,
-            });
+            parsed.edge_label = Some(label);
+            return Ok(parsed);
         }
-        if let Some(after_comma) = rest.strip_prefix(',') {
-            let after_comma = after_comma.trim();
-            if let Some(exit_code) = parse_exit_code_text(after_comma)? {
-                return Ok(ParsedLabel {
-                    edge_label: Some(EdgeLabel::Yes),
-                    exit_code: Some(exit_code),
-                });
-            }
+        let item = rest
+            .strip_prefix(',')
+            .map(str::trim)
+            .filter(|item| {
+                strip_keyword(item, "return").is_some() || strip_keyword(item, "exit").is_some()
+            })
+            .ok_or_else(|| SyntaxError::TextAfterGuard {
+                text: rest.to_string(),
+            })?;
+        (Some(label), item)
+    } else {
+        match trimmed.split_once(',') {
+            Some((head, rest)) => match branch_label(head) {
+                Some(label) => (Some(label), rest.trim()),
+                None => (None, trimmed),
+            },
+            None => (None, trimmed),
         }
-    }
+    };
 
-    // Check for "no" or "no, exit N"
-    if let Some(rest) = trimmed_lower.strip_prefix("no") {
-        let rest = rest.trim();
+    // Check for "return expr"
+    if let Some(rest) = strip_keyword(item, "return") {
         if rest.is_empty() {
-            return Ok(ParsedLabel {
-                edge_label: Some(EdgeLabel::No),
-                exit_code: None,
-            });
-        }
-        if let Some(after_comma) = rest.strip_prefix(',') {
-            let after_comma = after_comma.trim();
-            if let Some(exit_code) = parse_exit_code_text(after_comma)? {
-                return Ok(ParsedLabel {
-                    edge_label: Some(EdgeLabel::No),
-                    exit_code: Some(exit_code),
-                });
-            }
+            return Err(SyntaxError::MissingReturnValue);
         }
+        parsed.edge_label = branch;
+        parsed.return_value = Some(parse_label_expression(ExprRole::ReturnValue, rest)?);
+        return Ok(parsed);
     }
 
-    // Check for standalone "exit N"
-    if let Some(rest) = trimmed_lower.strip_prefix("exit") {
-        // Ensure "exit" is a whole word (followed by whitespace or end of string)
-        if rest.is_empty() || rest.starts_with(|c: char| c.is_whitespace()) {
-            let rest = rest.trim();
-            if !rest.is_empty() {
-                let exit_code = rest.parse::<u8>().map_err(|_| {
-                    SyntaxError::new(format!(
-                        "invalid exit code '{}': must be an integer between 0 and 255",
-                        rest
-                    ))
-                })?;
-                return Ok(ParsedLabel {
-                    edge_label: None,
-                    exit_code: Some(exit_code),
-                });
-            }
-            // "exit" without a number → treat as custom label
-        }
-        // "exit<non-whitespace>" (e.g., "exiting") → treat as custom label
+    // Check for "exit expr"; a standalone "exit" without a value is a custom label
+    if let Some(rest) = strip_keyword(item, "exit")
+        && (branch.is_some() || !rest.is_empty())
+    {
+        parsed.edge_label = branch;
+        parsed.exit_code = Some(parse_exit_expr(rest)?);
+        return Ok(parsed);
     }
 
     // Everything else is a custom label
-    Ok(ParsedLabel {
-        edge_label: Some(EdgeLabel::Custom(text.to_string())),
-        exit_code: None,
-    })
+    parsed.edge_label = Some(EdgeLabel::Custom(text.to_string()));
+    Ok(parsed)
+}
+
+/// Returns the Yes/No label for `"yes"` or `"no"` (case-insensitive).
+fn branch_label(text: &str) -> Option<EdgeLabel> {
+    let text = text.trim();
+    if text.eq_ignore_ascii_case("yes") {
+        Some(EdgeLabel::Yes)
+    } else if text.eq_ignore_ascii_case("no") {
+        Some(EdgeLabel::No)
+    } else {
+        None
+    }
+}
+
+/// Parses the text between the brackets of a guard label.
+///
+/// `else` (case-insensitive) is the fallback; anything else must be an
+/// expression.
+fn parse_guard(text: &str) -> Result<EdgeLabel, SyntaxError> {
+    if text.eq_ignore_ascii_case("else") {
+        Ok(EdgeLabel::Else)
+    } else {
+        parse_label_expression(ExprRole::Guard, text).map(EdgeLabel::Guard)
+    }
+}
+
+/// Strips a leading keyword (case-insensitive), returning the trimmed rest.
+///
+/// The keyword must be a whole word, followed by whitespace or the end of
+/// the text, so `"exiting"` does not match `"exit"`.
+fn strip_keyword<'a>(text: &'a str, keyword: &str) -> Option<&'a str> {
+    let head = text.get(..keyword.len())?;
+    let rest = &text[keyword.len()..];
+    (head.eq_ignore_ascii_case(keyword)
+        && (rest.is_empty() || rest.starts_with(char::is_whitespace)))
+    .then(|| rest.trim())
 }
 
-/// Parses "exit N" text and returns the exit code, or None if not matching.
+/// Parses the value of an `exit` edge label.
+///
+/// Integer literals must be between 0 and 255. Any other expression is
+/// checked when it is evaluated.
+fn parse_exit_expr(text: &str) -> Result<Expr, SyntaxError> {
+    if text.is_empty() {
+        return Err(SyntaxError::MissingExitCode);
+    }
+    // Integer literals, including negative ones, are range-checked here
+    let digits = text.strip_prefix('-').map_or(text, str::trim_start);
+    if !digits.is_empty() && digits.bytes().all(|b| b.is_ascii_digit()) {
+        let exit_code = text
+            .parse::<u8>()
+            .map_err(|_| SyntaxError::ExitCodeOutOfRange {
+                text: text.to_string(),
+            })?;
+        return Ok(Expr::IntLit {
+            value: exit_code.into(),
+        });
+    }
+    parse_label_expression(ExprRole::ExitCode, text)
+}
+
+/// Parses an expression that appears in an edge label.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] naming the expression's `role` (e.g. exit code)
+/// if the text is not a valid expression.
+fn parse_label_expression(role: ExprRole, text: &str) -> Result<Expr, SyntaxError> {
+    let entry = MermaidParser::parse(Rule::label_expression, text)
+        .map_err(|e| SyntaxError::InvalidExpression {
+            role,
+            text: text.to_string(),
+            message: e.to_string(),
+        })?
+        .next()
+        .ok_or_else(|| SyntaxError::internal("expected label_expression"))?;
+    let expr_pair = entry
+        .into_inner()
+        .find(|p| p.as_rule() == Rule::expression)
+        .ok_or_else(|| SyntaxError::internal("expected expr in label_expression"))?;
+    parse_expression(expr_pair)
+}
+
+/// Parses "exit N" text (the keyword is case-insensitive) and returns the
+/// exit code, or None if not matching.
 fn parse_exit_code_text(text: &str) -> Result<Option<u8>, SyntaxError> {
-    if let Some(rest) = text.strip_prefix("exit") {
-        // Ensure "exit" is a whole word (followed by whitespace or end of string)
-        if !rest.is_empty() && !rest.starts_with(|c: char| c.is_whitespace()) {
-            return Ok(None); // Not "exit", e.g. "exiting"
-        }
-        let rest = rest.trim();
-        if rest.is_empty() {
-            return Err(SyntaxError::new(
-                "exit code requires a numeric value (e.g., 'exit 1')",
-            ));
-        }
-        let exit_code = rest.parse::<u8>().map_err(|_| {
-            SyntaxError::new(format!(
-                "invalid exit code '{}': must be an integer between 0 and 255",
-                rest
-            ))
-        })?;
-        return Ok(Some(exit_code));
+    let Some(rest) = strip_keyword(text, "exit") else {
+        return Ok(None); // Not "exit", e.g. "exiting"
+    };
+    if rest.is_empty() {
+        return Err(SyntaxError::MissingExitCode);
     }
-    Ok(None)
+    let exit_code = rest
+        .parse::<u8>()
+        .map_err(|_| SyntaxError::ExitCodeOutOfRange {
+            text: rest.to_string(),
+        })?;
+    Ok(Some(exit_code))
 }
 
 /// Parses a node reference, which may be a full definition or a bare identifier.
@@ -464,7 +748,7 @@ fn parse_node_ref(pair: Pair<Rule>) -> Result<(String, Option<Node>), SyntaxErro
     let inner = pair
         .into_inner()
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected inner in node_ref"))?;
+        .ok_or_else(|| SyntaxError::internal("expected inner in node_ref"))?;
 
     match inner.as_rule() {
         Rule::node_with_def => {
@@ -482,12 +766,17 @@ fn parse_node_ref(pair: Pair<Rule>) -> Result<(String, Option<Node>), SyntaxErro
 
 /// Parses a node with its full definition (shape and content).
 ///
-/// Handles the four node types supported by the grammar:
+/// Handles the node types supported by the grammar:
 /// - `Start`: The entry point of the flowchart
 /// - `End`: A termination point of the flowchart
+/// - Entry and terminal nodes: `id([Start name])`, `id([End: result])` - other
+///   stadium nodes, see [`parse_stadium_node`]
 /// - Process nodes: `id[statements]` - rectangular nodes with executable statements
 /// - Condition nodes: `id{expr?}` - diamond nodes with a boolean expression
 ///
+/// Any of these may instead be declared with Mermaid v11 metadata
+/// (`id@{ shape: ..., label: ... }`); see [`parse_shaped_node`].
+///
 /// # Arguments
 ///
 /// * `pair` - A pest [`Pair`] matching the `node_with_def` rule
@@ -503,9 +792,10 @@ fn parse_node_with_def(pair: Pair<Rule>) -> Result<Node, SyntaxError> {
     let inner = pair
         .into_inner()
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected inner in node_with_def"))?;
+        .ok_or_else(|| SyntaxError::internal("expected inner in node_with_def"))?;
 
     match inner.as_rule() {
+        Rule::shaped_node => parse_shaped_node(inner),
         Rule::start_node => {
             let label = parse_stadium_label(&inner);
             Ok(Node::Start { label })
@@ -514,44 +804,86 @@ fn parse_node_with_def(pair: Pair<Rule>) -> Result<Node, SyntaxError> {
             let label = parse_stadium_label(&inner);
             Ok(Node::End { label })
         }
+        Rule::stadium_node => {
+            let id = inner
+                .clone()
+                .into_inner()
+                .next()
+                .ok_or_else(|| SyntaxError::internal("expected id in stadium_node"))?
+                .as_str()
+                .to_string();
+            let label = parse_stadium_label(&inner);
+            parse_stadium_node(id, label)
+        }
+        Rule::table_node => {
+            let mut parts = inner.into_inner();
+            let id = parts
+                .next()
+                .ok_or_else(|| SyntaxError::internal("expected id in table_node"))?
+                .as_str()
+                .to_string();
+            let label = parts
+                .next()
+                .ok_or_else(|| SyntaxError::internal("expected label in table_node"))?;
+            parse_table_node(id, &decode_entities(label.as_str()))
+        }
+        Rule::loop_node => {
+            let mut parts = inner.into_inner();
+            let id = parts
+                .next()
+                .ok_or_else(|| SyntaxError::internal("expected id in loop_node"))?
+                .as_str()
+                .to_string();
+            let label = parts
+                .next()
+                .ok_or_else(|| SyntaxError::internal("expected label in loop_node"))?;
+            parse_loop_node(id, &decode_entities(label.as_str()))
+        }
         Rule::process_node => {
             let mut parts = inner.into_inner();
             let id = parts
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: expected id in process_node"))?
+                .ok_or_else(|| SyntaxError::internal("expected id in process_node"))?
                 .as_str()
                 .to_string();
             let statements_pair = parts
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: expected statements in process_node"))?;
-            let statements = parse_statements(statements_pair)?;
+                .ok_or_else(|| SyntaxError::internal("expected statements in process_node"))?;
+            let statements = match statements_pair.as_rule() {
+                Rule::process_quoted_text => parse_label_statements(&id, statements_pair.as_str())?,
+                _ => parse_statements(statements_pair)?,
+            };
             Ok(Node::Process { id, statements })
         }
         Rule::condition_node => {
             let mut parts = inner.into_inner();
             let id = parts
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: expected id in condition_node"))?
+                .ok_or_else(|| SyntaxError::internal("expected id in condition_node"))?
                 .as_str()
                 .to_string();
             let expr_pair = parts
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: expected expr in condition_node"))?;
-            let condition = parse_expression(expr_pair)?;
+                .ok_or_else(|| SyntaxError::internal("expected expr in condition_node"))?;
+            let condition = match expr_pair.as_rule() {
+                Rule::condition_quoted_text => parse_label_condition(&id, expr_pair.as_str())?,
+                _ => parse_expression(expr_pair)?,
+            };
             Ok(Node::Condition { id, condition })
         }
         _ => unreachable!(),
     }
 }
 
-/// Parses the optional stadium label from a start or end node.
+/// Parses the optional stadium label from a start, end, or terminal node.
 ///
 /// Stadium labels use the Mermaid syntax `([label text])` and provide
 /// a display name for Start and End nodes.
 ///
 /// # Arguments
 ///
-/// * `pair` - A pest [`Pair`] matching `start_node` or `end_node` rule
+/// * `pair` - A pest [`Pair`] matching the `start_node`, `end_node`, or
+///   `stadium_node` rule
 ///
 /// # Returns
 ///
@@ -559,13 +891,61 @@ fn parse_node_with_def(pair: Pair<Rule>) -> Result<Node, SyntaxError> {
 fn parse_stadium_label(pair: &Pair<Rule>) -> Option<String> {
     for inner in pair.clone().into_inner() {
         if inner.as_rule() == Rule::stadium_label {
-            return inner.into_inner().next().map(|p| p.as_str().to_string());
+            return inner
+                .into_inner()
+                .next()
+                .map(|p| decode_entities(p.as_str()).into_owned());
         }
     }
     None
 }
 
-/// Parses a semicolon-separated list of statements.
+/// Parses the text of a double-quoted or metadata label as statements.
+///
+/// Entity codes are decoded first. Statements may be separated by `;`,
+/// `<br>`, or newlines.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] naming the node if the label is not a valid
+/// list of statements.
+fn parse_label_statements(id: &str, text: &str) -> Result<Vec<Statement>, SyntaxError> {
+    let text = decode_entities(text);
+    let entry = parse_label(id, Rule::label_statements, &text)?;
+    parse_statements(entry)
+}
+
+/// Parses the text of a double-quoted or metadata label as a condition
+/// (`expr?`).
+///
+/// Entity codes are decoded first.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] naming the node if the label is not a valid
+/// condition.
+fn parse_label_condition(id: &str, text: &str) -> Result<Expr, SyntaxError> {
+    let text = decode_entities(text);
+    let entry = parse_label(id, Rule::label_condition, &text)?;
+    let expr_pair = entry
+        .into_inner()
+        .find(|p| p.as_rule() == Rule::expression)
+        .ok_or_else(|| SyntaxError::internal("expected expr in label_condition"))?;
+    parse_expression(expr_pair)
+}
+
+/// Parses label text with the given entry rule, returning the entry pair.
+fn parse_label<'i>(id: &str, rule: Rule, text: &'i str) -> Result<Pair<'i, Rule>, SyntaxError> {
+    MermaidParser::parse(rule, text)
+        .map_err(|e| SyntaxError::InvalidNodeLabel {
+            node_id: id.to_string(),
+            message: e.to_string(),
+        })?
+        .next()
+        .ok_or_else(|| SyntaxError::internal("expected entry rule in label"))
+}
+
+/// Parses a list of statements separated by `;` or `<br>`.
 ///
 /// Statements appear inside process nodes and are executed sequentially
 /// when the node is visited during flowchart execution.
@@ -595,11 +975,20 @@ fn parse_statements(pair: Pair<Rule>) -> Result<Vec<Statement>, SyntaxError> {
 
 /// Parses a single statement.
 ///
-/// Supports four statement types:
+/// Supports the following statement types:
 /// - `println expr`: Outputs the expression value to stdout with newline
 /// - `print expr`: Outputs the expression value to stdout without newline
 /// - `error expr`: Outputs the expression value to stderr
+/// - `sleep expr`: Pauses for the given number of milliseconds
+/// - `exit expr`: Sets the program's exit code
+/// - `return expr`: Sets the program's result value
+/// - `const variable = expr`: Defines a constant
+/// - `unset variable`: Removes a variable
 /// - `variable = expr`: Assigns the expression value to a variable
+/// - `variable += expr` (and `-=`, `*=`, `/=`, `%=`): Updates a variable
+///   with an arithmetic operator
+/// - `a, b = expr1, expr2`: Assigns several variables at once, evaluating
+///   every value first
 ///
 /// # Arguments
 ///
@@ -616,14 +1005,14 @@ fn parse_statement(pair: Pair<Rule>) -> Result<Statement, SyntaxError> {
     let inner = pair
         .into_inner()
         .next()
-        .ok_or_else(|| SyntaxError::new("internal: expected inner in statement"))?;
+        .ok_or_else(|| SyntaxError::internal("expected inner in statement"))?;
 
     match inner.as_rule() {
         Rule::println_stmt => {
             let expr_pair = inner
                 .into_inner()
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: expected expr in println_stmt"))?;
+                .ok_or_else(|| SyntaxError::internal("expected expr in println_stmt"))?;
             let expr = parse_expression(expr_pair)?;
             Ok(Statement::Println { expr })
         }
@@ -631,7 +1020,7 @@ fn parse_statement(pair: Pair<Rule>) -> Result<Statement, SyntaxError> {
             let expr_pair = inner
                 .into_inner()
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: expected expr in print_stmt"))?;
+                .ok_or_else(|| SyntaxError::internal("expected expr in print_stmt"))?;
             let expr = parse_expression(expr_pair)?;
             Ok(Statement::Print { expr })
         }
@@ -639,24 +1028,127 @@ fn parse_statement(pair: Pair<Rule>) -> Result<Statement, SyntaxError> {
             let expr_pair = inner
                 .into_inner()
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: expected expr in error_stmt"))?;
+                .ok_or_else(|| SyntaxError::internal("expected expr in error_stmt"))?;
             let message = parse_expression(expr_pair)?;
             Ok(Statement::Error { message })
         }
+        Rule::sleep_stmt => {
+            let expr_pair = inner
+                .into_inner()
+                .find(|p| p.as_rule() == Rule::expression)
+                .ok_or_else(|| SyntaxError::internal("expected expr in sleep_stmt"))?;
+            let duration = parse_expression(expr_pair)?;
+            Ok(Statement::Sleep { duration })
+        }
+        Rule::exit_stmt => {
+            let expr_pair = inner
+                .into_inner()
+                .find(|p| p.as_rule() == Rule::expression)
+                .ok_or_else(|| SyntaxError::internal("expected expr in exit_stmt"))?;
+            let code = parse_expression(expr_pair)?;
+            Ok(Statement::Exit { code })
+        }
+        Rule::return_stmt => {
+            let expr_pair = inner
+                .into_inner()
+                .find(|p| p.as_rule() == Rule::expression)
+                .ok_or_else(|| SyntaxError::internal("expected expr in return_stmt"))?;
+            let value = parse_expression(expr_pair)?;
+            Ok(Statement::Return { value })
+        }
         Rule::assign_stmt => {
             let mut parts = inner.into_inner();
             let variable = parts
                 .next()
-                .ok_or_else(|| SyntaxError::new("internal: expected variable in assign_stmt"))?
+                .ok_or_else(|| SyntaxError::internal("expected variable in assign_stmt"))?
                 .as_str()
                 .to_string();
             let value = parse_expression(
                 parts
                     .next()
-                    .ok_or_else(|| SyntaxError::new("internal: expected value in assign_stmt"))?,
+                    .ok_or_else(|| SyntaxError::internal("expected value in assign_stmt"))?,
             )?;
             Ok(Statement::Assign { variable, value })
         }
+        Rule::const_stmt => {
+            let mut parts = inner
+                .into_inner()
+                .filter(|p| p.as_rule() != Rule::const_keyword);
+            let variable = parts
+                .next()
+                .ok_or_else(|| SyntaxError::internal("expected variable in const_stmt"))?
+                .as_str()
+                .to_string();
+            let value = parse_expression(
+                parts
+                    .next()
+                    .ok_or_else(|| SyntaxError::internal("expected value in const_stmt"))?,
+            )?;
+            Ok(Statement::Const { variable, value })
+        }
+        Rule::unset_stmt => {
+            let variable = inner
+                .into_inner()
+                .find(|p| p.as_rule() == Rule::identifier)
+                .ok_or_else(|| SyntaxError::internal("expected variable in unset_stmt"))?
+                .as_str()
+                .to_string();
+            Ok(Statement::Unset { variable })
+        }
+        Rule::compound_assign_stmt => {
+            let mut parts = inner.into_inner();
+            let variable = parts
+                .next()
+                .ok_or_else(|| SyntaxError::internal("expected variable in compound_assign_stmt"))?
+                .as_str()
+                .to_string();
+            let op = match parts.next().map(|p| p.as_str()) {
+                Some("+=") => BinaryOp::Add,
+                Some("-=") => BinaryOp::Sub,
+                Some("*=") => BinaryOp::Mul,
+                Some("/=") => BinaryOp::Div,
+                Some("%=") => BinaryOp::Mod,
+                _ => {
+                    return Err(SyntaxError::internal(
+                        "expected operator in compound_assign_stmt",
+                    ));
+                }
+            };
+            let value =
+                parse_expression(parts.next().ok_or_else(|| {
+                    SyntaxError::internal("expected value in compound_assign_stmt")
+                })?)?;
+            Ok(Statement::CompoundAssign {
+                variable,
+                op,
+                value,
+            })
+        }
+        Rule::parallel_assign_stmt => {
+            let mut variables: Vec<String> = Vec::new();
+            let mut values = Vec::new();
+            for part in inner.into_inner() {
+                match part.as_rule() {
+                    Rule::identifier => {
+                        let name = part.as_str();
+                        if variables.iter().any(|v| v == name) {
+                            return Err(SyntaxError::DuplicateAssignment {
+                                name: name.to_string(),
+                            });
+                        }
+                        variables.push(name.to_string());
+                    }
+                    _ => values.push(parse_expression(part)?),
+                }
+            }
+            if variables.len() != values.len() {
+                return Err(SyntaxError::AssignmentCountMismatch {
+                    variables: variables.len(),
+                    values: values.len(),
+                });
+            }
+            Ok(Statement::ParallelAssign { variables, values })
+        }
         _ => unreachable!(),
     }
 }
@@ -665,7 +1157,8 @@ fn parse_statement(pair: Pair<Rule>) -> Result<Statement, SyntaxError> {
 mod tests {
     use super::expr::unescape_string;
     use super::*;
-    use crate::ast::{BinaryOp, Expr, TypeName, UnaryOp};
+    use crate::ast::{BinaryOp, Expr, Function, TypeName, UnaryOp};
+    use crate::codes::ErrorCode;
 
     // Helper function to parse an expression from a condition node
     fn parse_condition_expr(expr_str: &str) -> Expr {
@@ -2091,4 +2584,512 @@ flowchart TD
             .find(|e| e.from == "A" && e.to == "End");
         assert!(matches!(no_edge.unwrap().label, Some(EdgeLabel::No)));
     }
+
+    #[test]
+    fn test_parse_function_call() {
+        let expr = parse_assign_expr("rand_int(1, 6)");
+        match expr {
+            Expr::Call { function, args } => {
+                assert_eq!(function, Function::RandInt);
+                assert_eq!(args.len(), 2);
+                assert!(matches!(args[0], Expr::IntLit { value: 1 }));
+                assert!(matches!(args[1], Expr::IntLit { value: 6 }));
+            }
+            _ => panic!("Expected Call, got {:?}", expr),
+        }
+    }
+
+    #[test]
+    fn test_parse_variadic_function_call() {
+        let expr = parse_assign_expr("rand_choice('a', 'b', 'c')");
+        match expr {
+            Expr::Call { function, args } => {
+                assert_eq!(function, Function::RandChoice);
+                assert_eq!(args.len(), 3);
+            }
+            _ => panic!("Expected Call, got {:?}", expr),
+        }
+    }
+
+    #[test]
+    fn test_parse_unknown_function() {
+        let input = r#"flowchart TD
+    Start --> A[x = foo(1)]
+    A --> End
+"#;
+        let err = parse(input).unwrap_err();
+        assert!(matches!(err, AnalysisError::Syntax(_)));
+        assert!(
+            err.to_string().contains("unknown function 'foo'"),
+            "Unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_parse_function_wrong_arity() {
+        let input = r#"flowchart TD
+    Start --> A[x = rand_int(1)]
+    A --> End
+"#;
+        let err = parse(input).unwrap_err();
+        assert!(matches!(err, AnalysisError::Syntax(_)));
+        assert!(
+            err.to_string()
+                .contains("function 'rand_int' expects 2 arguments, but got 1"),
+            "Unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_parse_variadic_function_no_arguments() {
+        let input = r#"flowchart TD
+    Start --> A[x = rand_choice()]
+    A --> End
+"#;
+        let err = parse(input).unwrap_err();
+        assert!(
+            err.to_string()
+                .contains("function 'rand_choice' expects at least 1 argument, but got 0"),
+            "Unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_parse_sleep_statement() {
+        let input = r#"flowchart TD
+    Start --> A[sleep 100]
+    A --> End
+"#;
+        let flowchart = parse(input).unwrap();
+        let process_node = flowchart
+            .nodes
+            .iter()
+            .find(|n| matches!(n, Node::Process { .. }))
+            .unwrap();
+        match process_node {
+            Node::Process { statements, .. } => {
+                assert!(matches!(
+                    statements[0],
+                    Statement::Sleep {
+                        duration: Expr::IntLit { value: 100 }
+                    }
+                ));
+            }
+            _ => unreachable!(),
+        }
+    }
+
+    #[test]
+    fn test_parse_identifier_starting_with_sleep() {
+        let input = r#"flowchart TD
+    Start --> A[sleepy = 1]
+    A --> End
+"#;
+        let flowchart = parse(input).unwrap();
+        let process_node = flowchart
+            .nodes
+            .iter()
+            .find(|n| matches!(n, Node::Process { .. }))
+            .unwrap();
+        match process_node {
+            Node::Process { statements, .. } => {
+                assert!(matches!(
+                    &statements[0],
+                    Statement::Assign { variable, .. } if variable == "sleepy"
+                ));
+            }
+            _ => unreachable!(),
+        }
+    }
+
+    #[test]
+    fn test_parse_time_function_calls() {
+        let expr = parse_assign_expr("format_date(now_ms(), '%Y')");
+        match expr {
+            Expr::Call { function, args } => {
+                assert_eq!(function, Function::FormatDate);
+                assert!(matches!(
+                    args[0],
+                    Expr::Call {
+                        function: Function::NowMs,
+                        ..
+                    }
+                ));
+            }
+            _ => panic!("Expected Call, got {:?}", expr),
+        }
+    }
+
+    #[test]
+    fn test_parse_shaped_nodes() {
+        let input = r#"flowchart TD
+    Start@{ shape: stadium, label: "Begin" } --> A@{ shape: rect, label: "x = 1" }
+    A --> B@{ shape: diamond, label: "x > 0?" }
+    B -->|Yes| C@{ shape: rounded, label: "println x" }
+    B -->|No| End@{ shape: terminal }
+    C --> End
+"#;
+        let flowchart = parse(input).unwrap();
+        let find = |id: &str| flowchart.nodes.iter().find(|n| n.id() == id).unwrap();
+
+        assert_eq!(
+            find("Start"),
+            &Node::Start {
+                label: Some("Begin".to_string())
+            }
+        );
+        assert!(matches!(find("A"), Node::Process { statements, .. } if statements.len() == 1));
+        assert!(matches!(
+            find("B"),
+            Node::Condition {
+                condition: Expr::Binary {
+                    op: BinaryOp::Gt,
+                    ..
+                },
+                ..
+            }
+        ));
+        assert!(matches!(
+            find("C"),
+            Node::Process { statements, .. } if matches!(statements[0], Statement::Println { .. })
+        ));
+        assert_eq!(find("End"), &Node::End { label: None });
+    }
+
+    #[test]
+    fn test_parse_shaped_node_declaration_lines() {
+        let input = r#"flowchart TD
+    A@{ shape: rect, label: "x = 1; println x" }
+    B@{
+      shape: diam
+      label: "x == 1?"
+    }
+    Start --> A
+    A --> B
+    B -->|Yes| End
+    B -->|No| End
+"#;
+        let flowchart = parse(input).unwrap();
+        assert_eq!(flowchart.nodes.len(), 4);
+        assert_eq!(flowchart.edges.len(), 4);
+    }
+
+    #[test]
+    fn test_parse_shaped_node_defaults_to_rect() {
+        let input = r#"flowchart TD
+    Start --> A@{ label: "println 1", icon: "fa:gear" }
+    A --> End
+"#;
+        let flowchart = parse(input).unwrap();
+        assert!(
+            flowchart
+                .nodes
+                .iter()
+                .any(|n| matches!(n, Node::Process { id, .. } if id == "A"))
+        );
+    }
+
+    #[test]
+    fn test_parse_shaped_node_unsupported_shape() {
+        let input = r#"flowchart TD
+    Start --> A@{ shape: cyl, label: "x = 1" }
+    A --> End
+"#;
+        let err = parse(input).unwrap_err().to_string();
+        assert!(
+            err.contains("shape 'cyl' of node 'A' is not supported"),
+            "got: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_parse_shaped_node_terminal_shape_misuse() {
+        let input = r#"flowchart TD
+    Start --> A@{ shape: stadium, label: "x = 1" }
+    A --> End
+"#;
+        let err = parse(input).unwrap_err().to_string();
+        assert!(err.contains("Terminal node 'A' cannot have outgoing edges"));
+
+        let input = r#"flowchart TD
+    Start@{ shape: rect, label: "x = 1" } --> End
+"#;
+        let err = parse(input).unwrap_err().to_string();
+        assert!(err.contains("node 'Start' must use a terminal shape"));
+    }
+
+    #[test]
+    fn test_parse_shaped_node_missing_or_invalid_label() {
+        let input = r#"flowchart TD
+    Start --> A@{ shape: rect }
+    A --> End
+"#;
+        let err = parse(input).unwrap_err().to_string();
+        assert!(err.contains("node 'A' requires a label"));
+
+        let input = r#"flowchart TD
+    Start --> A@{ shape: diamond, label: "x > 0" }
+    A -->|Yes| End
+    A -->|No| End
+"#;
+        let err = parse(input).unwrap_err().to_string();
+        assert!(err.contains("invalid label of node 'A'"));
+    }
+
+    fn parse_process_statements(input: &str) -> Vec<Statement> {
+        let flowchart = parse(input).unwrap();
+        match flowchart
+            .nodes
+            .into_iter()
+            .find(|n| matches!(n, Node::Process { .. }))
+            .unwrap()
+        {
+            Node::Process { statements, .. } => statements,
+            _ => unreachable!(),
+        }
+    }
+
+    #[test]
+    fn test_parse_compound_assign() {
+        let input = r#"flowchart TD
+    Start --> A[x = 1; x -= 2; x %= 3]
+    A --> End
+"#;
+        let flowchart = parse(input).unwrap();
+        let statements = flowchart
+            .nodes
+            .iter()
+            .find_map(|n| match n {
+                Node::Process { statements, .. } => Some(statements),
+                _ => None,
+            })
+            .unwrap();
+        assert_eq!(
+            statements[1],
+            Statement::CompoundAssign {
+                variable: "x".to_string(),
+                op: BinaryOp::Sub,
+                value: Expr::IntLit { value: 2 },
+            }
+        );
+        assert!(matches!(
+            statements[2],
+            Statement::CompoundAssign {
+                op: BinaryOp::Mod,
+                ..
+            }
+        ));
+    }
+
+    #[test]
+    fn test_parse_parallel_assign() {
+        let input = r#"flowchart TD
+    Start --> A[a, b = b, a + b]
+    A --> End
+"#;
+        let flowchart = parse(input).unwrap();
+        let statements = flowchart
+            .nodes
+            .iter()
+            .find_map(|n| match n {
+                Node::Process { statements, .. } => Some(statements),
+                _ => None,
+            })
+            .unwrap();
+        match &statements[0] {
+            Statement::ParallelAssign { variables, values } => {
+                assert_eq!(variables, &["a", "b"]);
+                assert_eq!(values.len(), 2);
+            }
+            other => panic!("expected a parallel assignment, got {:?}", other),
+        }
+    }
+
+    #[test]
+    fn test_parse_parallel_assign_errors() {
+        let err = parse("flowchart TD\n    Start --> A[a, b = 1]\n    A --> End\n").unwrap_err();
+        assert!(
+            err.to_string()
+                .contains("expected 2 values for 2 variables, but got 1")
+        );
+        let err = parse("flowchart TD\n    Start --> A[a, a = 1, 2]\n    A --> End\n").unwrap_err();
+        assert!(
+            err.to_string()
+                .contains("variable 'a' is assigned more than once")
+        );
+    }
+
+    #[test]
+    fn test_parse_const_and_unset() {
+        let input = r#"flowchart TD
+    Start --> A[const RATE = 8; tmp = 1; unset tmp]
+    A --> End
+"#;
+        let flowchart = parse(input).unwrap();
+        let statements = flowchart
+            .nodes
+            .iter()
+            .find_map(|n| match n {
+                Node::Process { statements, .. } => Some(statements),
+                _ => None,
+            })
+            .unwrap();
+        assert_eq!(
+            statements[0],
+            Statement::Const {
+                variable: "RATE".to_string(),
+                value: Expr::IntLit { value: 8 },
+            }
+        );
+        assert_eq!(
+            statements[2],
+            Statement::Unset {
+                variable: "tmp".to_string(),
+            }
+        );
+    }
+
+    #[test]
+    fn test_parse_keyword_prefixed_identifiers() {
+        // `constant` and `unsettled` are ordinary variable names
+        let input = r#"flowchart TD
+    Start --> A[constant = 1; unsettled = constant]
+    A --> End
+"#;
+        assert!(parse(input).is_ok());
+    }
+
+    #[test]
+    fn test_parse_defined() {
+        let expected = Expr::Defined {
+            name: "x".to_string(),
+        };
+        assert_eq!(parse_assign_expr("exists(x)"), expected);
+        assert_eq!(parse_assign_expr("defined('x')"), expected);
+    }
+
+    #[test]
+    fn test_parse_defined_errors() {
+        for (call, message, code) in [
+            (
+                "exists('x')",
+                "function 'exists' expects a variable name argument",
+                ErrorCode::InvalidNameArgument,
+            ),
+            (
+                "exists(x, y)",
+                "function 'exists' expects 1 argument, but got 2",
+                ErrorCode::WrongArgumentCount,
+            ),
+            (
+                "defined(x)",
+                "function 'defined' expects a string literal argument",
+                ErrorCode::InvalidNameArgument,
+            ),
+        ] {
+            let input = format!(
+                "flowchart TD\n    Start --> A[b = {}]\n    A --> End\n",
+                call
+            );
+            let err = parse(&input).unwrap_err();
+            assert_eq!(err.code(), code, "{}", call);
+            let err = err.to_string();
+            assert!(err.contains(message), "{}: {}", call, err);
+        }
+    }
+
+    #[test]
+    fn test_parse_br_separated_statements() {
+        for sep in ["<br>", "<br/>", "<br />", "<BR>"] {
+            let input = format!(
+                "flowchart TD\n    Start --> A[x = 1{}println x]\n    A --> End\n",
+                sep
+            );
+            let statements = parse_process_statements(&input);
+            assert_eq!(statements.len(), 2, "separator {}", sep);
+            assert!(matches!(statements[1], Statement::Println { .. }));
+        }
+    }
+
+    #[test]
+    fn test_parse_less_than_is_not_br() {
+        let expr = parse_condition_expr("x < br");
+        assert!(matches!(
+            expr,
+            Expr::Binary {
+                op: BinaryOp::Lt,
+                ..
+            }
+        ));
+    }
+
+    #[test]
+    fn test_parse_multiline_quoted_label() {
+        let input = "flowchart TD\n    Start --> A[\"x = 1\n    y = 2<br>\n    println x + y\n\"]\n    A --> End\n";
+        let statements = parse_process_statements(input);
+        assert_eq!(statements.len(), 3);
+        assert!(matches!(statements[2], Statement::Println { .. }));
+    }
+
+    #[test]
+    fn test_parse_br_inside_string_is_literal() {
+        let input = "flowchart TD\n    Start --> A[\"println 'a<br>b'\"]\n    A --> End\n";
+        let statements = parse_process_statements(input);
+        assert_eq!(statements.len(), 1);
+        match &statements[0] {
+            Statement::Println {
+                expr: Expr::StrLit { value: s },
+            } => assert_eq!(s, "a<br>b"),
+            other => panic!("Expected Println of a string, got {:?}", other),
+        }
+    }
+
+    #[test]
+    fn test_parse_entity_codes_in_quoted_labels() {
+        let input = r##"flowchart TD
+    Start(["#quot;Begin#quot;"]) --> A["println '#quot;#35;1#quot;'"]
+    A --> B{"1 #lt; 2?"}
+    B -->|Yes| End
+    B -->|No| End
+"##;
+        let flowchart = parse(input).unwrap();
+        let find = |id: &str| flowchart.nodes.iter().find(|n| n.id() == id).unwrap();
+
+        assert_eq!(
+            find("Start"),
+            &Node::Start {
+                label: Some("\"Begin\"".to_string())
+            }
+        );
+        match find("A") {
+            Node::Process { statements, .. } => match &statements[0] {
+                Statement::Println {
+                    expr: Expr::StrLit { value: s },
+                } => assert_eq!(s, "\"#1\""),
+                other => panic!("Expected Println of a string, got {:?}", other),
+            },
+            _ => unreachable!(),
+        }
+        assert!(matches!(
+            find("B"),
+            Node::Condition {
+                condition: Expr::Binary {
+                    op: BinaryOp::Lt,
+                    ..
+                },
+                ..
+            }
+        ));
+    }
+
+    #[test]
+    fn test_parse_quoted_label_syntax_error_names_node() {
+        let input = "flowchart TD\n    Start --> A[\"x = \"]\n    A --> End\n";
+        let err = parse(input).unwrap_err().to_string();
+        assert!(err.contains("invalid label of node 'A'"), "got: {}", err);
+    }
 }
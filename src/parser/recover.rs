@@ -0,0 +1,366 @@
+//! Parsing that recovers from syntax errors.
+//!
+//! [`parse`](super::parse) stops at the first syntax error. For reporting
+//! every error at once, and for tools that need to make sense of a file that
+//! is still being edited, [`parse_partial`] parses the header and each line
+//! on its own instead. When a line cannot be parsed, its error is recorded
+//! and parsing resumes at the next line.
+//!
+//! A line may span several source lines (a double-quoted label, or a node
+//! with `@{ ... }` metadata). If such a line is broken, parsing resumes in
+//! the middle of it, which can report further errors for the same mistake.
+
+use std::path::Path;
+
+use pest::Parser;
+use pest::Position;
+use pest::error::{Error as PestError, ErrorVariant, InputLocation};
+use rustc_hash::FxHashMap;
+
+use crate::ast::{Direction, Edge, Flowchart, Node};
+
+use super::error::SyntaxError;
+use super::table::load_tables;
+use super::{LineDecl, MermaidParser, Rule, add_line_decl, parse_header, parse_line_decl};
+
+/// The result of [`parse_partial`].
+#[derive(Debug)]
+pub struct PartialParse {
+    /// The nodes and edges of every line that could be parsed. The
+    /// flowchart is not validated, and may be incomplete.
+    pub flowchart: Flowchart,
+
+    /// The syntax errors, in the order they appear in the source.
+    pub errors: Vec<SyntaxError>,
+}
+
+/// Parses as much of a Mermaid flowchart as possible, collecting every
+/// syntax error instead of stopping at the first.
+///
+/// Decision tables stored in CSV files are read relative to the current
+/// directory; use [`parse_partial_in_dir`] to resolve them against the
+/// program's directory instead.
+///
+/// # Examples
+///
+/// ```
+/// use merx::parser::parse_partial;
+///
+/// let partial = parse_partial("flowchart TD\n    Start --> A[x = ]\n    A --> B{x >}\n    Start --> End\n");
+/// assert_eq!(partial.errors.len(), 2);
+/// assert_eq!(partial.flowchart.edges.len(), 1);
+/// ```
+pub fn parse_partial(input: &str) -> PartialParse {
+    parse_partial_in_dir(input, Path::new(""))
+}
+
+/// Parses as much of a Mermaid flowchart as possible like [`parse_partial`],
+/// reading CSV decision tables relative to `dir`.
+pub fn parse_partial_in_dir(input: &str, dir: &Path) -> PartialParse {
+    let header = parse_header_at(input);
+    let mut segments = Vec::new();
+    let mut pos = header.end;
+    while let Some(segment) = parse_segment(input, pos) {
+        pos = segment.end;
+        segments.push(segment);
+    }
+    assemble(input, dir, &header, &segments)
+}
+
+/// An error in the header or a line, kept in a form that does not depend on
+/// where the line is, so that it stays correct when earlier lines change.
+#[derive(Debug, Clone)]
+pub(super) enum LineError {
+    /// A grammar error, `offset` bytes after the start of the line.
+    Grammar {
+        variant: ErrorVariant<Rule>,
+        offset: usize,
+    },
+    /// An error building the line's nodes or edge.
+    Decl(SyntaxError),
+}
+
+impl LineError {
+    /// Converts the error of the line starting at `start` into a
+    /// [`SyntaxError`] that points into `input`.
+    pub(super) fn to_syntax_error(&self, input: &str, start: usize) -> SyntaxError {
+        match self {
+            LineError::Grammar { variant, offset } => {
+                let pos = Position::new(input, start + offset)
+                    .unwrap_or_else(|| Position::from_start(input));
+                SyntaxError::from(PestError::new_from_pos(variant.clone(), pos))
+            }
+            LineError::Decl(e) => SyntaxError::Line {
+                line: line_number(input, start),
+                error: Box::new(e.clone()),
+            },
+        }
+    }
+}
+
+/// The `flowchart` header, parsed on its own.
+#[derive(Debug, Clone)]
+pub(super) struct Header {
+    pub(super) direction: Direction,
+    /// Where the search for the first line starts.
+    pub(super) end: usize,
+    /// The end of the text the result depends on.
+    pub(super) reach: usize,
+    pub(super) error: Option<LineError>,
+}
+
+/// Parses the header at the start of `input`. If it is invalid, the lines
+/// are searched for from the line after the error.
+pub(super) fn parse_header_at(input: &str) -> Header {
+    match MermaidParser::parse(Rule::header, input) {
+        Ok(mut pairs) => {
+            let end = pairs.peek().map_or(0, |pair| pair.as_span().end());
+            Header {
+                direction: pairs.next().map_or(Direction::Td, parse_header),
+                end,
+                reach: end,
+                error: None,
+            }
+        }
+        Err(e) => {
+            let at = error_pos(&e);
+            let end = next_line(input, at);
+            Header {
+                direction: Direction::Td,
+                end,
+                reach: end.max(at),
+                error: Some(LineError::Grammar {
+                    variant: e.variant,
+                    offset: at,
+                }),
+            }
+        }
+    }
+}
+
+/// A line of the flowchart, parsed on its own.
+#[derive(Debug, Clone)]
+pub(super) struct Segment {
+    /// Where the line starts, after any blank lines and comments.
+    pub(super) start: usize,
+    /// Where the search for the next line starts.
+    pub(super) end: usize,
+    /// The end of the text the result depends on. For an invalid line, the
+    /// error can be past `end`.
+    pub(super) reach: usize,
+    pub(super) decl: Result<LineDecl, LineError>,
+}
+
+impl Segment {
+    /// Moves the segment after an edit before it removed `removed` bytes and
+    /// inserted `added`.
+    pub(super) fn shift(&mut self, removed: usize, added: usize) {
+        for pos in [&mut self.start, &mut self.end, &mut self.reach] {
+            *pos = *pos - removed + added;
+        }
+    }
+}
+
+/// Parses the next line at or after `pos`, or returns `None` if only blank
+/// lines and comments are left.
+///
+/// An invalid line ends at the next line break.
+pub(super) fn parse_segment(input: &str, pos: usize) -> Option<Segment> {
+    let start = skip_blank(input, pos);
+    if start == input.len() {
+        return None;
+    }
+    Some(
+        match MermaidParser::parse(Rule::recovery_line, &input[start..]) {
+            Ok(mut pairs) => {
+                let end = start + pairs.peek().map_or(0, |pair| pair.as_span().end());
+                let decl = match pairs.next() {
+                    Some(pair) => parse_line_decl(pair).map_err(LineError::Decl),
+                    None => Err(LineError::Decl(SyntaxError::internal(
+                        "expected recovery_line",
+                    ))),
+                };
+                Segment {
+                    start,
+                    end,
+                    reach: end,
+                    decl,
+                }
+            }
+            Err(e) => {
+                let offset = error_pos(&e);
+                let end = next_line(input, start);
+                Segment {
+                    start,
+                    end,
+                    reach: end.max(start + offset),
+                    decl: Err(LineError::Grammar {
+                        variant: e.variant,
+                        offset,
+                    }),
+                }
+            }
+        },
+    )
+}
+
+/// Builds the unvalidated flowchart and the list of syntax errors from a
+/// parsed header and lines.
+pub(super) fn assemble(
+    input: &str,
+    dir: &Path,
+    header: &Header,
+    segments: &[Segment],
+) -> PartialParse {
+    let mut nodes: FxHashMap<String, Node> = FxHashMap::default();
+    let mut edges: Vec<Edge> = Vec::new();
+    let mut errors = Vec::new();
+
+    if let Some(e) = &header.error {
+        errors.push(e.to_syntax_error(input, 0));
+    }
+    for segment in segments {
+        match &segment.decl {
+            // Conflicting node definitions are left to validation, which
+            // runs once there are no syntax errors
+            Ok(decl) => {
+                let _ = add_line_decl(decl.clone(), &mut nodes, &mut edges);
+            }
+            Err(e) => errors.push(e.to_syntax_error(input, segment.start)),
+        }
+    }
+    if let Err(e) = load_tables(&mut nodes, input, dir) {
+        errors.push(e);
+    }
+
+    PartialParse {
+        flowchart: Flowchart {
+            direction: header.direction,
+            nodes: nodes.into_values().collect(),
+            edges,
+        },
+        errors,
+    }
+}
+
+/// Returns the position of the first character after `pos` that is not
+/// whitespace, a line break or part of a comment.
+fn skip_blank(input: &str, mut pos: usize) -> usize {
+    loop {
+        let rest = &input[pos..];
+        let trimmed = rest.trim_start_matches([' ', '\t', '\r', '\n']);
+        pos += rest.len() - trimmed.len();
+        if !trimmed.starts_with("%%") {
+            return pos;
+        }
+        pos += trimmed.find('\n').unwrap_or(trimmed.len());
+    }
+}
+
+/// Returns the position just after the line break that ends the line
+/// containing `pos`.
+fn next_line(input: &str, pos: usize) -> usize {
+    input[pos..].find('\n').map_or(input.len(), |i| pos + i + 1)
+}
+
+/// Returns the 1-based line number of `pos`.
+fn line_number(input: &str, pos: usize) -> usize {
+    input[..pos].matches('\n').count() + 1
+}
+
+/// Returns the position a pest error points at.
+fn error_pos(err: &PestError<Rule>) -> usize {
+    match err.location {
+        InputLocation::Pos(pos) | InputLocation::Span((pos, _)) => pos,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::codes::ErrorCode;
+
+    #[test]
+    fn test_valid_input_has_no_errors() {
+        let partial = parse_partial("flowchart LR\n    Start --> A[x = 1]\n    A --> End\n");
+        assert!(partial.errors.is_empty());
+        assert_eq!(partial.flowchart.direction, Direction::Lr);
+        assert_eq!(partial.flowchart.nodes.len(), 3);
+        assert_eq!(partial.flowchart.edges.len(), 2);
+    }
+
+    #[test]
+    fn test_errors_are_reported_with_their_lines() {
+        let partial = parse_partial(
+            "flowchart TD\n    Start --> A[x = ]\n    %% comment\n\n    A --> B[x = 1]\n    B --> C{x >}\n    C --> End\n",
+        );
+        let lines: Vec<String> = partial.errors.iter().map(|e| e.to_string()).collect();
+        assert_eq!(lines.len(), 2, "{:?}", lines);
+        assert!(lines[0].contains("2:"), "{}", lines[0]);
+        assert!(lines[1].contains("6:"), "{}", lines[1]);
+
+        let edges: Vec<(&str, &str)> = partial
+            .flowchart
+            .edges
+            .iter()
+            .map(|e| (e.from.as_str(), e.to.as_str()))
+            .collect();
+        assert_eq!(edges, vec![("A", "B"), ("C", "End")]);
+    }
+
+    #[test]
+    fn test_bad_header_is_skipped() {
+        let partial = parse_partial("flowchart XY\n    Start --> End\n");
+        assert_eq!(partial.errors.len(), 1);
+        assert!(partial.errors[0].to_string().contains("1:11"));
+        assert_eq!(partial.flowchart.edges.len(), 1);
+    }
+
+    #[test]
+    fn test_unclosed_table_label_ends_at_the_line() {
+        let partial = parse_partial(
+            "flowchart TD\n    Start --> P[[table pricing\n    P --> Q[[table other]]\n    Q --> A[x = ]\n    A --> End\n",
+        );
+        let lines: Vec<String> = partial.errors.iter().map(|e| e.to_string()).collect();
+        assert!(lines[0].contains("2:"), "{:?}", lines);
+        assert!(lines.iter().any(|e| e.contains("4:")), "{:?}", lines);
+
+        // The next line is parsed on its own
+        assert!(
+            partial
+                .flowchart
+                .edges
+                .iter()
+                .any(|e| (e.from.as_str(), e.to.as_str()) == ("P", "Q"))
+        );
+    }
+
+    #[test]
+    fn test_unclosed_loop_label_ends_at_the_line() {
+        let partial = parse_partial(
+            "flowchart TD\n    Start --> L{{for i in 1..n\n    L -->|body| M{{for j in 1..3}}\n    M -->|done| End\n",
+        );
+        assert_eq!(partial.errors.len(), 1, "{:?}", partial.errors);
+        assert!(partial.errors[0].to_string().contains("2:"));
+
+        // The next line is parsed on its own
+        assert!(
+            partial
+                .flowchart
+                .edges
+                .iter()
+                .any(|e| (e.from.as_str(), e.to.as_str()) == ("L", "M"))
+        );
+    }
+
+    #[test]
+    fn test_label_errors_name_the_line() {
+        let partial = parse_partial("flowchart TD\n    Start --> A[x = nope(1)]\n    A --> End\n");
+        assert_eq!(partial.errors.len(), 1);
+        assert_eq!(
+            partial.errors[0].to_string(),
+            "line 2: unknown function 'nope'"
+        );
+        assert_eq!(partial.errors[0].code(), ErrorCode::UnknownFunction);
+    }
+}
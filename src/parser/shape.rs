@@ -0,0 +1,145 @@
+//! Mermaid v11 node metadata (`id@{ shape: ..., label: ... }`).
+//!
+//! Newer Mermaid versions declare a node's shape by name instead of with
+//! bracket syntax. This module maps those shape names onto merx node types
+//! and parses the label according to the resulting type.
+//!
+//! | Shapes | Node type |
+//! |--------|-----------|
+//! | `rect`, `rounded` (and aliases) | Process |
+//! | `diamond` (and aliases) | Condition |
+//! | `stadium`, `terminal` (and aliases) | Start / End / Entry / Terminal |
+//! | `subroutine` (and aliases) | Decision table |
+//! | `hex` (and aliases) | Loop |
+
+use pest::iterators::Pair;
+
+use crate::ast::Node;
+
+use super::entity::decode_entities;
+use super::error::SyntaxError;
+use super::loops::parse_loop_node;
+use super::stadium::parse_stadium_node;
+use super::table::parse_table_node;
+use super::{Rule, parse_label_condition, parse_label_statements};
+
+/// The merx node type a Mermaid shape maps to.
+#[derive(Debug, Clone, Copy, PartialEq)]
+enum ShapeKind {
+    Process,
+    Condition,
+    Terminal,
+    Table,
+    Loop,
+}
+
+/// The shape used when a node's metadata has no `shape` property.
+const DEFAULT_SHAPE: &str = "rect";
+
+/// Looks up the node type for a Mermaid shape name or alias.
+///
+/// Returns `None` for shapes that have no merx equivalent (for example
+/// `cyl`, `doc`, or `fork`).
+fn shape_kind(shape: &str) -> Option<ShapeKind> {
+    match shape {
+        "rect" | "rectangle" | "proc" | "process" | "rounded" | "event" => Some(ShapeKind::Process),
+        "diamond" | "diam" | "decision" | "question" => Some(ShapeKind::Condition),
+        "stadium" | "terminal" | "pill" => Some(ShapeKind::Terminal),
+        "subroutine" | "subproc" | "fr-rect" | "framed-rectangle" => Some(ShapeKind::Table),
+        "hex" | "hexagon" | "prepare" => Some(ShapeKind::Loop),
+        _ => None,
+    }
+}
+
+/// Parses a node declared with `id@{ ... }` metadata.
+///
+/// Properties other than `shape` and `label` (such as `icon` or `pos`)
+/// only affect rendering and are ignored.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if:
+/// - The shape has no merx equivalent
+/// - `Start`/`End` is given a non-terminal shape
+/// - A process, condition, decision table, or loop node has no label
+/// - The label is not valid for the node type
+pub(super) fn parse_shaped_node(pair: Pair<Rule>) -> Result<Node, SyntaxError> {
+    let mut parts = pair.into_inner();
+    let id = parts
+        .next()
+        .ok_or_else(|| SyntaxError::internal("expected id in shaped_node"))?
+        .as_str()
+        .to_string();
+
+    let mut shape: Option<String> = None;
+    let mut label: Option<String> = None;
+    for prop in parts {
+        let mut kv = prop.into_inner();
+        let key = kv
+            .next()
+            .ok_or_else(|| SyntaxError::internal("expected key in shape_prop"))?
+            .as_str();
+        let value = kv
+            .next()
+            .and_then(|v| v.into_inner().next())
+            .ok_or_else(|| SyntaxError::internal("expected value in shape_prop"))?;
+        let value = match value.as_rule() {
+            Rule::shape_quoted_text => value.as_str(),
+            _ => value.as_str().trim(),
+        };
+        match key {
+            "shape" => shape = Some(value.to_string()),
+            "label" => label = Some(value.to_string()),
+            _ => {}
+        }
+    }
+
+    let shape = shape.as_deref().unwrap_or(DEFAULT_SHAPE);
+    let kind = shape_kind(shape).ok_or_else(|| SyntaxError::UnsupportedShape {
+        node_id: id.clone(),
+        shape: shape.to_string(),
+    })?;
+
+    let is_keyword_id = id == "Start" || id == "End";
+    if kind != ShapeKind::Terminal && is_keyword_id {
+        return Err(SyntaxError::NonTerminalShape {
+            node_id: id,
+            shape: shape.to_string(),
+        });
+    }
+
+    match kind {
+        ShapeKind::Terminal => {
+            let label = label.map(|l| decode_entities(&l).into_owned());
+            match id.as_str() {
+                "Start" => Ok(Node::Start { label }),
+                "End" => Ok(Node::End { label }),
+                _ => parse_stadium_node(id, label),
+            }
+        }
+        ShapeKind::Process => {
+            let text = require_label(&id, label)?;
+            let statements = parse_label_statements(&id, &text)?;
+            Ok(Node::Process { id, statements })
+        }
+        ShapeKind::Condition => {
+            let text = require_label(&id, label)?;
+            let condition = parse_label_condition(&id, &text)?;
+            Ok(Node::Condition { id, condition })
+        }
+        ShapeKind::Table => {
+            let text = require_label(&id, label)?;
+            parse_table_node(id, &decode_entities(&text))
+        }
+        ShapeKind::Loop => {
+            let text = require_label(&id, label)?;
+            parse_loop_node(id, &decode_entities(&text))
+        }
+    }
+}
+
+fn require_label(id: &str, label: Option<String>) -> Result<String, SyntaxError> {
+    label.ok_or_else(|| SyntaxError::MissingLabel {
+        node_id: id.to_string(),
+    })
+}
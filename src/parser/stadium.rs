@@ -0,0 +1,187 @@
+//! Named stadium nodes: entries and terminals.
+//!
+//! A stadium-shaped node other than `Start` and `End` is one of:
+//!
+//! - An entry, if its label is `Start <name>` (e.g. `Start_refund([Start refund])`).
+//!   Execution can begin there instead of at `Start`.
+//! - Otherwise a terminal, which ends execution. If its label has the form
+//!   `End: item, ...`, each item either declares the default exit code
+//!   (`exit N`) or names the terminal's result. Any other label is for
+//!   display only.
+//!
+//! Like the keywords of edge labels, `Start`, `End` and `exit` are
+//! case-insensitive.
+
+use crate::ast::Node;
+
+use super::error::SyntaxError;
+use super::{parse_exit_code_text, strip_keyword};
+
+/// Builds a [`Node::Entry`] or [`Node::Terminal`] from a stadium node's id
+/// and (already decoded) label.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if:
+/// - A `Start` label has no name, or a name that is not an identifier
+/// - An `End:` label has an empty item, an invalid exit code, or more than
+///   one exit code or result
+pub(super) fn parse_stadium_node(id: String, label: Option<String>) -> Result<Node, SyntaxError> {
+    if let Some(name) = label.as_deref().and_then(entry_name) {
+        if !is_entry_name(name) {
+            return Err(SyntaxError::InvalidEntryName { node_id: id });
+        }
+        return Ok(Node::Entry {
+            name: name.to_string(),
+            id,
+            label,
+        });
+    }
+
+    let mut exit_code = None;
+    let mut result = None;
+
+    if let Some(items) = label.as_deref().and_then(terminal_items) {
+        for item in items {
+            if item.is_empty() {
+                return Err(SyntaxError::EmptyTerminalItem { node_id: id });
+            }
+            if let Some(code) = parse_exit_code_text(item)? {
+                if exit_code.replace(code).is_some() {
+                    return Err(SyntaxError::MultipleTerminalExitCodes { node_id: id });
+                }
+            } else if result.replace(item.to_string()).is_some() {
+                return Err(SyntaxError::MultipleTerminalResults { node_id: id });
+            }
+        }
+    }
+
+    Ok(Node::Terminal {
+        id,
+        label,
+        exit_code,
+        result,
+    })
+}
+
+/// Returns the name of a `Start <name>` label (possibly empty), or `None` if
+/// the label does not start with the `Start` keyword.
+fn entry_name(label: &str) -> Option<&str> {
+    strip_keyword(label.trim(), "Start")
+}
+
+fn is_entry_name(name: &str) -> bool {
+    !name.is_empty() && name.chars().all(|c| c.is_ascii_alphanumeric() || c == '_')
+}
+
+/// Returns the items of an `End` or `End: ...` label, or `None` if the
+/// label is plain display text.
+fn terminal_items(label: &str) -> Option<Vec<&str>> {
+    let label = label.trim();
+    let head = label.get(.."End".len())?;
+    if !head.eq_ignore_ascii_case("End") {
+        return None;
+    }
+    let rest = label["End".len()..].trim_start();
+    if rest.is_empty() {
+        return Some(Vec::new());
+    }
+    Some(rest.strip_prefix(':')?.split(',').map(str::trim).collect())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn terminal(label: &str) -> Result<Node, SyntaxError> {
+        parse_stadium_node("T".to_string(), Some(label.to_string()))
+    }
+
+    #[test]
+    fn test_terminal_spec() {
+        assert_eq!(
+            terminal("End: rejected, exit 2").unwrap(),
+            Node::Terminal {
+                id: "T".to_string(),
+                label: Some("End: rejected, exit 2".to_string()),
+                exit_code: Some(2),
+                result: Some("rejected".to_string()),
+            }
+        );
+        assert!(matches!(
+            terminal("End").unwrap(),
+            Node::Terminal {
+                exit_code: None,
+                result: None,
+                ..
+            }
+        ));
+        assert!(matches!(
+            terminal("Ending soon").unwrap(),
+            Node::Terminal {
+                exit_code: None,
+                result: None,
+                ..
+            }
+        ));
+    }
+
+    #[test]
+    fn test_terminal_spec_is_case_insensitive() {
+        assert_eq!(
+            terminal("end: rejected, Exit 2").unwrap(),
+            Node::Terminal {
+                id: "T".to_string(),
+                label: Some("end: rejected, Exit 2".to_string()),
+                exit_code: Some(2),
+                result: Some("rejected".to_string()),
+            }
+        );
+        assert!(matches!(
+            terminal("END: EXIT 3").unwrap(),
+            Node::Terminal {
+                exit_code: Some(3),
+                result: None,
+                ..
+            }
+        ));
+        assert!(matches!(
+            terminal("End: Exiting").unwrap(),
+            Node::Terminal {
+                exit_code: None,
+                result: Some(_),
+                ..
+            }
+        ));
+    }
+
+    #[test]
+    fn test_terminal_spec_errors() {
+        assert!(terminal("End:").is_err());
+        assert!(terminal("End: a, b").is_err());
+        assert!(terminal("End: exit 1, exit 2").is_err());
+        assert!(terminal("End: exit 256").is_err());
+    }
+
+    #[test]
+    fn test_entry_label() {
+        assert_eq!(
+            terminal("Start refund").unwrap(),
+            Node::Entry {
+                id: "T".to_string(),
+                name: "refund".to_string(),
+                label: Some("Start refund".to_string()),
+            }
+        );
+        assert!(matches!(
+            terminal("Starting").unwrap(),
+            Node::Terminal { .. }
+        ));
+        assert!(matches!(
+            terminal("start refund").unwrap(),
+            Node::Entry { ref name, .. } if name == "refund"
+        ));
+        assert!(terminal("Start").is_err());
+        assert!(terminal("Start two words").is_err());
+    }
+}
@@ -0,0 +1,409 @@
+//! Decision table nodes (`P[[table pricing first]]`).
+//!
+//! A table node's label names its rows: either an inline table declared in
+//! a block of `%%` comments, or a CSV file. The node is created with only
+//! its source and hit policy; [`load_tables`] fills in the columns and rules
+//! once the whole program has been parsed.
+//!
+//! ```text
+//! flowchart TD
+//!     Start --> P[[table pricing first]]
+//!     P -->|bulk| A[println price]
+//!     P -->|retail| B[println price]
+//!     A --> End
+//!     B --> End
+//!
+//! %% table pricing
+//! %% qty,    member, price =, ->
+//! %% >= 100, true,   80,      bulk
+//! %% -,      -,      100,     retail
+//! ```
+//!
+//! An inline table runs until the first line that is not a comment, or an
+//! empty comment line. Cells follow CSV rules, so a cell containing a comma
+//! can be enclosed in double quotes.
+
+use std::fs;
+use std::path::Path;
+
+use rustc_hash::FxHashMap;
+
+use super::error::{ExprRole, SyntaxError, TableError};
+use super::parse_label_expression;
+use crate::ast::{
+    BinaryOp, CellTest, DecisionTable, HitPolicy, Node, TableInput, TableRule, TableSource,
+};
+
+/// Comparison operators that may start an input cell, longest first.
+const CELL_OPERATORS: &[(&str, BinaryOp)] = &[
+    ("==", BinaryOp::Eq),
+    ("!=", BinaryOp::Ne),
+    ("<=", BinaryOp::Le),
+    (">=", BinaryOp::Ge),
+    ("<", BinaryOp::Lt),
+    (">", BinaryOp::Gt),
+];
+
+/// Builds a [`Node::DecisionTable`] from a table node's id and label.
+///
+/// The label has the form `table <name> [first|unique]` for an inline table
+/// or `table '<file.csv>' [first|unique]` for a CSV file. The table's
+/// columns and rules are left empty until [`load_tables`] runs.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if the label does not have that form.
+pub(super) fn parse_table_node(id: String, label: &str) -> Result<Node, SyntaxError> {
+    let invalid = || SyntaxError::InvalidTableLabel {
+        node_id: id.clone(),
+    };
+
+    let rest = label
+        .trim()
+        .strip_prefix("table")
+        .filter(|rest| rest.starts_with(char::is_whitespace))
+        .ok_or_else(invalid)?
+        .trim_start();
+
+    let (source, rest) = if let Some(quoted) = rest.strip_prefix('\'') {
+        let end = quoted.find('\'').ok_or_else(invalid)?;
+        (
+            TableSource::File(quoted[..end].to_string()),
+            &quoted[end + 1..],
+        )
+    } else {
+        let end = rest.find(char::is_whitespace).unwrap_or(rest.len());
+        let name = &rest[..end];
+        if !is_table_name(name) {
+            return Err(invalid());
+        }
+        (TableSource::Inline(name.to_string()), &rest[end..])
+    };
+
+    let hit_policy = match rest.trim() {
+        "" | "unique" => HitPolicy::Unique,
+        "first" => HitPolicy::First,
+        _ => return Err(invalid()),
+    };
+
+    Ok(Node::DecisionTable {
+        id,
+        table: DecisionTable {
+            source,
+            hit_policy,
+            inputs: Vec::new(),
+            outputs: Vec::new(),
+            has_target: false,
+            rules: Vec::new(),
+        },
+    })
+}
+
+/// Loads the columns and rules of every decision table node.
+///
+/// Inline tables are taken from `source`; CSV files are read relative to
+/// `dir`.
+///
+/// # Errors
+///
+/// Returns [`SyntaxError`] if an inline table is declared twice or is not
+/// declared at all, a file cannot be read, or a table is malformed.
+pub(super) fn load_tables(
+    nodes: &mut FxHashMap<String, Node>,
+    source: &str,
+    dir: &Path,
+) -> Result<(), SyntaxError> {
+    let inline = inline_tables(source)?;
+    for node in nodes.values_mut() {
+        let Node::DecisionTable { id, table } = node else {
+            continue;
+        };
+        let text = match &table.source {
+            TableSource::Inline(name) => {
+                inline
+                    .get(name.as_str())
+                    .cloned()
+                    .ok_or_else(|| SyntaxError::UndeclaredTable {
+                        node_id: id.clone(),
+                        name: name.clone(),
+                    })?
+            }
+            TableSource::File(path) => {
+                fs::read_to_string(dir.join(path)).map_err(|e| SyntaxError::UnreadableTable {
+                    node_id: id.clone(),
+                    path: path.clone(),
+                    message: e.to_string(),
+                })?
+            }
+        };
+        fill_table(table, &text).map_err(|error| SyntaxError::InvalidTable {
+            node_id: id.clone(),
+            error,
+        })?;
+    }
+    Ok(())
+}
+
+/// Collects the inline tables declared in `%% table <name>` comment blocks,
+/// keyed by name.
+fn inline_tables(source: &str) -> Result<FxHashMap<&str, String>, SyntaxError> {
+    let mut tables = FxHashMap::default();
+    let mut lines = source.lines().map(str::trim);
+    while let Some(line) = lines.next() {
+        let Some(name) = comment_text(line)
+            .and_then(|text| text.strip_prefix("table "))
+            .map(str::trim)
+            .filter(|name| is_table_name(name))
+        else {
+            continue;
+        };
+
+        let mut text = String::new();
+        for row in lines.by_ref().map_while(comment_text) {
+            if row.is_empty() {
+                break;
+            }
+            text.push_str(row);
+            text.push('\n');
+        }
+        if tables.insert(name, text).is_some() {
+            return Err(SyntaxError::DuplicateTable {
+                name: name.to_string(),
+            });
+        }
+    }
+    Ok(tables)
+}
+
+/// Returns the trimmed text of a `%%` comment line, or `None` for any other
+/// line.
+fn comment_text(line: &str) -> Option<&str> {
+    line.strip_prefix("%%").map(str::trim)
+}
+
+fn is_table_name(name: &str) -> bool {
+    !name.is_empty()
+        && !name.starts_with(|c: char| c.is_ascii_digit())
+        && name.chars().all(|c| c.is_ascii_alphanumeric() || c == '_')
+}
+
+/// Parses CSV `text` into the table's columns and rules.
+fn fill_table(table: &mut DecisionTable, text: &str) -> Result<(), TableError> {
+    let mut rows = csv_rows(text)?.into_iter().enumerate();
+    let (_, header) = rows.next().ok_or(TableError::Empty)?;
+
+    // The kind of each column, in order
+    enum Column {
+        Input,
+        Output,
+        Target,
+    }
+    let mut columns = Vec::with_capacity(header.len());
+    for cell in &header {
+        if cell == "->" {
+            if table.has_target {
+                return Err(TableError::MultipleTargetColumns);
+            }
+            table.has_target = true;
+            columns.push(Column::Target);
+        } else if let Some(name) = output_name(cell) {
+            table.outputs.push(name.to_string());
+            columns.push(Column::Output);
+        } else {
+            let expr = parse_label_expression(ExprRole::InputColumn, cell)
+                .map_err(|e| TableError::InvalidColumn(Box::new(e)))?;
+            table.inputs.push(TableInput {
+                header: cell.clone(),
+                expr,
+            });
+            columns.push(Column::Input);
+        }
+    }
+
+    // Rules are numbered from 1, after the header
+    for (number, row) in rows {
+        if row.len() != columns.len() {
+            return Err(TableError::RuleLength {
+                rule: number,
+                cells: row.len(),
+                columns: columns.len(),
+            });
+        }
+
+        let mut rule = TableRule {
+            tests: Vec::with_capacity(table.inputs.len()),
+            values: Vec::with_capacity(table.outputs.len()),
+            target: None,
+        };
+        for (column, cell) in columns.iter().zip(&row) {
+            let context = |e| TableError::InvalidCell {
+                rule: number,
+                error: Box::new(e),
+            };
+            match column {
+                Column::Input => rule.tests.push(parse_cell_test(cell).map_err(context)?),
+                Column::Output => rule.values.push(if is_any(cell) {
+                    None
+                } else {
+                    Some(parse_label_expression(ExprRole::Output, cell).map_err(context)?)
+                }),
+                Column::Target => {
+                    if is_any(cell) {
+                        return Err(TableError::MissingTarget { rule: number });
+                    }
+                    rule.target = Some(cell.clone());
+                }
+            }
+        }
+        table.rules.push(rule);
+    }
+
+    if table.rules.is_empty() {
+        return Err(TableError::NoRules);
+    }
+    Ok(())
+}
+
+/// Returns the variable name of an output column header (`name =`).
+fn output_name(header: &str) -> Option<&str> {
+    let name = header.strip_suffix('=')?.trim_end();
+    is_table_name(name).then_some(name)
+}
+
+/// Returns `true` for a cell that matches, or assigns, nothing in particular
+/// (empty or `-`).
+fn is_any(cell: &str) -> bool {
+    cell.is_empty() || cell == "-"
+}
+
+/// Parses an input cell: `-` (any value), `op expr`, or `expr` (equality).
+fn parse_cell_test(cell: &str) -> Result<Option<CellTest>, SyntaxError> {
+    if is_any(cell) {
+        return Ok(None);
+    }
+    let (op, value) = CELL_OPERATORS
+        .iter()
+        .find_map(|(prefix, op)| cell.strip_prefix(prefix).map(|rest| (*op, rest.trim())))
+        .unwrap_or((BinaryOp::Eq, cell));
+    let value = parse_label_expression(ExprRole::Test, value)?;
+    Ok(Some(CellTest { op, value }))
+}
+
+/// Splits CSV text into rows of trimmed cells, skipping blank lines.
+///
+/// Cells may be enclosed in double quotes to contain commas; a doubled quote
+/// inside a quoted cell stands for one quote.
+fn csv_rows(text: &str) -> Result<Vec<Vec<String>>, TableError> {
+    let mut rows = Vec::new();
+    for (index, line) in text.lines().enumerate() {
+        if line.trim().is_empty() {
+            continue;
+        }
+        let mut cells = Vec::new();
+        let mut chars = line.chars().peekable();
+        loop {
+            while chars.next_if(|c| c.is_whitespace()).is_some() {}
+            let mut cell = String::new();
+            if chars.next_if_eq(&'"').is_some() {
+                loop {
+                    match chars.next() {
+                        Some('"') if chars.next_if_eq(&'"').is_some() => cell.push('"'),
+                        Some('"') => break,
+                        Some(c) => cell.push(c),
+                        None => return Err(TableError::UnclosedQuote { line: index + 1 }),
+                    }
+                }
+                while chars.next_if(|c| c.is_whitespace()).is_some() {}
+                if chars.peek().is_some_and(|&c| c != ',') {
+                    return Err(TableError::TextAfterQuote { line: index + 1 });
+                }
+            } else {
+                while let Some(c) = chars.next_if(|&c| c != ',') {
+                    cell.push(c);
+                }
+                cell.truncate(cell.trim_end().len());
+            }
+            cells.push(cell);
+            if chars.next().is_none() {
+                break;
+            }
+        }
+        rows.push(cells);
+    }
+    Ok(rows)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ast::Expr;
+
+    #[test]
+    fn test_csv_rows() {
+        let rows = csv_rows("a, b ,c\n\n\"x, y\", \"say \"\"hi\"\"\",\n").unwrap();
+        assert_eq!(
+            rows,
+            vec![vec!["a", "b", "c"], vec!["x, y", "say \"hi\"", ""],]
+        );
+        assert!(csv_rows("\"open").is_err());
+    }
+
+    #[test]
+    fn test_parse_table_node_label() {
+        let node = parse_table_node("P".to_string(), "table pricing first").unwrap();
+        let Node::DecisionTable { table, .. } = node else {
+            panic!("expected a decision table");
+        };
+        assert_eq!(table.source, TableSource::Inline("pricing".to_string()));
+        assert_eq!(table.hit_policy, HitPolicy::First);
+
+        let node = parse_table_node("Q".to_string(), "table 'tiers.csv'").unwrap();
+        let Node::DecisionTable { table, .. } = node else {
+            panic!("expected a decision table");
+        };
+        assert_eq!(table.source, TableSource::File("tiers.csv".to_string()));
+        assert_eq!(table.hit_policy, HitPolicy::Unique);
+
+        assert!(parse_table_node("R".to_string(), "pricing").is_err());
+        assert!(parse_table_node("R".to_string(), "table pricing all").is_err());
+    }
+
+    #[test]
+    fn test_inline_tables() {
+        let source =
+            "flowchart TD\n%% table a\n%% x, ->\n%% 1, one\n\n%% table b\n%% y =\n%%\n%% 2\n";
+        let tables = inline_tables(source).unwrap();
+        assert_eq!(tables["a"], "x, ->\n1, one\n");
+        assert_eq!(tables["b"], "y =\n");
+    }
+
+    #[test]
+    fn test_fill_table() {
+        let mut table = DecisionTable {
+            source: TableSource::Inline("t".to_string()),
+            hit_policy: HitPolicy::Unique,
+            inputs: Vec::new(),
+            outputs: Vec::new(),
+            has_target: false,
+            rules: Vec::new(),
+        };
+        fill_table(
+            &mut table,
+            "qty, price =, ->\n>= 100, 80, bulk\n-, -, retail\n",
+        )
+        .unwrap();
+        assert_eq!(table.inputs.len(), 1);
+        assert_eq!(table.outputs, vec!["price"]);
+        assert!(table.has_target);
+        assert_eq!(
+            table.rules[0].tests[0],
+            Some(CellTest {
+                op: BinaryOp::Ge,
+                value: Expr::IntLit { value: 100 },
+            })
+        );
+        assert_eq!(table.rules[1].tests[0], None);
+        assert_eq!(table.rules[1].values[0], None);
+        assert_eq!(table.rules[1].target.as_deref(), Some("retail"));
+    }
+}
@@ -1,8 +1,12 @@
-use rustc_hash::FxHashMap;
+use rustc_hash::{FxHashMap, FxHashSet};
 
 use crate::ast::{Edge, EdgeLabel, Node};
 
+use super::consts::validate_constants;
+use super::coverage::check_coverage;
 use super::error::ValidationError;
+use super::guard::validate_guards;
+use super::loops::validate_loop;
 
 pub(super) fn insert_node(
     nodes: &mut FxHashMap<String, Node>,
@@ -25,10 +29,7 @@ pub(super) fn insert_node(
             // Identical redefinition is allowed.
             (existing, new) if existing == new => Ok(()),
 
-            _ => Err(ValidationError::new(format!(
-                "Node '{}' is defined multiple times",
-                node_id
-            ))),
+            _ => Err(ValidationError::DuplicateNode { node_id }),
         },
         None => {
             nodes.insert(node_id, node);
@@ -41,126 +42,251 @@ pub(super) fn validate_flowchart(
     nodes: &FxHashMap<String, Node>,
     edges: &[Edge],
 ) -> Result<(), ValidationError> {
+    // The edges from each node, in order
+    let mut outgoing: FxHashMap<&str, Vec<&Edge>> = FxHashMap::default();
+    for edge in edges {
+        outgoing.entry(&edge.from).or_default().push(edge);
+    }
+
     // Validate: condition nodes must have both Yes and No edges
     for node in nodes.values() {
         if let Node::Condition { id, .. } = node {
             let mut has_yes = false;
             let mut has_no = false;
 
-            for edge in edges {
-                if &edge.from != id {
-                    continue;
-                }
-
+            for edge in outgoing.get(id.as_str()).into_iter().flatten() {
                 match &edge.label {
                     Some(EdgeLabel::Yes) => {
                         if has_yes {
-                            return Err(ValidationError::new(format!(
-                                "Condition node '{}' has multiple 'Yes' edges",
-                                id
-                            )));
+                            return Err(ValidationError::MultipleConditionEdges {
+                                node_id: id.clone(),
+                                yes: true,
+                            });
                         }
                         has_yes = true;
                     }
                     Some(EdgeLabel::No) => {
                         if has_no {
-                            return Err(ValidationError::new(format!(
-                                "Condition node '{}' has multiple 'No' edges",
-                                id
-                            )));
+                            return Err(ValidationError::MultipleConditionEdges {
+                                node_id: id.clone(),
+                                yes: false,
+                            });
                         }
                         has_no = true;
                     }
                     Some(EdgeLabel::Custom(s)) => {
-                        return Err(ValidationError::new(format!(
-                            "Condition node '{}' must have 'Yes' or 'No' label, but got '{}'",
-                            id, s
-                        )));
+                        return Err(ValidationError::InvalidConditionLabel {
+                            node_id: id.clone(),
+                            label: s.clone(),
+                        });
+                    }
+                    Some(EdgeLabel::Guard(_) | EdgeLabel::Else) => {
+                        return Err(ValidationError::GuardOnConditionEdge {
+                            node_id: id.clone(),
+                        });
                     }
                     None => {
-                        return Err(ValidationError::new(format!(
-                            "Edge from condition node '{}' must have 'Yes' or 'No' label",
-                            id
-                        )));
+                        return Err(ValidationError::UnlabeledConditionEdge {
+                            node_id: id.clone(),
+                        });
                     }
                 }
             }
 
             if !has_yes {
-                return Err(ValidationError::new(format!(
-                    "Condition node '{}' is missing 'Yes' edge",
-                    id
-                )));
+                return Err(ValidationError::MissingConditionEdge {
+                    node_id: id.clone(),
+                    yes: true,
+                });
             }
             if !has_no {
-                return Err(ValidationError::new(format!(
-                    "Condition node '{}' is missing 'No' edge",
-                    id
-                )));
+                return Err(ValidationError::MissingConditionEdge {
+                    node_id: id.clone(),
+                    yes: false,
+                });
+            }
+        }
+    }
+
+    // Validate: every decision table rule must have an edge for its target
+    // label, and the rules must neither leave gaps nor (unless the table
+    // uses the first hit policy) overlap
+    for node in nodes.values() {
+        let Node::DecisionTable { id, table } = node else {
+            continue;
+        };
+        if table.has_target {
+            let mut labels: FxHashSet<&str> = FxHashSet::default();
+            for edge in outgoing.get(id.as_str()).into_iter().flatten() {
+                let Some(EdgeLabel::Custom(label)) = &edge.label else {
+                    return Err(ValidationError::UnlabeledTableEdge {
+                        node_id: id.clone(),
+                    });
+                };
+                if !labels.insert(label) {
+                    return Err(ValidationError::MultipleTableEdges {
+                        node_id: id.clone(),
+                        label: label.clone(),
+                    });
+                }
+            }
+            for rule in &table.rules {
+                if let Some(target) = &rule.target
+                    && !labels.contains(target.as_str())
+                {
+                    return Err(ValidationError::MissingTableEdge {
+                        node_id: id.clone(),
+                        label: target.clone(),
+                    });
+                }
             }
         }
+        check_coverage(id, table)?;
     }
 
     // Validate: Flowchart must have Start and End nodes
     if !nodes.values().any(|n| matches!(n, Node::Start { .. })) {
-        return Err(ValidationError::new("Missing 'Start' node"));
+        return Err(ValidationError::MissingStartNode);
     }
-    if !nodes.values().any(|n| matches!(n, Node::End { .. })) {
-        return Err(ValidationError::new("Missing 'End' node"));
+    if !nodes.values().any(Node::is_terminal) {
+        return Err(ValidationError::MissingEndNode);
     }
 
     // Validate: All edge references must point to defined nodes
     for edge in edges {
-        if !nodes.contains_key(&edge.from) {
-            return Err(ValidationError::new(format!(
-                "Undefined node '{}' referenced in edge from '{}' to '{}'",
-                edge.from, edge.from, edge.to
-            )));
-        }
-        if !nodes.contains_key(&edge.to) {
-            return Err(ValidationError::new(format!(
-                "Undefined node '{}' referenced in edge from '{}' to '{}'",
-                edge.to, edge.from, edge.to
-            )));
+        for node_id in [&edge.from, &edge.to] {
+            if !nodes.contains_key(node_id) {
+                return Err(ValidationError::UndefinedNode {
+                    node_id: node_id.clone(),
+                    from: edge.from.clone(),
+                    to: edge.to.clone(),
+                });
+            }
         }
     }
 
-    // Validate: End node must not have outgoing edges
+    // Validate: terminal nodes must not have outgoing edges
     for edge in edges {
-        if edge.from == "End" {
-            return Err(ValidationError::new("End node cannot have outgoing edges"));
+        match &nodes[&edge.from] {
+            Node::End { .. } => {
+                return Err(ValidationError::EdgeFromEnd);
+            }
+            Node::Terminal { id, .. } => {
+                return Err(ValidationError::EdgeFromTerminal {
+                    node_id: id.clone(),
+                });
+            }
+            _ => {}
         }
     }
 
-    // Validate: Non-condition nodes must have at most one outgoing edge
-    let mut edge_counts: FxHashMap<String, usize> = FxHashMap::default();
-    for edge in edges {
-        *edge_counts.entry(edge.from.clone()).or_insert(0) += 1;
+    // Validate: loop nodes must have both body and done edges, and the body
+    // must lead back to the loop
+    for node in nodes.values() {
+        if let Node::Loop { id, .. } = node {
+            validate_loop(id, &outgoing, nodes)?;
+        }
     }
-    for (node_id, count) in edge_counts {
-        if count > 1 {
-            // Condition nodes are allowed to have 2 edges (Yes and No)
-            let is_condition = nodes
-                .get(&node_id)
-                .is_some_and(|n| matches!(n, Node::Condition { .. }));
-            if !is_condition {
-                return Err(ValidationError::new(format!(
-                    "Node '{}' has multiple outgoing edges (expected at most 1)",
-                    node_id
-                )));
-            }
+
+    // Validate: Non-condition nodes must have at most one outgoing edge,
+    // unless their outgoing edges are guarded
+    for (&node_id, node_edges) in &outgoing {
+        // Condition nodes are allowed to have 2 edges (Yes and No), loop
+        // nodes 2 edges (body and done), and decision tables with a target
+        // column one edge per target
+        match nodes.get(node_id) {
+            Some(Node::Condition { .. } | Node::Loop { .. }) => continue,
+            Some(Node::DecisionTable { table, .. }) if table.has_target => continue,
+            _ => {}
+        }
+        if node_edges
+            .iter()
+            .any(|e| e.label.as_ref().is_some_and(EdgeLabel::is_guard))
+        {
+            validate_guards(node_id, node_edges)?;
+        } else if node_edges.len() > 1 {
+            return Err(ValidationError::MultipleOutgoingEdges {
+                node_id: node_id.to_string(),
+            });
+        }
+    }
+
+    // Validate: entry names must be unique
+    let mut entry_names: FxHashMap<&str, &str> = FxHashMap::default();
+    for node in nodes.values() {
+        if let Node::Entry { id, name, .. } = node
+            && let Some(other) = entry_names.insert(name, id)
+        {
+            let (first, second) = if other < id.as_str() {
+                (other, id.as_str())
+            } else {
+                (id.as_str(), other)
+            };
+            return Err(ValidationError::DuplicateEntry {
+                name: name.clone(),
+                first: first.to_string(),
+                second: second.to_string(),
+            });
+        }
+    }
+
+    // Validate: every entry must be able to reach a terminal node
+    for node in nodes.values() {
+        let entry = match node {
+            Node::Start { .. } => None,
+            Node::Entry { name, .. } => Some(name.clone()),
+            _ => continue,
+        };
+        if !reaches_terminal(node.id(), nodes, &outgoing) {
+            return Err(ValidationError::UnreachableEnd { entry });
         }
     }
 
-    // Validate: exit code is only allowed on edges pointing to the End node
+    // Validate: exit code and return value are only allowed on edges pointing to a terminal node
     for edge in edges {
-        if edge.exit_code.is_some() && edge.to != "End" {
-            return Err(ValidationError::new(format!(
-                "Exit code can only be specified on edges to 'End' node, but found on edge from '{}' to '{}'",
-                edge.from, edge.to
-            )));
+        if edge.exit_code.is_some() && !nodes[&edge.to].is_terminal() {
+            return Err(ValidationError::MisplacedExitCode {
+                from: edge.from.clone(),
+                to: edge.to.clone(),
+            });
+        }
+        if edge.return_value.is_some() && !nodes[&edge.to].is_terminal() {
+            return Err(ValidationError::MisplacedReturn {
+                from: edge.from.clone(),
+                to: edge.to.clone(),
+            });
         }
     }
 
+    // Validate: constants are defined once and never changed
+    validate_constants(nodes, edges)?;
+
     Ok(())
 }
+
+/// Returns `true` if a terminal node can be reached from `from` by following
+/// edges.
+fn reaches_terminal(
+    from: &str,
+    nodes: &FxHashMap<String, Node>,
+    outgoing: &FxHashMap<&str, Vec<&Edge>>,
+) -> bool {
+    let mut seen: FxHashSet<&str> = FxHashSet::default();
+    let mut stack = vec![from];
+    while let Some(id) = stack.pop() {
+        if !seen.insert(id) {
+            continue;
+        }
+        if nodes[id].is_terminal() {
+            return true;
+        }
+        stack.extend(
+            outgoing
+                .get(id)
+                .into_iter()
+                .flatten()
+                .map(|e| e.to.as_str()),
+        );
+    }
+    false
+}
@@ -0,0 +1,150 @@
+//! One-call execution of flowchart source code.
+//!
+//! This module provides [`run_str`], which parses, validates, and executes a
+//! program entirely in memory and returns everything it produced as a
+//! [`RunResult`]. It is intended for embedders and test suites that would
+//! otherwise have to wire up a parser, an interpreter, and I/O mocks by hand.
+
+use std::fmt;
+
+use crate::codes::ErrorCode;
+use crate::i18n::{Lang, Localize};
+use crate::parser::{self, AnalysisError};
+use crate::runtime::{
+    CapturingWriter, Interpreter, OutputChunk, RuntimeError, Value, VecInputReader,
+};
+
+/// An error that stopped a program run by [`run_str`].
+#[derive(Debug)]
+pub enum RunError {
+    /// The source failed to parse or validate; the program never started.
+    Analysis(AnalysisError),
+
+    /// The program started but failed during execution.
+    Runtime(RuntimeError),
+}
+
+impl RunError {
+    /// Returns the code of the wrapped error.
+    pub fn code(&self) -> ErrorCode {
+        match self {
+            RunError::Analysis(e) => e.code(),
+            RunError::Runtime(e) => e.code(),
+        }
+    }
+}
+
+impl fmt::Display for RunError {
+    /// Formats the error as the `merx run` command would print it.
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        self.localize(Lang::En, f)
+    }
+}
+
+impl std::error::Error for RunError {
+    fn source(&self) -> Option<&(dyn std::error::Error + 'static)> {
+        match self {
+            RunError::Analysis(e) => Some(e),
+            RunError::Runtime(e) => Some(e),
+        }
+    }
+}
+
+/// Everything a program run by [`run_str`] produced.
+#[derive(Debug)]
+pub struct RunResult {
+    /// Standard output, exactly as a terminal would have received it.
+    pub stdout: String,
+
+    /// Standard error, exactly as a terminal would have received it.
+    ///
+    /// Does not include the message for [`error`](RunResult::error).
+    pub stderr: String,
+
+    /// Every write to stdout and stderr, in the order it happened.
+    pub output: Vec<OutputChunk>,
+
+    /// The exit code `merx run` would have returned.
+    ///
+    /// This is the program's exit code on success, `1` for a runtime
+    /// error, and `2` for a parse or validation error.
+    pub exit_code: u8,
+
+    /// The program's result value, if it produced one.
+    ///
+    /// See [`RunOutcome::value`](crate::runtime::RunOutcome::value).
+    pub value: Option<Value>,
+
+    /// The error that stopped the program, if any.
+    pub error: Option<RunError>,
+}
+
+impl RunResult {
+    /// Returns `true` if the program ran to completion without an error.
+    pub fn is_ok(&self) -> bool {
+        self.error.is_none()
+    }
+
+    fn from_output(output: CapturingWriter, exit_code: u8, error: Option<RunError>) -> Self {
+        Self {
+            stdout: output.stdout_text(),
+            stderr: output.stderr_text(),
+            output: output.into_chunks(),
+            exit_code,
+            value: None,
+            error,
+        }
+    }
+
+    fn failed(output: CapturingWriter, exit_code: u8, error: RunError) -> Self {
+        Self::from_output(output, exit_code, Some(error))
+    }
+}
+
+/// Parses and runs a program with in-memory I/O.
+///
+/// Input is supplied by `stdin_lines`; reading past the last line is a
+/// runtime error. Output produced before an error is still captured.
+///
+/// # Arguments
+///
+/// * `source` - The flowchart source code
+/// * `stdin_lines` - Lines returned, in order, by `input`
+///
+/// # Examples
+///
+/// ```ignore
+/// let result = merx::run_str(
+///     r#"flowchart TD
+///     Start --> A[println 'Hello, ' + input + '!']
+///     A --> End
+/// "#,
+///     &["merx"],
+/// );
+///
+/// assert_eq!(result.stdout, "Hello, merx!\n");
+/// assert_eq!(result.exit_code, 0);
+/// assert!(result.error.is_none());
+/// ```
+pub fn run_str(source: &str, stdin_lines: &[&str]) -> RunResult {
+    let flowchart = match parser::parse(source) {
+        Ok(f) => f,
+        Err(e) => return RunResult::failed(CapturingWriter::new(), 2, RunError::Analysis(e)),
+    };
+
+    let input = VecInputReader::new(stdin_lines.iter().copied());
+    let mut interpreter = match Interpreter::with_io(flowchart, input, CapturingWriter::new()) {
+        Ok(i) => i,
+        Err(e) => return RunResult::failed(CapturingWriter::new(), 1, RunError::Runtime(e)),
+    };
+
+    let result = interpreter.run_outcome();
+    let output = interpreter.into_output_writer();
+    match result {
+        Ok(outcome) => RunResult {
+            value: outcome.value,
+            ..RunResult::from_output(output, outcome.exit_code, None)
+        },
+        Err(e) => RunResult::failed(output, 1, RunError::Runtime(e)),
+    }
+}
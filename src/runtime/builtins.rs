@@ -0,0 +1,378 @@
+//! Built-in function support.
+//!
+//! This module provides [`Builtins`], the interpreter-owned state that
+//! built-in functions ([`Function`]) draw on, and the dispatch that turns
+//! evaluated arguments into a result value.
+//!
+//! # Randomness
+//!
+//! The random functions (`rand_int`, `rand_choice`, `shuffle`) share a single
+//! pseudo-random number generator. Seeding it makes every run of a program
+//! produce the same values; without a seed it is initialized from OS entropy.
+//! Randomness can also be denied entirely, in which case calling a random
+//! function is a runtime error.
+//!
+//! # Time
+//!
+//! The time functions (`now_ms`, `today`) and the `sleep` statement read from
+//! a [`Clock`]. The system clock is used by default; tests can substitute a
+//! [`ManualClock`](super::ManualClock) so that `sleep` returns immediately.
+//! Dates are always interpreted in UTC.
+
+use std::fmt;
+
+use crate::ast::Function;
+
+use super::clock::{Clock, SystemClock};
+use super::date;
+use super::error::RuntimeError;
+use super::random::Rng;
+use super::value::Value;
+
+/// State shared by built-in functions during execution.
+///
+/// An [`Interpreter`](super::Interpreter) owns one `Builtins` and passes it
+/// to [`eval_expr`](super::eval_expr) and
+/// [`exec_statement`](super::exec_statement).
+///
+/// # Examples
+///
+/// ```
+/// use merx::runtime::Builtins;
+///
+/// // Reproducible: the same seed always yields the same values.
+/// let builtins = Builtins::new().with_seed(42);
+///
+/// // Sandboxed: random functions fail at runtime.
+/// let sandboxed = Builtins::new().deny_random();
+/// ```
+pub struct Builtins {
+    /// The generator backing the random functions.
+    rng: Rng,
+
+    /// Whether calling a random function is a runtime error.
+    random_denied: bool,
+
+    /// The source of time for `now_ms`, `today`, and `sleep`.
+    clock: Box<dyn Clock>,
+}
+
+impl Builtins {
+    /// Creates builtin state with a generator seeded from OS entropy and
+    /// the system clock.
+    pub fn new() -> Self {
+        Self {
+            rng: Rng::from_entropy(),
+            random_denied: false,
+            clock: Box::new(SystemClock::new()),
+        }
+    }
+
+    /// Reseeds the random number generator with `seed`.
+    pub fn with_seed(mut self, seed: u64) -> Self {
+        self.rng = Rng::seed_from(seed);
+        self
+    }
+
+    /// Replaces the clock used by time functions and `sleep`.
+    pub fn with_clock(mut self, clock: impl Clock + 'static) -> Self {
+        self.clock = Box::new(clock);
+        self
+    }
+
+    /// Denies access to randomness.
+    ///
+    /// After this call, `rand_int`, `rand_choice`, and `shuffle` return
+    /// [`RuntimeError::RandomDenied`].
+    pub fn deny_random(mut self) -> Self {
+        self.random_denied = true;
+        self
+    }
+
+    /// Invokes a built-in function with already-evaluated arguments.
+    ///
+    /// The argument count is validated at parse time, so this function
+    /// assumes `args` matches the function's arity.
+    ///
+    /// # Errors
+    ///
+    /// - [`RuntimeError::RandomDenied`] - A random function was called while randomness is denied
+    /// - [`RuntimeError::TypeError`] - An argument has the wrong type
+    /// - [`RuntimeError::InvalidArgument`] - An argument has an unusable value
+    pub fn call(&mut self, function: Function, args: &[Value]) -> Result<Value, RuntimeError> {
+        if function.is_random() && self.random_denied {
+            return Err(RuntimeError::RandomDenied {
+                function: function.name(),
+            });
+        }
+
+        match function {
+            Function::RandInt => {
+                let lo = expect_int(function, &args[0])?;
+                let hi = expect_int(function, &args[1])?;
+                if lo > hi {
+                    return Err(RuntimeError::InvalidArgument {
+                        function: function.name(),
+                        message: format!("lower bound {} is greater than upper bound {}", lo, hi),
+                    });
+                }
+                Ok(Value::Int(self.rng.range_inclusive(lo, hi)))
+            }
+            Function::RandChoice => {
+                let index = self.rng.below(args.len() as u64) as usize;
+                Ok(args[index].clone())
+            }
+            Function::Shuffle => {
+                let s = expect_str(function, &args[0])?;
+                let mut chars: Vec<char> = s.chars().collect();
+                self.rng.shuffle(&mut chars);
+                Ok(Value::from(chars.into_iter().collect::<String>()))
+            }
+            Function::NowMs => Ok(Value::Int(self.clock.now_ms())),
+            Function::Today => {
+                let today = date::format(self.clock.now_ms(), "%Y-%m-%d")
+                    .expect("static date pattern is valid");
+                Ok(Value::from(today))
+            }
+            Function::FormatDate => {
+                let ms = expect_int(function, &args[0])?;
+                let pattern = expect_str(function, &args[1])?;
+                date::format(ms, pattern)
+                    .map(Value::from)
+                    .map_err(|message| RuntimeError::InvalidArgument {
+                        function: function.name(),
+                        message,
+                    })
+            }
+            Function::ParseDate => {
+                let text = expect_str(function, &args[0])?;
+                date::parse(text)
+                    .map(Value::Int)
+                    .ok_or_else(|| RuntimeError::InvalidArgument {
+                        function: function.name(),
+                        message: format!("'{}' is not a valid date", text),
+                    })
+            }
+        }
+    }
+
+    /// Waits for `ms` milliseconds on the configured clock.
+    ///
+    /// # Errors
+    ///
+    /// Returns [`RuntimeError::InvalidArgument`] if `ms` is negative.
+    pub fn sleep(&mut self, ms: i64) -> Result<(), RuntimeError> {
+        let ms = u64::try_from(ms).map_err(|_| RuntimeError::InvalidArgument {
+            function: "sleep",
+            message: format!("duration {} is negative", ms),
+        })?;
+        self.clock.sleep(ms);
+        Ok(())
+    }
+}
+
+impl Default for Builtins {
+    fn default() -> Self {
+        Self::new()
+    }
+}
+
+impl fmt::Debug for Builtins {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.debug_struct("Builtins")
+            .field("rng", &self.rng)
+            .field("random_denied", &self.random_denied)
+            .finish_non_exhaustive()
+    }
+}
+
+/// Extracts an integer argument, reporting a type error naming `function`.
+fn expect_int(function: Function, value: &Value) -> Result<i64, RuntimeError> {
+    value.as_int().ok_or_else(|| RuntimeError::TypeError {
+        expected: "int",
+        actual: value.type_name(),
+        operation: function.name().to_string(),
+    })
+}
+
+/// Extracts a string argument, reporting a type error naming `function`.
+fn expect_str(function: Function, value: &Value) -> Result<&str, RuntimeError> {
+    value.as_str().ok_or_else(|| RuntimeError::TypeError {
+        expected: "str",
+        actual: value.type_name(),
+        operation: function.name().to_string(),
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::super::clock::ManualClock;
+    use super::*;
+
+    #[test]
+    fn test_rand_int_in_range() {
+        let mut builtins = Builtins::new().with_seed(1);
+        for _ in 0..100 {
+            let v = builtins
+                .call(Function::RandInt, &[Value::Int(1), Value::Int(6)])
+                .unwrap();
+            let n = v.as_int().unwrap();
+            assert!((1..=6).contains(&n));
+        }
+    }
+
+    #[test]
+    fn test_rand_int_seeded_is_reproducible() {
+        let args = [Value::Int(0), Value::Int(1_000_000)];
+        let mut a = Builtins::new().with_seed(42);
+        let mut b = Builtins::new().with_seed(42);
+        for _ in 0..10 {
+            assert_eq!(
+                a.call(Function::RandInt, &args).unwrap(),
+                b.call(Function::RandInt, &args).unwrap()
+            );
+        }
+    }
+
+    #[test]
+    fn test_rand_int_inverted_bounds() {
+        let mut builtins = Builtins::new().with_seed(1);
+        let result = builtins.call(Function::RandInt, &[Value::Int(6), Value::Int(1)]);
+        assert!(matches!(
+            result,
+            Err(RuntimeError::InvalidArgument {
+                function: "rand_int",
+                ..
+            })
+        ));
+    }
+
+    #[test]
+    fn test_rand_int_type_error() {
+        let mut builtins = Builtins::new().with_seed(1);
+        let result = builtins.call(Function::RandInt, &[Value::from("1"), Value::Int(6)]);
+        assert!(matches!(
+            result,
+            Err(RuntimeError::TypeError {
+                expected: "int",
+                actual: "str",
+                ..
+            })
+        ));
+    }
+
+    #[test]
+    fn test_rand_choice_returns_an_argument() {
+        let mut builtins = Builtins::new().with_seed(5);
+        let args = [
+            Value::from("rock"),
+            Value::from("paper"),
+            Value::from("scissors"),
+        ];
+        for _ in 0..20 {
+            let v = builtins.call(Function::RandChoice, &args).unwrap();
+            assert!(args.contains(&v));
+        }
+    }
+
+    #[test]
+    fn test_shuffle_keeps_characters() {
+        let mut builtins = Builtins::new().with_seed(9);
+        let v = builtins
+            .call(Function::Shuffle, &[Value::from("abcdef")])
+            .unwrap();
+        let mut chars: Vec<char> = v.as_str().unwrap().chars().collect();
+        chars.sort();
+        assert_eq!(chars.into_iter().collect::<String>(), "abcdef");
+    }
+
+    #[test]
+    fn test_shuffle_type_error() {
+        let mut builtins = Builtins::new().with_seed(9);
+        let result = builtins.call(Function::Shuffle, &[Value::Int(1)]);
+        assert!(matches!(result, Err(RuntimeError::TypeError { .. })));
+    }
+
+    #[test]
+    fn test_deny_random() {
+        let mut builtins = Builtins::new().with_seed(1).deny_random();
+        let result = builtins.call(Function::RandInt, &[Value::Int(1), Value::Int(6)]);
+        assert!(matches!(
+            result,
+            Err(RuntimeError::RandomDenied {
+                function: "rand_int"
+            })
+        ));
+    }
+
+    #[test]
+    fn test_now_ms_reads_clock() {
+        let clock = ManualClock::new(1_000);
+        let mut builtins = Builtins::new().with_clock(clock.clone());
+        assert_eq!(
+            builtins.call(Function::NowMs, &[]).unwrap(),
+            Value::Int(1_000)
+        );
+        clock.advance(500);
+        assert_eq!(
+            builtins.call(Function::NowMs, &[]).unwrap(),
+            Value::Int(1_500)
+        );
+    }
+
+    #[test]
+    fn test_today() {
+        // 2024-02-29T12:34:56Z
+        let mut builtins = Builtins::new().with_clock(ManualClock::new(1_709_210_096_000));
+        assert_eq!(
+            builtins.call(Function::Today, &[]).unwrap(),
+            Value::from("2024-02-29")
+        );
+    }
+
+    #[test]
+    fn test_format_date_invalid_pattern() {
+        let mut builtins = Builtins::new();
+        let result = builtins.call(Function::FormatDate, &[Value::Int(0), Value::from("%Q")]);
+        assert!(matches!(
+            result,
+            Err(RuntimeError::InvalidArgument {
+                function: "format_date",
+                ..
+            })
+        ));
+    }
+
+    #[test]
+    fn test_parse_date_invalid() {
+        let mut builtins = Builtins::new();
+        let result = builtins.call(Function::ParseDate, &[Value::from("soon")]);
+        assert!(matches!(
+            result,
+            Err(RuntimeError::InvalidArgument {
+                function: "parse_date",
+                ..
+            })
+        ));
+    }
+
+    #[test]
+    fn test_sleep_advances_manual_clock() {
+        let clock = ManualClock::new(0);
+        let mut builtins = Builtins::new().with_clock(clock.clone());
+        builtins.sleep(3_000).unwrap();
+        assert_eq!(clock.now_ms(), 3_000);
+    }
+
+    #[test]
+    fn test_sleep_negative() {
+        let mut builtins = Builtins::new().with_clock(ManualClock::new(0));
+        assert!(matches!(
+            builtins.sleep(-1),
+            Err(RuntimeError::InvalidArgument {
+                function: "sleep",
+                ..
+            })
+        ));
+    }
+}
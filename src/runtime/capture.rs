@@ -0,0 +1,243 @@
+//! In-memory I/O for embedding and testing.
+//!
+//! This module provides ready-made implementations of [`InputReader`] and
+//! [`OutputWriter`] that work entirely in memory:
+//!
+//! - [`VecInputReader`]: Supplies input lines from a vector
+//! - [`CapturingWriter`]: Records all output, preserving the order of
+//!   stdout and stderr writes relative to each other
+//!
+//! For the common case of running a whole program from source, see
+//! [`run_str`](crate::run_str).
+
+use std::collections::VecDeque;
+
+use super::error::RuntimeError;
+use super::eval::InputReader;
+use super::exec::{OutputStream, OutputWriter};
+
+/// An [`InputReader`] that returns pre-supplied lines in order.
+///
+/// Once all lines have been consumed, further reads fail with
+/// [`RuntimeError::IoError`], so a program that reads more input than
+/// expected is reported rather than silently receiving empty strings.
+///
+/// # Examples
+///
+/// ```
+/// use merx::runtime::{InputReader, VecInputReader};
+///
+/// let mut input = VecInputReader::new(["alice", "42"]);
+/// assert_eq!(input.read_line().unwrap(), "alice");
+/// assert_eq!(input.read_line().unwrap(), "42");
+/// assert!(input.read_line().is_err());
+/// ```
+#[derive(Debug, Clone, Default)]
+pub struct VecInputReader {
+    lines: VecDeque<String>,
+}
+
+impl VecInputReader {
+    /// Creates a reader that returns `lines` in order.
+    pub fn new<I, S>(lines: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        Self {
+            lines: lines.into_iter().map(Into::into).collect(),
+        }
+    }
+
+    /// Returns the number of lines not yet read.
+    pub fn remaining(&self) -> usize {
+        self.lines.len()
+    }
+}
+
+impl InputReader for VecInputReader {
+    fn read_line(&mut self) -> Result<String, RuntimeError> {
+        self.lines.pop_front().ok_or_else(|| RuntimeError::IoError {
+            message: "No more input".to_string(),
+        })
+    }
+}
+
+/// A single write recorded by a [`CapturingWriter`].
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct OutputChunk {
+    /// The stream that was written to.
+    pub stream: OutputStream,
+
+    /// The text that was written, without any trailing newline.
+    pub text: String,
+
+    /// Whether the write was followed by a newline (`false` only for `print`).
+    pub newline: bool,
+}
+
+/// An [`OutputWriter`] that records all output in memory.
+///
+/// Every write is kept as an [`OutputChunk`] in the order it happened, so
+/// assertions can check how stdout and stderr were interleaved. Convenience
+/// accessors return each stream on its own.
+///
+/// # Examples
+///
+/// ```
+/// use merx::runtime::{CapturingWriter, OutputWriter};
+///
+/// let mut output = CapturingWriter::new();
+/// output.write_stdout_no_newline("Hello, ").unwrap();
+/// output.write_stderr("warning").unwrap();
+/// output.write_stdout("World!").unwrap();
+///
+/// assert_eq!(output.stdout(), vec!["Hello, ", "World!"]);
+/// assert_eq!(output.stdout_text(), "Hello, World!\n");
+/// assert_eq!(output.stderr_text(), "warning\n");
+/// ```
+#[derive(Debug, Clone, Default)]
+pub struct CapturingWriter {
+    chunks: Vec<OutputChunk>,
+}
+
+impl CapturingWriter {
+    /// Creates an empty capturing writer.
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Returns every recorded write, in order.
+    pub fn chunks(&self) -> &[OutputChunk] {
+        &self.chunks
+    }
+
+    /// Consumes the writer, returning every recorded write in order.
+    pub fn into_chunks(self) -> Vec<OutputChunk> {
+        self.chunks
+    }
+
+    /// Returns the text of each stdout write, in order.
+    pub fn stdout(&self) -> Vec<String> {
+        self.texts(OutputStream::Stdout)
+    }
+
+    /// Returns the text of each stderr write, in order.
+    pub fn stderr(&self) -> Vec<String> {
+        self.texts(OutputStream::Stderr)
+    }
+
+    /// Returns stdout exactly as a terminal would have received it.
+    pub fn stdout_text(&self) -> String {
+        render(
+            self.chunks
+                .iter()
+                .filter(|c| c.stream == OutputStream::Stdout),
+        )
+    }
+
+    /// Returns stderr exactly as a terminal would have received it.
+    pub fn stderr_text(&self) -> String {
+        render(
+            self.chunks
+                .iter()
+                .filter(|c| c.stream == OutputStream::Stderr),
+        )
+    }
+
+    /// Returns stdout and stderr merged in the order they were written,
+    /// as a terminal showing both streams would display them.
+    pub fn combined_text(&self) -> String {
+        render(self.chunks.iter())
+    }
+
+    fn texts(&self, stream: OutputStream) -> Vec<String> {
+        self.chunks
+            .iter()
+            .filter(|c| c.stream == stream)
+            .map(|c| c.text.clone())
+            .collect()
+    }
+
+    fn push(&mut self, stream: OutputStream, s: &str, newline: bool) {
+        self.chunks.push(OutputChunk {
+            stream,
+            text: s.to_string(),
+            newline,
+        });
+    }
+}
+
+/// Concatenates chunks, appending a newline after each line-terminated write.
+fn render<'a>(chunks: impl Iterator<Item = &'a OutputChunk>) -> String {
+    let mut out = String::new();
+    for chunk in chunks {
+        out.push_str(&chunk.text);
+        if chunk.newline {
+            out.push('\n');
+        }
+    }
+    out
+}
+
+impl OutputWriter for CapturingWriter {
+    fn write_stdout(&mut self, s: &str) -> Result<(), RuntimeError> {
+        self.push(OutputStream::Stdout, s, true);
+        Ok(())
+    }
+
+    fn write_stdout_no_newline(&mut self, s: &str) -> Result<(), RuntimeError> {
+        self.push(OutputStream::Stdout, s, false);
+        Ok(())
+    }
+
+    fn write_stderr(&mut self, s: &str) -> Result<(), RuntimeError> {
+        self.push(OutputStream::Stderr, s, true);
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_vec_input_reader_exhausted() {
+        let mut input = VecInputReader::new(["a"]);
+        assert_eq!(input.remaining(), 1);
+        assert_eq!(input.read_line().unwrap(), "a");
+        assert_eq!(input.remaining(), 0);
+        assert!(matches!(
+            input.read_line(),
+            Err(RuntimeError::IoError { message }) if message == "No more input"
+        ));
+    }
+
+    #[test]
+    fn test_vec_input_reader_default_is_empty() {
+        let mut input = VecInputReader::default();
+        assert!(input.read_line().is_err());
+    }
+
+    #[test]
+    fn test_capturing_writer_preserves_interleaving() {
+        let mut output = CapturingWriter::new();
+        output.write_stdout("one").unwrap();
+        output.write_stderr("two").unwrap();
+        output.write_stdout_no_newline("three").unwrap();
+        output.write_stdout("four").unwrap();
+
+        assert_eq!(output.stdout(), vec!["one", "three", "four"]);
+        assert_eq!(output.stderr(), vec!["two"]);
+        assert_eq!(output.stdout_text(), "one\nthreefour\n");
+        assert_eq!(output.combined_text(), "one\ntwo\nthreefour\n");
+        assert_eq!(
+            output.chunks()[1],
+            OutputChunk {
+                stream: OutputStream::Stderr,
+                text: "two".to_string(),
+                newline: true,
+            }
+        );
+    }
+}
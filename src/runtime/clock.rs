@@ -0,0 +1,145 @@
+//! Wall-clock abstraction.
+//!
+//! This module provides the [`Clock`] trait, which backs the time built-in
+//! functions (`now_ms`, `today`) and the `sleep` statement. Like
+//! [`InputReader`](super::InputReader) and [`OutputWriter`](super::OutputWriter),
+//! it allows the source of time to be injected, so tests can run against a
+//! fake clock instead of the system time.
+//!
+//! # Implementors
+//!
+//! - [`SystemClock`] - Reads the system time and actually sleeps
+//! - [`ManualClock`] - A fake clock whose time only moves when told to
+
+use std::sync::Arc;
+use std::sync::atomic::{AtomicI64, Ordering};
+use std::thread;
+use std::time::{Duration, SystemTime, UNIX_EPOCH};
+
+/// Abstraction for reading the current time and waiting.
+///
+/// Times are expressed as milliseconds since the Unix epoch (UTC).
+///
+/// # Examples
+///
+/// ```
+/// use merx::runtime::{Clock, ManualClock};
+///
+/// let mut clock = ManualClock::new(1_000);
+/// clock.sleep(500);
+/// assert_eq!(clock.now_ms(), 1_500);
+/// ```
+pub trait Clock {
+    /// Returns the current time in milliseconds since the Unix epoch.
+    fn now_ms(&self) -> i64;
+
+    /// Blocks for `ms` milliseconds.
+    fn sleep(&mut self, ms: u64);
+}
+
+/// A [`Clock`] backed by the system time.
+///
+/// `sleep` blocks the current thread.
+#[derive(Debug, Default, Clone, Copy)]
+pub struct SystemClock;
+
+impl SystemClock {
+    /// Creates a new system clock.
+    pub fn new() -> Self {
+        Self
+    }
+}
+
+impl Clock for SystemClock {
+    fn now_ms(&self) -> i64 {
+        match SystemTime::now().duration_since(UNIX_EPOCH) {
+            Ok(d) => d.as_millis() as i64,
+            Err(e) => -(e.duration().as_millis() as i64),
+        }
+    }
+
+    fn sleep(&mut self, ms: u64) {
+        thread::sleep(Duration::from_millis(ms));
+    }
+}
+
+/// A fake [`Clock`] for tests.
+///
+/// The time starts at a fixed value and only changes through
+/// [`advance`](ManualClock::advance), [`set`](ManualClock::set), or `sleep`,
+/// which advances the time instantly instead of blocking.
+///
+/// Clones share the same time, so a test can keep a handle to the clock
+/// after passing it to an [`Interpreter`](super::Interpreter).
+///
+/// # Examples
+///
+/// ```
+/// use merx::runtime::{Clock, ManualClock};
+///
+/// let clock = ManualClock::new(0);
+/// let handle = clock.clone();
+///
+/// clock.advance(250);
+/// assert_eq!(handle.now_ms(), 250);
+/// ```
+#[derive(Debug, Default, Clone)]
+pub struct ManualClock {
+    now: Arc<AtomicI64>,
+}
+
+impl ManualClock {
+    /// Creates a fake clock reading `start_ms` milliseconds since the Unix epoch.
+    pub fn new(start_ms: i64) -> Self {
+        Self {
+            now: Arc::new(AtomicI64::new(start_ms)),
+        }
+    }
+
+    /// Moves the time forward by `ms` milliseconds.
+    pub fn advance(&self, ms: i64) {
+        self.now.fetch_add(ms, Ordering::SeqCst);
+    }
+
+    /// Sets the time to `ms` milliseconds since the Unix epoch.
+    pub fn set(&self, ms: i64) {
+        self.now.store(ms, Ordering::SeqCst);
+    }
+}
+
+impl Clock for ManualClock {
+    fn now_ms(&self) -> i64 {
+        self.now.load(Ordering::SeqCst)
+    }
+
+    fn sleep(&mut self, ms: u64) {
+        self.advance(ms as i64);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_manual_clock_sleep_advances() {
+        let mut clock = ManualClock::new(100);
+        clock.sleep(50);
+        assert_eq!(clock.now_ms(), 150);
+    }
+
+    #[test]
+    fn test_manual_clock_clones_share_time() {
+        let clock = ManualClock::new(0);
+        let handle = clock.clone();
+        clock.set(1_000);
+        handle.advance(1);
+        assert_eq!(clock.now_ms(), 1_001);
+    }
+
+    #[test]
+    fn test_system_clock_is_after_2020() {
+        // 2020-01-01T00:00:00Z
+        assert!(SystemClock::new().now_ms() > 1_577_836_800_000);
+    }
+}
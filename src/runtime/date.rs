@@ -0,0 +1,261 @@
+//! Calendar date arithmetic.
+//!
+//! This module converts between Unix timestamps in milliseconds and
+//! proleptic Gregorian calendar dates in UTC, backing the `today`,
+//! `format_date`, and `parse_date` built-in functions.
+//!
+//! # Algorithm
+//!
+//! Day numbers are converted with Howard Hinnant's `days_from_civil` and
+//! `civil_from_days` algorithms, which are exact for every date
+//! representable by an `i64` day count and need no lookup tables.
+
+const MS_PER_SECOND: i64 = 1_000;
+const MS_PER_MINUTE: i64 = 60 * MS_PER_SECOND;
+const MS_PER_HOUR: i64 = 60 * MS_PER_MINUTE;
+const MS_PER_DAY: i64 = 24 * MS_PER_HOUR;
+
+/// A UTC date and time broken into calendar fields.
+#[derive(Debug, Clone, Copy, PartialEq)]
+struct DateTime {
+    year: i64,
+    month: u32,
+    day: u32,
+    hour: u32,
+    minute: u32,
+    second: u32,
+}
+
+/// Returns the number of days from 1970-01-01 to the given date.
+fn days_from_civil(year: i64, month: u32, day: u32) -> i64 {
+    let y = if month <= 2 { year - 1 } else { year };
+    let era = y.div_euclid(400);
+    let yoe = y - era * 400;
+    let m = i64::from(month);
+    let mp = if m > 2 { m - 3 } else { m + 9 };
+    let doy = (153 * mp + 2) / 5 + i64::from(day) - 1;
+    let doe = yoe * 365 + yoe / 4 - yoe / 100 + doy;
+    era * 146_097 + doe - 719_468
+}
+
+/// Returns the `(year, month, day)` that is `days` days after 1970-01-01.
+fn civil_from_days(days: i64) -> (i64, u32, u32) {
+    let z = days + 719_468;
+    let era = z.div_euclid(146_097);
+    let doe = z - era * 146_097;
+    let yoe = (doe - doe / 1_460 + doe / 36_524 - doe / 146_096) / 365;
+    let doy = doe - (365 * yoe + yoe / 4 - yoe / 100);
+    let mp = (5 * doy + 2) / 153;
+    let day = (doy - (153 * mp + 2) / 5 + 1) as u32;
+    let month = if mp < 10 { mp + 3 } else { mp - 9 } as u32;
+    let year = yoe + era * 400 + i64::from(month <= 2);
+    (year, month, day)
+}
+
+/// Returns the number of days in the given month.
+fn days_in_month(year: i64, month: u32) -> u32 {
+    match month {
+        1 | 3 | 5 | 7 | 8 | 10 | 12 => 31,
+        4 | 6 | 9 | 11 => 30,
+        _ => {
+            let leap = (year % 4 == 0 && year % 100 != 0) || year % 400 == 0;
+            if leap { 29 } else { 28 }
+        }
+    }
+}
+
+impl DateTime {
+    fn from_ms(ms: i64) -> Self {
+        let days = ms.div_euclid(MS_PER_DAY);
+        let rem = ms.rem_euclid(MS_PER_DAY);
+        let (year, month, day) = civil_from_days(days);
+        Self {
+            year,
+            month,
+            day,
+            hour: (rem / MS_PER_HOUR) as u32,
+            minute: (rem % MS_PER_HOUR / MS_PER_MINUTE) as u32,
+            second: (rem % MS_PER_MINUTE / MS_PER_SECOND) as u32,
+        }
+    }
+
+    fn to_ms(self) -> i64 {
+        days_from_civil(self.year, self.month, self.day) * MS_PER_DAY
+            + i64::from(self.hour) * MS_PER_HOUR
+            + i64::from(self.minute) * MS_PER_MINUTE
+            + i64::from(self.second) * MS_PER_SECOND
+    }
+}
+
+/// Formats a timestamp according to a `strftime`-style pattern.
+///
+/// # Supported Specifiers
+///
+/// | Specifier | Meaning | Example |
+/// |-----------|---------|---------|
+/// | `%Y` | Year | `2024` |
+/// | `%m` | Month (01-12) | `03` |
+/// | `%d` | Day of month (01-31) | `09` |
+/// | `%H` | Hour (00-23) | `14` |
+/// | `%M` | Minute (00-59) | `05` |
+/// | `%S` | Second (00-59) | `30` |
+/// | `%%` | A literal `%` | `%` |
+///
+/// # Errors
+///
+/// Returns a description of the problem if the pattern contains an
+/// unsupported specifier or ends with a lone `%`.
+pub(crate) fn format(ms: i64, pattern: &str) -> Result<String, String> {
+    let dt = DateTime::from_ms(ms);
+    let mut out = String::with_capacity(pattern.len() + 8);
+    let mut chars = pattern.chars();
+
+    while let Some(c) = chars.next() {
+        if c != '%' {
+            out.push(c);
+            continue;
+        }
+        match chars.next() {
+            Some('Y') => out.push_str(&format!("{:04}", dt.year)),
+            Some('m') => out.push_str(&format!("{:02}", dt.month)),
+            Some('d') => out.push_str(&format!("{:02}", dt.day)),
+            Some('H') => out.push_str(&format!("{:02}", dt.hour)),
+            Some('M') => out.push_str(&format!("{:02}", dt.minute)),
+            Some('S') => out.push_str(&format!("{:02}", dt.second)),
+            Some('%') => out.push('%'),
+            Some(other) => return Err(format!("unsupported format specifier '%{}'", other)),
+            None => return Err("format string ends with '%'".to_string()),
+        }
+    }
+
+    Ok(out)
+}
+
+/// Parses an ISO 8601 date or date-time in UTC into a timestamp.
+///
+/// Accepted forms:
+///
+/// - `YYYY-MM-DD`
+/// - `YYYY-MM-DD HH:MM:SS` or `YYYY-MM-DDTHH:MM:SS`, optionally followed by `Z`
+///
+/// # Returns
+///
+/// Milliseconds since the Unix epoch, or `None` if the text is not a
+/// valid date.
+pub(crate) fn parse(s: &str) -> Option<i64> {
+    let s = s.strip_suffix('Z').unwrap_or(s);
+    let (date, time) = match s.find(['T', ' ']) {
+        Some(i) => (&s[..i], Some(&s[i + 1..])),
+        None => (s, None),
+    };
+
+    let mut date_parts = date.split('-');
+    let year = parse_field(date_parts.next()?, 4)?;
+    let month = parse_field(date_parts.next()?, 2)? as u32;
+    let day = parse_field(date_parts.next()?, 2)? as u32;
+    if date_parts.next().is_some() {
+        return None;
+    }
+    if !(1..=12).contains(&month) || day == 0 || day > days_in_month(year, month) {
+        return None;
+    }
+
+    let (hour, minute, second) = match time {
+        Some(time) => {
+            let mut time_parts = time.split(':');
+            let hour = parse_field(time_parts.next()?, 2)? as u32;
+            let minute = parse_field(time_parts.next()?, 2)? as u32;
+            let second = parse_field(time_parts.next()?, 2)? as u32;
+            if time_parts.next().is_some() || hour > 23 || minute > 59 || second > 59 {
+                return None;
+            }
+            (hour, minute, second)
+        }
+        None => (0, 0, 0),
+    };
+
+    Some(
+        DateTime {
+            year,
+            month,
+            day,
+            hour,
+            minute,
+            second,
+        }
+        .to_ms(),
+    )
+}
+
+/// Parses a fixed-width, all-digit field.
+fn parse_field(s: &str, width: usize) -> Option<i64> {
+    if s.len() != width || !s.bytes().all(|b| b.is_ascii_digit()) {
+        return None;
+    }
+    s.parse().ok()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_epoch() {
+        assert_eq!(
+            format(0, "%Y-%m-%d %H:%M:%S").unwrap(),
+            "1970-01-01 00:00:00"
+        );
+        assert_eq!(parse("1970-01-01"), Some(0));
+    }
+
+    #[test]
+    fn test_known_timestamp() {
+        // 2024-02-29T12:34:56Z
+        let ms = 1_709_210_096_000;
+        assert_eq!(
+            format(ms, "%Y-%m-%dT%H:%M:%S").unwrap(),
+            "2024-02-29T12:34:56"
+        );
+        assert_eq!(parse("2024-02-29T12:34:56Z"), Some(ms));
+        assert_eq!(parse("2024-02-29 12:34:56"), Some(ms));
+    }
+
+    #[test]
+    fn test_before_epoch() {
+        assert_eq!(
+            format(-1, "%Y-%m-%d %H:%M:%S").unwrap(),
+            "1969-12-31 23:59:59"
+        );
+        assert_eq!(parse("1969-12-31"), Some(-MS_PER_DAY));
+    }
+
+    #[test]
+    fn test_round_trip_days() {
+        for days in [-800_000, -1, 0, 1, 59, 365, 10_957, 19_782, 800_000] {
+            let (y, m, d) = civil_from_days(days);
+            assert_eq!(days_from_civil(y, m, d), days);
+        }
+    }
+
+    #[test]
+    fn test_format_literal_percent() {
+        assert_eq!(format(0, "100%%").unwrap(), "100%");
+    }
+
+    #[test]
+    fn test_format_unsupported_specifier() {
+        assert!(format(0, "%Q").is_err());
+        assert!(format(0, "%").is_err());
+    }
+
+    #[test]
+    fn test_parse_invalid() {
+        assert_eq!(parse("2023-02-29"), None);
+        assert_eq!(parse("2024-13-01"), None);
+        assert_eq!(parse("2024-1-01"), None);
+        assert_eq!(parse("2024-01-01T24:00:00"), None);
+        assert_eq!(parse("2024-01-01T10:00"), None);
+        assert_eq!(parse("yesterday"), None);
+        assert_eq!(parse(""), None);
+    }
+}
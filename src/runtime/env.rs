@@ -12,8 +12,9 @@
 //! # Variable Lifecycle
 //!
 //! 1. Variables come into existence via assignment statements
-//! 2. Variables can be reassigned at any time
-//! 3. Variables persist until program ends
+//! 2. Variables can be reassigned at any time, unless they were defined
+//!    with `const`
+//! 3. Variables persist until program ends, or until removed with `unset`
 //! 4. Accessing an undefined variable is a runtime error
 //!
 //! # Implementation
@@ -24,7 +25,7 @@
 //! no HashDoS resistance (acceptable since variable names come from trusted
 //! `.mmd` source files).
 
-use rustc_hash::FxHashMap;
+use rustc_hash::{FxHashMap, FxHashSet};
 
 use super::error::RuntimeError;
 use super::value::Value;
@@ -69,6 +70,9 @@ use super::value::Value;
 pub struct Environment {
     /// Map from variable names to their current values.
     variables: FxHashMap<String, Value>,
+
+    /// Names of the variables defined as constants.
+    constants: FxHashSet<String>,
 }
 
 impl Environment {
@@ -112,6 +116,117 @@ impl Environment {
         }
     }
 
+    /// Sets a variable binding, unless the variable is a constant.
+    ///
+    /// This is [`set`](Self::set) for assignments made by the program.
+    ///
+    /// # Errors
+    ///
+    /// Returns [`RuntimeError::ConstantReassigned`] if the variable was
+    /// defined with [`define_const`](Self::define_const).
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::runtime::{Environment, Value};
+    ///
+    /// let mut env = Environment::new();
+    /// env.assign("x", Value::Int(1)).unwrap();
+    ///
+    /// env.define_const("RATE", Value::Int(8)).unwrap();
+    /// assert!(env.assign("RATE", Value::Int(10)).is_err());
+    /// ```
+    pub fn assign(&mut self, name: &str, value: Value) -> Result<(), RuntimeError> {
+        self.check_not_const(name)?;
+        self.set(name, value);
+        Ok(())
+    }
+
+    /// Retrieves a variable's value for modification in place.
+    ///
+    /// # Errors
+    ///
+    /// - [`RuntimeError::UndefinedVariable`] - The variable is not defined
+    /// - [`RuntimeError::ConstantReassigned`] - The variable is a constant
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::runtime::{Environment, Value};
+    ///
+    /// let mut env = Environment::new();
+    /// env.set("s", Value::from("ab"));
+    /// env.get_mut("s").unwrap().push_str("c");
+    /// assert_eq!(env.get("s").unwrap(), &Value::from("abc"));
+    /// ```
+    pub fn get_mut(&mut self, name: &str) -> Result<&mut Value, RuntimeError> {
+        self.check_not_const(name)?;
+        self.variables
+            .get_mut(name)
+            .ok_or_else(|| RuntimeError::UndefinedVariable {
+                name: name.to_string(),
+            })
+    }
+
+    /// Defines a constant, which can no longer be assigned or unset.
+    ///
+    /// Defining a constant over an existing (non-constant) variable replaces
+    /// its value.
+    ///
+    /// # Errors
+    ///
+    /// Returns [`RuntimeError::ConstantReassigned`] if the variable is
+    /// already a constant.
+    pub fn define_const(&mut self, name: &str, value: Value) -> Result<(), RuntimeError> {
+        self.check_not_const(name)?;
+        self.set(name, value);
+        self.constants.insert(name.to_string());
+        Ok(())
+    }
+
+    /// Removes a variable binding. Removing an undefined variable does
+    /// nothing.
+    ///
+    /// # Errors
+    ///
+    /// Returns [`RuntimeError::ConstantReassigned`] if the variable is a
+    /// constant.
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::runtime::{Environment, Value};
+    ///
+    /// let mut env = Environment::new();
+    /// env.set("tmp", Value::Int(1));
+    /// env.unset("tmp").unwrap();
+    /// assert!(!env.contains("tmp"));
+    /// ```
+    pub fn unset(&mut self, name: &str) -> Result<(), RuntimeError> {
+        self.check_not_const(name)?;
+        self.variables.remove(name);
+        Ok(())
+    }
+
+    /// Returns `true` if the variable is defined.
+    pub fn contains(&self, name: &str) -> bool {
+        self.variables.contains_key(name)
+    }
+
+    /// Returns `true` if the variable was defined as a constant.
+    pub fn is_const(&self, name: &str) -> bool {
+        self.constants.contains(name)
+    }
+
+    fn check_not_const(&self, name: &str) -> Result<(), RuntimeError> {
+        if self.is_const(name) {
+            return Err(RuntimeError::ConstantReassigned {
+                name: name.to_string(),
+            });
+        }
+        Ok(())
+    }
+
     /// Retrieves a variable's value by name.
     ///
     /// # Arguments
@@ -182,6 +297,32 @@ mod tests {
         assert_eq!(env.get("x").unwrap(), &Value::Int(2));
     }
 
+    #[test]
+    fn test_constant_cannot_change() {
+        let mut env = Environment::new();
+        env.define_const("RATE", Value::Int(8)).unwrap();
+        assert!(env.is_const("RATE"));
+        assert!(matches!(
+            env.assign("RATE", Value::Int(10)),
+            Err(RuntimeError::ConstantReassigned { name }) if name == "RATE"
+        ));
+        assert!(env.define_const("RATE", Value::Int(10)).is_err());
+        assert!(env.unset("RATE").is_err());
+        assert_eq!(env.get("RATE").unwrap(), &Value::Int(8));
+    }
+
+    #[test]
+    fn test_unset() {
+        let mut env = Environment::new();
+        env.assign("x", Value::Int(1)).unwrap();
+        assert!(env.contains("x"));
+        env.unset("x").unwrap();
+        assert!(!env.contains("x"));
+        assert!(env.get("x").is_err());
+        // Unsetting again is a no-op
+        env.unset("x").unwrap();
+    }
+
     #[test]
     fn test_env_many_variables() {
         let mut env = Environment::new();
@@ -198,14 +339,11 @@ mod tests {
 
         // Verify different value types can coexist
         env.set("int_var", Value::Int(42));
-        env.set("str_var", Value::Str("hello".to_string()));
+        env.set("str_var", Value::from("hello"));
         env.set("bool_var", Value::Bool(true));
 
         assert_eq!(env.get("int_var").unwrap(), &Value::Int(42));
-        assert_eq!(
-            env.get("str_var").unwrap(),
-            &Value::Str("hello".to_string())
-        );
+        assert_eq!(env.get("str_var").unwrap(), &Value::from("hello"));
         assert_eq!(env.get("bool_var").unwrap(), &Value::Bool(true));
     }
 
@@ -226,8 +364,8 @@ mod tests {
         env.set("y", Value::Int(1));
         assert_eq!(env.get("y").unwrap(), &Value::Int(1));
 
-        env.set("y", Value::Str("changed".to_string()));
-        assert_eq!(env.get("y").unwrap(), &Value::Str("changed".to_string()));
+        env.set("y", Value::from("changed"));
+        assert_eq!(env.get("y").unwrap(), &Value::from("changed"));
 
         env.set("y", Value::Bool(false));
         assert_eq!(env.get("y").unwrap(), &Value::Bool(false));
@@ -270,14 +408,14 @@ mod tests {
     fn test_env_clone_independence() {
         let mut env1 = Environment::new();
         env1.set("x", Value::Int(10));
-        env1.set("y", Value::Str("original".to_string()));
+        env1.set("y", Value::from("original"));
 
         // Clone the environment
         let mut env2 = env1.clone();
 
         // Verify clone has the same values
         assert_eq!(env2.get("x").unwrap(), &Value::Int(10));
-        assert_eq!(env2.get("y").unwrap(), &Value::Str("original".to_string()));
+        assert_eq!(env2.get("y").unwrap(), &Value::from("original"));
 
         // Modify the clone
         env2.set("x", Value::Int(20));
@@ -292,10 +430,10 @@ mod tests {
         assert_eq!(env2.get("z").unwrap(), &Value::Bool(true));
 
         // Modify original
-        env1.set("y", Value::Str("modified".to_string()));
+        env1.set("y", Value::from("modified"));
 
         // Original changed, clone unchanged
-        assert_eq!(env1.get("y").unwrap(), &Value::Str("modified".to_string()));
-        assert_eq!(env2.get("y").unwrap(), &Value::Str("original".to_string()));
+        assert_eq!(env1.get("y").unwrap(), &Value::from("modified"));
+        assert_eq!(env2.get("y").unwrap(), &Value::from("original"));
     }
 }
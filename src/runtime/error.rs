@@ -9,6 +9,7 @@
 //!
 //! ## Variable Errors
 //! - [`UndefinedVariable`](RuntimeError::UndefinedVariable) - Reference to a variable that hasn't been assigned
+//! - [`ConstantReassigned`](RuntimeError::ConstantReassigned) - Assignment to or removal of a constant
 //!
 //! ## Type Errors
 //! - [`TypeError`](RuntimeError::TypeError) - Operation applied to incompatible type
@@ -16,26 +17,41 @@
 //!
 //! ## Arithmetic Errors
 //! - [`DivisionByZero`](RuntimeError::DivisionByZero) - Division or modulo with zero divisor
+//! - [`InvalidExitCode`](RuntimeError::InvalidExitCode) - Computed exit code is outside 0-255
 //!
 //! ## Structural Errors
 //! - [`MissingStartNode`](RuntimeError::MissingStartNode) - Flowchart lacks a `Start` node
 //! - [`MissingEndNode`](RuntimeError::MissingEndNode) - Flowchart lacks an `End` node
+//! - [`UnknownEntry`](RuntimeError::UnknownEntry) - Requested entry point does not exist
 //!
 //! ## Navigation Errors
 //! - [`NoOutgoingEdge`](RuntimeError::NoOutgoingEdge) - Node has no edge to follow
 //! - [`NoMatchingConditionEdge`](RuntimeError::NoMatchingConditionEdge) - Condition node lacks required Yes/No edge
+//! - [`NoMatchingGuard`](RuntimeError::NoMatchingGuard) - No guard on a node's outgoing edges is true
+//! - [`NoMatchingRule`](RuntimeError::NoMatchingRule) - No rule of a decision table matched
+//! - [`MultipleMatchingRules`](RuntimeError::MultipleMatchingRules) - Several rules of a unique decision table matched
+//! - [`NoMatchingTableEdge`](RuntimeError::NoMatchingTableEdge) - Decision table rule targets a missing edge label
+//! - [`NoMatchingLoopEdge`](RuntimeError::NoMatchingLoopEdge) - Loop node lacks a `body` or `done` edge
 //! - [`NodeNotFound`](RuntimeError::NodeNotFound) - Edge references non-existent node
 //!
+//! ## Built-in Function Errors
+//! - [`InvalidArgument`](RuntimeError::InvalidArgument) - Built-in function received an unusable argument
+//! - [`RandomDenied`](RuntimeError::RandomDenied) - Random function called while randomness is denied
+//!
 //! ## I/O Errors
 //! - [`IoError`](RuntimeError::IoError) - Failed to read input or write output
 
 use std::fmt;
 
+use crate::codes::ErrorCode;
+use crate::i18n::{Lang, Localize};
+
 /// An error that occurred during program execution.
 ///
 /// This enum captures all possible runtime failures, from type mismatches
 /// to structural problems with the flowchart. All variants implement
-/// [`Display`](std::fmt::Display) for user-friendly error messages.
+/// [`Display`](std::fmt::Display) for user-friendly error messages in
+/// English, and [`Localize`] for other languages.
 ///
 /// # Error Handling
 ///
@@ -51,6 +67,7 @@ use std::fmt;
 ///     name: "x".to_string(),
 /// };
 /// assert_eq!(err.to_string(), "Undefined variable: 'x'");
+/// assert_eq!(err.code().to_string(), "MX0302");
 /// ```
 #[derive(Debug, Clone)]
 pub enum RuntimeError {
@@ -64,6 +81,16 @@ pub enum RuntimeError {
     /// - `name` - The undefined variable's identifier
     UndefinedVariable { name: String },
 
+    /// Assignment to, redefinition of, or removal of a constant.
+    ///
+    /// Most such statements are rejected during validation; this covers
+    /// the rest, such as a `const` statement that runs a second time.
+    ///
+    /// # Fields
+    ///
+    /// - `name` - The constant's identifier
+    ConstantReassigned { name: String },
+
     /// Type mismatch in an operation.
     ///
     /// This occurs when an operator or function receives a value of
@@ -104,6 +131,18 @@ pub enum RuntimeError {
     /// this error instead of causing undefined behavior.
     DivisionByZero,
 
+    /// Computed exit code is out of range.
+    ///
+    /// Exit codes given by an expression (`|exit errors|` or an `exit`
+    /// statement) must be between 0 and 255. Under
+    /// [`ExitCodePolicy::Clamp`](super::ExitCodePolicy::Clamp) the value is
+    /// clamped into range instead.
+    ///
+    /// # Fields
+    ///
+    /// - `value` - The out-of-range value
+    InvalidExitCode { value: i64 },
+
     /// Flowchart is missing a `Start` node.
     ///
     /// Every valid flowchart must have exactly one `Start` node where
@@ -114,12 +153,22 @@ pub enum RuntimeError {
 
     /// Flowchart is missing an `End` node.
     ///
-    /// Every valid flowchart must have exactly one `End` node where
-    /// execution terminates. This is normally caught at parse time, but is
-    /// also checked at runtime as a defensive measure for manually
-    /// constructed flowcharts.
+    /// Every valid flowchart must have at least one terminal node (`End` or
+    /// a named terminal) where execution terminates. This is normally
+    /// caught at parse time, but is also checked at runtime as a defensive
+    /// measure for manually constructed flowcharts.
     MissingEndNode,
 
+    /// The requested entry point does not exist.
+    ///
+    /// Returned by [`Interpreter::with_entry`](super::Interpreter::with_entry)
+    /// when no entry node is labeled `Start <name>` with the given name.
+    ///
+    /// # Fields
+    ///
+    /// - `name` - The entry name that was requested
+    UnknownEntry { name: String },
+
     /// Node has no outgoing edge.
     ///
     /// Non-terminal nodes must have at least one outgoing edge.
@@ -146,6 +195,60 @@ pub enum RuntimeError {
         condition_result: bool,
     },
 
+    /// No guard on a node's outgoing edges evaluated to `true`.
+    ///
+    /// Validation requires an `[else]` edge unless the guards are provably
+    /// exhaustive, so this normally only occurs for manually constructed
+    /// flowcharts.
+    ///
+    /// # Fields
+    ///
+    /// - `node_id` - The node whose guards all evaluated to `false`
+    NoMatchingGuard { node_id: String },
+
+    /// No rule of a decision table matched the current inputs.
+    ///
+    /// # Fields
+    ///
+    /// - `node_id` - The identifier of the decision table node
+    NoMatchingRule { node_id: String },
+
+    /// More than one rule of a decision table with the unique hit policy
+    /// matched the current inputs.
+    ///
+    /// # Fields
+    ///
+    /// - `node_id` - The identifier of the decision table node
+    /// - `first` - The 1-based number of the first matching rule
+    /// - `second` - The 1-based number of the second matching rule
+    MultipleMatchingRules {
+        node_id: String,
+        first: usize,
+        second: usize,
+    },
+
+    /// The selected decision table rule names an edge label that the node
+    /// has no outgoing edge for.
+    ///
+    /// Validation checks every rule's target, so this normally only occurs
+    /// for manually constructed flowcharts.
+    ///
+    /// # Fields
+    ///
+    /// - `node_id` - The identifier of the decision table node
+    /// - `label` - The target label that had no matching edge
+    NoMatchingTableEdge { node_id: String, label: String },
+
+    /// Loop node lacks the edge to follow next.
+    ///
+    /// Loop nodes must have both a `body` edge and a `done` edge.
+    ///
+    /// # Fields
+    ///
+    /// - `node_id` - The identifier of the loop node
+    /// - `body` - `true` if the `body` edge was missing, `false` for `done`
+    NoMatchingLoopEdge { node_id: String, body: bool },
+
     /// Edge references a node that doesn't exist.
     ///
     /// This typically indicates a malformed flowchart where an edge's
@@ -156,6 +259,32 @@ pub enum RuntimeError {
     /// - `node_id` - The identifier that couldn't be found
     NodeNotFound { node_id: String },
 
+    /// Built-in function received an argument it cannot use.
+    ///
+    /// This occurs when an argument has the right type but an invalid
+    /// value, such as `rand_int(6, 1)` where the lower bound exceeds
+    /// the upper bound.
+    ///
+    /// # Fields
+    ///
+    /// - `function` - The name of the built-in function
+    /// - `message` - Description of the problem
+    InvalidArgument {
+        function: &'static str,
+        message: String,
+    },
+
+    /// Random built-in function called while randomness is denied.
+    ///
+    /// This occurs when the interpreter is sandboxed with `--deny-random`
+    /// (or [`Interpreter::with_random_denied`](super::Interpreter::with_random_denied))
+    /// and the program calls `rand_int`, `rand_choice`, or `shuffle`.
+    ///
+    /// # Fields
+    ///
+    /// - `function` - The name of the random function that was called
+    RandomDenied { function: &'static str },
+
     /// I/O operation failed.
     ///
     /// This wraps errors from reading user input, writing output, or other I/O operations.
@@ -166,66 +295,56 @@ pub enum RuntimeError {
     IoError { message: String },
 }
 
+impl RuntimeError {
+    /// Returns the error's code.
+    ///
+    /// Variants that are normally caught by validation share the code of
+    /// the validation error, so that a missing `Yes` edge is `MX0101`
+    /// whichever stage finds it.
+    pub fn code(&self) -> ErrorCode {
+        match self {
+            RuntimeError::UndefinedVariable { .. } => ErrorCode::UndefinedVariable,
+            RuntimeError::ConstantReassigned { .. } => ErrorCode::ConstantReassigned,
+            RuntimeError::TypeError { .. } => ErrorCode::TypeMismatch,
+            RuntimeError::CastError { .. } => ErrorCode::InvalidCast,
+            RuntimeError::DivisionByZero => ErrorCode::DivisionByZero,
+            RuntimeError::InvalidExitCode { .. } => ErrorCode::ExitCodeOutOfRange,
+            RuntimeError::MissingStartNode => ErrorCode::MissingStart,
+            RuntimeError::MissingEndNode => ErrorCode::MissingEnd,
+            RuntimeError::UnknownEntry { .. } => ErrorCode::UnknownEntry,
+            RuntimeError::NoOutgoingEdge { .. } => ErrorCode::NoOutgoingEdge,
+            RuntimeError::NoMatchingConditionEdge {
+                condition_result: true,
+                ..
+            } => ErrorCode::MissingYesEdge,
+            RuntimeError::NoMatchingConditionEdge {
+                condition_result: false,
+                ..
+            } => ErrorCode::MissingNoEdge,
+            RuntimeError::NoMatchingGuard { .. } => ErrorCode::NonExhaustiveGuards,
+            RuntimeError::NoMatchingRule { .. } => ErrorCode::NoMatchingRule,
+            RuntimeError::MultipleMatchingRules { .. } => ErrorCode::MultipleMatchingRules,
+            RuntimeError::NoMatchingTableEdge { .. } => ErrorCode::MissingTableEdge,
+            RuntimeError::NoMatchingLoopEdge { .. } => ErrorCode::MissingLoopEdge,
+            RuntimeError::NodeNotFound { .. } => ErrorCode::UndefinedNode,
+            RuntimeError::InvalidArgument { .. } => ErrorCode::InvalidArgument,
+            RuntimeError::RandomDenied { .. } => ErrorCode::RandomDenied,
+            RuntimeError::IoError { .. } => ErrorCode::IoError,
+        }
+    }
+}
+
 impl fmt::Display for RuntimeError {
-    /// Formats a user-friendly error message.
+    /// Formats a user-friendly error message in English.
     ///
     /// Error messages are designed to be helpful for debugging:
     /// - They identify the error type
     /// - They include relevant context (variable names, node IDs, types)
     /// - They avoid internal jargon where possible
+    ///
+    /// Use [`Localize::in_lang`] for another language.
     fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
-        match self {
-            RuntimeError::UndefinedVariable { name } => {
-                write!(f, "Undefined variable: '{}'", name)
-            }
-            RuntimeError::TypeError {
-                expected,
-                actual,
-                operation,
-            } => {
-                write!(
-                    f,
-                    "Type error in {}: expected {}, got {}",
-                    operation, expected, actual
-                )
-            }
-            RuntimeError::CastError {
-                from_type,
-                to_type,
-                value,
-            } => {
-                write!(f, "Cannot cast {} '{}' to {}", from_type, value, to_type)
-            }
-            RuntimeError::DivisionByZero => {
-                write!(f, "Division by zero")
-            }
-            RuntimeError::MissingStartNode => {
-                write!(f, "Missing 'Start' node")
-            }
-            RuntimeError::MissingEndNode => {
-                write!(f, "Missing 'End' node")
-            }
-            RuntimeError::NoOutgoingEdge { node_id } => {
-                write!(f, "No outgoing edge from node '{}'", node_id)
-            }
-            RuntimeError::NoMatchingConditionEdge {
-                node_id,
-                condition_result,
-            } => {
-                write!(
-                    f,
-                    "No '{}' edge from condition node '{}'",
-                    if *condition_result { "Yes" } else { "No" },
-                    node_id
-                )
-            }
-            RuntimeError::NodeNotFound { node_id } => {
-                write!(f, "Node '{}' not found", node_id)
-            }
-            RuntimeError::IoError { message } => {
-                write!(f, "I/O error: {}", message)
-            }
-        }
+        self.localize(Lang::En, f)
     }
 }
 
@@ -243,6 +362,14 @@ mod tests {
         assert_eq!(err.to_string(), "Undefined variable: 'x'");
     }
 
+    #[test]
+    fn test_constant_reassigned_display() {
+        let err = RuntimeError::ConstantReassigned {
+            name: "RATE".to_string(),
+        };
+        assert_eq!(err.to_string(), "Cannot change constant 'RATE'");
+    }
+
     #[test]
     fn test_type_error_display() {
         let err = RuntimeError::TypeError {
@@ -271,4 +398,68 @@ mod tests {
         let err = RuntimeError::DivisionByZero;
         assert_eq!(err.to_string(), "Division by zero");
     }
+
+    #[test]
+    fn test_invalid_exit_code_display() {
+        let err = RuntimeError::InvalidExitCode { value: 300 };
+        assert_eq!(
+            err.to_string(),
+            "Invalid exit code 300: must be an integer between 0 and 255"
+        );
+    }
+
+    #[test]
+    fn test_invalid_argument_display() {
+        let err = RuntimeError::InvalidArgument {
+            function: "rand_int",
+            message: "lower bound 6 is greater than upper bound 1".to_string(),
+        };
+        assert_eq!(
+            err.to_string(),
+            "Invalid argument to rand_int: lower bound 6 is greater than upper bound 1"
+        );
+    }
+
+    #[test]
+    fn test_codes() {
+        assert_eq!(
+            RuntimeError::DivisionByZero.code(),
+            ErrorCode::DivisionByZero
+        );
+        assert_eq!(RuntimeError::DivisionByZero.code().as_str(), "MX0301");
+        let err = RuntimeError::NoMatchingConditionEdge {
+            node_id: "A".to_string(),
+            condition_result: false,
+        };
+        assert_eq!(err.code(), ErrorCode::MissingNoEdge);
+    }
+
+    #[test]
+    fn test_random_denied_display() {
+        let err = RuntimeError::RandomDenied {
+            function: "shuffle",
+        };
+        assert_eq!(err.to_string(), "Randomness is denied: cannot call shuffle");
+    }
+
+    #[test]
+    fn test_japanese_display() {
+        let err = RuntimeError::CastError {
+            from_type: "str",
+            to_type: "int",
+            value: "abc".to_string(),
+        };
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "str 'abc' を int に変換できません"
+        );
+        let err = RuntimeError::NoMatchingLoopEdge {
+            node_id: "L".to_string(),
+            body: false,
+        };
+        assert_eq!(
+            err.in_lang(Lang::Ja).to_string(),
+            "ループノード 'L' に 'done' エッジがありません"
+        );
+    }
 }
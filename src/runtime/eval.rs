@@ -15,6 +15,7 @@
 //! - **Unary operations**: Negation (`-`) and logical NOT (`!`)
 //! - **Binary operations**: Arithmetic, comparison, equality, and logical operators
 //! - **Type casts**: Explicit type conversions via `as`
+//! - **Function calls**: Built-in functions such as `rand_int`, dispatched via [`Builtins`]
 //!
 //! # Operator Semantics
 //!
@@ -54,6 +55,7 @@ use std::io::{self, BufRead};
 
 use crate::ast::{BinaryOp, Expr, TypeName, UnaryOp};
 
+use super::builtins::Builtins;
 use super::env::Environment;
 use super::error::RuntimeError;
 use super::value::Value;
@@ -66,6 +68,7 @@ use super::value::Value;
 /// # Implementors
 ///
 /// - [`StdinReader`] - Reads from standard input
+/// - [`VecInputReader`](super::VecInputReader) - Returns pre-supplied lines, for tests and embedding
 /// - Test code can provide mock implementations
 ///
 /// # Examples
@@ -160,6 +163,7 @@ impl<R: BufRead> InputReader for StdinReader<R> {
 /// * `expr` - The expression AST node to evaluate
 /// * `env` - The variable environment for lookups
 /// * `input_reader` - The input source for `input` expressions
+/// * `builtins` - State for built-in function calls (e.g., the random number generator)
 ///
 /// # Returns
 ///
@@ -174,55 +178,69 @@ impl<R: BufRead> InputReader for StdinReader<R> {
 /// - [`RuntimeError::CastError`] - Type cast failed
 /// - [`RuntimeError::DivisionByZero`] - Division/modulo by zero
 /// - [`RuntimeError::IoError`] - Input reading failed
+/// - [`RuntimeError::InvalidArgument`] - Built-in function received an unusable argument
+/// - [`RuntimeError::RandomDenied`] - Random function called while randomness is denied
 ///
 /// # Examples
 ///
 /// ```
 /// use merx::ast::Expr;
-/// use merx::runtime::{Environment, Value, StdinReader, eval_expr};
+/// use merx::runtime::{Builtins, Environment, Value, StdinReader, eval_expr};
 ///
 /// let mut env = Environment::new();
 /// env.set("x", Value::Int(5));
 ///
 /// let mut input = StdinReader::new();
+/// let mut builtins = Builtins::new();
 /// let expr = Expr::Variable { name: "x".to_string() };
 ///
 /// // eval_expr returns Cow<Value>; use * to access the inner Value
-/// // let result: Cow<Value> = eval_expr(&expr, &env, &mut input)?;
+/// // let result: Cow<Value> = eval_expr(&expr, &env, &mut input, &mut builtins)?;
 /// // let value: &Value = &*result;
 /// ```
 pub fn eval_expr<'a, R: InputReader>(
     expr: &Expr,
     env: &'a Environment,
     input_reader: &mut R,
+    builtins: &mut Builtins,
 ) -> Result<Cow<'a, Value>, RuntimeError> {
     match expr {
         Expr::IntLit { value } => Ok(Cow::Owned(Value::Int(*value))),
-        Expr::StrLit { value } => Ok(Cow::Owned(Value::Str(value.clone()))),
+        Expr::StrLit { value } => Ok(Cow::Owned(Value::from(value.as_str()))),
         Expr::BoolLit { value } => Ok(Cow::Owned(Value::Bool(*value))),
 
         Expr::Variable { name } => env.get(name).map(Cow::Borrowed),
 
         Expr::Input => {
             let line = input_reader.read_line()?;
-            Ok(Cow::Owned(Value::Str(line)))
+            Ok(Cow::Owned(Value::from(line)))
         }
 
         Expr::Unary { op, operand } => {
-            let val = eval_expr(operand, env, input_reader)?;
+            let val = eval_expr(operand, env, input_reader, builtins)?;
             eval_unary(*op, &val).map(Cow::Owned)
         }
 
         Expr::Binary { op, left, right } => {
-            let left_val = eval_expr(left, env, input_reader)?;
-            let right_val = eval_expr(right, env, input_reader)?;
+            let left_val = eval_expr(left, env, input_reader, builtins)?;
+            let right_val = eval_expr(right, env, input_reader, builtins)?;
             eval_binary(*op, &left_val, &right_val).map(Cow::Owned)
         }
 
         Expr::Cast { expr, target_type } => {
-            let val = eval_expr(expr, env, input_reader)?;
+            let val = eval_expr(expr, env, input_reader, builtins)?;
             eval_cast(&val, *target_type).map(Cow::Owned)
         }
+
+        Expr::Call { function, args } => {
+            let values = args
+                .iter()
+                .map(|arg| eval_expr(arg, env, input_reader, builtins).map(Cow::into_owned))
+                .collect::<Result<Vec<_>, _>>()?;
+            builtins.call(*function, &values).map(Cow::Owned)
+        }
+
+        Expr::Defined { name } => Ok(Cow::Owned(Value::Bool(env.contains(name)))),
     }
 }
 
@@ -310,12 +328,21 @@ fn eval_unary(op: UnaryOp, operand: &Value) -> Result<Value, RuntimeError> {
 ///
 /// - [`RuntimeError::TypeError`] - Operand has wrong type for operator
 /// - [`RuntimeError::DivisionByZero`] - Division or modulo by zero
-fn eval_binary(op: BinaryOp, left: &Value, right: &Value) -> Result<Value, RuntimeError> {
+pub(super) fn eval_binary(
+    op: BinaryOp,
+    left: &Value,
+    right: &Value,
+) -> Result<Value, RuntimeError> {
     match op {
         // Addition: int + int → int, str + str → str
         BinaryOp::Add => match (left, right) {
             (Value::Int(l), Value::Int(r)) => Ok(Value::Int(l.wrapping_add(*r))),
-            (Value::Str(l), Value::Str(r)) => Ok(Value::Str(format!("{}{}", l, r))),
+            (Value::Str(l), Value::Str(r)) => {
+                let mut s = String::with_capacity(l.len() + r.len());
+                s.push_str(l);
+                s.push_str(r);
+                Ok(Value::from(s))
+            }
             (Value::Int(_), _) => Err(RuntimeError::TypeError {
                 expected: "int",
                 actual: right.type_name(),
@@ -451,7 +478,7 @@ fn eval_cast(val: &Value, target: TypeName) -> Result<Value, RuntimeError> {
                     .map_err(|_| RuntimeError::CastError {
                         from_type: "str",
                         to_type: "int",
-                        value: s.clone(),
+                        value: s.to_string(),
                     })
             }
             Value::Bool(_) => Err(RuntimeError::CastError {
@@ -460,41 +487,45 @@ fn eval_cast(val: &Value, target: TypeName) -> Result<Value, RuntimeError> {
                 value: val.to_string(),
             }),
         },
-        TypeName::Str => Ok(Value::Str(val.to_string())),
+        TypeName::Str => match val {
+            // Share the contents rather than copying them
+            Value::Str(_) => Ok(val.clone()),
+            _ => Ok(Value::from(val.to_string())),
+        },
     }
 }
 
 #[cfg(test)]
 mod tests {
-    use super::super::test_helpers::MockInputReader;
+    use super::super::capture::VecInputReader;
     use super::*;
 
     #[test]
     fn test_eval_int_literal() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::IntLit { value: 42 };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(42));
     }
 
     #[test]
     fn test_eval_str_literal() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::StrLit {
             value: "hello".to_string(),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str("hello".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from("hello"));
     }
 
     #[test]
     fn test_eval_bool_literal() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::BoolLit { value: true };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Bool(true));
     }
 
@@ -502,22 +533,22 @@ mod tests {
     fn test_eval_variable() {
         let mut env = Environment::new();
         env.set("x", Value::Int(10));
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Variable {
             name: "x".to_string(),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(10));
     }
 
     #[test]
     fn test_eval_undefined_variable() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Variable {
             name: "x".to_string(),
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(
             result,
             Err(RuntimeError::UndefinedVariable { name }) if name == "x"
@@ -527,131 +558,131 @@ mod tests {
     #[test]
     fn test_eval_input() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec!["hello"]);
+        let mut input = VecInputReader::new(["hello"]);
         let expr = Expr::Input;
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str("hello".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from("hello"));
     }
 
     #[test]
     fn test_eval_unary_not() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Unary {
             op: UnaryOp::Not,
             operand: Box::new(Expr::BoolLit { value: true }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Bool(false));
     }
 
     #[test]
     fn test_eval_unary_neg() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Unary {
             op: UnaryOp::Neg,
             operand: Box::new(Expr::IntLit { value: 42 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(-42));
     }
 
     #[test]
     fn test_eval_binary_add() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Add,
             left: Box::new(Expr::IntLit { value: 1 }),
             right: Box::new(Expr::IntLit { value: 2 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(3));
     }
 
     #[test]
     fn test_eval_binary_sub() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Sub,
             left: Box::new(Expr::IntLit { value: 5 }),
             right: Box::new(Expr::IntLit { value: 3 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(2));
     }
 
     #[test]
     fn test_eval_binary_mul() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Mul,
             left: Box::new(Expr::IntLit { value: 3 }),
             right: Box::new(Expr::IntLit { value: 4 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(12));
     }
 
     #[test]
     fn test_eval_binary_div() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Div,
             left: Box::new(Expr::IntLit { value: 10 }),
             right: Box::new(Expr::IntLit { value: 3 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(3));
     }
 
     #[test]
     fn test_eval_binary_mod() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Mod,
             left: Box::new(Expr::IntLit { value: 10 }),
             right: Box::new(Expr::IntLit { value: 3 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(1));
     }
 
     #[test]
     fn test_eval_division_by_zero() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Div,
             left: Box::new(Expr::IntLit { value: 10 }),
             right: Box::new(Expr::IntLit { value: 0 }),
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(result, Err(RuntimeError::DivisionByZero)));
     }
 
     #[test]
     fn test_eval_mod_by_zero() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Mod,
             left: Box::new(Expr::IntLit { value: 10 }),
             right: Box::new(Expr::IntLit { value: 0 }),
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(result, Err(RuntimeError::DivisionByZero)));
     }
 
     #[test]
     fn test_eval_comparison() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
 
         let expr_lt = Expr::Binary {
             op: BinaryOp::Lt,
@@ -659,7 +690,7 @@ mod tests {
             right: Box::new(Expr::IntLit { value: 2 }),
         };
         assert_eq!(
-            *eval_expr(&expr_lt, &env, &mut input).unwrap(),
+            *eval_expr(&expr_lt, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(true)
         );
 
@@ -669,7 +700,7 @@ mod tests {
             right: Box::new(Expr::IntLit { value: 2 }),
         };
         assert_eq!(
-            *eval_expr(&expr_le, &env, &mut input).unwrap(),
+            *eval_expr(&expr_le, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(true)
         );
 
@@ -679,7 +710,7 @@ mod tests {
             right: Box::new(Expr::IntLit { value: 2 }),
         };
         assert_eq!(
-            *eval_expr(&expr_gt, &env, &mut input).unwrap(),
+            *eval_expr(&expr_gt, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(true)
         );
 
@@ -689,7 +720,7 @@ mod tests {
             right: Box::new(Expr::IntLit { value: 2 }),
         };
         assert_eq!(
-            *eval_expr(&expr_ge, &env, &mut input).unwrap(),
+            *eval_expr(&expr_ge, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(true)
         );
     }
@@ -697,7 +728,7 @@ mod tests {
     #[test]
     fn test_eval_equality() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
 
         let expr_eq = Expr::Binary {
             op: BinaryOp::Eq,
@@ -705,7 +736,7 @@ mod tests {
             right: Box::new(Expr::IntLit { value: 1 }),
         };
         assert_eq!(
-            *eval_expr(&expr_eq, &env, &mut input).unwrap(),
+            *eval_expr(&expr_eq, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(true)
         );
 
@@ -715,7 +746,7 @@ mod tests {
             right: Box::new(Expr::IntLit { value: 2 }),
         };
         assert_eq!(
-            *eval_expr(&expr_ne, &env, &mut input).unwrap(),
+            *eval_expr(&expr_ne, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(true)
         );
 
@@ -728,7 +759,7 @@ mod tests {
             }),
         };
         assert_eq!(
-            *eval_expr(&expr_eq_diff_type, &env, &mut input).unwrap(),
+            *eval_expr(&expr_eq_diff_type, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(false)
         );
     }
@@ -736,7 +767,7 @@ mod tests {
     #[test]
     fn test_eval_logical() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
 
         let expr_and = Expr::Binary {
             op: BinaryOp::And,
@@ -744,7 +775,7 @@ mod tests {
             right: Box::new(Expr::BoolLit { value: false }),
         };
         assert_eq!(
-            *eval_expr(&expr_and, &env, &mut input).unwrap(),
+            *eval_expr(&expr_and, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(false)
         );
 
@@ -754,7 +785,7 @@ mod tests {
             right: Box::new(Expr::BoolLit { value: false }),
         };
         assert_eq!(
-            *eval_expr(&expr_or, &env, &mut input).unwrap(),
+            *eval_expr(&expr_or, &env, &mut input, &mut Builtins::new()).unwrap(),
             Value::Bool(true)
         );
     }
@@ -762,59 +793,59 @@ mod tests {
     #[test]
     fn test_eval_cast_str_to_int() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Cast {
             expr: Box::new(Expr::StrLit {
                 value: "123".to_string(),
             }),
             target_type: TypeName::Int,
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(123));
     }
 
     #[test]
     fn test_eval_cast_str_to_int_invalid() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Cast {
             expr: Box::new(Expr::StrLit {
                 value: "abc".to_string(),
             }),
             target_type: TypeName::Int,
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(result, Err(RuntimeError::CastError { .. })));
     }
 
     #[test]
     fn test_eval_cast_int_to_str() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Cast {
             expr: Box::new(Expr::IntLit { value: 42 }),
             target_type: TypeName::Str,
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str("42".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from("42"));
     }
 
     #[test]
     fn test_eval_cast_bool_to_str() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Cast {
             expr: Box::new(Expr::BoolLit { value: true }),
             target_type: TypeName::Str,
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str("true".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from("true"));
     }
 
     #[test]
     fn test_eval_binary_add_str() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Add,
             left: Box::new(Expr::StrLit {
@@ -824,14 +855,14 @@ mod tests {
                 value: "bar".to_string(),
             }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str("foobar".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from("foobar"));
     }
 
     #[test]
     fn test_eval_type_error_arithmetic() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Add,
             left: Box::new(Expr::IntLit { value: 1 }),
@@ -839,7 +870,7 @@ mod tests {
                 value: "2".to_string(),
             }),
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(
             result,
             Err(RuntimeError::TypeError {
@@ -853,7 +884,7 @@ mod tests {
     #[test]
     fn test_eval_type_error_add_str_plus_int() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Add,
             left: Box::new(Expr::StrLit {
@@ -861,7 +892,7 @@ mod tests {
             }),
             right: Box::new(Expr::IntLit { value: 1 }),
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(
             result,
             Err(RuntimeError::TypeError {
@@ -875,13 +906,13 @@ mod tests {
     #[test]
     fn test_eval_type_error_add_bool() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Add,
             left: Box::new(Expr::BoolLit { value: true }),
             right: Box::new(Expr::IntLit { value: 1 }),
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(
             result,
             Err(RuntimeError::TypeError {
@@ -919,8 +950,8 @@ mod tests {
         };
 
         let env = Environment::new();
-        let mut mock_input = MockInputReader::new(vec![]);
-        eval_expr(&expr, &env, &mut mock_input)
+        let mut mock_input = VecInputReader::default();
+        eval_expr(&expr, &env, &mut mock_input, &mut Builtins::new())
             .expect("Evaluation failed")
             .into_owned()
     }
@@ -947,8 +978,8 @@ mod tests {
         };
 
         let env = Environment::new();
-        let mut mock_input = MockInputReader::new(vec![]);
-        eval_expr(&expr, &env, &mut mock_input)
+        let mut mock_input = VecInputReader::default();
+        eval_expr(&expr, &env, &mut mock_input, &mut Builtins::new())
             .expect("Evaluation failed")
             .into_owned()
     }
@@ -991,9 +1022,9 @@ mod tests {
     #[test]
     fn test_int_max_value() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::IntLit { value: i64::MAX };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(i64::MAX));
         assert_eq!(*result, Value::Int(9223372036854775807));
     }
@@ -1001,9 +1032,9 @@ mod tests {
     #[test]
     fn test_int_min_value() {
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::IntLit { value: i64::MIN };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(i64::MIN));
         assert_eq!(*result, Value::Int(-9223372036854775808));
     }
@@ -1012,13 +1043,13 @@ mod tests {
     fn test_int_overflow_add() {
         // i64::MAX + 1 should wrap to i64::MIN (wrapping_add behavior)
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Add,
             left: Box::new(Expr::IntLit { value: i64::MAX }),
             right: Box::new(Expr::IntLit { value: 1 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(i64::MIN));
     }
 
@@ -1028,13 +1059,13 @@ mod tests {
         // i64::MAX = 9223372036854775807
         // i64::MAX * 2 wraps to -2
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Mul,
             left: Box::new(Expr::IntLit { value: i64::MAX }),
             right: Box::new(Expr::IntLit { value: 2 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(i64::MAX.wrapping_mul(2)));
         assert_eq!(*result, Value::Int(-2));
     }
@@ -1043,13 +1074,13 @@ mod tests {
     fn test_negative_mod() {
         // -10 % 3 should be -1 (Rust's remainder semantics)
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Binary {
             op: BinaryOp::Mod,
             left: Box::new(Expr::IntLit { value: -10 }),
             right: Box::new(Expr::IntLit { value: 3 }),
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(-1));
     }
 
@@ -1057,14 +1088,14 @@ mod tests {
     fn test_cast_max_int_string() {
         // "9223372036854775807" as int should succeed with i64::MAX
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Cast {
             expr: Box::new(Expr::StrLit {
                 value: "9223372036854775807".to_string(),
             }),
             target_type: TypeName::Int,
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(i64::MAX));
     }
 
@@ -1072,14 +1103,14 @@ mod tests {
     fn test_cast_overflow_string() {
         // "9223372036854775808" as int should fail (overflow)
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Cast {
             expr: Box::new(Expr::StrLit {
                 value: "9223372036854775808".to_string(),
             }),
             target_type: TypeName::Int,
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(
             result,
             Err(RuntimeError::CastError {
@@ -1094,14 +1125,14 @@ mod tests {
     fn test_cast_negative_string() {
         // "-42" as int should succeed with -42
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Cast {
             expr: Box::new(Expr::StrLit {
                 value: "-42".to_string(),
             }),
             target_type: TypeName::Int,
         };
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         assert_eq!(*result, Value::Int(-42));
     }
 
@@ -1109,14 +1140,14 @@ mod tests {
     fn test_cast_empty_string() {
         // "" as int should fail
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let expr = Expr::Cast {
             expr: Box::new(Expr::StrLit {
                 value: "".to_string(),
             }),
             target_type: TypeName::Int,
         };
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(
             result,
             Err(RuntimeError::CastError {
@@ -1129,12 +1160,12 @@ mod tests {
 
     #[test]
     fn test_input_eof_mock_reader() {
-        // MockInputReader returns IoError when no more input is available
+        // VecInputReader returns IoError when no more input is available
         let env = Environment::new();
-        let mut input = MockInputReader::new(vec![]); // No input available
+        let mut input = VecInputReader::default(); // No input available
         let expr = Expr::Input;
 
-        let result = eval_expr(&expr, &env, &mut input);
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new());
         assert!(matches!(
             result,
             Err(RuntimeError::IoError { message }) if message == "No more input"
@@ -1154,9 +1185,9 @@ mod tests {
         };
         let expr = Expr::Input;
 
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         // At EOF, read_line returns Ok("") (empty string after trimming)
-        assert_eq!(*result, Value::Str("".to_string()));
+        assert_eq!(*result, Value::from(""));
     }
 
     #[test]
@@ -1169,8 +1200,8 @@ mod tests {
         let mut input = StdinReader { reader: buffer };
         let expr = Expr::Input;
 
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str("".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from(""));
     }
 
     #[test]
@@ -1183,8 +1214,8 @@ mod tests {
         let mut input = StdinReader { reader: buffer };
         let expr = Expr::Input;
 
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str("".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from(""));
     }
 
     #[test]
@@ -1197,9 +1228,9 @@ mod tests {
         let mut input = StdinReader { reader: buffer };
         let expr = Expr::Input;
 
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         // Only trailing \r and \n are trimmed, spaces are preserved
-        assert_eq!(*result, Value::Str("   ".to_string()));
+        assert_eq!(*result, Value::from("   "));
     }
 
     #[test]
@@ -1212,9 +1243,9 @@ mod tests {
         let mut input = StdinReader { reader: buffer };
         let expr = Expr::Input;
 
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
         // Only trailing \r and \n are trimmed, tabs are preserved
-        assert_eq!(*result, Value::Str("\t\t".to_string()));
+        assert_eq!(*result, Value::from("\t\t"));
     }
 
     #[test]
@@ -1227,8 +1258,8 @@ mod tests {
         let mut input = StdinReader { reader: buffer };
         let expr = Expr::Input;
 
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str(" \t \t ".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from(" \t \t "));
     }
 
     #[test]
@@ -1242,18 +1273,18 @@ mod tests {
 
         let expr = Expr::Input;
 
-        let result1 = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result1, Value::Str("first".to_string()));
+        let result1 = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result1, Value::from("first"));
 
-        let result2 = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result2, Value::Str("second".to_string()));
+        let result2 = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result2, Value::from("second"));
 
-        let result3 = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result3, Value::Str("third".to_string()));
+        let result3 = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result3, Value::from("third"));
 
         // Fourth read should return empty string (EOF)
-        let result4 = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result4, Value::Str("".to_string()));
+        let result4 = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result4, Value::from(""));
     }
 
     #[test]
@@ -1266,7 +1297,7 @@ mod tests {
         let mut input = StdinReader { reader: buffer };
         let expr = Expr::Input;
 
-        let result = eval_expr(&expr, &env, &mut input).unwrap();
-        assert_eq!(*result, Value::Str("no newline at end".to_string()));
+        let result = eval_expr(&expr, &env, &mut input, &mut Builtins::new()).unwrap();
+        assert_eq!(*result, Value::from("no newline at end"));
     }
 }
@@ -11,18 +11,28 @@
 //! | `Println` | `println expr` | Writes value to stdout with newline |
 //! | `Print` | `print expr` | Writes value to stdout without newline |
 //! | `Error` | `error expr` | Writes value to stderr |
+//! | `Sleep` | `sleep expr` | Waits for the given number of milliseconds |
 //!
 //! # Output Handling
 //!
 //! The executor uses the [`OutputWriter`] trait for output operations,
 //! allowing dependency injection for testing. Output is line-based:
 //! each `println` or `error` statement produces one line.
+//!
+//! Writers may buffer output. [`OutputWriter::flush`] is called before
+//! reading input, so prompts printed with `print` are visible, and when
+//! execution ends or fails. [`BufferedStdioWriter`] additionally flushes
+//! stdout before every write to stderr to preserve interleaving.
+
+use std::io::{self, BufWriter, StdoutLock, Write};
 
-use crate::ast::Statement;
+use crate::ast::{BinaryOp, Expr, Statement};
 
+use super::builtins::Builtins;
 use super::env::Environment;
 use super::error::RuntimeError;
-use super::eval::{InputReader, eval_expr};
+use super::eval::{InputReader, eval_binary, eval_expr};
+use super::value::Value;
 
 /// Abstraction for writing program output.
 ///
@@ -32,6 +42,8 @@ use super::eval::{InputReader, eval_expr};
 /// # Implementors
 ///
 /// - [`StdioWriter`] - Writes to standard output/error
+/// - [`BufferedStdioWriter`] - Writes to standard output/error through a buffer
+/// - [`CapturingWriter`](super::CapturingWriter) - Records output in memory, for tests and embedding
 /// - Test code can provide mock implementations
 ///
 /// # Examples
@@ -65,13 +77,95 @@ pub trait OutputWriter {
 
     /// Writes to standard output without a trailing newline.
     ///
-    /// The implementation should flush stdout to ensure immediate output.
+    /// The implementation need not flush: the runtime calls
+    /// [`flush`](OutputWriter::flush) before the program waits, so a prompt
+    /// written with `print` is visible by then.
     fn write_stdout_no_newline(&mut self, s: &str) -> Result<(), RuntimeError>;
 
     /// Writes a line to standard error.
     ///
     /// The implementation should append a newline after the content.
     fn write_stderr(&mut self, s: &str) -> Result<(), RuntimeError>;
+
+    /// Flushes any buffered output.
+    ///
+    /// Called before reading input, before `sleep` pauses, and when
+    /// execution ends, whether normally or with an error. The default implementation does nothing,
+    /// which is correct for writers that do not buffer.
+    fn flush(&mut self) -> Result<(), RuntimeError> {
+        Ok(())
+    }
+
+    /// Receives a structured output event.
+    ///
+    /// This is the entry point the runtime uses for every `println`, `print`,
+    /// and `error` statement. Override it to observe the original [`Value`]
+    /// and the node that produced the output, e.g., to attribute each line
+    /// to a node in a GUI.
+    ///
+    /// The default implementation formats the value and forwards it to
+    /// [`write_stdout`](OutputWriter::write_stdout),
+    /// [`write_stdout_no_newline`](OutputWriter::write_stdout_no_newline), or
+    /// [`write_stderr`](OutputWriter::write_stderr), so writers that only
+    /// implement the string methods keep working unchanged.
+    fn on_output(&mut self, event: OutputEvent<'_>) -> Result<(), RuntimeError> {
+        let text = event.value.to_string();
+        match (event.stream, event.newline) {
+            (OutputStream::Stdout, true) => self.write_stdout(&text),
+            (OutputStream::Stdout, false) => self.write_stdout_no_newline(&text),
+            (OutputStream::Stderr, _) => self.write_stderr(&text),
+        }
+    }
+}
+
+/// The destination stream of an [`OutputEvent`].
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum OutputStream {
+    /// Standard output, written by `println` and `print`.
+    Stdout,
+
+    /// Standard error, written by `error`.
+    Stderr,
+}
+
+/// A single piece of program output, with its origin.
+///
+/// Passed to [`OutputWriter::on_output`] for every output statement.
+///
+/// # Examples
+///
+/// ```
+/// use merx::runtime::{OutputEvent, OutputStream, OutputWriter, RuntimeError};
+///
+/// /// Collects `(node_id, text)` pairs for stdout.
+/// struct Attributed(Vec<(String, String)>);
+///
+/// impl OutputWriter for Attributed {
+///     fn write_stdout(&mut self, _s: &str) -> Result<(), RuntimeError> { Ok(()) }
+///     fn write_stdout_no_newline(&mut self, _s: &str) -> Result<(), RuntimeError> { Ok(()) }
+///     fn write_stderr(&mut self, _s: &str) -> Result<(), RuntimeError> { Ok(()) }
+///
+///     fn on_output(&mut self, event: OutputEvent<'_>) -> Result<(), RuntimeError> {
+///         if event.stream == OutputStream::Stdout {
+///             self.0.push((event.node_id.to_string(), event.value.to_string()));
+///         }
+///         Ok(())
+///     }
+/// }
+/// ```
+#[derive(Debug, Clone, Copy)]
+pub struct OutputEvent<'a> {
+    /// The stream the output is destined for.
+    pub stream: OutputStream,
+
+    /// The evaluated value, before formatting.
+    pub value: &'a Value,
+
+    /// The ID of the node whose statement produced the output.
+    pub node_id: &'a str,
+
+    /// Whether a newline follows the value (`false` only for `print`).
+    pub newline: bool,
 }
 
 /// Output writer that writes to standard output and error.
@@ -123,6 +217,114 @@ impl OutputWriter for StdioWriter {
     }
 }
 
+/// Output writer that buffers standard output.
+///
+/// Writes to stdout go through a [`BufWriter`] over a locked stdout handle,
+/// so output-heavy programs avoid a system call and a lock per statement.
+/// This is the writer used by the `merx run` command unless `--unbuffered`
+/// is given.
+///
+/// # Flushing
+///
+/// Buffered stdout is flushed:
+///
+/// - Before each write to stderr, so stdout and stderr stay in program order
+/// - When [`flush`](OutputWriter::flush) is called, which the interpreter does
+///   before reading input and when execution ends or fails
+/// - When the writer is dropped
+///
+/// # Examples
+///
+/// ```
+/// use merx::runtime::BufferedStdioWriter;
+///
+/// let writer = BufferedStdioWriter::new();
+/// ```
+pub struct BufferedStdioWriter {
+    stdout: BufWriter<StdoutLock<'static>>,
+}
+
+impl BufferedStdioWriter {
+    /// Creates a new buffered stdio writer.
+    ///
+    /// The stdout lock is held for the writer's lifetime.
+    pub fn new() -> Self {
+        Self {
+            stdout: BufWriter::new(io::stdout().lock()),
+        }
+    }
+}
+
+impl Default for BufferedStdioWriter {
+    fn default() -> Self {
+        Self::new()
+    }
+}
+
+impl OutputWriter for BufferedStdioWriter {
+    fn write_stdout(&mut self, s: &str) -> Result<(), RuntimeError> {
+        writeln!(self.stdout, "{}", s).map_err(|e| RuntimeError::IoError {
+            message: e.to_string(),
+        })
+    }
+
+    fn write_stdout_no_newline(&mut self, s: &str) -> Result<(), RuntimeError> {
+        write!(self.stdout, "{}", s).map_err(|e| RuntimeError::IoError {
+            message: e.to_string(),
+        })
+    }
+
+    fn write_stderr(&mut self, s: &str) -> Result<(), RuntimeError> {
+        self.flush()?;
+        writeln!(io::stderr(), "{}", s).map_err(|e| RuntimeError::IoError {
+            message: e.to_string(),
+        })
+    }
+
+    fn flush(&mut self) -> Result<(), RuntimeError> {
+        self.stdout.flush().map_err(|e| RuntimeError::IoError {
+            message: e.to_string(),
+        })
+    }
+}
+
+/// Input reader adapter that flushes an output writer before each read.
+///
+/// Statements and conditions evaluate expressions through this adapter so
+/// that a buffered prompt (e.g., `print 'Name: '`) is visible before the
+/// program blocks on `input`.
+pub(crate) struct FlushingReader<'a, R: InputReader, W: OutputWriter> {
+    reader: &'a mut R,
+    writer: &'a mut W,
+}
+
+impl<'a, R: InputReader, W: OutputWriter> FlushingReader<'a, R, W> {
+    /// Wraps `reader` so that `writer` is flushed before each read.
+    pub(crate) fn new(reader: &'a mut R, writer: &'a mut W) -> Self {
+        Self { reader, writer }
+    }
+}
+
+impl<R: InputReader, W: OutputWriter> InputReader for FlushingReader<'_, R, W> {
+    fn read_line(&mut self) -> Result<String, RuntimeError> {
+        self.writer.flush()?;
+        self.reader.read_line()
+    }
+}
+
+/// A value produced by a statement for the interpreter to record.
+///
+/// Neither effect changes control flow; see [`exec_statement`].
+#[derive(Debug, Clone, PartialEq)]
+pub enum StatementEffect {
+    /// A `return` statement set the program's result value.
+    Return(Value),
+
+    /// An `exit` statement set the exit code. The value is not yet checked;
+    /// see [`ExitCodePolicy`](super::ExitCodePolicy).
+    Exit(Value),
+}
+
 /// Executes a single statement.
 ///
 /// This function handles all statement types, evaluating expressions
@@ -131,19 +333,24 @@ impl OutputWriter for StdioWriter {
 /// # Arguments
 ///
 /// * `stmt` - The statement AST node to execute
+/// * `node_id` - The ID of the node containing the statement, reported in [`OutputEvent`]s
 /// * `env` - The variable environment (may be modified by assignment)
 /// * `input_reader` - The input source (used if statement contains `input` expression)
 /// * `output_writer` - The output destination for print/error statements
+/// * `builtins` - State for built-in function calls (e.g., the random number generator)
 ///
 /// # Returns
 ///
-/// `Ok(())` on success, or a [`RuntimeError`] if execution fails.
+/// `Ok(Some(effect))` for a `return` or `exit` statement, carrying the value
+/// the interpreter should record; `Ok(None)` for any other statement.
+/// Returns a [`RuntimeError`] if execution fails.
 ///
 /// # Errors
 ///
 /// Any error from expression evaluation or output writing is propagated. Common errors:
 ///
 /// - [`RuntimeError::UndefinedVariable`] - Expression references undefined variable
+/// - [`RuntimeError::ConstantReassigned`] - Statement assigns, redefines, or unsets a constant
 /// - [`RuntimeError::TypeError`] - Type mismatch in expression
 /// - [`RuntimeError::IoError`] - Input reading or output writing failed
 ///
@@ -151,7 +358,7 @@ impl OutputWriter for StdioWriter {
 ///
 /// ```ignore
 /// use merx::ast::{Statement, Expr};
-/// use merx::runtime::{Environment, exec_statement, StdinReader, StdioWriter};
+/// use merx::runtime::{Builtins, Environment, exec_statement, StdinReader, StdioWriter};
 ///
 /// let stmt = Statement::Println {
 ///     expr: Expr::StrLit { value: "Hello".to_string() },
@@ -160,69 +367,273 @@ impl OutputWriter for StdioWriter {
 /// let mut env = Environment::new();
 /// let mut input = StdinReader::new();
 /// let mut output = StdioWriter::new();
+/// let mut builtins = Builtins::new();
 ///
-/// exec_statement(&stmt, &mut env, &mut input, &mut output).unwrap();
+/// exec_statement(&stmt, "A", &mut env, &mut input, &mut output, &mut builtins).unwrap();
 /// // Prints: Hello
 /// ```
 pub fn exec_statement<R: InputReader, W: OutputWriter>(
     stmt: &Statement,
+    node_id: &str,
     env: &mut Environment,
     input_reader: &mut R,
     output_writer: &mut W,
-) -> Result<(), RuntimeError> {
+    builtins: &mut Builtins,
+) -> Result<Option<StatementEffect>, RuntimeError> {
     match stmt {
         Statement::Assign { variable, value } => {
-            let val = eval_expr(value, env, input_reader)?.into_owned();
-            env.set(variable, val);
-            Ok(())
+            if let Expr::Binary {
+                op: BinaryOp::Add,
+                left,
+                right,
+            } = value
+                && matches!(&**left, Expr::Variable { name } if name == variable)
+                && matches!(env.get(variable), Ok(Value::Str(_)))
+            {
+                append_in_place(variable, right, env, input_reader, output_writer, builtins)?;
+                return Ok(None);
+            }
+            let val = eval_expr(
+                value,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?
+            .into_owned();
+            env.assign(variable, val)?;
+            Ok(None)
+        }
+        Statement::Const { variable, value } => {
+            let val = eval_expr(
+                value,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?
+            .into_owned();
+            env.define_const(variable, val)?;
+            Ok(None)
+        }
+        Statement::Unset { variable } => {
+            env.unset(variable)?;
+            Ok(None)
+        }
+        Statement::CompoundAssign {
+            variable,
+            op,
+            value,
+        } => {
+            if *op == BinaryOp::Add && matches!(env.get(variable), Ok(Value::Str(_))) {
+                append_in_place(variable, value, env, input_reader, output_writer, builtins)?;
+                return Ok(None);
+            }
+            // Check the variable before evaluating the value, which may read
+            // input
+            env.get(variable)?;
+            let rhs = eval_expr(
+                value,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?;
+            let val = eval_binary(*op, env.get(variable)?, &rhs)?;
+            env.assign(variable, val)?;
+            Ok(None)
+        }
+        Statement::ParallelAssign { variables, values } => {
+            let mut reader = FlushingReader::new(input_reader, output_writer);
+            let vals = values
+                .iter()
+                .map(|value| Ok(eval_expr(value, env, &mut reader, builtins)?.into_owned()))
+                .collect::<Result<Vec<Value>, RuntimeError>>()?;
+            for (variable, val) in variables.iter().zip(vals) {
+                env.assign(variable, val)?;
+            }
+            Ok(None)
         }
         Statement::Println { expr } => {
-            let val = eval_expr(expr, env, input_reader)?;
-            output_writer.write_stdout(&val.to_string())?;
-            Ok(())
+            let val = eval_expr(
+                expr,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?;
+            output_writer.on_output(OutputEvent {
+                stream: OutputStream::Stdout,
+                value: &val,
+                node_id,
+                newline: true,
+            })?;
+            Ok(None)
         }
         Statement::Print { expr } => {
-            let val = eval_expr(expr, env, input_reader)?;
-            output_writer.write_stdout_no_newline(&val.to_string())?;
-            Ok(())
+            let val = eval_expr(
+                expr,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?;
+            output_writer.on_output(OutputEvent {
+                stream: OutputStream::Stdout,
+                value: &val,
+                node_id,
+                newline: false,
+            })?;
+            Ok(None)
         }
         Statement::Error { message } => {
-            let val = eval_expr(message, env, input_reader)?;
-            output_writer.write_stderr(&val.to_string())?;
-            Ok(())
+            let val = eval_expr(
+                message,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?;
+            output_writer.on_output(OutputEvent {
+                stream: OutputStream::Stderr,
+                value: &val,
+                node_id,
+                newline: true,
+            })?;
+            Ok(None)
+        }
+        Statement::Sleep { duration } => {
+            let val = eval_expr(
+                duration,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?;
+            let ms = val.as_int().ok_or_else(|| RuntimeError::TypeError {
+                expected: "int",
+                actual: val.type_name(),
+                operation: "sleep".to_string(),
+            })?;
+            // Show output written so far before pausing, such as progress
+            // printed in a loop
+            output_writer.flush()?;
+            builtins.sleep(ms)?;
+            Ok(None)
         }
+        Statement::Return { value } => {
+            let val = eval_expr(
+                value,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?
+            .into_owned();
+            Ok(Some(StatementEffect::Return(val)))
+        }
+        Statement::Exit { code } => {
+            let val = eval_expr(
+                code,
+                env,
+                &mut FlushingReader::new(input_reader, output_writer),
+                builtins,
+            )?
+            .into_owned();
+            Ok(Some(StatementEffect::Exit(val)))
+        }
+    }
+}
+
+/// Executes `s = s + expr` or `s += expr` for a string variable `s`.
+///
+/// When `expr` is also a string it is appended to the variable's value in
+/// place, so building a string in a loop takes linear rather than quadratic
+/// time. Otherwise the concatenation fails as usual.
+fn append_in_place<R: InputReader, W: OutputWriter>(
+    variable: &str,
+    suffix: &Expr,
+    env: &mut Environment,
+    input_reader: &mut R,
+    output_writer: &mut W,
+    builtins: &mut Builtins,
+) -> Result<(), RuntimeError> {
+    let rhs = eval_expr(
+        suffix,
+        env,
+        &mut FlushingReader::new(input_reader, output_writer),
+        builtins,
+    )?
+    .into_owned();
+    if let Value::Str(suffix) = &rhs {
+        env.get_mut(variable)?.push_str(suffix);
+        return Ok(());
     }
+    let val = eval_binary(BinaryOp::Add, env.get(variable)?, &rhs)?;
+    env.assign(variable, val)
 }
 
 #[cfg(test)]
 mod tests {
-    use super::super::test_helpers::{FailingOutputWriter, MockInputReader, MockOutputWriter};
+    use super::super::capture::{CapturingWriter, VecInputReader};
+    use super::super::test_helpers::FailingOutputWriter;
     use super::*;
-    use crate::ast::Expr;
+    use crate::ast::{BinaryOp, Expr};
 
     #[test]
     fn test_exec_assign() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
 
         let stmt = Statement::Assign {
             variable: "x".to_string(),
             value: Expr::IntLit { value: 42 },
         };
 
-        exec_statement(&stmt, &mut env, &mut input, &mut output).unwrap();
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
 
         assert_eq!(env.get("x").unwrap(), &super::super::value::Value::Int(42));
-        assert!(output.stdout.is_empty());
-        assert!(output.stderr.is_empty());
+        assert!(output.stdout().is_empty());
+        assert!(output.stderr().is_empty());
+    }
+
+    #[test]
+    fn test_exec_return() {
+        let mut env = Environment::new();
+        env.set("x", Value::Int(20));
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
+
+        let stmt = Statement::Return {
+            value: Expr::Binary {
+                op: crate::ast::BinaryOp::Add,
+                left: Box::new(Expr::Variable {
+                    name: "x".to_string(),
+                }),
+                right: Box::new(Expr::IntLit { value: 1 }),
+            },
+        };
+
+        let returned = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(returned, Some(StatementEffect::Return(Value::Int(21))));
+        assert!(output.stdout().is_empty());
     }
 
     #[test]
     fn test_exec_print() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
 
         let stmt = Statement::Println {
             expr: Expr::StrLit {
@@ -230,32 +641,48 @@ mod tests {
             },
         };
 
-        exec_statement(&stmt, &mut env, &mut input, &mut output).unwrap();
-
-        assert_eq!(output.stdout, vec!["hello"]);
-        assert!(output.stderr.is_empty());
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(output.stdout(), vec!["hello"]);
+        assert!(output.stderr().is_empty());
     }
 
     #[test]
     fn test_exec_print_int() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
 
         let stmt = Statement::Println {
             expr: Expr::IntLit { value: 42 },
         };
 
-        exec_statement(&stmt, &mut env, &mut input, &mut output).unwrap();
-
-        assert_eq!(output.stdout, vec!["42"]);
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(output.stdout(), vec!["42"]);
     }
 
     #[test]
     fn test_exec_print_no_newline() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
 
         let stmt = Statement::Print {
             expr: Expr::StrLit {
@@ -263,17 +690,25 @@ mod tests {
             },
         };
 
-        exec_statement(&stmt, &mut env, &mut input, &mut output).unwrap();
-
-        assert_eq!(output.stdout, vec!["hello"]);
-        assert!(output.stderr.is_empty());
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(output.stdout(), vec!["hello"]);
+        assert!(output.stderr().is_empty());
     }
 
     #[test]
     fn test_exec_error() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
 
         let stmt = Statement::Error {
             message: Expr::StrLit {
@@ -281,29 +716,44 @@ mod tests {
             },
         };
 
-        let result = exec_statement(&stmt, &mut env, &mut input, &mut output);
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
 
         assert!(result.is_ok());
-        assert!(output.stdout.is_empty());
-        assert_eq!(output.stderr, vec!["error message"]);
+        assert!(output.stdout().is_empty());
+        assert_eq!(output.stderr(), vec!["error message"]);
     }
 
     #[test]
     fn test_exec_assign_with_input() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec!["test input"]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::new(["test input"]);
+        let mut output = CapturingWriter::new();
 
         let stmt = Statement::Assign {
             variable: "x".to_string(),
             value: Expr::Input,
         };
 
-        exec_statement(&stmt, &mut env, &mut input, &mut output).unwrap();
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
 
         assert_eq!(
             env.get("x").unwrap(),
-            &super::super::value::Value::Str("test input".to_string())
+            &super::super::value::Value::from("test input")
         );
     }
 
@@ -312,8 +762,8 @@ mod tests {
         use super::super::value::Value;
 
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
 
         // Execute multiple statements sequentially
         let statements = vec![
@@ -345,20 +795,28 @@ mod tests {
         ];
 
         for stmt in &statements {
-            exec_statement(stmt, &mut env, &mut input, &mut output).unwrap();
+            exec_statement(
+                stmt,
+                "A",
+                &mut env,
+                &mut input,
+                &mut output,
+                &mut Builtins::new(),
+            )
+            .unwrap();
         }
 
         assert_eq!(env.get("x").unwrap(), &Value::Int(10));
         assert_eq!(env.get("y").unwrap(), &Value::Int(20));
         assert_eq!(env.get("z").unwrap(), &Value::Int(30));
-        assert_eq!(output.stdout, vec!["30"]);
+        assert_eq!(output.stdout(), vec!["30"]);
     }
 
     #[test]
     fn test_exec_error_propagation() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
 
         // Try to print an undefined variable - should propagate error
         let stmt = Statement::Println {
@@ -367,7 +825,14 @@ mod tests {
             },
         };
 
-        let result = exec_statement(&stmt, &mut env, &mut input, &mut output);
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
         assert!(result.is_err());
         match result {
             Err(RuntimeError::UndefinedVariable { name }) => {
@@ -384,7 +849,14 @@ mod tests {
             },
         };
 
-        let result = exec_statement(&stmt, &mut env, &mut input, &mut output);
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
         assert!(result.is_err());
         match result {
             Err(RuntimeError::UndefinedVariable { name }) => {
@@ -400,7 +872,14 @@ mod tests {
             },
         };
 
-        let result = exec_statement(&stmt, &mut env, &mut input, &mut output);
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
         assert!(result.is_err());
         match result {
             Err(RuntimeError::UndefinedVariable { name }) => {
@@ -413,8 +892,8 @@ mod tests {
     #[test]
     fn test_exec_print_order() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
 
         // Execute multiple print statements and verify order is preserved
         let statements = vec![
@@ -442,10 +921,179 @@ mod tests {
         ];
 
         for stmt in &statements {
-            exec_statement(stmt, &mut env, &mut input, &mut output).unwrap();
+            exec_statement(
+                stmt,
+                "A",
+                &mut env,
+                &mut input,
+                &mut output,
+                &mut Builtins::new(),
+            )
+            .unwrap();
         }
 
-        assert_eq!(output.stdout, vec!["first", "second", "third", "4", "true"]);
+        assert_eq!(
+            output.stdout(),
+            vec!["first", "second", "third", "4", "true"]
+        );
+    }
+
+    #[test]
+    fn test_exec_compound_assign() {
+        use super::super::value::Value;
+
+        let mut env = Environment::new();
+        env.set("s", Value::from("ab"));
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
+
+        let stmt = Statement::CompoundAssign {
+            variable: "s".to_string(),
+            op: BinaryOp::Add,
+            value: Expr::StrLit {
+                value: "c".to_string(),
+            },
+        };
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(env.get("s").unwrap(), &Value::from("abc"));
+    }
+
+    #[test]
+    fn test_exec_compound_assign_undefined_reads_no_input() {
+        let mut env = Environment::new();
+        let mut input = VecInputReader::new(["1"]);
+        let mut output = CapturingWriter::new();
+
+        let stmt = Statement::CompoundAssign {
+            variable: "n".to_string(),
+            op: BinaryOp::Add,
+            value: Expr::Input,
+        };
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
+
+        assert!(matches!(
+            result,
+            Err(RuntimeError::UndefinedVariable { .. })
+        ));
+        assert_eq!(input.read_line().unwrap(), "1");
+    }
+
+    #[test]
+    fn test_exec_self_append_leaves_copies_unchanged() {
+        use super::super::value::Value;
+
+        let mut env = Environment::new();
+        env.set("s", Value::from("ab"));
+        env.set("t", env.get("s").unwrap().clone());
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
+
+        let var = |name: &str| {
+            Box::new(Expr::Variable {
+                name: name.to_string(),
+            })
+        };
+        let stmt = Statement::Assign {
+            variable: "s".to_string(),
+            value: Expr::Binary {
+                op: BinaryOp::Add,
+                left: var("s"),
+                right: var("s"),
+            },
+        };
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(env.get("s").unwrap(), &Value::from("abab"));
+        assert_eq!(env.get("t").unwrap(), &Value::from("ab"));
+    }
+
+    #[test]
+    fn test_exec_self_append_type_error() {
+        use super::super::value::Value;
+
+        let mut env = Environment::new();
+        env.set("s", Value::from("ab"));
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
+
+        let stmt = Statement::CompoundAssign {
+            variable: "s".to_string(),
+            op: BinaryOp::Add,
+            value: Expr::IntLit { value: 1 },
+        };
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
+
+        assert!(matches!(result, Err(RuntimeError::TypeError { .. })));
+        assert_eq!(env.get("s").unwrap(), &Value::from("ab"));
+    }
+
+    #[test]
+    fn test_exec_parallel_assign_evaluates_before_binding() {
+        use super::super::value::Value;
+
+        let mut env = Environment::new();
+        env.set("a", Value::Int(1));
+        env.set("b", Value::Int(2));
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
+
+        let var = |name: &str| Expr::Variable {
+            name: name.to_string(),
+        };
+        let stmt = Statement::ParallelAssign {
+            variables: vec!["a".to_string(), "b".to_string()],
+            values: vec![
+                var("b"),
+                Expr::Binary {
+                    op: BinaryOp::Add,
+                    left: Box::new(var("a")),
+                    right: Box::new(var("b")),
+                },
+            ],
+        };
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(env.get("a").unwrap(), &Value::Int(2));
+        assert_eq!(env.get("b").unwrap(), &Value::Int(3));
     }
 
     #[test]
@@ -453,8 +1101,8 @@ mod tests {
         use super::super::value::Value;
 
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec!["hello", "42", "true"]);
-        let mut output = MockOutputWriter::new();
+        let mut input = VecInputReader::new(["hello", "42", "true"]);
+        let mut output = CapturingWriter::new();
 
         // Assign multiple variables from input
         let statements = vec![
@@ -473,13 +1121,21 @@ mod tests {
         ];
 
         for stmt in &statements {
-            exec_statement(stmt, &mut env, &mut input, &mut output).unwrap();
+            exec_statement(
+                stmt,
+                "A",
+                &mut env,
+                &mut input,
+                &mut output,
+                &mut Builtins::new(),
+            )
+            .unwrap();
         }
 
         // All inputs are read as strings
-        assert_eq!(env.get("a").unwrap(), &Value::Str("hello".to_string()));
-        assert_eq!(env.get("b").unwrap(), &Value::Str("42".to_string()));
-        assert_eq!(env.get("c").unwrap(), &Value::Str("true".to_string()));
+        assert_eq!(env.get("a").unwrap(), &Value::from("hello"));
+        assert_eq!(env.get("b").unwrap(), &Value::from("42"));
+        assert_eq!(env.get("c").unwrap(), &Value::from("true"));
 
         // Test input exhaustion - attempting to read more input should fail
         let stmt = Statement::Assign {
@@ -487,7 +1143,14 @@ mod tests {
             value: Expr::Input,
         };
 
-        let result = exec_statement(&stmt, &mut env, &mut input, &mut output);
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
         assert!(result.is_err());
         match result {
             Err(RuntimeError::IoError { message }) => {
@@ -500,7 +1163,7 @@ mod tests {
     #[test]
     fn test_println_propagates_write_error() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let mut output = FailingOutputWriter;
 
         let stmt = Statement::Println {
@@ -509,14 +1172,21 @@ mod tests {
             },
         };
 
-        let result = exec_statement(&stmt, &mut env, &mut input, &mut output);
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
         assert!(matches!(result, Err(RuntimeError::IoError { .. })));
     }
 
     #[test]
     fn test_print_propagates_write_error() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let mut output = FailingOutputWriter;
 
         let stmt = Statement::Print {
@@ -525,14 +1195,21 @@ mod tests {
             },
         };
 
-        let result = exec_statement(&stmt, &mut env, &mut input, &mut output);
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
         assert!(matches!(result, Err(RuntimeError::IoError { .. })));
     }
 
     #[test]
     fn test_error_stmt_propagates_write_error() {
         let mut env = Environment::new();
-        let mut input = MockInputReader::new(vec![]);
+        let mut input = VecInputReader::default();
         let mut output = FailingOutputWriter;
 
         let stmt = Statement::Error {
@@ -541,7 +1218,246 @@ mod tests {
             },
         };
 
-        let result = exec_statement(&stmt, &mut env, &mut input, &mut output);
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
         assert!(matches!(result, Err(RuntimeError::IoError { .. })));
     }
+
+    #[test]
+    fn test_exec_sleep_uses_clock() {
+        use super::super::clock::{Clock, ManualClock};
+
+        let mut env = Environment::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
+        let clock = ManualClock::new(0);
+        let mut builtins = Builtins::new().with_clock(clock.clone());
+
+        let stmt = Statement::Sleep {
+            duration: Expr::IntLit { value: 1500 },
+        };
+
+        exec_statement(&stmt, "A", &mut env, &mut input, &mut output, &mut builtins).unwrap();
+        assert_eq!(clock.now_ms(), 1500);
+    }
+
+    #[test]
+    fn test_exec_sleep_flushes_output_first() {
+        use super::super::clock::{Clock, ManualClock};
+
+        /// Records the time of each flush.
+        struct FlushTimes {
+            clock: ManualClock,
+            times: Vec<i64>,
+        }
+
+        impl OutputWriter for FlushTimes {
+            fn write_stdout(&mut self, _s: &str) -> Result<(), RuntimeError> {
+                Ok(())
+            }
+            fn write_stdout_no_newline(&mut self, _s: &str) -> Result<(), RuntimeError> {
+                Ok(())
+            }
+            fn write_stderr(&mut self, _s: &str) -> Result<(), RuntimeError> {
+                Ok(())
+            }
+            fn flush(&mut self) -> Result<(), RuntimeError> {
+                self.times.push(self.clock.now_ms());
+                Ok(())
+            }
+        }
+
+        let mut env = Environment::new();
+        let mut input = VecInputReader::default();
+        let clock = ManualClock::new(0);
+        let mut output = FlushTimes {
+            clock: clock.clone(),
+            times: Vec::new(),
+        };
+        let mut builtins = Builtins::new().with_clock(clock.clone());
+
+        let stmt = Statement::Sleep {
+            duration: Expr::IntLit { value: 1000 },
+        };
+
+        exec_statement(&stmt, "A", &mut env, &mut input, &mut output, &mut builtins).unwrap();
+        assert_eq!(output.times, vec![0]);
+        assert_eq!(clock.now_ms(), 1000);
+    }
+
+    #[test]
+    fn test_exec_sleep_type_error() {
+        let mut env = Environment::new();
+        let mut input = VecInputReader::default();
+        let mut output = CapturingWriter::new();
+
+        let stmt = Statement::Sleep {
+            duration: Expr::StrLit {
+                value: "1s".to_string(),
+            },
+        };
+
+        let result = exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        );
+        assert!(matches!(
+            result,
+            Err(RuntimeError::TypeError {
+                expected: "int",
+                actual: "str",
+                ..
+            })
+        ));
+    }
+
+    #[test]
+    fn test_exec_flushes_output_before_input() {
+        use super::super::test_helpers::BufferingOutputWriter;
+
+        let mut env = Environment::new();
+        let mut input = VecInputReader::new(["Alice"]);
+        let mut output = BufferingOutputWriter::new();
+        output.pending.push("Name: ".to_string());
+
+        let stmt = Statement::Assign {
+            variable: "name".to_string(),
+            value: Expr::Input,
+        };
+
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(output.flushed, vec!["Name: "]);
+        assert!(output.pending.is_empty());
+    }
+
+    #[test]
+    fn test_exec_does_not_flush_without_input() {
+        use super::super::test_helpers::BufferingOutputWriter;
+
+        let mut env = Environment::new();
+        let mut input = VecInputReader::default();
+        let mut output = BufferingOutputWriter::new();
+
+        let stmt = Statement::Println {
+            expr: Expr::IntLit { value: 1 },
+        };
+
+        exec_statement(
+            &stmt,
+            "A",
+            &mut env,
+            &mut input,
+            &mut output,
+            &mut Builtins::new(),
+        )
+        .unwrap();
+
+        assert_eq!(output.pending, vec!["1"]);
+        assert!(output.flushed.is_empty());
+    }
+
+    /// Records every output event instead of formatting it.
+    struct EventRecorder {
+        events: Vec<(OutputStream, Value, String, bool)>,
+    }
+
+    impl OutputWriter for EventRecorder {
+        fn write_stdout(&mut self, _s: &str) -> Result<(), RuntimeError> {
+            unreachable!("on_output is overridden")
+        }
+
+        fn write_stdout_no_newline(&mut self, _s: &str) -> Result<(), RuntimeError> {
+            unreachable!("on_output is overridden")
+        }
+
+        fn write_stderr(&mut self, _s: &str) -> Result<(), RuntimeError> {
+            unreachable!("on_output is overridden")
+        }
+
+        fn on_output(&mut self, event: OutputEvent<'_>) -> Result<(), RuntimeError> {
+            self.events.push((
+                event.stream,
+                event.value.clone(),
+                event.node_id.to_string(),
+                event.newline,
+            ));
+            Ok(())
+        }
+    }
+
+    #[test]
+    fn test_exec_output_events() {
+        let mut env = Environment::new();
+        let mut input = VecInputReader::default();
+        let mut output = EventRecorder { events: Vec::new() };
+        let mut builtins = Builtins::new();
+
+        let statements = [
+            Statement::Println {
+                expr: Expr::IntLit { value: 1 },
+            },
+            Statement::Print {
+                expr: Expr::BoolLit { value: true },
+            },
+            Statement::Error {
+                message: Expr::StrLit {
+                    value: "oops".to_string(),
+                },
+            },
+        ];
+        for stmt in &statements {
+            exec_statement(
+                stmt,
+                "Step",
+                &mut env,
+                &mut input,
+                &mut output,
+                &mut builtins,
+            )
+            .unwrap();
+        }
+
+        assert_eq!(
+            output.events,
+            vec![
+                (
+                    OutputStream::Stdout,
+                    Value::Int(1),
+                    "Step".to_string(),
+                    true
+                ),
+                (
+                    OutputStream::Stdout,
+                    Value::Bool(true),
+                    "Step".to_string(),
+                    false
+                ),
+                (
+                    OutputStream::Stderr,
+                    Value::from("oops"),
+                    "Step".to_string(),
+                    true
+                ),
+            ]
+        );
+    }
 }
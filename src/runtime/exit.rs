@@ -0,0 +1,94 @@
+//! Conversion of computed exit codes.
+//!
+//! Exit codes written as expressions (`|exit errors|` or an `exit`
+//! statement) are only known at runtime. [`ExitCodePolicy`] decides what
+//! happens when such a value does not fit in a process exit code.
+
+use super::error::RuntimeError;
+use super::value::Value;
+
+/// How out-of-range computed exit codes are handled.
+///
+/// # Examples
+///
+/// ```
+/// use merx::runtime::{ExitCodePolicy, Value};
+///
+/// assert_eq!(ExitCodePolicy::Clamp.exit_code(&Value::Int(300)).unwrap(), 255);
+/// assert!(ExitCodePolicy::Error.exit_code(&Value::Int(300)).is_err());
+/// ```
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum ExitCodePolicy {
+    /// Fail with [`RuntimeError::InvalidExitCode`] (the default).
+    #[default]
+    Error,
+
+    /// Clamp the value into range: negative values become 0 and values
+    /// above 255 become 255.
+    Clamp,
+}
+
+impl ExitCodePolicy {
+    /// Converts a computed value into an exit code.
+    ///
+    /// # Errors
+    ///
+    /// - [`RuntimeError::TypeError`] - The value is not an integer
+    /// - [`RuntimeError::InvalidExitCode`] - The value is outside 0-255 and
+    ///   the policy is [`Error`](ExitCodePolicy::Error)
+    pub fn exit_code(self, value: &Value) -> Result<u8, RuntimeError> {
+        let n = value.as_int().ok_or_else(|| RuntimeError::TypeError {
+            expected: "int",
+            actual: value.type_name(),
+            operation: "exit code".to_string(),
+        })?;
+        match self {
+            ExitCodePolicy::Error => {
+                u8::try_from(n).map_err(|_| RuntimeError::InvalidExitCode { value: n })
+            }
+            ExitCodePolicy::Clamp => Ok(n.clamp(0, 255) as u8),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_exit_code_in_range() {
+        for policy in [ExitCodePolicy::Error, ExitCodePolicy::Clamp] {
+            assert_eq!(policy.exit_code(&Value::Int(0)).unwrap(), 0);
+            assert_eq!(policy.exit_code(&Value::Int(255)).unwrap(), 255);
+        }
+    }
+
+    #[test]
+    fn test_exit_code_out_of_range() {
+        let err = ExitCodePolicy::Error
+            .exit_code(&Value::Int(-1))
+            .unwrap_err();
+        assert!(matches!(err, RuntimeError::InvalidExitCode { value: -1 }));
+
+        assert_eq!(ExitCodePolicy::Clamp.exit_code(&Value::Int(-1)).unwrap(), 0);
+        assert_eq!(
+            ExitCodePolicy::Clamp.exit_code(&Value::Int(1000)).unwrap(),
+            255
+        );
+    }
+
+    #[test]
+    fn test_exit_code_not_int() {
+        let err = ExitCodePolicy::Clamp
+            .exit_code(&Value::from("1"))
+            .unwrap_err();
+        assert!(matches!(
+            err,
+            RuntimeError::TypeError {
+                expected: "int",
+                actual: "str",
+                ..
+            }
+        ));
+    }
+}
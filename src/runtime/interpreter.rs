@@ -10,17 +10,28 @@
 //! 1. **Initialization**: Build node and edge lookup tables from the flowchart
 //! 2. **Validation**: Verify Start and End nodes exist (defensive; normally caught at parse time)
 //! 3. **Execution**: Starting from `Start`, follow edges through the graph
-//! 4. **Termination**: Execution ends when the `End` node is reached
+//! 4. **Termination**: Execution ends when a terminal node (`End` or a named
+//!    terminal such as `Done([End: approved])`) is reached
 //!
 //! # Node Handling
 //!
 //! | Node Type | Behavior |
 //! |-----------|----------|
-//! | `Start` | Entry point; follow single outgoing edge |
-//! | `End` | Terminal; execution stops |
+//! | `Start`, `Entry` | Entry point; follow single outgoing edge |
+//! | `End`, `Terminal` | Terminal; execution stops |
 //! | `Process` | Execute all statements; follow single outgoing edge |
 //! | `Condition` | Evaluate expression; follow Yes or No edge based on result |
 //!
+//! # Process Chains
+//!
+//! When the interpreter is created, straight-line runs of process nodes
+//! (each joined to the next by a plain edge, and each after the first
+//! reached from nowhere else) are fused into a single block. A block runs
+//! all of its statements in one step, without the edge lookup and exit code
+//! bookkeeping between nodes. Every statement still reports the node it was
+//! written in, and the block's outgoing edges are those of its last node,
+//! so output events and errors are the same as without fusing.
+//!
 //! # Control Flow
 //!
 //! - **Sequential**: Process nodes have one outgoing edge
@@ -33,6 +44,9 @@
 //! - Production use with stdin/stdout
 //! - Testing with mock I/O
 //!
+//! The source of time is injected the same way through a [`Clock`], so tests
+//! can use a fake clock instead of the system time.
+//!
 //! # Example
 //!
 //! ```ignore
@@ -57,12 +71,18 @@ use std::io;
 
 use rustc_hash::FxHashMap;
 
-use crate::ast::{EdgeLabel, Flowchart, Node};
+use crate::ast::{EdgeLabel, Expr, Flowchart, Node, Statement};
 
+use super::builtins::Builtins;
+use super::clock::Clock;
 use super::env::Environment;
 use super::error::RuntimeError;
 use super::eval::{InputReader, StdinReader, eval_expr};
-use super::exec::{OutputWriter, StdioWriter, exec_statement};
+use super::exec::{FlushingReader, OutputWriter, StatementEffect, StdioWriter, exec_statement};
+use super::exit::ExitCodePolicy;
+use super::loops::LoopState;
+use super::table::select_rule;
+use super::value::Value;
 
 /// An internal edge representation using node indices instead of string IDs.
 ///
@@ -70,7 +90,47 @@ use super::exec::{OutputWriter, StdioWriter, exec_statement};
 struct InternalEdge {
     to: usize,
     label: Option<EdgeLabel>,
-    exit_code: Option<u8>,
+    exit_code: Option<Expr>,
+    return_value: Option<Expr>,
+    actions: Vec<Statement>,
+    /// Whether this edge leads from the body of a loop node back to it.
+    ///
+    /// Following any other edge into a loop node starts the loop over.
+    continues_loop: bool,
+}
+
+/// A straight-line run of process nodes, executed as a single step.
+struct Block {
+    /// The statements of every node in the run, in order, each paired with
+    /// the index of the node it was written in.
+    statements: Vec<(usize, Statement)>,
+
+    /// The index of the last node in the run, whose outgoing edges are
+    /// followed once the statements have been executed.
+    tail: usize,
+}
+
+/// What a completed run produced, returned by
+/// [`Interpreter::run_outcome`].
+///
+/// Hosts that use merx as a rules engine read the computed decision from
+/// [`value`](RunOutcome::value) and any other variables from
+/// [`env`](RunOutcome::env), instead of parsing stdout.
+#[derive(Debug, Clone)]
+pub struct RunOutcome {
+    /// The exit code, as returned by [`Interpreter::run`].
+    pub exit_code: u8,
+
+    /// The program's result value.
+    ///
+    /// This is the value of the last `return` statement or `|return expr|`
+    /// edge label. If neither was executed, it is the result declared by the
+    /// terminal node that was reached (e.g. `'approved'` for
+    /// `Done([End: approved])`), or `None`.
+    pub value: Option<Value>,
+
+    /// The variables as they were when the program ended.
+    pub env: Environment,
 }
 
 /// The main execution engine for Mermaid flowchart programs.
@@ -121,9 +181,18 @@ pub struct Interpreter<R: InputReader, W: OutputWriter> {
     /// (labeled Yes and No).
     outgoing_edges: Vec<Vec<InternalEdge>>,
 
+    /// The fused statements of each process node that starts a chain,
+    /// indexed by the same position as `nodes`.
+    ///
+    /// `None` for other nodes, including process nodes fused into the block
+    /// of the node before them.
+    blocks: Vec<Option<Block>>,
+
     /// The index of the node currently being executed.
     ///
-    /// Starts at the Start node's index and updated as edges are followed.
+    /// Starts at the Start node's index (or the entry selected with
+    /// [`with_entry`](Interpreter::with_entry)) and updated as edges are
+    /// followed.
     current_node: usize,
 
     /// The variable environment storing all variable bindings.
@@ -135,11 +204,39 @@ pub struct Interpreter<R: InputReader, W: OutputWriter> {
     /// The output destination for print/error statements.
     output_writer: W,
 
+    /// State for built-in function calls, including the random number
+    /// generator and the clock.
+    ///
+    /// Seeded from OS entropy and backed by the system clock by default; see
+    /// [`with_seed`](Interpreter::with_seed),
+    /// [`with_random_denied`](Interpreter::with_random_denied), and
+    /// [`with_clock`](Interpreter::with_clock).
+    builtins: Builtins,
+
     /// The exit code from the most recently traversed edge.
     ///
-    /// Updated each time an edge is followed. When the `End` node is reached,
-    /// this value is returned (defaulting to 0 if `None`).
+    /// Updated each time an edge is followed. When a terminal node is
+    /// reached, this value is returned (falling back to the terminal's own
+    /// exit code, then 0, if `None`).
     last_exit_code: Option<u8>,
+
+    /// The exit code set by the last `exit` statement, if any.
+    ///
+    /// Used when the edge into the terminal node has no exit code of its own.
+    exit_status: Option<u8>,
+
+    /// How computed exit codes outside 0-255 are handled.
+    exit_code_policy: ExitCodePolicy,
+
+    /// The index of the terminal node the last run ended at, if any.
+    terminal: Option<usize>,
+
+    /// The result value set by the last `return` statement or edge label.
+    value: Option<Value>,
+
+    /// The iteration state of each loop node that is running, indexed by
+    /// the same position as `nodes`.
+    loops: Vec<Option<LoopState>>,
 }
 
 impl Interpreter<StdinReader<io::BufReader<io::Stdin>>, StdioWriter> {
@@ -159,7 +256,7 @@ impl Interpreter<StdinReader<io::BufReader<io::Stdin>>, StdioWriter> {
     /// # Errors
     ///
     /// - [`RuntimeError::MissingStartNode`] - No `Start` node found
-    /// - [`RuntimeError::MissingEndNode`] - No `End` node found
+    /// - [`RuntimeError::MissingEndNode`] - No `End` or terminal node found
     ///
     /// # Examples
     ///
@@ -194,7 +291,7 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
     /// # Errors
     ///
     /// - [`RuntimeError::MissingStartNode`] - No `Start` node found
-    /// - [`RuntimeError::MissingEndNode`] - No `End` node found
+    /// - [`RuntimeError::MissingEndNode`] - No `End` or terminal node found
     ///
     /// # Implementation Details
     ///
@@ -202,7 +299,8 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
     /// 1. Build name-to-index mapping from flowchart nodes
     /// 2. Validate Start and End nodes exist (defensive; normally caught at parse time)
     /// 3. Convert edges to index-based `InternalEdge` representations
-    /// 4. Initialize empty environment
+    /// 4. Fuse straight-line chains of process nodes into blocks
+    /// 5. Initialize empty environment
     pub fn with_io(
         flowchart: Flowchart,
         input_reader: R,
@@ -217,7 +315,7 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
             name_to_index.insert(node.id().to_string(), i);
             match node {
                 Node::Start { .. } => start_index = Some(i),
-                Node::End { .. } => has_end = true,
+                n if n.is_terminal() => has_end = true,
                 _ => {}
             }
         }
@@ -244,41 +342,177 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
             outgoing_edges[from_idx].push(InternalEdge {
                 to: to_idx,
                 label: edge.label.clone(),
-                exit_code: edge.exit_code,
+                exit_code: edge.exit_code.clone(),
+                return_value: edge.return_value.clone(),
+                actions: edge.actions.clone(),
+                continues_loop: false,
             });
         }
+        mark_loop_back_edges(&flowchart.nodes, &mut outgoing_edges);
+        let mut nodes = flowchart.nodes;
+        let blocks = fuse_process_chains(&mut nodes, &outgoing_edges);
+        let loops = (0..nodes.len()).map(|_| None).collect();
 
         Ok(Self {
-            nodes: flowchart.nodes,
+            nodes,
             outgoing_edges,
+            blocks,
             current_node: start_index,
             env: Environment::new(),
             input_reader,
             output_writer,
+            builtins: Builtins::new(),
             last_exit_code: None,
+            exit_status: None,
+            exit_code_policy: ExitCodePolicy::default(),
+            terminal: None,
+            value: None,
+            loops,
         })
     }
 
+    /// Seeds the random number generator used by random built-in functions.
+    ///
+    /// Runs with the same seed and the same input always produce the same
+    /// results, which makes programs using `rand_int`, `rand_choice`, or
+    /// `shuffle` reproducible.
+    ///
+    /// # Arguments
+    ///
+    /// * `seed` - The seed value
+    ///
+    /// # Examples
+    ///
+    /// ```ignore
+    /// let mut interpreter = Interpreter::new(flowchart).unwrap().with_seed(42);
+    /// interpreter.run().unwrap();
+    /// ```
+    pub fn with_seed(mut self, seed: u64) -> Self {
+        self.builtins = self.builtins.with_seed(seed);
+        self
+    }
+
+    /// Replaces the clock used by time functions and the `sleep` statement.
+    ///
+    /// The system clock is used by default. Pass a
+    /// [`ManualClock`](super::ManualClock) in tests to control the current
+    /// time and make `sleep` return immediately.
+    ///
+    /// # Arguments
+    ///
+    /// * `clock` - The clock implementation
+    ///
+    /// # Examples
+    ///
+    /// ```ignore
+    /// use merx::runtime::ManualClock;
+    ///
+    /// let clock = ManualClock::new(0);
+    /// let mut interpreter = Interpreter::with_io(flowchart, input, output)
+    ///     .unwrap()
+    ///     .with_clock(clock.clone());
+    /// interpreter.run().unwrap();
+    /// println!("slept for {} ms", clock.now_ms());
+    /// ```
+    pub fn with_clock(mut self, clock: impl Clock + 'static) -> Self {
+        self.builtins = self.builtins.with_clock(clock);
+        self
+    }
+
+    /// Denies access to randomness.
+    ///
+    /// Any call to a random built-in function fails with
+    /// [`RuntimeError::RandomDenied`]. Use this to sandbox programs that
+    /// must behave deterministically.
+    ///
+    /// # Examples
+    ///
+    /// ```ignore
+    /// let mut interpreter = Interpreter::new(flowchart).unwrap().with_random_denied();
+    /// interpreter.run().unwrap();
+    /// ```
+    pub fn with_random_denied(mut self) -> Self {
+        self.builtins = self.builtins.deny_random();
+        self
+    }
+
+    /// Begins execution at the named entry instead of the `Start` node.
+    ///
+    /// Entries are stadium nodes labeled `Start <name>`, such as
+    /// `Start_refund([Start refund])`.
+    ///
+    /// # Arguments
+    ///
+    /// * `name` - The entry name (`refund` in `Start refund`)
+    ///
+    /// # Errors
+    ///
+    /// Returns [`RuntimeError::UnknownEntry`] if no entry has that name.
+    ///
+    /// # Examples
+    ///
+    /// ```ignore
+    /// let mut interpreter = Interpreter::new(flowchart)?.with_entry("refund")?;
+    /// interpreter.run()?;
+    /// ```
+    pub fn with_entry(mut self, name: &str) -> Result<Self, RuntimeError> {
+        self.current_node = self
+            .nodes
+            .iter()
+            .position(|n| matches!(n, Node::Entry { name: entry, .. } if entry == name))
+            .ok_or_else(|| RuntimeError::UnknownEntry {
+                name: name.to_string(),
+            })?;
+        Ok(self)
+    }
+
+    /// Sets how computed exit codes outside 0-255 are handled.
+    ///
+    /// By default such a code is a [`RuntimeError::InvalidExitCode`];
+    /// [`ExitCodePolicy::Clamp`] clamps it into range instead. Literal exit
+    /// codes are checked at parse time and are not affected.
+    ///
+    /// # Examples
+    ///
+    /// ```ignore
+    /// let mut interpreter = Interpreter::new(flowchart)?.with_exit_code_policy(ExitCodePolicy::Clamp);
+    /// ```
+    pub fn with_exit_code_policy(mut self, policy: ExitCodePolicy) -> Self {
+        self.exit_code_policy = policy;
+        self
+    }
+
     /// Executes the program from start to completion.
     ///
     /// This is the main execution loop. It processes nodes sequentially,
-    /// following edges until the `End` node is reached or an error occurs.
+    /// following edges until a terminal node is reached or an error occurs.
     ///
     /// # Execution Flow
     ///
     /// For each node:
     /// 1. Look up the current node by ID
     /// 2. Execute based on node type:
-    ///    - `Start`: Follow the outgoing edge
-    ///    - `End`: Return successfully
+    ///    - `Start` or `Entry`: Follow the outgoing edge
+    ///    - `End` or `Terminal`: Return successfully
     ///    - `Process`: Execute all statements, then follow edge
     ///    - `Condition`: Evaluate condition, follow Yes/No edge
-    /// 3. Repeat until End or error
+    ///    - `DecisionTable`: Select a rule, assign its outputs, then follow
+    ///      its target edge (or the only edge)
+    ///    - `Loop`: Assign the next value and follow the `body` edge, or
+    ///      follow the `done` edge once the values run out
+    /// 3. Repeat until a terminal node or error
     ///
     /// # Returns
     ///
-    /// `Ok(exit_code)` when execution reaches the `End` node normally.
-    /// The exit code is determined by the edge leading to `End` (defaults to `0`).
+    /// `Ok(exit_code)` when execution reaches a terminal node normally.
+    /// The exit code is determined by the edge leading to the terminal, then
+    /// by the last `exit` statement, then by the terminal's own `exit N`
+    /// (defaults to `0`). Use
+    /// [`terminal_id`](Interpreter::terminal_id) and
+    /// [`result`](Interpreter::result) to find out which terminal was reached.
+    ///
+    /// The output writer is [flushed](OutputWriter::flush) before returning,
+    /// whether execution succeeded or failed.
     ///
     /// # Errors
     ///
@@ -299,33 +533,80 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
     /// }
     /// ```
     pub fn run(&mut self) -> Result<u8, RuntimeError> {
+        let result = self.execute();
+        // Flush buffered output on every exit path so nothing printed before
+        // an error is lost. An execution error takes precedence over a
+        // flush error.
+        let flushed = self.output_writer.flush();
+        let exit_code = result?;
+        flushed?;
+        Ok(exit_code)
+    }
+
+    /// Runs the main execution loop until a terminal node or an error.
+    fn execute(&mut self) -> Result<u8, RuntimeError> {
+        self.terminal = None;
+        self.value = None;
+        self.exit_status = None;
+        self.loops.iter_mut().for_each(|state| *state = None);
         loop {
             let node = &self.nodes[self.current_node];
 
             match node {
-                Node::Start { .. } => {
-                    // Move to the next node from Start
+                Node::Start { .. } | Node::Entry { .. } => {
+                    // Move to the next node from the entry point
                     self.move_to_next()?;
                 }
                 Node::End { .. } => {
-                    // Terminate with the exit code from the last edge (default: 0)
-                    return Ok(self.last_exit_code.unwrap_or(0));
+                    // Terminate with the exit code from the last edge or
+                    // `exit` statement (default: 0)
+                    self.terminal = Some(self.current_node);
+                    return Ok(self.last_exit_code.or(self.exit_status).unwrap_or(0));
                 }
-                Node::Process { statements, .. } => {
-                    // Execute all statements
-                    for stmt in statements {
-                        exec_statement(
+                Node::Terminal { exit_code, .. } => {
+                    // The edge's and `exit` statement's exit codes override
+                    // the terminal's default
+                    self.terminal = Some(self.current_node);
+                    return Ok(self
+                        .last_exit_code
+                        .or(self.exit_status)
+                        .or(*exit_code)
+                        .unwrap_or(0));
+                }
+                Node::Process { .. } => {
+                    // Execute the statements of this node and of any nodes
+                    // fused after it, then leave from the last of them
+                    let Some(block) = &self.blocks[self.current_node] else {
+                        // Fused nodes are only reached through their block
+                        unreachable!("process node fused into another block was reached");
+                    };
+                    for (index, stmt) in &block.statements {
+                        let effect = exec_statement(
                             stmt,
+                            self.nodes[*index].id(),
                             &mut self.env,
                             &mut self.input_reader,
                             &mut self.output_writer,
+                            &mut self.builtins,
+                        )?;
+                        record_effect(
+                            effect,
+                            &mut self.value,
+                            &mut self.exit_status,
+                            self.exit_code_policy,
                         )?;
                     }
+                    self.current_node = block.tail;
                     self.move_to_next()?;
                 }
                 Node::Condition { condition, .. } => {
                     // Evaluate the condition
-                    let val = eval_expr(condition, &self.env, &mut self.input_reader)?;
+                    let val = eval_expr(
+                        condition,
+                        &self.env,
+                        &mut FlushingReader::new(&mut self.input_reader, &mut self.output_writer),
+                        &mut self.builtins,
+                    )?;
                     let result = val.as_bool().ok_or_else(|| RuntimeError::TypeError {
                         expected: "bool",
                         actual: val.type_name(),
@@ -333,19 +614,79 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
                     })?;
                     self.move_to_condition_branch(result)?;
                 }
+                Node::DecisionTable { id, table } => {
+                    let index = select_rule(
+                        table,
+                        id,
+                        &self.env,
+                        &mut self.input_reader,
+                        &mut self.output_writer,
+                        &mut self.builtins,
+                    )?;
+                    let rule = &table.rules[index];
+
+                    // Evaluate every output before assigning any, so the
+                    // outputs of a rule do not see each other
+                    let mut assignments = Vec::with_capacity(table.outputs.len());
+                    for (name, value) in table.outputs.iter().zip(&rule.values) {
+                        let Some(value) = value else { continue };
+                        let val = eval_expr(
+                            value,
+                            &self.env,
+                            &mut FlushingReader::new(
+                                &mut self.input_reader,
+                                &mut self.output_writer,
+                            ),
+                            &mut self.builtins,
+                        )?
+                        .into_owned();
+                        assignments.push((name, val));
+                    }
+                    for (name, val) in assignments {
+                        self.env.assign(name, val)?;
+                    }
+
+                    match rule.target.clone() {
+                        Some(label) => self.move_to_labeled_edge(&label)?,
+                        None => self.move_to_next()?,
+                    }
+                }
+                Node::Loop { var, iter, .. } => {
+                    let state = match &mut self.loops[self.current_node] {
+                        Some(state) => state,
+                        state => state.insert(LoopState::start(
+                            iter,
+                            &self.env,
+                            &mut self.input_reader,
+                            &mut self.output_writer,
+                            &mut self.builtins,
+                        )?),
+                    };
+                    match state.next() {
+                        Some(value) => {
+                            self.env.assign(var, value)?;
+                            self.move_to_loop_edge(true)?;
+                        }
+                        None => {
+                            self.loops[self.current_node] = None;
+                            self.move_to_loop_edge(false)?;
+                        }
+                    }
+                }
             }
         }
     }
 
-    /// Follows an unconditional edge to the next node.
+    /// Follows the outgoing edge of a non-condition node to the next node.
     ///
-    /// Used by `Start` and `Process` nodes which have exactly one
-    /// outgoing edge (not labeled with Yes/No).
+    /// Used by `Start`, `Entry`, and `Process` nodes, which have either
+    /// exactly one outgoing edge or several guarded edges.
     ///
     /// # Errors
     ///
     /// Returns [`RuntimeError::NoOutgoingEdge`] if the current node
-    /// has no outgoing edges.
+    /// has no outgoing edges, or any error from
+    /// [`move_to_guarded_edge`](Self::move_to_guarded_edge).
     fn move_to_next(&mut self) -> Result<(), RuntimeError> {
         let edges = &self.outgoing_edges[self.current_node];
 
@@ -355,10 +696,99 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
             });
         }
 
+        if edges[0].label.as_ref().is_some_and(EdgeLabel::is_guard) {
+            return self.move_to_guarded_edge();
+        }
+
         // Use the first edge from normal nodes
-        self.last_exit_code = edges[0].exit_code;
-        self.current_node = edges[0].to;
-        Ok(())
+        self.follow_edge(0)
+    }
+
+    /// Follows the first outgoing edge whose guard is `true`, or the
+    /// `[else]` edge if there is none.
+    ///
+    /// Guards are evaluated in edge order, stopping at the first match.
+    ///
+    /// # Errors
+    ///
+    /// - [`RuntimeError::TypeError`] - A guard did not evaluate to a bool
+    /// - [`RuntimeError::NoMatchingGuard`] - No guard matched and there is
+    ///   no `[else]` edge
+    fn move_to_guarded_edge(&mut self) -> Result<(), RuntimeError> {
+        let mut fallback = None;
+        for index in 0..self.outgoing_edges[self.current_node].len() {
+            let guard = match &self.outgoing_edges[self.current_node][index].label {
+                Some(EdgeLabel::Guard(guard)) => guard,
+                Some(EdgeLabel::Else) => {
+                    fallback.get_or_insert(index);
+                    continue;
+                }
+                _ => continue,
+            };
+            let val = eval_expr(
+                guard,
+                &self.env,
+                &mut FlushingReader::new(&mut self.input_reader, &mut self.output_writer),
+                &mut self.builtins,
+            )?;
+            let matched = val.as_bool().ok_or_else(|| RuntimeError::TypeError {
+                expected: "bool",
+                actual: val.type_name(),
+                operation: "guard evaluation".to_string(),
+            })?;
+            if matched {
+                return self.follow_edge(index);
+            }
+        }
+
+        match fallback {
+            Some(index) => self.follow_edge(index),
+            None => Err(RuntimeError::NoMatchingGuard {
+                node_id: self.nodes[self.current_node].id().to_string(),
+            }),
+        }
+    }
+
+    /// Follows the outgoing edge with the given custom label.
+    ///
+    /// Used by `DecisionTable` nodes whose selected rule names a target.
+    ///
+    /// # Errors
+    ///
+    /// Returns [`RuntimeError::NoMatchingTableEdge`] if no edge has the
+    /// label.
+    fn move_to_labeled_edge(&mut self, label: &str) -> Result<(), RuntimeError> {
+        let index = self.outgoing_edges[self.current_node]
+            .iter()
+            .position(|edge| matches!(&edge.label, Some(EdgeLabel::Custom(l)) if l == label))
+            .ok_or_else(|| RuntimeError::NoMatchingTableEdge {
+                node_id: self.nodes[self.current_node].id().to_string(),
+                label: label.to_string(),
+            })?;
+        self.follow_edge(index)
+    }
+
+    /// Follows the `body` edge of a loop node if `body` is `true`, or its
+    /// `done` edge otherwise.
+    ///
+    /// # Errors
+    ///
+    /// Returns [`RuntimeError::NoMatchingLoopEdge`] if the edge does not
+    /// exist.
+    fn move_to_loop_edge(&mut self, body: bool) -> Result<(), RuntimeError> {
+        let is_target = if body {
+            EdgeLabel::is_loop_body
+        } else {
+            EdgeLabel::is_loop_done
+        };
+        let index = self.outgoing_edges[self.current_node]
+            .iter()
+            .position(|edge| edge.label.as_ref().is_some_and(is_target))
+            .ok_or_else(|| RuntimeError::NoMatchingLoopEdge {
+                node_id: self.nodes[self.current_node].id().to_string(),
+                body,
+            })?;
+        self.follow_edge(index)
     }
 
     /// Follows a conditional edge based on the condition result.
@@ -391,25 +821,151 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
             EdgeLabel::No
         };
 
-        for edge in edges {
-            if let Some(label) = &edge.label
-                && matches!(
+        let index = edges.iter().position(|edge| {
+            edge.label.as_ref().is_some_and(|label| {
+                matches!(
                     (label, &target_label),
                     (EdgeLabel::Yes, EdgeLabel::Yes) | (EdgeLabel::No, EdgeLabel::No)
                 )
-            {
-                self.last_exit_code = edge.exit_code;
-                self.current_node = edge.to;
-                return Ok(());
+            })
+        });
+
+        match index {
+            Some(index) => self.follow_edge(index),
+            None => Err(RuntimeError::NoMatchingConditionEdge {
+                node_id: self.nodes[self.current_node].id().to_string(),
+                condition_result,
+            }),
+        }
+    }
+
+    /// Follows the `index`-th outgoing edge of the current node.
+    ///
+    /// Executes the edge's actions, then evaluates and records its exit
+    /// code and, if the edge has a `return expr` label, evaluates the
+    /// expression as the program's result value.
+    fn follow_edge(&mut self, index: usize) -> Result<(), RuntimeError> {
+        let edge = &self.outgoing_edges[self.current_node][index];
+        let node_id = self.nodes[self.current_node].id();
+        for stmt in &edge.actions {
+            let effect = exec_statement(
+                stmt,
+                node_id,
+                &mut self.env,
+                &mut self.input_reader,
+                &mut self.output_writer,
+                &mut self.builtins,
+            )?;
+            record_effect(
+                effect,
+                &mut self.value,
+                &mut self.exit_status,
+                self.exit_code_policy,
+            )?;
+        }
+        self.last_exit_code = match &edge.exit_code {
+            Some(expr) => {
+                let val = eval_expr(
+                    expr,
+                    &self.env,
+                    &mut FlushingReader::new(&mut self.input_reader, &mut self.output_writer),
+                    &mut self.builtins,
+                )?;
+                Some(self.exit_code_policy.exit_code(&val)?)
             }
+            None => None,
+        };
+        if let Some(expr) = &edge.return_value {
+            let val = eval_expr(
+                expr,
+                &self.env,
+                &mut FlushingReader::new(&mut self.input_reader, &mut self.output_writer),
+                &mut self.builtins,
+            )?
+            .into_owned();
+            self.value = Some(val);
         }
+        if !edge.continues_loop {
+            self.loops[edge.to] = None;
+        }
+        self.current_node = edge.to;
+        Ok(())
+    }
 
-        Err(RuntimeError::NoMatchingConditionEdge {
-            node_id: self.nodes[self.current_node].id().to_string(),
-            condition_result,
+    /// Executes the program like [`run`](Interpreter::run), returning its
+    /// result value and final variables along with the exit code.
+    ///
+    /// # Errors
+    ///
+    /// Returns the same errors as [`run`](Interpreter::run).
+    ///
+    /// # Examples
+    ///
+    /// ```ignore
+    /// let outcome = Interpreter::new(flowchart)?.run_outcome()?;
+    /// if outcome.value == Some(Value::from("approved")) {
+    ///     approve(outcome.env.get("amount")?);
+    /// }
+    /// ```
+    pub fn run_outcome(&mut self) -> Result<RunOutcome, RuntimeError> {
+        let exit_code = self.run()?;
+        let value = self
+            .value
+            .clone()
+            .or_else(|| self.result().map(Value::from));
+        Ok(RunOutcome {
+            exit_code,
+            value,
+            env: self.env.clone(),
         })
     }
 
+    /// Returns the variables as they are now, or as they were when the last
+    /// run ended.
+    pub fn env(&self) -> &Environment {
+        &self.env
+    }
+
+    /// Returns the result value set by the last run's `return` statements
+    /// or `|return expr|` edge labels, if any.
+    ///
+    /// Unlike [`RunOutcome::value`], this does not fall back to the
+    /// terminal node's declared [`result`](Interpreter::result).
+    pub fn value(&self) -> Option<&Value> {
+        self.value.as_ref()
+    }
+
+    /// Returns the ID of the terminal node the last run ended at.
+    ///
+    /// This is `"End"` for the `End` node, or the node's ID for a named
+    /// terminal. Returns `None` if the program has not run or did not reach
+    /// a terminal.
+    ///
+    /// # Examples
+    ///
+    /// ```ignore
+    /// let mut interpreter = Interpreter::new(flowchart).unwrap();
+    /// interpreter.run().unwrap();
+    /// if interpreter.terminal_id() == Some("Rejected") {
+    ///     notify_applicant();
+    /// }
+    /// ```
+    pub fn terminal_id(&self) -> Option<&str> {
+        self.terminal.map(|i| self.nodes[i].id())
+    }
+
+    /// Returns the result declared by the terminal node the last run ended at
+    /// (e.g. `approved` for `Done([End: approved])`).
+    ///
+    /// Returns `None` if the terminal declares no result, or if the program
+    /// did not reach a terminal.
+    pub fn result(&self) -> Option<&str> {
+        match self.terminal.map(|i| &self.nodes[i]) {
+            Some(Node::Terminal { result, .. }) => result.as_deref(),
+            _ => None,
+        }
+    }
+
     /// Consumes the interpreter and returns the output writer.
     ///
     /// This is useful for testing when you need to inspect the output
@@ -423,9 +979,130 @@ impl<R: InputReader, W: OutputWriter> Interpreter<R, W> {
     }
 }
 
+/// Records the result value or exit code set by a `return` or `exit`
+/// statement.
+fn record_effect(
+    effect: Option<StatementEffect>,
+    value: &mut Option<Value>,
+    exit_status: &mut Option<u8>,
+    policy: ExitCodePolicy,
+) -> Result<(), RuntimeError> {
+    match effect {
+        Some(StatementEffect::Return(val)) => *value = Some(val),
+        Some(StatementEffect::Exit(val)) => *exit_status = Some(policy.exit_code(&val)?),
+        None => {}
+    }
+    Ok(())
+}
+
+/// Fuses straight-line chains of process nodes into [`Block`]s, moving
+/// their statements out of `nodes`.
+///
+/// A process node is fused into the one before it if that node is also a
+/// process node whose only outgoing edge leads to it, the edge has no label,
+/// actions, exit code or result value, and no other edge leads to it.
+fn fuse_process_chains(
+    nodes: &mut [Node],
+    outgoing_edges: &[Vec<InternalEdge>],
+) -> Vec<Option<Block>> {
+    let mut predecessors = vec![0usize; nodes.len()];
+    for edge in outgoing_edges.iter().flatten() {
+        predecessors[edge.to] += 1;
+    }
+
+    // The node each process node continues into, if the two can be fused
+    let next: Vec<Option<usize>> = (0..nodes.len())
+        .map(|index| {
+            let [edge] = outgoing_edges[index].as_slice() else {
+                return None;
+            };
+            let plain = edge.label.is_none()
+                && edge.actions.is_empty()
+                && edge.exit_code.is_none()
+                && edge.return_value.is_none();
+            (plain
+                && predecessors[edge.to] == 1
+                && matches!(nodes[index], Node::Process { .. })
+                && matches!(nodes[edge.to], Node::Process { .. }))
+            .then_some(edge.to)
+        })
+        .collect();
+    let mut fused = vec![false; nodes.len()];
+    for &to in next.iter().flatten() {
+        fused[to] = true;
+    }
+
+    // Each fused node has exactly one predecessor, so following `next` from
+    // a node that is not fused visits every node of its chain once
+    let mut blocks: Vec<Option<Block>> = (0..nodes.len()).map(|_| None).collect();
+    for head in 0..nodes.len() {
+        if fused[head] || !matches!(nodes[head], Node::Process { .. }) {
+            continue;
+        }
+        let mut statements = Vec::new();
+        let mut index = head;
+        loop {
+            if let Node::Process {
+                statements: node_statements,
+                ..
+            } = &mut nodes[index]
+            {
+                statements.extend(
+                    std::mem::take(node_statements)
+                        .into_iter()
+                        .map(|s| (index, s)),
+                );
+            }
+            match next[index] {
+                Some(to) => index = to,
+                None => break,
+            }
+        }
+        blocks[head] = Some(Block {
+            statements,
+            tail: index,
+        });
+    }
+    blocks
+}
+
+/// Marks the edges that lead from the body of each loop node back to it.
+///
+/// A loop's body is every node reachable from its `body` edge without
+/// passing through the loop node itself.
+fn mark_loop_back_edges(nodes: &[Node], outgoing_edges: &mut [Vec<InternalEdge>]) {
+    for (loop_index, node) in nodes.iter().enumerate() {
+        if !matches!(node, Node::Loop { .. }) {
+            continue;
+        }
+        let mut in_body = vec![false; nodes.len()];
+        let mut stack: Vec<usize> = outgoing_edges[loop_index]
+            .iter()
+            .filter(|edge| edge.label.as_ref().is_some_and(EdgeLabel::is_loop_body))
+            .map(|edge| edge.to)
+            .collect();
+        while let Some(index) = stack.pop() {
+            if index == loop_index || in_body[index] {
+                continue;
+            }
+            in_body[index] = true;
+            stack.extend(outgoing_edges[index].iter().map(|edge| edge.to));
+        }
+
+        for (index, edges) in outgoing_edges.iter_mut().enumerate() {
+            for edge in edges.iter_mut() {
+                if edge.to == loop_index && (in_body[index] || index == loop_index) {
+                    edge.continues_loop = true;
+                }
+            }
+        }
+    }
+}
+
 #[cfg(test)]
 mod tests {
-    use super::super::test_helpers::{FailingOutputWriter, MockInputReader, MockOutputWriter};
+    use super::super::capture::{CapturingWriter, VecInputReader};
+    use super::super::test_helpers::FailingOutputWriter;
     use super::*;
     use crate::ast::{Direction, Edge, Expr, Statement};
 
@@ -451,12 +1128,16 @@ mod tests {
                     to: "A".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         }
@@ -511,36 +1192,48 @@ mod tests {
                     to: "A".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "B".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "B".to_string(),
                     to: "C".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "B".to_string(),
                     to: "D".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "D".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         }
@@ -549,25 +1242,25 @@ mod tests {
     #[test]
     fn test_simple_execution() {
         let flowchart = create_simple_flowchart();
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         interpreter.run().unwrap();
 
-        assert_eq!(interpreter.output_writer.stdout, vec!["hello"]);
+        assert_eq!(interpreter.output_writer.stdout(), vec!["hello"]);
     }
 
     #[test]
     fn test_condition_yes_branch() {
         let flowchart = create_condition_flowchart();
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         interpreter.run().unwrap();
 
-        assert_eq!(interpreter.output_writer.stdout, vec!["big"]);
+        assert_eq!(interpreter.output_writer.stdout(), vec!["big"]);
     }
 
     #[test]
@@ -577,8 +1270,8 @@ mod tests {
             nodes: vec![Node::End { label: None }],
             edges: vec![],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let result = Interpreter::with_io(flowchart, input, output);
         assert!(matches!(result, Err(RuntimeError::MissingStartNode)));
@@ -591,8 +1284,8 @@ mod tests {
             nodes: vec![Node::Start { label: None }],
             edges: vec![],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let result = Interpreter::with_io(flowchart, input, output);
         assert!(matches!(result, Err(RuntimeError::MissingEndNode)));
@@ -605,8 +1298,8 @@ mod tests {
             nodes: vec![Node::Start { label: None }, Node::End { label: None }],
             edges: vec![], // No edge from Start
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         let result = interpreter.run();
@@ -636,23 +1329,27 @@ mod tests {
                     to: "A".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         let result = interpreter.run();
 
         assert!(result.is_ok());
-        assert_eq!(interpreter.output_writer.stderr, vec!["test error"]);
+        assert_eq!(interpreter.output_writer.stderr(), vec!["test error"]);
     }
 
     #[test]
@@ -709,40 +1406,50 @@ mod tests {
                     to: "A".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "B".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "B".to_string(),
                     to: "C".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "B".to_string(),
                     to: "End".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C".to_string(),
                     to: "B".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         interpreter.run().unwrap();
 
-        assert_eq!(interpreter.output_writer.stdout, vec!["1", "2", "3"]);
+        assert_eq!(interpreter.output_writer.stdout(), vec!["1", "2", "3"]);
     }
 
     #[test]
@@ -764,17 +1471,21 @@ mod tests {
                     to: "A".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "End".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         let result = interpreter.run();
@@ -808,17 +1519,21 @@ mod tests {
                     to: "A".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "End".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         let result = interpreter.run();
@@ -851,17 +1566,21 @@ mod tests {
                     to: "A".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "End".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         let result = interpreter.run();
@@ -886,10 +1605,12 @@ mod tests {
                 to: "NonExistent".to_string(),
                 label: None,
                 exit_code: None,
+                return_value: None,
+                actions: vec![],
             }],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         // NodeNotFound is now detected at construction time (fail-fast)
         let result = Interpreter::with_io(flowchart, input, output);
@@ -969,65 +1690,83 @@ mod tests {
                     to: "Init".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "Init".to_string(),
                     to: "A".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "B".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "A".to_string(),
                     to: "E".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "B".to_string(),
                     to: "C".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "B".to_string(),
                     to: "D".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "D".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "E".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         interpreter.run().unwrap();
 
         // x = 3, so x > 0 (Yes) -> x > 5 (No) -> print "medium"
-        assert_eq!(interpreter.output_writer.stdout, vec!["medium"]);
+        assert_eq!(interpreter.output_writer.stdout(), vec!["medium"]);
     }
 
     #[test]
@@ -1139,107 +1878,137 @@ mod tests {
                     to: "Init".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "Init".to_string(),
                     to: "C1".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C1".to_string(),
                     to: "C2".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C1".to_string(),
                     to: "P5".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C2".to_string(),
                     to: "C3".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C2".to_string(),
                     to: "P4".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C3".to_string(),
                     to: "C4".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C3".to_string(),
                     to: "P3".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C4".to_string(),
                     to: "P1".to_string(),
                     label: Some(EdgeLabel::Yes),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "C4".to_string(),
                     to: "P2".to_string(),
                     label: Some(EdgeLabel::No),
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "P1".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "P2".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "P3".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "P4".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
                 Edge {
                     from: "P5".to_string(),
                     to: "End".to_string(),
                     label: None,
                     exit_code: None,
+                    return_value: None,
+                    actions: vec![],
                 },
             ],
         };
-        let input = MockInputReader::new(vec![]);
-        let output = MockOutputWriter::new();
+        let input = VecInputReader::default();
+        let output = CapturingWriter::new();
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
         interpreter.run().unwrap();
 
         // x = 3: C1(>=1 Yes) -> C2(>=2 Yes) -> C3(>=3 Yes) -> C4(>=4 No) -> P2 "level 3"
-        assert_eq!(interpreter.output_writer.stdout, vec!["level 3"]);
+        assert_eq!(interpreter.output_writer.stdout(), vec!["level 3"]);
     }
 
     #[test]
     fn test_interpreter_propagates_write_error() {
         let flowchart = create_simple_flowchart();
-        let input = MockInputReader::new(vec![]);
+        let input = VecInputReader::default();
         let output = FailingOutputWriter;
 
         let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
@@ -1247,4 +2016,160 @@ mod tests {
 
         assert!(matches!(result, Err(RuntimeError::IoError { .. })));
     }
+
+    #[test]
+    fn test_run_flushes_output_at_end() {
+        use super::super::test_helpers::BufferingOutputWriter;
+
+        let flowchart = create_simple_flowchart();
+        let input = VecInputReader::default();
+        let output = BufferingOutputWriter::new();
+
+        let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
+        interpreter.run().unwrap();
+
+        let output = interpreter.into_output_writer();
+        assert_eq!(output.flushed, vec!["hello"]);
+        assert!(output.pending.is_empty());
+    }
+
+    #[test]
+    fn test_run_flushes_output_on_error() {
+        use super::super::test_helpers::BufferingOutputWriter;
+
+        // Start --> A[println 'hello'; println x] --> End, with x undefined
+        let mut flowchart = create_simple_flowchart();
+        if let Node::Process { statements, .. } = &mut flowchart.nodes[1] {
+            statements.push(Statement::Println {
+                expr: Expr::Variable {
+                    name: "x".to_string(),
+                },
+            });
+        }
+        let input = VecInputReader::default();
+        let output = BufferingOutputWriter::new();
+
+        let mut interpreter = Interpreter::with_io(flowchart, input, output).unwrap();
+        let result = interpreter.run();
+
+        assert!(matches!(
+            result,
+            Err(RuntimeError::UndefinedVariable { .. })
+        ));
+        let output = interpreter.into_output_writer();
+        assert_eq!(output.flushed, vec!["hello"]);
+    }
+
+    /// Returns the IDs of the nodes in each block, keyed by the block's
+    /// first node.
+    fn block_ids<R: InputReader, W: OutputWriter>(
+        interpreter: &Interpreter<R, W>,
+    ) -> Vec<(String, Vec<String>, String)> {
+        let id = |index: usize| interpreter.nodes[index].id().to_string();
+        let mut blocks: Vec<_> = interpreter
+            .blocks
+            .iter()
+            .enumerate()
+            .filter_map(|(head, block)| {
+                let block = block.as_ref()?;
+                let ids = block.statements.iter().map(|(i, _)| id(*i)).collect();
+                Some((id(head), ids, id(block.tail)))
+            })
+            .collect();
+        blocks.sort();
+        blocks
+    }
+
+    #[test]
+    fn test_fuse_process_chains() {
+        let source = r#"flowchart TD
+    Start --> A[x = 0]
+    A --> B[y = 1; z = 2]
+    B --> C{x < 2?}
+    C -->|Yes| D[x = x + 1]
+    D --> E[println x]
+    E --> C
+    C -->|No| F[println y]
+    F -->|/ y = 3| G[println y]
+    G --> End
+"#;
+        let flowchart = crate::parser::parse(source).unwrap();
+        let interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .unwrap();
+
+        let ids = |ids: &[&str]| ids.iter().map(|s| s.to_string()).collect::<Vec<_>>();
+        assert_eq!(
+            block_ids(&interpreter),
+            vec![
+                ("A".to_string(), ids(&["A", "B", "B"]), "B".to_string()),
+                ("D".to_string(), ids(&["D", "E"]), "E".to_string()),
+                // The edge into G has an action, so F and G stay separate
+                ("F".to_string(), ids(&["F"]), "F".to_string()),
+                ("G".to_string(), ids(&["G"]), "G".to_string()),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_fused_chain_runs_like_separate_nodes() {
+        let source = r#"flowchart TD
+    Start --> A[x = 0]
+    A --> B[y = 1; z = 2]
+    B --> C{x < 2?}
+    C -->|Yes| D[x = x + 1]
+    D --> E[println x]
+    E --> C
+    C -->|No| F[println y]
+    F -->|/ y = 3| G[println y]
+    G --> End
+"#;
+        let flowchart = crate::parser::parse(source).unwrap();
+        let mut interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .unwrap();
+        interpreter.run().unwrap();
+
+        assert_eq!(interpreter.output_writer.stdout(), vec!["1", "2", "1", "3"]);
+    }
+
+    #[test]
+    fn test_fused_chain_leaves_from_last_node() {
+        // Start --> A[x = 1] --> B[y = 2], with no edge out of B
+        let process = |id: &str, variable: &str| Node::Process {
+            id: id.to_string(),
+            statements: vec![Statement::Assign {
+                variable: variable.to_string(),
+                value: Expr::IntLit { value: 1 },
+            }],
+        };
+        let edge = |from: &str, to: &str| Edge {
+            from: from.to_string(),
+            to: to.to_string(),
+            label: None,
+            exit_code: None,
+            return_value: None,
+            actions: vec![],
+        };
+        let flowchart = Flowchart {
+            direction: Direction::Td,
+            nodes: vec![
+                Node::Start { label: None },
+                process("A", "x"),
+                process("B", "y"),
+                Node::End { label: None },
+            ],
+            edges: vec![edge("Start", "A"), edge("A", "B")],
+        };
+        let mut interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .unwrap();
+
+        let result = interpreter.run();
+        assert!(matches!(
+            result,
+            Err(RuntimeError::NoOutgoingEdge { node_id }) if node_id == "B"
+        ));
+        assert_eq!(interpreter.env().get("y").unwrap(), &Value::Int(1));
+    }
 }
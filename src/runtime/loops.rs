@@ -0,0 +1,121 @@
+//! Iteration state for loop nodes.
+
+use std::vec;
+
+use crate::ast::LoopIter;
+
+use super::builtins::Builtins;
+use super::env::Environment;
+use super::error::RuntimeError;
+use super::eval::{InputReader, eval_expr};
+use super::exec::{FlushingReader, OutputWriter};
+use super::value::Value;
+
+/// The values a running loop has yet to assign to its variable.
+pub(super) enum LoopState {
+    /// The integers from `next` to `end`, inclusive.
+    Range { next: i64, end: i64 },
+
+    /// The remaining values of a list, or characters of a string.
+    Values(vec::IntoIter<Value>),
+}
+
+impl LoopState {
+    /// Evaluates a loop's values when the loop starts.
+    ///
+    /// # Errors
+    ///
+    /// - [`RuntimeError::TypeError`] - A range bound is not an int, or a
+    ///   single value is not a str
+    /// - Any error from evaluating the expressions
+    pub(super) fn start<R: InputReader, W: OutputWriter>(
+        iter: &LoopIter,
+        env: &Environment,
+        input_reader: &mut R,
+        output_writer: &mut W,
+        builtins: &mut Builtins,
+    ) -> Result<Self, RuntimeError> {
+        let mut reader = FlushingReader::new(input_reader, output_writer);
+        match iter {
+            LoopIter::Range { start, end } => {
+                let mut bound = |expr| {
+                    let val = eval_expr(expr, env, &mut reader, builtins)?;
+                    match *val {
+                        Value::Int(n) => Ok(n),
+                        ref other => Err(RuntimeError::TypeError {
+                            expected: "int",
+                            actual: other.type_name(),
+                            operation: "loop range".to_string(),
+                        }),
+                    }
+                };
+                let next = bound(start)?;
+                let end = bound(end)?;
+                Ok(LoopState::Range { next, end })
+            }
+            LoopIter::Values(exprs) => {
+                let mut values = exprs
+                    .iter()
+                    .map(|expr| Ok(eval_expr(expr, env, &mut reader, builtins)?.into_owned()))
+                    .collect::<Result<Vec<Value>, RuntimeError>>()?;
+                // A single value must be a string, whose characters are
+                // iterated over
+                if let [value] = values.as_slice() {
+                    let Value::Str(s) = value else {
+                        return Err(RuntimeError::TypeError {
+                            expected: "str",
+                            actual: value.type_name(),
+                            operation: "loop over a single value".to_string(),
+                        });
+                    };
+                    values = s.chars().map(|c| Value::from(c.to_string())).collect();
+                }
+                Ok(LoopState::Values(values.into_iter()))
+            }
+        }
+    }
+}
+
+impl Iterator for LoopState {
+    type Item = Value;
+
+    fn next(&mut self) -> Option<Value> {
+        match self {
+            LoopState::Range { next, end } => {
+                if next > end {
+                    return None;
+                }
+                let value = *next;
+                // Past the last value, `next` only needs to exceed `end`
+                match next.checked_add(1) {
+                    Some(n) => *next = n,
+                    None => *end = i64::MIN,
+                }
+                Some(Value::Int(value))
+            }
+            LoopState::Values(values) => values.next(),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_range_is_inclusive() {
+        let values: Vec<Value> = LoopState::Range { next: 1, end: 3 }.collect();
+        assert_eq!(values, vec![Value::Int(1), Value::Int(2), Value::Int(3)]);
+        assert_eq!(LoopState::Range { next: 3, end: 1 }.count(), 0);
+    }
+
+    #[test]
+    fn test_range_ending_at_max_stops() {
+        let values: Vec<Value> = LoopState::Range {
+            next: i64::MAX - 1,
+            end: i64::MAX,
+        }
+        .collect();
+        assert_eq!(values, vec![Value::Int(i64::MAX - 1), Value::Int(i64::MAX)]);
+    }
+}
@@ -12,8 +12,14 @@
 //! - `env`: Variable storage and lookup ([`Environment`])
 //! - `eval`: Expression evaluation ([`eval_expr`], [`InputReader`])
 //! - `exec`: Statement execution ([`exec_statement`], [`OutputWriter`])
+//! - `builtins`: Built-in function state and dispatch ([`Builtins`])
+//! - `capture`: In-memory I/O for embedding and tests ([`VecInputReader`], [`CapturingWriter`])
+//! - `clock`: Time source for time functions and `sleep` ([`Clock`])
 //! - `error`: Runtime error definitions ([`RuntimeError`])
+//! - `exit`: Conversion of computed exit codes ([`ExitCodePolicy`])
 //! - `interpreter`: Main execution loop ([`Interpreter`])
+//! - `loops`: Iteration state for loop nodes
+//! - `table`: Rule selection for decision table nodes
 //!
 //! # Architecture
 //!
@@ -29,8 +35,9 @@
 //!
 //! The runtime uses trait-based dependency injection for I/O operations:
 //!
-//! - [`InputReader`]: Abstracts input reading (stdin or mock for testing)
-//! - [`OutputWriter`]: Abstracts output writing (stdout/stderr or mock for testing)
+//! - [`InputReader`]: Abstracts input reading (stdin, or [`VecInputReader`] for testing)
+//! - [`OutputWriter`]: Abstracts output writing (stdout/stderr, or [`CapturingWriter`] for testing)
+//! - [`Clock`]: Abstracts the current time and sleeping (system clock or fake for testing)
 //!
 //! # Example
 //!
@@ -49,18 +56,33 @@
 //! interpreter.run().unwrap();
 //! ```
 
+mod builtins;
+mod capture;
+mod clock;
+mod date;
 mod env;
 mod error;
 mod eval;
 mod exec;
+mod exit;
 mod interpreter;
+mod loops;
+mod random;
+mod table;
 #[cfg(test)]
 pub(crate) mod test_helpers;
 mod value;
 
+pub use builtins::Builtins;
+pub use capture::{CapturingWriter, OutputChunk, VecInputReader};
+pub use clock::{Clock, ManualClock, SystemClock};
 pub use env::Environment;
 pub use error::RuntimeError;
 pub use eval::{InputReader, StdinReader, eval_expr};
-pub use exec::{OutputWriter, StdioWriter, exec_statement};
-pub use interpreter::Interpreter;
+pub use exec::{
+    BufferedStdioWriter, OutputEvent, OutputStream, OutputWriter, StatementEffect, StdioWriter,
+    exec_statement,
+};
+pub use exit::ExitCodePolicy;
+pub use interpreter::{Interpreter, RunOutcome};
 pub use value::Value;
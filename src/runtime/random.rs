@@ -0,0 +1,167 @@
+//! Pseudo-random number generation.
+//!
+//! This module provides [`Rng`], a small deterministic generator backing the
+//! random built-in functions. Programs run with the same seed always produce
+//! the same sequence of values, which keeps seeded runs reproducible across
+//! platforms and merx versions.
+//!
+//! # Algorithm
+//!
+//! The generator is SplitMix64: a 64-bit counter advanced by a fixed odd
+//! constant and passed through a bijective mixing function. It is fast,
+//! has a full 2^64 period, and passes BigCrush, which is more than enough
+//! for games and simulations. It is **not** cryptographically secure.
+
+use std::collections::hash_map::RandomState;
+use std::hash::{BuildHasher, Hasher};
+use std::time::{SystemTime, UNIX_EPOCH};
+
+/// A seedable SplitMix64 pseudo-random number generator.
+///
+/// # Examples
+///
+/// ```ignore
+/// let mut a = Rng::seed_from(42);
+/// let mut b = Rng::seed_from(42);
+/// assert_eq!(a.next_u64(), b.next_u64());
+/// ```
+#[derive(Debug, Clone)]
+pub(crate) struct Rng {
+    state: u64,
+}
+
+impl Rng {
+    /// Creates a generator whose sequence is fully determined by `seed`.
+    pub(crate) fn seed_from(seed: u64) -> Self {
+        Self { state: seed }
+    }
+
+    /// Creates a generator seeded from operating system entropy.
+    ///
+    /// The standard library's [`RandomState`] is keyed from the OS random
+    /// source; hashing the current time through it yields a seed that
+    /// differs between runs without requiring an extra dependency.
+    pub(crate) fn from_entropy() -> Self {
+        let mut hasher = RandomState::new().build_hasher();
+        let nanos = SystemTime::now()
+            .duration_since(UNIX_EPOCH)
+            .map(|d| d.as_nanos())
+            .unwrap_or(0);
+        hasher.write_u128(nanos);
+        Self::seed_from(hasher.finish())
+    }
+
+    /// Returns the next 64 random bits.
+    pub(crate) fn next_u64(&mut self) -> u64 {
+        self.state = self.state.wrapping_add(0x9E37_79B9_7F4A_7C15);
+        let mut z = self.state;
+        z = (z ^ (z >> 30)).wrapping_mul(0xBF58_476D_1CE4_E5B9);
+        z = (z ^ (z >> 27)).wrapping_mul(0x94D0_49BB_1331_11EB);
+        z ^ (z >> 31)
+    }
+
+    /// Returns a uniformly distributed value in `0..bound`.
+    ///
+    /// Uses Lemire's multiply-and-reject method, so the result is unbiased
+    /// for every bound.
+    ///
+    /// # Panics
+    ///
+    /// Panics if `bound` is zero.
+    pub(crate) fn below(&mut self, bound: u64) -> u64 {
+        assert!(bound > 0, "bound must be positive");
+        let threshold = bound.wrapping_neg() % bound;
+        loop {
+            let product = u128::from(self.next_u64()) * u128::from(bound);
+            if (product as u64) >= threshold {
+                return (product >> 64) as u64;
+            }
+        }
+    }
+
+    /// Returns a uniformly distributed integer in `lo..=hi`.
+    ///
+    /// The caller must ensure `lo <= hi`.
+    pub(crate) fn range_inclusive(&mut self, lo: i64, hi: i64) -> i64 {
+        debug_assert!(lo <= hi);
+        let span = hi.wrapping_sub(lo) as u64;
+        let offset = if span == u64::MAX {
+            self.next_u64()
+        } else {
+            self.below(span + 1)
+        };
+        lo.wrapping_add(offset as i64)
+    }
+
+    /// Shuffles a slice in place using the Fisher-Yates algorithm.
+    pub(crate) fn shuffle<T>(&mut self, items: &mut [T]) {
+        for i in (1..items.len()).rev() {
+            let j = self.below(i as u64 + 1) as usize;
+            items.swap(i, j);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_same_seed_same_sequence() {
+        let mut a = Rng::seed_from(42);
+        let mut b = Rng::seed_from(42);
+        for _ in 0..100 {
+            assert_eq!(a.next_u64(), b.next_u64());
+        }
+    }
+
+    #[test]
+    fn test_different_seed_different_sequence() {
+        let mut a = Rng::seed_from(1);
+        let mut b = Rng::seed_from(2);
+        assert_ne!(a.next_u64(), b.next_u64());
+    }
+
+    #[test]
+    fn test_known_splitmix64_output() {
+        // Reference values for SplitMix64 seeded with 0.
+        let mut rng = Rng::seed_from(0);
+        assert_eq!(rng.next_u64(), 0xE220_A839_7B1D_CDAF);
+        assert_eq!(rng.next_u64(), 0x6E78_9E6A_A1B9_65F4);
+    }
+
+    #[test]
+    fn test_range_inclusive_bounds() {
+        let mut rng = Rng::seed_from(7);
+        let mut seen = [false; 6];
+        for _ in 0..1000 {
+            let n = rng.range_inclusive(1, 6);
+            assert!((1..=6).contains(&n));
+            seen[(n - 1) as usize] = true;
+        }
+        assert!(seen.iter().all(|&s| s));
+    }
+
+    #[test]
+    fn test_range_inclusive_single_value() {
+        let mut rng = Rng::seed_from(7);
+        assert_eq!(rng.range_inclusive(5, 5), 5);
+    }
+
+    #[test]
+    fn test_range_inclusive_full_range() {
+        let mut rng = Rng::seed_from(7);
+        // Must not overflow when the span covers all of i64.
+        rng.range_inclusive(i64::MIN, i64::MAX);
+    }
+
+    #[test]
+    fn test_shuffle_is_permutation() {
+        let mut rng = Rng::seed_from(3);
+        let mut items: Vec<u32> = (0..50).collect();
+        rng.shuffle(&mut items);
+        let mut sorted = items.clone();
+        sorted.sort();
+        assert_eq!(sorted, (0..50).collect::<Vec<_>>());
+    }
+}
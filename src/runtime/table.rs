@@ -0,0 +1,72 @@
+//! Rule selection for decision table nodes.
+
+use crate::ast::{DecisionTable, HitPolicy};
+
+use super::builtins::Builtins;
+use super::env::Environment;
+use super::error::RuntimeError;
+use super::eval::{InputReader, eval_binary, eval_expr};
+use super::exec::{FlushingReader, OutputWriter};
+use super::value::Value;
+
+/// Returns the index of the rule the table selects in the current
+/// environment.
+///
+/// Each input column is evaluated once, in order. The rules are then tested
+/// in table order: [`HitPolicy::First`] stops at the first match, while
+/// [`HitPolicy::Unique`] tests every rule to make sure only one matches.
+///
+/// # Errors
+///
+/// - [`RuntimeError::NoMatchingRule`] - No rule matched
+/// - [`RuntimeError::MultipleMatchingRules`] - More than one rule matched
+///   under the unique hit policy
+/// - [`RuntimeError::TypeError`] - A test compared incompatible values
+/// - Any error from evaluating an input or test expression
+pub(super) fn select_rule<R: InputReader, W: OutputWriter>(
+    table: &DecisionTable,
+    node_id: &str,
+    env: &Environment,
+    input_reader: &mut R,
+    output_writer: &mut W,
+    builtins: &mut Builtins,
+) -> Result<usize, RuntimeError> {
+    let mut reader = FlushingReader::new(input_reader, output_writer);
+    let inputs = table
+        .inputs
+        .iter()
+        .map(|input| Ok(eval_expr(&input.expr, env, &mut reader, builtins)?.into_owned()))
+        .collect::<Result<Vec<Value>, RuntimeError>>()?;
+
+    let mut selected = None;
+    for (index, rule) in table.rules.iter().enumerate() {
+        let mut matched = true;
+        for (test, input) in rule.tests.iter().zip(&inputs) {
+            let Some(test) = test else { continue };
+            let value = eval_expr(&test.value, env, &mut reader, builtins)?;
+            if eval_binary(test.op, input, &value)? != Value::Bool(true) {
+                matched = false;
+                break;
+            }
+        }
+        if !matched {
+            continue;
+        }
+
+        match (table.hit_policy, selected) {
+            (HitPolicy::First, _) => return Ok(index),
+            (HitPolicy::Unique, Some(first)) => {
+                return Err(RuntimeError::MultipleMatchingRules {
+                    node_id: node_id.to_string(),
+                    first: first + 1,
+                    second: index + 1,
+                });
+            }
+            (HitPolicy::Unique, None) => selected = Some(index),
+        }
+    }
+
+    selected.ok_or_else(|| RuntimeError::NoMatchingRule {
+        node_id: node_id.to_string(),
+    })
+}
@@ -1,91 +1,63 @@
 use super::error::RuntimeError;
-use super::eval::InputReader;
 use super::exec::OutputWriter;
 
-/// Mock input reader for testing.
-///
-/// Returns pre-configured lines in order, then returns an error when exhausted.
-pub(crate) struct MockInputReader {
-    lines: Vec<String>,
-    index: usize,
-}
+/// Output writer that always fails, for testing error propagation.
+pub(crate) struct FailingOutputWriter;
 
-impl MockInputReader {
-    pub(crate) fn new(lines: Vec<&str>) -> Self {
-        Self {
-            lines: lines.into_iter().map(|s| s.to_string()).collect(),
-            index: 0,
-        }
+impl OutputWriter for FailingOutputWriter {
+    fn write_stdout(&mut self, _s: &str) -> Result<(), RuntimeError> {
+        Err(RuntimeError::IoError {
+            message: "stdout write failed".to_string(),
+        })
     }
-}
 
-impl InputReader for MockInputReader {
-    fn read_line(&mut self) -> Result<String, RuntimeError> {
-        if self.index < self.lines.len() {
-            let line = self.lines[self.index].clone();
-            self.index += 1;
-            Ok(line)
-        } else {
-            Err(RuntimeError::IoError {
-                message: "No more input".to_string(),
-            })
-        }
+    fn write_stdout_no_newline(&mut self, _s: &str) -> Result<(), RuntimeError> {
+        Err(RuntimeError::IoError {
+            message: "stdout write failed".to_string(),
+        })
+    }
+
+    fn write_stderr(&mut self, _s: &str) -> Result<(), RuntimeError> {
+        Err(RuntimeError::IoError {
+            message: "stderr write failed".to_string(),
+        })
     }
 }
 
-/// Mock output writer for testing.
+/// Output writer that holds stdout in a buffer until flushed.
 ///
-/// Captures stdout and stderr output into separate vectors.
-pub(crate) struct MockOutputWriter {
-    pub stdout: Vec<String>,
-    pub stderr: Vec<String>,
+/// Used to verify that the runtime flushes at the right moments.
+pub(crate) struct BufferingOutputWriter {
+    pub pending: Vec<String>,
+    pub flushed: Vec<String>,
 }
 
-impl MockOutputWriter {
+impl BufferingOutputWriter {
     pub(crate) fn new() -> Self {
         Self {
-            stdout: Vec::new(),
-            stderr: Vec::new(),
+            pending: Vec::new(),
+            flushed: Vec::new(),
         }
     }
 }
 
-impl OutputWriter for MockOutputWriter {
+impl OutputWriter for BufferingOutputWriter {
     fn write_stdout(&mut self, s: &str) -> Result<(), RuntimeError> {
-        self.stdout.push(s.to_string());
+        self.pending.push(s.to_string());
         Ok(())
     }
 
     fn write_stdout_no_newline(&mut self, s: &str) -> Result<(), RuntimeError> {
-        self.stdout.push(s.to_string());
+        self.pending.push(s.to_string());
         Ok(())
     }
 
-    fn write_stderr(&mut self, s: &str) -> Result<(), RuntimeError> {
-        self.stderr.push(s.to_string());
+    fn write_stderr(&mut self, _s: &str) -> Result<(), RuntimeError> {
         Ok(())
     }
-}
-
-/// Output writer that always fails, for testing error propagation.
-pub(crate) struct FailingOutputWriter;
-
-impl OutputWriter for FailingOutputWriter {
-    fn write_stdout(&mut self, _s: &str) -> Result<(), RuntimeError> {
-        Err(RuntimeError::IoError {
-            message: "stdout write failed".to_string(),
-        })
-    }
 
-    fn write_stdout_no_newline(&mut self, _s: &str) -> Result<(), RuntimeError> {
-        Err(RuntimeError::IoError {
-            message: "stdout write failed".to_string(),
-        })
-    }
-
-    fn write_stderr(&mut self, _s: &str) -> Result<(), RuntimeError> {
-        Err(RuntimeError::IoError {
-            message: "stderr write failed".to_string(),
-        })
+    fn flush(&mut self) -> Result<(), RuntimeError> {
+        self.flushed.append(&mut self.pending);
+        Ok(())
     }
 }
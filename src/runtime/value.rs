@@ -10,7 +10,7 @@
 //! | Type | Rust Representation | Example |
 //! |------|---------------------|---------|
 //! | `int` | `i64` | `42`, `-17` |
-//! | `str` | `String` | `"hello"` |
+//! | `str` | `Arc<String>` | `"hello"` |
 //! | `bool` | `bool` | `true`, `false` |
 //!
 //! # Type Coercion
@@ -27,13 +27,21 @@
 //! - Integers: decimal notation (e.g., `42`)
 //! - Strings: raw content without quotes (e.g., `hello`)
 //! - Booleans: lowercase `true` or `false`
+//!
+//! # Strings
+//!
+//! String contents are reference counted, so copying a string value (for
+//! example, reading a variable into another) does not copy its characters.
+//! [`Value::push_str`] appends in place when the value holds the only
+//! reference to its contents, which keeps loops like `s = s + 'a'` linear.
 
 use std::fmt;
+use std::sync::Arc;
 
 /// A runtime value in the merx language.
 ///
 /// This enum represents the three fundamental types supported by the interpreter.
-/// Values are immutable and can be cloned efficiently.
+/// Values can be cloned cheaply: cloning a string shares its contents.
 ///
 /// # Variants
 ///
@@ -47,7 +55,7 @@ use std::fmt;
 /// use merx::runtime::Value;
 ///
 /// let int_val = Value::Int(42);
-/// let str_val = Value::Str("hello".to_string());
+/// let str_val = Value::from("hello");
 /// let bool_val = Value::Bool(true);
 ///
 /// assert_eq!(int_val.type_name(), "int");
@@ -65,7 +73,8 @@ pub enum Value {
     /// A UTF-8 string value.
     ///
     /// Strings are the result of string literals, user input, or type casts.
-    Str(String),
+    /// The contents are shared between clones; see [`Value::push_str`].
+    Str(Arc<String>),
 
     /// A boolean value.
     ///
@@ -90,7 +99,7 @@ impl Value {
     /// use merx::runtime::Value;
     ///
     /// assert_eq!(Value::Int(0).type_name(), "int");
-    /// assert_eq!(Value::Str(String::new()).type_name(), "str");
+    /// assert_eq!(Value::from(String::new()).type_name(), "str");
     /// assert_eq!(Value::Bool(false).type_name(), "bool");
     /// ```
     pub fn type_name(&self) -> &'static str {
@@ -114,7 +123,7 @@ impl Value {
     /// use merx::runtime::Value;
     ///
     /// assert_eq!(Value::Int(42).as_int(), Some(42));
-    /// assert_eq!(Value::Str("42".to_string()).as_int(), None);
+    /// assert_eq!(Value::from("42").as_int(), None);
     /// ```
     pub fn as_int(&self) -> Option<i64> {
         match self {
@@ -157,7 +166,7 @@ impl Value {
     /// ```
     /// use merx::runtime::Value;
     ///
-    /// assert_eq!(Value::Str("hello".to_string()).as_str(), Some("hello"));
+    /// assert_eq!(Value::from("hello").as_str(), Some("hello"));
     /// assert_eq!(Value::Int(42).as_str(), None);
     /// ```
     pub fn as_str(&self) -> Option<&str> {
@@ -166,6 +175,50 @@ impl Value {
             _ => None,
         }
     }
+
+    /// Appends `suffix` to a string value.
+    ///
+    /// The contents are modified in place if no other value shares them, and
+    /// copied first otherwise, so other clones never observe the change.
+    ///
+    /// # Returns
+    ///
+    /// `false` (leaving the value unchanged) if this is not a [`Value::Str`].
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use merx::runtime::Value;
+    ///
+    /// let mut s = Value::from("ab");
+    /// let copy = s.clone();
+    /// assert!(s.push_str("c"));
+    /// assert_eq!(s, Value::from("abc"));
+    /// assert_eq!(copy, Value::from("ab"));
+    ///
+    /// assert!(!Value::Int(1).push_str("c"));
+    /// ```
+    pub fn push_str(&mut self, suffix: &str) -> bool {
+        match self {
+            Value::Str(s) => {
+                Arc::make_mut(s).push_str(suffix);
+                true
+            }
+            _ => false,
+        }
+    }
+}
+
+impl From<String> for Value {
+    fn from(s: String) -> Self {
+        Value::Str(Arc::new(s))
+    }
+}
+
+impl From<&str> for Value {
+    fn from(s: &str) -> Self {
+        Value::Str(Arc::new(s.to_string()))
+    }
 }
 
 impl fmt::Display for Value {
@@ -192,7 +245,7 @@ mod tests {
     #[test]
     fn test_value_display() {
         assert_eq!(Value::Int(42).to_string(), "42");
-        assert_eq!(Value::Str("hello".to_string()).to_string(), "hello");
+        assert_eq!(Value::from("hello").to_string(), "hello");
         assert_eq!(Value::Bool(true).to_string(), "true");
         assert_eq!(Value::Bool(false).to_string(), "false");
     }
@@ -200,14 +253,14 @@ mod tests {
     #[test]
     fn test_value_type_name() {
         assert_eq!(Value::Int(0).type_name(), "int");
-        assert_eq!(Value::Str(String::new()).type_name(), "str");
+        assert_eq!(Value::from(String::new()).type_name(), "str");
         assert_eq!(Value::Bool(false).type_name(), "bool");
     }
 
     #[test]
     fn test_value_as_int() {
         assert_eq!(Value::Int(42).as_int(), Some(42));
-        assert_eq!(Value::Str("42".to_string()).as_int(), None);
+        assert_eq!(Value::from("42").as_int(), None);
         assert_eq!(Value::Bool(true).as_int(), None);
     }
 
@@ -215,77 +268,101 @@ mod tests {
     fn test_value_as_bool() {
         assert_eq!(Value::Bool(true).as_bool(), Some(true));
         assert_eq!(Value::Int(1).as_bool(), None);
-        assert_eq!(Value::Str("true".to_string()).as_bool(), None);
+        assert_eq!(Value::from("true").as_bool(), None);
     }
 
     #[test]
     fn test_value_as_str() {
-        assert_eq!(Value::Str("hello".to_string()).as_str(), Some("hello"));
+        assert_eq!(Value::from("hello").as_str(), Some("hello"));
         assert_eq!(Value::Int(42).as_str(), None);
         assert_eq!(Value::Bool(true).as_str(), None);
     }
 
     #[test]
     fn test_value_empty_string() {
-        let empty = Value::Str(String::new());
+        let empty = Value::from(String::new());
         assert_eq!(empty.type_name(), "str");
         assert_eq!(empty.as_str(), Some(""));
         assert_eq!(empty.to_string(), "");
 
-        let empty2 = Value::Str("".to_string());
+        let empty2 = Value::from("");
         assert_eq!(empty, empty2);
     }
 
     #[test]
     fn test_value_unicode_string() {
-        let japanese = Value::Str("こんにちは".to_string());
+        let japanese = Value::from("こんにちは");
         assert_eq!(japanese.type_name(), "str");
         assert_eq!(japanese.as_str(), Some("こんにちは"));
         assert_eq!(japanese.to_string(), "こんにちは");
 
-        let emoji = Value::Str("Hello 🌍🚀".to_string());
+        let emoji = Value::from("Hello 🌍🚀");
         assert_eq!(emoji.as_str(), Some("Hello 🌍🚀"));
         assert_eq!(emoji.to_string(), "Hello 🌍🚀");
 
-        let mixed = Value::Str("日本語とEnglish混在".to_string());
+        let mixed = Value::from("日本語とEnglish混在");
         assert_eq!(mixed.as_str(), Some("日本語とEnglish混在"));
     }
 
     #[test]
     fn test_value_long_string() {
         let long_str = "a".repeat(10_000);
-        let value = Value::Str(long_str.clone());
+        let value = Value::from(long_str.clone());
         assert_eq!(value.type_name(), "str");
         assert_eq!(value.as_str(), Some(long_str.as_str()));
         assert_eq!(value.to_string().len(), 10_000);
 
         let very_long = "x".repeat(1_000_000);
-        let value2 = Value::Str(very_long.clone());
+        let value2 = Value::from(very_long.clone());
         assert_eq!(value2.as_str().map(|s| s.len()), Some(1_000_000));
     }
 
     #[test]
     fn test_value_special_chars() {
-        let newline = Value::Str("line1\nline2".to_string());
+        let newline = Value::from("line1\nline2");
         assert_eq!(newline.as_str(), Some("line1\nline2"));
         assert_eq!(newline.to_string(), "line1\nline2");
 
-        let tab = Value::Str("col1\tcol2".to_string());
+        let tab = Value::from("col1\tcol2");
         assert_eq!(tab.as_str(), Some("col1\tcol2"));
 
-        let backslash = Value::Str("path\\to\\file".to_string());
+        let backslash = Value::from("path\\to\\file");
         assert_eq!(backslash.as_str(), Some("path\\to\\file"));
 
-        let mixed_special = Value::Str("a\nb\tc\\d".to_string());
+        let mixed_special = Value::from("a\nb\tc\\d");
         assert_eq!(mixed_special.as_str(), Some("a\nb\tc\\d"));
 
-        let carriage_return = Value::Str("line1\r\nline2".to_string());
+        let carriage_return = Value::from("line1\r\nline2");
         assert_eq!(carriage_return.as_str(), Some("line1\r\nline2"));
 
-        let null_char = Value::Str("before\0after".to_string());
+        let null_char = Value::from("before\0after");
         assert_eq!(null_char.as_str(), Some("before\0after"));
     }
 
+    #[test]
+    fn test_push_str_shares_until_written() {
+        let mut s = Value::from("ab");
+        let copy = s.clone();
+        let (Value::Str(a), Value::Str(b)) = (&s, &copy) else {
+            unreachable!()
+        };
+        assert!(Arc::ptr_eq(a, b));
+
+        // The first append copies, later ones reuse the same buffer
+        s.push_str("c");
+        let Value::Str(before) = &s else {
+            unreachable!()
+        };
+        let before = Arc::as_ptr(before);
+        s.push_str("d");
+        let Value::Str(after) = &s else {
+            unreachable!()
+        };
+        assert_eq!(Arc::as_ptr(after), before);
+        assert_eq!(s, Value::from("abcd"));
+        assert_eq!(copy, Value::from("ab"));
+    }
+
     #[test]
     fn test_value_partial_eq() {
         // Int == Int
@@ -297,15 +374,9 @@ mod tests {
         assert_eq!(Value::Int(i64::MIN), Value::Int(i64::MIN));
 
         // Str == Str
-        assert_eq!(
-            Value::Str("hello".to_string()),
-            Value::Str("hello".to_string())
-        );
-        assert_ne!(
-            Value::Str("hello".to_string()),
-            Value::Str("world".to_string())
-        );
-        assert_eq!(Value::Str(String::new()), Value::Str(String::new()));
+        assert_eq!(Value::from("hello"), Value::from("hello"));
+        assert_ne!(Value::from("hello"), Value::from("world"));
+        assert_eq!(Value::from(String::new()), Value::from(String::new()));
 
         // Bool == Bool
         assert_eq!(Value::Bool(true), Value::Bool(true));
@@ -313,15 +384,15 @@ mod tests {
         assert_ne!(Value::Bool(true), Value::Bool(false));
 
         // Int != Str
-        assert_ne!(Value::Int(42), Value::Str("42".to_string()));
-        assert_ne!(Value::Int(0), Value::Str("0".to_string()));
+        assert_ne!(Value::Int(42), Value::from("42"));
+        assert_ne!(Value::Int(0), Value::from("0"));
 
         // Int != Bool
         assert_ne!(Value::Int(1), Value::Bool(true));
         assert_ne!(Value::Int(0), Value::Bool(false));
 
         // Str != Bool
-        assert_ne!(Value::Str("true".to_string()), Value::Bool(true));
-        assert_ne!(Value::Str("false".to_string()), Value::Bool(false));
+        assert_ne!(Value::from("true"), Value::Bool(true));
+        assert_ne!(Value::from("false"), Value::Bool(false));
     }
 }
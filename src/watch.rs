@@ -0,0 +1,447 @@
+//! File watching for `--watch` mode.
+//!
+//! [`watch`] runs an action, prints a summary line, and waits for the
+//! program file, or a file the program reads, to change on disk before
+//! running it again, until the process is interrupted.
+//!
+//! # Backends
+//!
+//! On Linux, changes are detected with inotify. The watch is placed on the
+//! file's parent directory rather than the file itself, so editors that
+//! save by writing a temporary file and renaming it over the original are
+//! still noticed. On other platforms, or if inotify cannot be initialized,
+//! the file's modification time and size are polled instead.
+//!
+//! Besides the program file, the CSV files its decision tables are read
+//! from are watched. The action reports them after each run, since they
+//! change as the program is edited.
+
+use std::fs;
+use std::io::{self, IsTerminal, Write};
+use std::path::{Path, PathBuf};
+use std::process::ExitCode;
+use std::thread;
+use std::time::{Duration, Instant, SystemTime};
+
+use merx::i18n::{Lang, Localize, WatchError, WatchSummary};
+
+/// How often the polling backend checks the file.
+const POLL_INTERVAL: Duration = Duration::from_millis(250);
+
+/// How long to wait after a change for further writes to settle.
+const DEBOUNCE: Duration = Duration::from_millis(50);
+
+/// Runs `action` now and again every time `path`, or one of the files the
+/// last run reported, changes.
+///
+/// Before each run the terminal is cleared (when stdout is a terminal), and
+/// after each run a summary line with the exit code and duration is printed
+/// to stderr in `lang`.
+///
+/// This function only returns if the file can no longer be watched.
+///
+/// # Arguments
+///
+/// * `path` - The file to watch
+/// * `lang` - The language of the summary and of errors
+/// * `action` - Runs the program once and returns its exit code, along
+///   with the other files the program reads
+pub fn watch(path: &Path, lang: Lang, mut action: impl FnMut() -> (u8, Vec<PathBuf>)) -> ExitCode {
+    let mut watcher = Watcher::new(path);
+
+    loop {
+        clear_screen();
+        let start = Instant::now();
+        let (exit_code, files) = action();
+        let elapsed = start.elapsed();
+        watcher.set_extra_files(&files);
+        let summary = WatchSummary {
+            exit_code,
+            elapsed,
+            path,
+        };
+        eprintln!("{}", summary.in_lang(lang));
+
+        if let Err(e) = watcher.wait() {
+            let error = WatchError { path, error: &e };
+            eprintln!("{}", error.in_lang(lang));
+            return ExitCode::from(2);
+        }
+    }
+}
+
+/// Clears the terminal and moves the cursor to the top-left corner.
+fn clear_screen() {
+    let mut stdout = io::stdout();
+    if stdout.is_terminal() {
+        let _ = write!(stdout, "\x1b[2J\x1b[H");
+        let _ = stdout.flush();
+    }
+}
+
+/// Blocks until a watched file changes, using the best available backend.
+///
+/// The program file is always watched; other files can be added with
+/// [`set_extra_files`](Watcher::set_extra_files).
+enum Watcher {
+    #[cfg(target_os = "linux")]
+    Inotify(inotify::InotifyWatcher),
+    Poll(PollWatcher),
+}
+
+impl Watcher {
+    /// Creates a watcher for `path`, falling back to polling if the
+    /// native backend is unavailable.
+    fn new(path: &Path) -> Self {
+        #[cfg(target_os = "linux")]
+        if let Ok(w) = inotify::InotifyWatcher::new(path) {
+            return Watcher::Inotify(w);
+        }
+        Watcher::Poll(PollWatcher::new(path, POLL_INTERVAL))
+    }
+
+    /// Watches `paths` besides the program file, in place of the files
+    /// passed to the previous call.
+    fn set_extra_files(&mut self, paths: &[PathBuf]) {
+        match self {
+            #[cfg(target_os = "linux")]
+            Watcher::Inotify(w) => w.set_extra_files(paths),
+            Watcher::Poll(w) => w.set_extra_files(paths),
+        }
+    }
+
+    /// Blocks until a watched file changes.
+    fn wait(&mut self) -> io::Result<()> {
+        match self {
+            #[cfg(target_os = "linux")]
+            Watcher::Inotify(w) => w.wait(),
+            Watcher::Poll(w) => w.wait(),
+        }
+    }
+}
+
+/// The parts of a file's metadata that change when it is written.
+type Stamp = (SystemTime, u64);
+
+/// Detects changes by periodically comparing the files' modification times
+/// and sizes.
+struct PollWatcher {
+    interval: Duration,
+    /// Each watched file with the last stamp seen, starting with the
+    /// program file.
+    files: Vec<(PathBuf, Option<Stamp>)>,
+}
+
+impl PollWatcher {
+    fn new(path: &Path, interval: Duration) -> Self {
+        Self {
+            interval,
+            files: vec![(path.to_path_buf(), stamp(path))],
+        }
+    }
+
+    /// Watches `paths` besides the program file. Files that were already
+    /// watched keep their last stamp, so a change made during the run is
+    /// still noticed.
+    fn set_extra_files(&mut self, paths: &[PathBuf]) {
+        let old = self.files.split_off(1);
+        for path in paths {
+            let last = match old.iter().find(|(p, _)| p == path) {
+                Some((_, last)) => *last,
+                None => stamp(path),
+            };
+            self.files.push((path.clone(), last));
+        }
+    }
+
+    /// Blocks until the stamp of a file differs from the last one seen.
+    ///
+    /// A file that is temporarily missing (for example, mid-save) is not
+    /// treated as a change; the watcher waits for it to reappear.
+    fn wait(&mut self) -> io::Result<()> {
+        loop {
+            thread::sleep(self.interval);
+            for (path, last) in &mut self.files {
+                if let Some(current) = stamp(path)
+                    && *last != Some(current)
+                {
+                    *last = Some(current);
+                    return Ok(());
+                }
+            }
+        }
+    }
+}
+
+fn stamp(path: &Path) -> Option<Stamp> {
+    let metadata = fs::metadata(path).ok()?;
+    Some((metadata.modified().ok()?, metadata.len()))
+}
+
+#[cfg(target_os = "linux")]
+mod inotify {
+    //! Watching with inotify, through the system calls of the `libc` crate.
+    //!
+    //! The prototypes, constants and the layout of `struct inotify_event`
+    //! all come from `libc`, which has the right values for each target.
+
+    use std::ffi::{CString, OsStr, c_int};
+    use std::io;
+    use std::mem::{offset_of, size_of};
+    use std::os::unix::ffi::OsStrExt;
+    use std::path::{Path, PathBuf};
+    use std::thread;
+
+    use libc::{IN_CLOEXEC, IN_CLOSE_WRITE, IN_MOVED_TO, POLLIN, inotify_event, pollfd};
+
+    use super::DEBOUNCE;
+
+    /// Size of `struct inotify_event` without its trailing name.
+    const EVENT_HEADER_LEN: usize = size_of::<inotify_event>();
+
+    /// Watches files' parent directories for writes to, or renames onto,
+    /// the files.
+    pub(super) struct InotifyWatcher {
+        fd: c_int,
+        /// The watch descriptor of each watched file's directory, and the
+        /// file's name, starting with the program file.
+        files: Vec<(c_int, Vec<u8>)>,
+    }
+
+    impl InotifyWatcher {
+        pub(super) fn new(path: &Path) -> io::Result<Self> {
+            // SAFETY: inotify_init1 takes no pointers.
+            let fd = unsafe { libc::inotify_init1(IN_CLOEXEC) };
+            if fd < 0 {
+                return Err(io::Error::last_os_error());
+            }
+            let mut watcher = Self {
+                fd,
+                files: Vec::new(),
+            };
+            watcher.add(path)?;
+            Ok(watcher)
+        }
+
+        /// Watches `paths` besides the program file.
+        ///
+        /// A file whose directory cannot be watched, such as one that does
+        /// not exist, is skipped.
+        pub(super) fn set_extra_files(&mut self, paths: &[PathBuf]) {
+            self.files.truncate(1);
+            for path in paths {
+                let _ = self.add(path);
+            }
+        }
+
+        /// Starts watching `path`. Watching a directory again returns the
+        /// descriptor it already has, so files can share a directory.
+        fn add(&mut self, path: &Path) -> io::Result<()> {
+            let file_name = path
+                .file_name()
+                .ok_or_else(|| io::Error::new(io::ErrorKind::InvalidInput, "not a file path"))?
+                .as_bytes()
+                .to_vec();
+            let dir = match path.parent() {
+                Some(p) if !p.as_os_str().is_empty() => p.as_os_str(),
+                _ => OsStr::new("."),
+            };
+            let dir = CString::new(dir.as_bytes())
+                .map_err(|e| io::Error::new(io::ErrorKind::InvalidInput, e))?;
+
+            let mask = IN_CLOSE_WRITE | IN_MOVED_TO;
+            // SAFETY: `dir` is a valid NUL-terminated string for the duration of the call.
+            let wd = unsafe { libc::inotify_add_watch(self.fd, dir.as_ptr(), mask) };
+            if wd < 0 {
+                return Err(io::Error::last_os_error());
+            }
+            self.files.push((wd, file_name));
+            Ok(())
+        }
+
+        /// Blocks until an event for a watched file arrives, then
+        /// discards any further events that arrive within [`DEBOUNCE`].
+        pub(super) fn wait(&mut self) -> io::Result<()> {
+            let mut buf = [0u8; 4096];
+            loop {
+                let n = self.read_events(&mut buf)?;
+                if self.matches(&buf[..n]) {
+                    break;
+                }
+            }
+
+            thread::sleep(DEBOUNCE);
+            while self.has_pending()? {
+                self.read_events(&mut buf)?;
+            }
+            Ok(())
+        }
+
+        fn read_events(&self, buf: &mut [u8]) -> io::Result<usize> {
+            loop {
+                // SAFETY: `buf` is valid for writes of `buf.len()` bytes.
+                let n = unsafe { libc::read(self.fd, buf.as_mut_ptr().cast(), buf.len()) };
+                if n >= 0 {
+                    return Ok(n as usize);
+                }
+                let err = io::Error::last_os_error();
+                if err.kind() != io::ErrorKind::Interrupted {
+                    return Err(err);
+                }
+            }
+        }
+
+        fn has_pending(&self) -> io::Result<bool> {
+            let mut pfd = pollfd {
+                fd: self.fd,
+                events: POLLIN,
+                revents: 0,
+            };
+            // SAFETY: `pfd` is a single valid pollfd.
+            let n = unsafe { libc::poll(&mut pfd, 1, 0) };
+            if n < 0 {
+                return Err(io::Error::last_os_error());
+            }
+            Ok(n > 0 && pfd.revents & POLLIN != 0)
+        }
+
+        /// Returns `true` if any event in `buf` names a watched file.
+        fn matches(&self, mut buf: &[u8]) -> bool {
+            while buf.len() >= EVENT_HEADER_LEN {
+                let wd = read_field::<4>(buf, offset_of!(inotify_event, wd));
+                let wd = c_int::from_ne_bytes(wd);
+                let len = read_field::<4>(buf, offset_of!(inotify_event, len));
+                let len = u32::from_ne_bytes(len) as usize;
+                let end = (EVENT_HEADER_LEN + len).min(buf.len());
+                let name = &buf[EVENT_HEADER_LEN..end];
+                let name = match name.iter().position(|&b| b == 0) {
+                    Some(i) => &name[..i],
+                    None => name,
+                };
+                if self.files.iter().any(|(w, n)| *w == wd && n == name) {
+                    return true;
+                }
+                buf = &buf[end..];
+            }
+            false
+        }
+    }
+
+    impl Drop for InotifyWatcher {
+        fn drop(&mut self) {
+            // SAFETY: `fd` was returned by inotify_init1 and is closed only here.
+            unsafe { libc::close(self.fd) };
+        }
+    }
+
+    /// Reads the `N` bytes of an event field at `offset`.
+    fn read_field<const N: usize>(buf: &[u8], offset: usize) -> [u8; N] {
+        buf[offset..offset + N].try_into().unwrap()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    /// Creates a fresh directory under the system temp dir for one test.
+    fn temp_dir(name: &str) -> PathBuf {
+        let dir = std::env::temp_dir().join(format!("merx-watch-{}-{}", name, std::process::id()));
+        let _ = fs::remove_dir_all(&dir);
+        fs::create_dir_all(&dir).unwrap();
+        dir
+    }
+
+    #[test]
+    fn test_poll_watcher_detects_write() {
+        let dir = temp_dir("poll");
+        let file = dir.join("main.mmd");
+        fs::write(&file, "flowchart TD\n").unwrap();
+
+        let mut watcher = PollWatcher::new(&file, Duration::from_millis(10));
+        let writer = {
+            let file = file.clone();
+            thread::spawn(move || {
+                thread::sleep(Duration::from_millis(100));
+                fs::write(file, "flowchart TD\n    Start --> End\n").unwrap();
+            })
+        };
+        watcher.wait().unwrap();
+        writer.join().unwrap();
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+
+    #[test]
+    fn test_poll_watcher_detects_write_to_extra_file() {
+        let dir = temp_dir("poll-extra");
+        let file = dir.join("main.mmd");
+        let table = dir.join("rates.csv");
+        fs::write(&file, "flowchart TD\n").unwrap();
+        fs::write(&table, "qty\n").unwrap();
+
+        let mut watcher = PollWatcher::new(&file, Duration::from_millis(10));
+        watcher.set_extra_files(std::slice::from_ref(&table));
+        let writer = thread::spawn(move || {
+            thread::sleep(Duration::from_millis(100));
+            fs::write(table, "qty,tier\n").unwrap();
+        });
+        watcher.wait().unwrap();
+        writer.join().unwrap();
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+
+    #[cfg(target_os = "linux")]
+    #[test]
+    fn test_inotify_watcher_detects_rename() {
+        let dir = temp_dir("inotify");
+        let file = dir.join("main.mmd");
+        fs::write(&file, "flowchart TD\n").unwrap();
+
+        let mut watcher = inotify::InotifyWatcher::new(&file).unwrap();
+
+        // Events for other files in the directory are skipped; the wait
+        // ends only when the temporary file is renamed over the program.
+        let tmp = dir.join("main.mmd.tmp");
+        fs::write(&tmp, "flowchart TD\n    Start --> End\n").unwrap();
+        let renamer = {
+            let file = file.clone();
+            thread::spawn(move || {
+                thread::sleep(Duration::from_millis(100));
+                fs::rename(tmp, file).unwrap();
+            })
+        };
+        watcher.wait().unwrap();
+        renamer.join().unwrap();
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+
+    #[cfg(target_os = "linux")]
+    #[test]
+    fn test_inotify_watcher_detects_write_to_extra_file() {
+        let dir = temp_dir("inotify-extra");
+        let file = dir.join("main.mmd");
+        let tables = dir.join("tables");
+        fs::create_dir(&tables).unwrap();
+        let table = tables.join("rates.csv");
+        fs::write(&file, "flowchart TD\n").unwrap();
+        fs::write(&table, "qty\n").unwrap();
+
+        let mut watcher = inotify::InotifyWatcher::new(&file).unwrap();
+        watcher.set_extra_files(std::slice::from_ref(&table));
+
+        // A file of the same name in the program's directory is not the
+        // table, so writing it does not end the wait
+        fs::write(dir.join("rates.csv"), "qty\n").unwrap();
+        let writer = thread::spawn(move || {
+            thread::sleep(Duration::from_millis(100));
+            fs::write(table, "qty,tier\n").unwrap();
+        });
+        watcher.wait().unwrap();
+        writer.join().unwrap();
+
+        fs::remove_dir_all(dir).unwrap();
+    }
+}
@@ -4,68 +4,7 @@
 //! testing the complete pipeline from parsing to execution.
 
 use merx::parser;
-use merx::runtime::{InputReader, Interpreter, OutputWriter, RuntimeError};
-
-/// Mock input reader for testing.
-struct MockInputReader {
-    lines: Vec<String>,
-    index: usize,
-}
-
-impl MockInputReader {
-    fn new(lines: Vec<&str>) -> Self {
-        Self {
-            lines: lines.into_iter().map(|s| s.to_string()).collect(),
-            index: 0,
-        }
-    }
-}
-
-impl InputReader for MockInputReader {
-    fn read_line(&mut self) -> Result<String, RuntimeError> {
-        if self.index < self.lines.len() {
-            let line = self.lines[self.index].clone();
-            self.index += 1;
-            Ok(line)
-        } else {
-            Err(RuntimeError::IoError {
-                message: "No more input".to_string(),
-            })
-        }
-    }
-}
-
-/// Mock output writer for testing.
-struct MockOutputWriter {
-    pub stdout: Vec<String>,
-    pub stderr: Vec<String>,
-}
-
-impl MockOutputWriter {
-    fn new() -> Self {
-        Self {
-            stdout: Vec::new(),
-            stderr: Vec::new(),
-        }
-    }
-}
-
-impl OutputWriter for MockOutputWriter {
-    fn write_stdout(&mut self, s: &str) -> Result<(), RuntimeError> {
-        self.stdout.push(s.to_string());
-        Ok(())
-    }
-
-    fn write_stdout_no_newline(&mut self, s: &str) -> Result<(), RuntimeError> {
-        self.stdout.push(s.to_string());
-        Ok(())
-    }
-
-    fn write_stderr(&mut self, s: &str) -> Result<(), RuntimeError> {
-        self.stderr.push(s.to_string());
-        Ok(())
-    }
-}
+use merx::runtime::{CapturingWriter, ExitCodePolicy, Interpreter, RuntimeError, VecInputReader};
 
 /// Helper function to run a flowchart from source code.
 fn run_flowchart(source: &str) -> Result<(Vec<String>, Vec<String>), String> {
@@ -79,8 +18,8 @@ fn run_flowchart_with_input(
 ) -> Result<(Vec<String>, Vec<String>), String> {
     let flowchart = parser::parse(source).map_err(|e| e.to_string())?;
 
-    let input = MockInputReader::new(input_lines);
-    let output = MockOutputWriter::new();
+    let input = VecInputReader::new(input_lines);
+    let output = CapturingWriter::new();
 
     let mut interpreter =
         Interpreter::with_io(flowchart, input, output).map_err(|e| e.to_string())?;
@@ -88,15 +27,32 @@ fn run_flowchart_with_input(
     interpreter.run().map_err(|e| e.to_string())?;
 
     let output = interpreter.into_output_writer();
-    Ok((output.stdout, output.stderr))
+    Ok((output.stdout(), output.stderr()))
+}
+
+/// Helper function to run a flowchart with a seeded random number generator.
+fn run_flowchart_with_seed(source: &str, seed: u64) -> Result<(Vec<String>, Vec<String>), String> {
+    let flowchart = parser::parse(source).map_err(|e| e.to_string())?;
+
+    let input = VecInputReader::default();
+    let output = CapturingWriter::new();
+
+    let mut interpreter = Interpreter::with_io(flowchart, input, output)
+        .map_err(|e| e.to_string())?
+        .with_seed(seed);
+
+    interpreter.run().map_err(|e| e.to_string())?;
+
+    let output = interpreter.into_output_writer();
+    Ok((output.stdout(), output.stderr()))
 }
 
 /// Helper function to run a flowchart and return the exit code along with output.
 fn run_flowchart_with_exit_code(source: &str) -> Result<(u8, Vec<String>, Vec<String>), String> {
     let flowchart = parser::parse(source).map_err(|e| e.to_string())?;
 
-    let input = MockInputReader::new(vec![]);
-    let output = MockOutputWriter::new();
+    let input = VecInputReader::default();
+    let output = CapturingWriter::new();
 
     let mut interpreter =
         Interpreter::with_io(flowchart, input, output).map_err(|e| e.to_string())?;
@@ -104,7 +60,7 @@ fn run_flowchart_with_exit_code(source: &str) -> Result<(u8, Vec<String>, Vec<St
     let exit_code = interpreter.run().map_err(|e| e.to_string())?;
 
     let output = interpreter.into_output_writer();
-    Ok((exit_code, output.stdout, output.stderr))
+    Ok((exit_code, output.stdout(), output.stderr()))
 }
 
 // =============================================================================
@@ -403,6 +359,7 @@ mod valid_flowcharts {
 
 mod invalid_flowcharts {
     use super::*;
+    use merx::codes::ErrorCode;
     use merx::parser::AnalysisError;
 
     #[test]
@@ -411,7 +368,9 @@ mod invalid_flowcharts {
         let result = parser::parse(source);
 
         assert!(result.is_err(), "Should fail to parse invalid syntax");
-        assert!(matches!(result.unwrap_err(), AnalysisError::Syntax(_)));
+        let err = result.unwrap_err();
+        assert!(matches!(err, AnalysisError::Syntax(_)));
+        assert_eq!(err.code(), ErrorCode::InvalidSyntax);
     }
 
     #[test]
@@ -434,6 +393,7 @@ mod invalid_flowcharts {
         assert!(result.is_err(), "Should fail with missing Yes edge");
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::MissingYesEdge);
         assert!(
             err.to_string().contains("missing 'Yes' edge"),
             "Error should mention missing Yes edge: {}",
@@ -449,6 +409,7 @@ mod invalid_flowcharts {
         assert!(result.is_err(), "Should fail with missing No edge");
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::MissingNoEdge);
         assert!(
             err.to_string().contains("missing 'No' edge"),
             "Error should mention missing No edge: {}",
@@ -464,6 +425,7 @@ mod invalid_flowcharts {
         assert!(result.is_err(), "Should fail with multiple Yes edges");
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::DuplicateConditionEdge);
         assert!(
             err.to_string().contains("multiple 'Yes' edges"),
             "Error should mention multiple Yes edges: {}",
@@ -491,6 +453,7 @@ mod invalid_flowcharts {
         assert!(result.is_err(), "Should fail when End has outgoing edge");
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::EdgeFromTerminal);
         assert!(
             err.to_string().contains("End node cannot have outgoing"),
             "Error should mention End node outgoing edge: {}",
@@ -520,6 +483,7 @@ mod invalid_flowcharts {
         );
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::MissingStart);
         assert!(
             err.to_string().contains("Missing 'Start' node"),
             "Error should mention missing Start node: {}",
@@ -537,6 +501,7 @@ mod invalid_flowcharts {
         );
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::MissingEnd);
         assert!(
             err.to_string().contains("Missing 'End' node"),
             "Error should mention missing End node: {}",
@@ -554,6 +519,7 @@ mod invalid_flowcharts {
         );
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::DuplicateNode);
         assert!(
             err.to_string().contains("defined multiple times"),
             "Error should mention duplicate node definition: {}",
@@ -571,6 +537,7 @@ mod invalid_flowcharts {
         );
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::UndefinedNode);
         assert!(
             err.to_string().contains("Undefined node"),
             "Error should mention undefined node: {}",
@@ -586,7 +553,9 @@ mod invalid_flowcharts {
             result.is_err(),
             "Should fail when both inline and pipe labels are used"
         );
-        assert!(matches!(result.unwrap_err(), AnalysisError::Syntax(_)));
+        let err = result.unwrap_err();
+        assert!(matches!(err, AnalysisError::Syntax(_)));
+        assert_eq!(err.code(), ErrorCode::InvalidEdgeLabel);
     }
 
     // --- Tests added from TESTS.md section 1 ---
@@ -598,6 +567,7 @@ mod invalid_flowcharts {
         assert!(result.is_err(), "Should fail with multiple No edges");
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::DuplicateConditionEdge);
         assert!(
             err.to_string().contains("multiple 'No' edges"),
             "Error should mention multiple No edges: {}",
@@ -615,6 +585,7 @@ mod invalid_flowcharts {
         );
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::InvalidConditionEdge);
         assert!(
             err.to_string()
                 .contains("must have 'Yes' or 'No' label, but got"),
@@ -633,6 +604,7 @@ mod invalid_flowcharts {
         );
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::InvalidConditionEdge);
         assert!(
             err.to_string().contains("must have 'Yes' or 'No' label"),
             "Error should mention missing label: {}",
@@ -650,6 +622,7 @@ mod invalid_flowcharts {
         );
         let err = result.unwrap_err();
         assert!(matches!(err, AnalysisError::Validation(_)));
+        assert_eq!(err.code(), ErrorCode::MultipleOutgoingEdges);
         assert!(
             err.to_string().contains("has multiple outgoing edges"),
             "Error should mention multiple outgoing edges: {}",
@@ -659,13 +632,15 @@ mod invalid_flowcharts {
 
     #[test]
     fn test_negative_exit_code() {
-        // "-" is not allowed in label_text, so this is a syntax error
+        // Literal exit codes are range-checked at parse time
         let source = r#"flowchart TD
     Start -->|exit -1| End
 "#;
         let result = parser::parse(source);
         assert!(result.is_err(), "Should fail with negative exit code");
-        assert!(matches!(result.unwrap_err(), AnalysisError::Syntax(_)));
+        let err = result.unwrap_err();
+        assert!(matches!(err, AnalysisError::Syntax(_)));
+        assert_eq!(err.code(), ErrorCode::InvalidExitCode);
     }
 }
 
@@ -1178,6 +1153,76 @@ mod double_quoted_labels {
         assert_eq!(stdout, vec!["it's working"]);
         assert!(stderr.is_empty());
     }
+
+    #[test]
+    fn test_multiline_and_br_separated_statements() {
+        let source = r#"flowchart TD
+    Start --> A["x = 1
+    y = 2<br/>println x + y"]
+    A --> B[println x<br>println y]
+    B --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["3", "1", "2"]);
+    }
+
+    #[test]
+    fn test_entity_codes_in_double_quoted_labels() {
+        let source = r##"flowchart TD
+    Start --> A["n = 3"]
+    A --> B{"n #gt; 2?"}
+    B -->|Yes| C["println '#quot;Item #35;' + n as str + '#quot;'"]
+    B -->|No| End
+    C --> End
+"##;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["\"Item #3\""]);
+    }
+}
+
+// =============================================================================
+// Mermaid v11 shape metadata tests
+// =============================================================================
+
+mod shaped_nodes {
+    use super::*;
+
+    #[test]
+    fn test_shaped_nodes_execute() {
+        let source = r#"flowchart TD
+    Start@{ shape: stadium, label: "Begin" } --> Init@{ shape: rect, label: "x = 10" }
+    Init --> Check@{ shape: diamond, label: "x > 5?" }
+    Check -->|Yes| Big@{ shape: rounded, label: "println 'big'" }
+    Check -->|No| Small@{ shape: rounded, label: "println 'small'" }
+    Big --> End@{ shape: stadium, label: "Done" }
+    Small --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["big"]);
+    }
+
+    #[test]
+    fn test_standalone_shaped_node_declarations() {
+        let source = r#"flowchart TD
+    A@{ shape: rect, label: "x = 1; y = 2" }
+    B@{ shape: rect, label: "println x + y" }
+    Start --> A
+    A --> B
+    B --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["3"]);
+    }
+
+    #[test]
+    fn test_unsupported_shape_is_error() {
+        let source = r#"flowchart TD
+    Start --> A@{ shape: fork }
+    A --> End
+"#;
+        let err = run_flowchart(source).unwrap_err();
+        assert!(err.contains("shape 'fork' of node 'A' is not supported"));
+    }
 }
 
 // =============================================================================
@@ -1413,253 +1458,1334 @@ mod exit_code {
         let err = result.unwrap_err().to_string();
         assert!(err.contains("invalid exit code"));
     }
-}
-
-// =============================================================================
-// Cast operation tests (TESTS.md section 3)
-// =============================================================================
-
-mod cast_operations {
-    use super::*;
 
     #[test]
-    fn test_cast_int_as_int() {
+    fn test_computed_exit_code() {
         let source = r#"flowchart TD
-    Start --> A[x = 42 as int; println x]
-    A --> End
+    Start --> A[failures = 2 + 1]
+    A -->|exit failures| End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["42"]);
+        let (exit_code, _, _) = run_flowchart_with_exit_code(source).unwrap();
+        assert_eq!(exit_code, 3);
     }
 
     #[test]
-    fn test_cast_str_as_str() {
+    fn test_yes_computed_exit_code() {
         let source = r#"flowchart TD
-    Start --> A[x = 'hello' as str; println x]
-    A --> End
+    Start --> A[code = 7]
+    A --> B{code > 0?}
+    B -->|Yes, exit code * 2| End
+    B -->|No| End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["hello"]);
+        let (exit_code, _, _) = run_flowchart_with_exit_code(source).unwrap();
+        assert_eq!(exit_code, 14);
     }
 
     #[test]
-    fn test_cast_bool_true_as_str() {
+    fn test_exit_statement() {
         let source = r#"flowchart TD
-    Start --> A[x = true as str; println x]
+    Start --> A[failures = 4; exit failures; println 'continues']
     A --> End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["true"]);
+        let (exit_code, stdout, _) = run_flowchart_with_exit_code(source).unwrap();
+        assert_eq!(exit_code, 4);
+        assert_eq!(stdout, vec!["continues"]);
     }
 
     #[test]
-    fn test_cast_bool_false_as_str() {
+    fn test_edge_exit_code_overrides_exit_statement() {
         let source = r#"flowchart TD
-    Start --> A[x = false as str; println x]
-    A --> End
+    Start --> A[exit 4]
+    A -->|exit 1| End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["false"]);
+        let (exit_code, _, _) = run_flowchart_with_exit_code(source).unwrap();
+        assert_eq!(exit_code, 1);
     }
 
     #[test]
-    fn test_cast_bool_as_int() {
+    fn test_computed_exit_code_out_of_range() {
         let source = r#"flowchart TD
-    Start --> A[x = true as int]
-    A --> End
+    Start --> A[n = 300]
+    A -->|exit n| End
 "#;
-        let result = run_flowchart(source);
-        assert!(result.is_err());
-        assert!(result.unwrap_err().contains("Cannot cast"));
+        let err = run_flowchart_with_exit_code(source).unwrap_err();
+        assert_eq!(
+            err,
+            "Invalid exit code 300: must be an integer between 0 and 255"
+        );
     }
 
     #[test]
-    fn test_cast_negative_string_to_int() {
+    fn test_computed_exit_code_not_int() {
         let source = r#"flowchart TD
-    Start --> A[x = '-42' as int; println x]
+    Start --> A[exit 'failed']
     A --> End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["-42"]);
+        let err = run_flowchart_with_exit_code(source).unwrap_err();
+        assert!(err.contains("exit code"), "unexpected error: {}", err);
     }
 
     #[test]
-    fn test_cast_empty_string_to_int() {
+    fn test_computed_exit_code_clamped() {
         let source = r#"flowchart TD
-    Start --> A[x = '' as int]
-    A --> End
+    Start --> A[n = 0 - 5]
+    A --> B[exit n * 100]
+    B --> End
 "#;
-        let result = run_flowchart(source);
-        assert!(result.is_err());
-        assert!(result.unwrap_err().contains("Cannot cast"));
+        let flowchart = parser::parse(source).unwrap();
+        let mut interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .unwrap()
+                .with_exit_code_policy(ExitCodePolicy::Clamp);
+        assert_eq!(interpreter.run().unwrap(), 0);
     }
 }
 
 // =============================================================================
-// Equality and comparison edge case tests (TESTS.md section 4)
+// Terminal node tests
 // =============================================================================
 
-mod equality_comparison {
+mod terminal_nodes {
     use super::*;
 
-    #[test]
-    fn test_cross_type_eq_int_str() {
-        let source = r#"flowchart TD
-    Start --> A{1 == '1'?}
-    A -->|Yes| B[println 'equal']
-    A -->|No| C[println 'not equal']
-    B --> End
-    C --> End
-"#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["not equal"]);
+    const REVIEW: &str = r#"flowchart TD
+    Start --> A[score = input as int]
+    A --> B{score >= 80?}
+    B -->|Yes| Approved([End: approved])
+    B -->|No| C{score >= 50?}
+    C -->|Yes| Escalated([End: escalated, exit 2])
+    C -->|No, exit 3| Rejected([End: rejected, exit 1])
+"#;
+
+    /// Runs `REVIEW` with the given score, returning the exit code, the
+    /// terminal reached, and its result.
+    fn review(score: &str) -> (u8, Option<String>, Option<String>) {
+        let flowchart = parser::parse(REVIEW).unwrap();
+        let input = VecInputReader::new([score]);
+        let mut interpreter =
+            Interpreter::with_io(flowchart, input, CapturingWriter::new()).unwrap();
+        let exit_code = interpreter.run().unwrap();
+        (
+            exit_code,
+            interpreter.terminal_id().map(String::from),
+            interpreter.result().map(String::from),
+        )
+    }
+
+    #[test]
+    fn test_multiple_terminals() {
+        assert_eq!(
+            review("90"),
+            (
+                0,
+                Some("Approved".to_string()),
+                Some("approved".to_string())
+            )
+        );
+        assert_eq!(
+            review("60"),
+            (
+                2,
+                Some("Escalated".to_string()),
+                Some("escalated".to_string())
+            )
+        );
     }
 
     #[test]
-    fn test_cross_type_ne_int_str() {
-        let source = r#"flowchart TD
-    Start --> A{1 != '1'?}
-    A -->|Yes| B[println 'not equal']
-    A -->|No| C[println 'equal']
-    B --> End
-    C --> End
-"#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["not equal"]);
+    fn test_edge_exit_code_overrides_terminal_default() {
+        assert_eq!(
+            review("10"),
+            (
+                3,
+                Some("Rejected".to_string()),
+                Some("rejected".to_string())
+            )
+        );
     }
 
     #[test]
-    fn test_cross_type_eq_bool_int() {
-        let source = r#"flowchart TD
-    Start --> A{true == 1?}
-    A -->|Yes| B[println 'equal']
-    A -->|No| C[println 'not equal']
-    B --> End
-    C --> End
-"#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["not equal"]);
+    fn test_end_node_reports_terminal() {
+        let flowchart = parser::parse("flowchart TD\n    Start --> End\n").unwrap();
+        let mut interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .unwrap();
+        assert_eq!(interpreter.terminal_id(), None);
+        interpreter.run().unwrap();
+        assert_eq!(interpreter.terminal_id(), Some("End"));
+        assert_eq!(interpreter.result(), None);
     }
 
     #[test]
-    fn test_str_eq_same() {
+    fn test_display_label_terminal() {
         let source = r#"flowchart TD
-    Start --> A{'abc' == 'abc'?}
-    A -->|Yes| B[println 'equal']
-    A -->|No| C[println 'not equal']
-    B --> End
-    C --> End
+    Start --> A[println 'done']
+    A --> Done([All done])
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["equal"]);
+        let (exit_code, stdout, _) = run_flowchart_with_exit_code(source).unwrap();
+        assert_eq!(exit_code, 0);
+        assert_eq!(stdout, vec!["done"]);
     }
 
     #[test]
-    fn test_bool_eq_same() {
+    fn test_terminal_cannot_have_outgoing_edges() {
         let source = r#"flowchart TD
-    Start --> A{true == true?}
-    A -->|Yes| B[println 'equal']
-    A -->|No| C[println 'not equal']
-    B --> End
-    C --> End
+    Start --> Done([End])
+    Done --> End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["equal"]);
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("Terminal node 'Done' cannot have outgoing edges"),
+            "got: {}",
+            err
+        );
     }
 
     #[test]
-    fn test_negative_comparison() {
+    fn test_ids_starting_with_keywords() {
         let source = r#"flowchart TD
-    Start --> A{-5 < 3?}
-    A -->|Yes| B[println 'true']
-    A -->|No| C[println 'false']
-    B --> End
-    C --> End
+    Start --> Starter[println 'a']
+    Starter --> Ending[println 'b']
+    Ending --> End_ok([End: ok])
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["true"]);
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["a", "b"]);
     }
 }
 
 // =============================================================================
-// Integer edge case tests (TESTS.md section 5)
+// Entry point tests
 // =============================================================================
 
-mod integer_edge_cases {
+mod entry_points {
     use super::*;
 
-    #[test]
-    fn test_int_overflow_addition() {
-        let source = r#"flowchart TD
-    Start --> A[x = 9223372036854775807 + 1; println x]
-    A --> End
+    const ORDERS: &str = r#"flowchart TD
+    Start --> A[kind = 'purchase']
+    Start_refund([Start refund]) --> B[kind = 'refund']
+    A --> Log[println kind]
+    B --> Log
+    Log --> End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["-9223372036854775808"]);
+
+    fn run_entry(source: &str, entry: &str) -> Result<Vec<String>, String> {
+        let flowchart = parser::parse(source).map_err(|e| e.to_string())?;
+        let mut interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .map_err(|e| e.to_string())?
+                .with_entry(entry)
+                .map_err(|e| e.to_string())?;
+        interpreter.run().map_err(|e| e.to_string())?;
+        Ok(interpreter.into_output_writer().stdout())
     }
 
     #[test]
-    fn test_int_overflow_multiplication() {
-        let source = r#"flowchart TD
-    Start --> A[x = 9223372036854775807 * 2; println x]
-    A --> End
-"#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["-2"]);
+    fn test_default_entry_is_start() {
+        let (stdout, _) = run_flowchart(ORDERS).unwrap();
+        assert_eq!(stdout, vec!["purchase"]);
     }
 
     #[test]
-    fn test_int_overflow_subtraction() {
-        // i64::MIN = 0 - 9223372036854775807 - 1
-        // i64::MIN - 1 wraps to i64::MAX
+    fn test_named_entry_shares_subflow() {
+        assert_eq!(run_entry(ORDERS, "refund").unwrap(), vec!["refund"]);
+    }
+
+    #[test]
+    fn test_unknown_entry() {
+        let err = run_entry(ORDERS, "exchange").unwrap_err();
+        assert_eq!(err, "Unknown entry 'exchange'");
+    }
+
+    #[test]
+    fn test_entry_must_reach_end() {
         let source = r#"flowchart TD
-    Start --> A[x = 0 - 9223372036854775807 - 1; y = x - 1; println y]
-    A --> End
+    Start --> End
+    Start_loop([Start loop]) --> A[x = 1]
+    A --> A
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["9223372036854775807"]);
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("Entry 'loop' cannot reach an End node"),
+            "got: {}",
+            err
+        );
     }
 
     #[test]
-    fn test_large_int_literal_output() {
+    fn test_duplicate_entry_names() {
         let source = r#"flowchart TD
-    Start --> A[println 9223372036854775807]
-    A --> End
+    Start --> End
+    A([Start refund]) --> End
+    B([Start refund]) --> End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["9223372036854775807"]);
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("Entry 'refund' is declared by both 'A' and 'B'"),
+            "got: {}",
+            err
+        );
     }
 }
 
 // =============================================================================
-// Variable scope tests (TESTS.md section 6)
+// Result value tests
 // =============================================================================
 
-mod variable_scope {
+mod result_values {
     use super::*;
+    use merx::runtime::{RunOutcome, Value};
+
+    fn outcome(source: &str, input_lines: Vec<&str>) -> Result<RunOutcome, String> {
+        let flowchart = parser::parse(source).map_err(|e| e.to_string())?;
+        let input = VecInputReader::new(input_lines);
+        let mut interpreter = Interpreter::with_io(flowchart, input, CapturingWriter::new())
+            .map_err(|e| e.to_string())?;
+        interpreter.run_outcome().map_err(|e| e.to_string())
+    }
 
     #[test]
-    fn test_variable_reassignment() {
+    fn test_return_statement() {
         let source = r#"flowchart TD
-    Start --> A[x = 1; x = 2; println x]
-    A --> End
+    Start --> A[price = input as int; qty = input as int]
+    A --> B[total = price * qty; return total]
+    B --> End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["2"]);
+        let outcome = outcome(source, vec!["15", "4"]).unwrap();
+        assert_eq!(outcome.exit_code, 0);
+        assert_eq!(outcome.value, Some(Value::Int(60)));
+        assert_eq!(outcome.env.get("qty").unwrap(), &Value::Int(4));
     }
 
     #[test]
-    fn test_variable_type_change() {
+    fn test_last_return_wins() {
         let source = r#"flowchart TD
-    Start --> A[x = 42; x = 'hello'; println x]
+    Start --> A[return 1; return 'two']
     A --> End
 "#;
-        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
-        assert_eq!(stdout, vec!["hello"]);
+        let outcome = outcome(source, vec![]).unwrap();
+        assert_eq!(outcome.value, Some(Value::from("two")));
     }
 
     #[test]
-    fn test_variable_after_branch() {
+    fn test_return_edge_label() {
+        let source = r#"flowchart TD
+    Start --> A[score = input as int]
+    A --> B{score >= 50?}
+    B -->|Yes, return score * 2| End
+    B -->|No, return false| Rejected([End: rejected, exit 1])
+"#;
+        let outcome_yes = outcome(source, vec!["70"]).unwrap();
+        assert_eq!(outcome_yes.value, Some(Value::Int(140)));
+        assert_eq!(outcome_yes.exit_code, 0);
+
+        let outcome_no = outcome(source, vec!["10"]).unwrap();
+        assert_eq!(outcome_no.value, Some(Value::Bool(false)));
+        assert_eq!(outcome_no.exit_code, 1);
+    }
+
+    #[test]
+    fn test_return_edge_label_with_entity() {
+        let source = r#"flowchart TD
+    Start --> A[a = false; b = true]
+    A -->|return a #124;#124; b| End
+"#;
+        let outcome = outcome(source, vec![]).unwrap();
+        assert_eq!(outcome.value, Some(Value::Bool(true)));
+    }
+
+    #[test]
+    fn test_value_falls_back_to_terminal_result() {
+        let source = r#"flowchart TD
+    Start --> Done([End: approved])
+"#;
+        let outcome = outcome(source, vec![]).unwrap();
+        assert_eq!(outcome.value, Some(Value::from("approved")));
+    }
+
+    #[test]
+    fn test_no_value() {
+        let outcome = outcome("flowchart TD\n    Start --> End\n", vec![]).unwrap();
+        assert_eq!(outcome.value, None);
+    }
+
+    #[test]
+    fn test_return_edge_must_point_to_terminal() {
+        let source = r#"flowchart TD
+    Start -->|return 1| A[x = 1]
+    A --> End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("Return value can only be specified on edges to 'End' node"),
+            "got: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_return_edge_without_value() {
+        let source = r#"flowchart TD
+    Start -->|return| End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("return requires a value"), "got: {}", err);
+    }
+
+    #[test]
+    fn test_run_str_value() {
+        let result = merx::run_str(
+            "flowchart TD\n    Start --> A[return 'ok']\n    A --> End\n",
+            &[],
+        );
+        assert_eq!(result.value, Some(Value::from("ok")));
+    }
+}
+
+// =============================================================================
+// Edge action tests
+// =============================================================================
+
+mod edge_actions {
+    use super::*;
+
+    #[test]
+    fn test_action_on_condition_branch() {
+        let source = r#"flowchart TD
+    Start --> A[i = 0]
+    A --> B{i < 3?}
+    B -->|Yes / println i; i = i + 1| B
+    B -->|No| End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["0", "1", "2"]);
+    }
+
+    #[test]
+    fn test_action_without_label() {
+        let source = r#"flowchart TD
+    Start -->|/ count = 41| A[count = count + 1]
+    A -->|/ println count| End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["42"]);
+    }
+
+    #[test]
+    fn test_action_runs_before_exit_code() {
+        let source = r#"flowchart TD
+    Start --> A[n = 1]
+    A -->|exit n / n = n * 5| End
+"#;
+        let (exit_code, _, _) = run_flowchart_with_exit_code(source).unwrap();
+        assert_eq!(exit_code, 5);
+    }
+
+    #[test]
+    fn test_division_stays_in_label() {
+        let source = r#"flowchart TD
+    Start --> A[total = 10; count = 4]
+    A -->|return total / count| End
+"#;
+        let flowchart = parser::parse(source).unwrap();
+        let outcome =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .unwrap()
+                .run_outcome()
+                .unwrap();
+        assert_eq!(outcome.value, Some(merx::runtime::Value::Int(2)));
+    }
+
+    #[test]
+    fn test_custom_label_with_slash() {
+        let source = r#"flowchart TD
+    Start -->|read/write| A[println 'ok']
+    A --> End
+"#;
+        let flowchart = parser::parse(source).unwrap();
+        assert_eq!(
+            flowchart.edges[0].label,
+            Some(merx::ast::EdgeLabel::Custom("read/write".to_string()))
+        );
+        assert!(flowchart.edges[0].actions.is_empty());
+    }
+
+    #[test]
+    fn test_invalid_action_is_error() {
+        let source = r#"flowchart TD
+    Start --> A{true?}
+    A -->|Yes / i = | End
+    A -->|No| End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("invalid edge action"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_invalid_action_after_guard_with_slash_is_error() {
+        let source = r#"flowchart TD
+    Start --> A[a = 4; b = 2; y = 1]
+    A -->|[a / b > 1] / x = y +| End
+    A -->|[else]| End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("invalid edge action 'x = y +'"),
+            "unexpected error: {}",
+            err
+        );
+    }
+}
+
+// =============================================================================
+// Guarded edge tests
+// =============================================================================
+
+mod guarded_edges {
+    use super::*;
+
+    const GRADE: &str = r#"flowchart TD
+    Start --> A[score = input as int]
+    A -->|[score >= 80]| B[println 'A']
+    A -->|[score >= 50]| C[println 'B']
+    A -->|[else]| D[println 'C']
+    B --> End
+    C --> End
+    D --> End
+"#;
+
+    #[test]
+    fn test_first_matching_guard_is_followed() {
+        let (stdout, _) = run_flowchart_with_input(GRADE, vec!["95"]).unwrap();
+        assert_eq!(stdout, vec!["A"]);
+        let (stdout, _) = run_flowchart_with_input(GRADE, vec!["60"]).unwrap();
+        assert_eq!(stdout, vec!["B"]);
+    }
+
+    #[test]
+    fn test_else_when_no_guard_matches() {
+        let (stdout, _) = run_flowchart_with_input(GRADE, vec!["10"]).unwrap();
+        assert_eq!(stdout, vec!["C"]);
+    }
+
+    #[test]
+    fn test_exhaustive_guards_without_else() {
+        let source = r#"flowchart TD
+    Start --> A[x = 12]
+    A -->|[x < 10]| B[println 'small']
+    A -->|[10 <= x]| C[println 'large']
+    B --> End
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["large"]);
+    }
+
+    #[test]
+    fn test_guard_with_exit_code_and_action() {
+        let source = r#"flowchart TD
+    Start --> A[x = 3]
+    A -->|[x > 1], exit x / println 'big'| End
+    A -->|[else]| End
+"#;
+        let (exit_code, stdout, _) = run_flowchart_with_exit_code(source).unwrap();
+        assert_eq!(exit_code, 3);
+        assert_eq!(stdout, vec!["big"]);
+    }
+
+    #[test]
+    fn test_missing_else_is_error() {
+        let source = r#"flowchart TD
+    Start --> A[x = 1]
+    A -->|[x > 10]| B[println 'big']
+    A -->|[x > 5]| C[println 'medium']
+    B --> End
+    C --> End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("Guards on edges from node 'A' may not cover every case"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_multiple_else_is_error() {
+        let source = r#"flowchart TD
+    Start --> A[x = 1]
+    A -->|[x > 10]| End
+    A -->|[else]| End
+    A -->|[ELSE]| End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("Node 'A' has multiple [else] edges"));
+    }
+
+    #[test]
+    fn test_mixed_guarded_and_unguarded_is_error() {
+        let source = r#"flowchart TD
+    Start --> A[x = 1]
+    A -->|[x > 10]| End
+    A --> End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("Node 'A' mixes guarded and unguarded outgoing edges"));
+    }
+
+    #[test]
+    fn test_guard_on_condition_node_is_error() {
+        let source = r#"flowchart TD
+    Start --> A{true?}
+    A -->|[true]| End
+    A -->|No| End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("Condition node 'A' must have 'Yes' or 'No' label, but got a guard"));
+    }
+
+    #[test]
+    fn test_non_bool_guard_is_runtime_error() {
+        let source = r#"flowchart TD
+    Start --> A[x = 1]
+    A -->|[x]| End
+    A -->|[else]| End
+"#;
+        let err = run_flowchart(source).unwrap_err();
+        assert!(
+            err.contains("guard evaluation"),
+            "unexpected error: {}",
+            err
+        );
+    }
+}
+
+// =============================================================================
+// Decision table tests
+// =============================================================================
+
+mod decision_tables {
+    use super::*;
+
+    const PRICING: &str = r#"flowchart TD
+    Start --> A[qty = input as int]
+    A --> B[member = input == 'y']
+    B --> P[[table pricing first]]
+    P -->|bulk| C[println 'bulk ' + price as str]
+    P -->|retail| D[println 'retail ' + price as str]
+    C --> End
+    D --> End
+
+%% table pricing
+%% qty,    member, price =, ->
+%% >= 100, true,   80,      bulk
+%% >= 100, -,      90,      bulk
+%% -,      -,      100,     retail
+"#;
+
+    #[test]
+    fn test_first_matching_rule_assigns_and_branches() {
+        let (stdout, _) = run_flowchart_with_input(PRICING, vec!["150", "y"]).unwrap();
+        assert_eq!(stdout, vec!["bulk 80"]);
+        let (stdout, _) = run_flowchart_with_input(PRICING, vec!["150", "n"]).unwrap();
+        assert_eq!(stdout, vec!["bulk 90"]);
+        let (stdout, _) = run_flowchart_with_input(PRICING, vec!["3", "y"]).unwrap();
+        assert_eq!(stdout, vec!["retail 100"]);
+    }
+
+    #[test]
+    fn test_table_without_target_follows_single_edge() {
+        let source = r#"flowchart TD
+    Start --> A[x = 7]
+    A --> T[[table sign]]
+    T --> B[println label]
+    B --> End
+
+%% table sign
+%% x,   label =
+%% < 0, 'negative'
+%% 0,   'zero'
+%% > 0, 'positive'
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["positive"]);
+    }
+
+    #[test]
+    fn test_dash_output_leaves_variable_unchanged() {
+        let source = r#"flowchart TD
+    Start --> A[x = 1]
+    A --> B[note = 'kept']
+    B --> T[[table t]]
+    T --> C[println note]
+    C --> End
+
+%% table t
+%% x,  note =
+%% 1,  -
+%% != 1, 'changed'
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["kept"]);
+    }
+
+    #[test]
+    fn test_csv_file_table() {
+        let source = include_str!("fixtures/valid/shipping.mmd");
+        let dir =
+            std::path::Path::new(concat!(env!("CARGO_MANIFEST_DIR"), "/tests/fixtures/valid"));
+        let flowchart = parser::parse_in_dir(source, dir).unwrap();
+        let mut interpreter = Interpreter::with_io(
+            flowchart,
+            VecInputReader::new(vec!["3"]),
+            CapturingWriter::new(),
+        )
+        .unwrap();
+        interpreter.run().unwrap();
+        assert_eq!(
+            interpreter.into_output_writer().stdout(),
+            vec!["freight: 12"]
+        );
+    }
+
+    #[test]
+    fn test_missing_rule_is_error() {
+        let source = r#"flowchart TD
+    Start --> A[x = 1]
+    A --> T[[table t]]
+    T --> End
+
+%% table t
+%% x,    y =
+%% < 10, 1
+%% > 10, 2
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("Decision table 'T' has no rule for x = 10"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_overlapping_unique_rules_is_error() {
+        let source = r#"flowchart TD
+    Start --> A[tier = 'gold']
+    A --> T[[table t unique]]
+    T --> End
+
+%% table t
+%% tier,   discount =
+%% 'gold', 20
+%% -,      0
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("Rules 1 and 2 of decision table 'T' overlap (both match tier = 'gold')"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_overlap_found_at_runtime() {
+        let source = r#"flowchart TD
+    Start --> A[x = 5]
+    A --> B[limit = 3]
+    B --> T[[table t]]
+    T --> End
+
+%% table t
+%% x,        y =
+%% > limit,  1
+%% <= limit, 2
+%% 5,        3
+"#;
+        let err = run_flowchart(source).unwrap_err();
+        assert!(
+            err.contains("Rules 1 and 3 both matched in decision table 'T'"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_missing_target_edge_is_error() {
+        let source = r#"flowchart TD
+    Start --> A[x = 1]
+    A --> T[[table t]]
+    T -->|low| End
+
+%% table t
+%% x,    ->
+%% < 5,  low
+%% >= 5, high
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("Decision table 'T' is missing 'high' edge"));
+    }
+
+    #[test]
+    fn test_undeclared_table_is_error() {
+        let source = r#"flowchart TD
+    Start --> T[[table nowhere]]
+    T --> End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("decision table 'nowhere' of node 'T' is not declared"));
+    }
+
+    #[test]
+    fn test_subroutine_shape() {
+        let source = r#"flowchart TD
+    Start --> A[x = 0]
+    A --> T@{ shape: subroutine, label: "table t" }
+    T --> B[println y]
+    B --> End
+
+%% table t
+%% x, y =
+%% -, 'any'
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["any"]);
+    }
+}
+
+// =============================================================================
+// Loop node tests
+// =============================================================================
+
+mod loop_nodes {
+    use super::*;
+
+    #[test]
+    fn test_range_loop_is_inclusive() {
+        let source = r#"flowchart TD
+    Start --> A[n = 5; sum = 0]
+    A --> L{{for i in 1..n}}
+    L -->|body| B[sum = sum + i]
+    B --> L
+    L -->|done| C[println sum]
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["15"]);
+    }
+
+    #[test]
+    fn test_empty_range_skips_body() {
+        let source = r#"flowchart TD
+    Start --> L{{for i in 3..1}}
+    L -->|body| A[println i]
+    A --> L
+    L -->|done| B[println 'done']
+    B --> End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["done"]);
+    }
+
+    #[test]
+    fn test_value_list_and_string_characters() {
+        let source = r#"flowchart TD
+    Start --> L{{for x in 'a', 1 + 1, true}}
+    L -->|body| A[println x]
+    A --> L
+    L -->|done| M{{for c in 'hi'}}
+    M -->|body| B[println c]
+    B --> M
+    M -->|done| End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["a", "2", "true", "h", "i"]);
+    }
+
+    #[test]
+    fn test_nested_loop_restarts_each_time() {
+        let source = r#"flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| M{{for j in 1..i}}
+    M -->|body| A[println i * 10 + j]
+    A --> M
+    M -->|done| L
+    L -->|done| End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["11", "21", "22", "31", "32", "33"]);
+    }
+
+    #[test]
+    fn test_range_bounds_evaluated_once() {
+        let source = r#"flowchart TD
+    Start --> A[n = 2]
+    A --> L{{for i in 1..n}}
+    L -->|body| B[n = 10; println i]
+    B --> L
+    L -->|done| End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["1", "2"]);
+    }
+
+    #[test]
+    fn test_hex_shape() {
+        let source = r#"flowchart TD
+    Start --> L@{ shape: hex, label: "for i in 1..2" }
+    L -->|body| A[println i]
+    A --> L
+    L -->|done| End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["1", "2"]);
+    }
+
+    #[test]
+    fn test_body_must_return_to_loop() {
+        let source = r#"flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| A[println i]
+    A --> End
+    L -->|done| End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("The body of loop node 'L' never returns to it"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_missing_done_edge_is_error() {
+        let source = r#"flowchart TD
+    Start --> L{{for i in 1..3}}
+    L -->|body| A[println i]
+    A -->|[i < 3]| L
+    A -->|[else]| End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("Loop node 'L' is missing 'done' edge"));
+    }
+
+    #[test]
+    fn test_unlabeled_edge_is_error() {
+        let source = r#"flowchart TD
+    Start --> L{{for i in 1..3}}
+    L --> End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("Edges from loop node 'L' must be labeled 'body' or 'done'"));
+    }
+
+    #[test]
+    fn test_non_int_range_is_runtime_error() {
+        let source = r#"flowchart TD
+    Start --> L{{for i in 1..'3'}}
+    L -->|body| A[println i]
+    A --> L
+    L -->|done| End
+"#;
+        let err = run_flowchart(source).unwrap_err();
+        assert!(err.contains("loop range"), "unexpected error: {}", err);
+    }
+}
+
+// =============================================================================
+// Compound and parallel assignment tests
+// =============================================================================
+
+mod compound_assignment {
+    use super::*;
+
+    #[test]
+    fn test_compound_operators() {
+        let source = r#"flowchart TD
+    Start --> A[x = 10; x += 5; x -= 3; x *= 2; x /= 5; x %= 3]
+    A --> B[s = 'ab'; s += 'c']
+    B --> C[println x; println s]
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["1", "abc"]);
+    }
+
+    #[test]
+    fn test_compound_assign_undefined_variable() {
+        let source = r#"flowchart TD
+    Start --> A[total += 1]
+    A --> End
+"#;
+        let err = run_flowchart(source).unwrap_err();
+        assert!(
+            err.contains("Undefined variable"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_parallel_assign_fibonacci() {
+        let source = r#"flowchart TD
+    Start --> A[a, b = 0, 1]
+    A --> L{{for i in 1..8}}
+    L -->|body / a, b = b, a + b| L
+    L -->|done| B[println a]
+    B --> End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["21"]);
+    }
+
+    #[test]
+    fn test_parallel_assign_reads_input_left_to_right() {
+        let source = r#"flowchart TD
+    Start --> A[x, y = input, input]
+    A --> B[println x + y]
+    B --> End
+"#;
+        let (stdout, _) = run_flowchart_with_input(source, vec!["a", "b"]).unwrap();
+        assert_eq!(stdout, vec!["ab"]);
+    }
+
+    #[test]
+    fn test_parallel_assign_count_mismatch_is_error() {
+        let source = r#"flowchart TD
+    Start --> A[a, b, c = 1, 2]
+    A --> End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(err.contains("expected 3 values for 3 variables, but got 2"));
+    }
+}
+
+// =============================================================================
+// Constants and unset tests
+// =============================================================================
+
+mod constants_and_unset {
+    use super::*;
+
+    #[test]
+    fn test_constant_value() {
+        let source = r#"flowchart TD
+    Start --> A[const TAX_RATE = 8; price = 250]
+    A --> B[println price * TAX_RATE / 100]
+    B --> End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["20"]);
+    }
+
+    #[test]
+    fn test_constant_reassignment_is_validation_error() {
+        let source = r#"flowchart TD
+    Start --> A[const TAX_RATE = 8]
+    A --> B[TAX_RATE = 10]
+    B --> End
+"#;
+        let err = parser::parse(source).unwrap_err().to_string();
+        assert!(
+            err.contains("Constant 'TAX_RATE'"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_constant_defined_twice_at_runtime() {
+        let source = r#"flowchart TD
+    Start --> L{{for i in 1..2}}
+    L -->|body| A[const LIMIT = i]
+    A --> L
+    L -->|done| End
+"#;
+        let err = run_flowchart(source).unwrap_err();
+        assert!(
+            err.contains("Cannot change constant 'LIMIT'"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_unset_removes_variable() {
+        let source = r#"flowchart TD
+    Start --> A[tmp = 1; println exists(tmp); unset tmp; println defined('tmp')]
+    A --> B[println tmp]
+    B --> End
+"#;
+        let err = run_flowchart(source).unwrap_err();
+        assert!(
+            err.contains("Undefined variable: 'tmp'"),
+            "unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_exists_in_condition() {
+        let source = r#"flowchart TD
+    Start --> C{exists(name)?}
+    C -->|Yes| A[println name]
+    C -->|No| B[name = 'guest'; println 'default']
+    B --> C
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).unwrap();
+        assert_eq!(stdout, vec!["default", "guest"]);
+    }
+}
+
+// =============================================================================
+// Cast operation tests (TESTS.md section 3)
+// =============================================================================
+
+mod cast_operations {
+    use super::*;
+
+    #[test]
+    fn test_cast_int_as_int() {
+        let source = r#"flowchart TD
+    Start --> A[x = 42 as int; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["42"]);
+    }
+
+    #[test]
+    fn test_cast_str_as_str() {
+        let source = r#"flowchart TD
+    Start --> A[x = 'hello' as str; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["hello"]);
+    }
+
+    #[test]
+    fn test_cast_bool_true_as_str() {
+        let source = r#"flowchart TD
+    Start --> A[x = true as str; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["true"]);
+    }
+
+    #[test]
+    fn test_cast_bool_false_as_str() {
+        let source = r#"flowchart TD
+    Start --> A[x = false as str; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["false"]);
+    }
+
+    #[test]
+    fn test_cast_bool_as_int() {
+        let source = r#"flowchart TD
+    Start --> A[x = true as int]
+    A --> End
+"#;
+        let result = run_flowchart(source);
+        assert!(result.is_err());
+        assert!(result.unwrap_err().contains("Cannot cast"));
+    }
+
+    #[test]
+    fn test_cast_negative_string_to_int() {
+        let source = r#"flowchart TD
+    Start --> A[x = '-42' as int; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["-42"]);
+    }
+
+    #[test]
+    fn test_cast_empty_string_to_int() {
+        let source = r#"flowchart TD
+    Start --> A[x = '' as int]
+    A --> End
+"#;
+        let result = run_flowchart(source);
+        assert!(result.is_err());
+        assert!(result.unwrap_err().contains("Cannot cast"));
+    }
+}
+
+// =============================================================================
+// Equality and comparison edge case tests (TESTS.md section 4)
+// =============================================================================
+
+mod equality_comparison {
+    use super::*;
+
+    #[test]
+    fn test_cross_type_eq_int_str() {
+        let source = r#"flowchart TD
+    Start --> A{1 == '1'?}
+    A -->|Yes| B[println 'equal']
+    A -->|No| C[println 'not equal']
+    B --> End
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["not equal"]);
+    }
+
+    #[test]
+    fn test_cross_type_ne_int_str() {
+        let source = r#"flowchart TD
+    Start --> A{1 != '1'?}
+    A -->|Yes| B[println 'not equal']
+    A -->|No| C[println 'equal']
+    B --> End
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["not equal"]);
+    }
+
+    #[test]
+    fn test_cross_type_eq_bool_int() {
+        let source = r#"flowchart TD
+    Start --> A{true == 1?}
+    A -->|Yes| B[println 'equal']
+    A -->|No| C[println 'not equal']
+    B --> End
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["not equal"]);
+    }
+
+    #[test]
+    fn test_str_eq_same() {
+        let source = r#"flowchart TD
+    Start --> A{'abc' == 'abc'?}
+    A -->|Yes| B[println 'equal']
+    A -->|No| C[println 'not equal']
+    B --> End
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["equal"]);
+    }
+
+    #[test]
+    fn test_bool_eq_same() {
+        let source = r#"flowchart TD
+    Start --> A{true == true?}
+    A -->|Yes| B[println 'equal']
+    A -->|No| C[println 'not equal']
+    B --> End
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["equal"]);
+    }
+
+    #[test]
+    fn test_negative_comparison() {
+        let source = r#"flowchart TD
+    Start --> A{-5 < 3?}
+    A -->|Yes| B[println 'true']
+    A -->|No| C[println 'false']
+    B --> End
+    C --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["true"]);
+    }
+}
+
+// =============================================================================
+// Integer edge case tests (TESTS.md section 5)
+// =============================================================================
+
+mod integer_edge_cases {
+    use super::*;
+
+    #[test]
+    fn test_int_overflow_addition() {
+        let source = r#"flowchart TD
+    Start --> A[x = 9223372036854775807 + 1; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["-9223372036854775808"]);
+    }
+
+    #[test]
+    fn test_int_overflow_multiplication() {
+        let source = r#"flowchart TD
+    Start --> A[x = 9223372036854775807 * 2; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["-2"]);
+    }
+
+    #[test]
+    fn test_int_overflow_subtraction() {
+        // i64::MIN = 0 - 9223372036854775807 - 1
+        // i64::MIN - 1 wraps to i64::MAX
+        let source = r#"flowchart TD
+    Start --> A[x = 0 - 9223372036854775807 - 1; y = x - 1; println y]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["9223372036854775807"]);
+    }
+
+    #[test]
+    fn test_large_int_literal_output() {
+        let source = r#"flowchart TD
+    Start --> A[println 9223372036854775807]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["9223372036854775807"]);
+    }
+}
+
+// =============================================================================
+// Variable scope tests (TESTS.md section 6)
+// =============================================================================
+
+mod variable_scope {
+    use super::*;
+
+    #[test]
+    fn test_variable_reassignment() {
+        let source = r#"flowchart TD
+    Start --> A[x = 1; x = 2; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["2"]);
+    }
+
+    #[test]
+    fn test_variable_type_change() {
+        let source = r#"flowchart TD
+    Start --> A[x = 42; x = 'hello'; println x]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["hello"]);
+    }
+
+    #[test]
+    fn test_variable_after_branch() {
         let source = r#"flowchart TD
     Start --> Init[x = 0]
     Init --> A{true?}
@@ -1916,3 +3042,447 @@ mod parse_edge_cases {
         assert_eq!(stdout, vec!["hello"]);
     }
 }
+
+// =============================================================================
+// Random function tests
+// =============================================================================
+
+mod random_functions {
+    use super::*;
+
+    const DICE: &str = r#"flowchart TD
+    Start --> A[i = 0]
+    A --> B{i < 20?}
+    B -->|Yes| C[println rand_int(1, 6); i = i + 1]
+    C --> B
+    B -->|No| End
+"#;
+
+    #[test]
+    fn test_same_seed_same_output() {
+        let (first, _) = run_flowchart_with_seed(DICE, 42).expect("Should execute successfully");
+        let (second, _) = run_flowchart_with_seed(DICE, 42).expect("Should execute successfully");
+        assert_eq!(first.len(), 20);
+        assert_eq!(first, second);
+    }
+
+    #[test]
+    fn test_different_seed_different_output() {
+        let (first, _) = run_flowchart_with_seed(DICE, 1).expect("Should execute successfully");
+        let (second, _) = run_flowchart_with_seed(DICE, 2).expect("Should execute successfully");
+        assert_ne!(first, second);
+    }
+
+    #[test]
+    fn test_rand_int_within_bounds() {
+        let (stdout, _) = run_flowchart(DICE).expect("Should execute successfully");
+        for line in stdout {
+            let n: i64 = line.parse().unwrap();
+            assert!((1..=6).contains(&n), "Out of range: {}", n);
+        }
+    }
+
+    #[test]
+    fn test_rand_int_equal_bounds() {
+        let source = r#"flowchart TD
+    Start --> A[println rand_int(7, 7)]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["7"]);
+    }
+
+    #[test]
+    fn test_rand_int_inverted_bounds() {
+        let source = r#"flowchart TD
+    Start --> A[println rand_int(6, 1)]
+    A --> End
+"#;
+        let err = run_flowchart(source).unwrap_err();
+        assert!(
+            err.contains("Invalid argument to rand_int"),
+            "Unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_rand_choice_single_argument() {
+        let source = r#"flowchart TD
+    Start --> A[println rand_choice('only')]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["only"]);
+    }
+
+    #[test]
+    fn test_shuffle_preserves_characters() {
+        let source = r#"flowchart TD
+    Start --> A[println shuffle('merx')]
+    A --> End
+"#;
+        let (stdout, _) = run_flowchart_with_seed(source, 7).expect("Should execute successfully");
+        let mut chars: Vec<char> = stdout[0].chars().collect();
+        chars.sort();
+        assert_eq!(chars, vec!['e', 'm', 'r', 'x']);
+    }
+
+    #[test]
+    fn test_deny_random() {
+        let source = r#"flowchart TD
+    Start --> A[println rand_int(1, 6)]
+    A --> End
+"#;
+        let flowchart = parser::parse(source).unwrap();
+        let mut interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .unwrap()
+                .with_random_denied();
+
+        let err = interpreter.run().unwrap_err();
+        assert!(matches!(
+            err,
+            RuntimeError::RandomDenied {
+                function: "rand_int"
+            }
+        ));
+    }
+
+    #[test]
+    fn test_deny_random_allows_deterministic_programs() {
+        let source = r#"flowchart TD
+    Start --> A[println 'hello']
+    A --> End
+"#;
+        let flowchart = parser::parse(source).unwrap();
+        let mut interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), CapturingWriter::new())
+                .unwrap()
+                .with_random_denied();
+
+        assert_eq!(interpreter.run().unwrap(), 0);
+    }
+}
+
+// =============================================================================
+// Time function tests
+// =============================================================================
+
+mod time_functions {
+    use super::*;
+    use merx::runtime::{Clock, ManualClock};
+
+    // 2024-03-15T09:30:00Z
+    const NOW_MS: i64 = 1_710_495_000_000;
+
+    /// Runs a flowchart against a fake clock, returning the output and the clock.
+    fn run_with_clock(
+        source: &str,
+        input_lines: Vec<&str>,
+    ) -> Result<(Vec<String>, ManualClock), String> {
+        let flowchart = parser::parse(source).map_err(|e| e.to_string())?;
+        let clock = ManualClock::new(NOW_MS);
+
+        let mut interpreter = Interpreter::with_io(
+            flowchart,
+            VecInputReader::new(input_lines),
+            CapturingWriter::new(),
+        )
+        .map_err(|e| e.to_string())?
+        .with_clock(clock.clone());
+
+        interpreter.run().map_err(|e| e.to_string())?;
+        Ok((interpreter.into_output_writer().stdout(), clock))
+    }
+
+    #[test]
+    fn test_now_ms_and_today() {
+        let source = r#"flowchart TD
+    Start --> A[println now_ms(); println today()]
+    A --> End
+"#;
+        let (stdout, _) = run_with_clock(source, vec![]).expect("Should execute successfully");
+        assert_eq!(stdout, vec![NOW_MS.to_string(), "2024-03-15".to_string()]);
+    }
+
+    #[test]
+    fn test_format_date() {
+        let source = r#"flowchart TD
+    Start --> A[println format_date(now_ms(), '%d/%m/%Y %H:%M')]
+    A --> End
+"#;
+        let (stdout, _) = run_with_clock(source, vec![]).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["15/03/2024 09:30"]);
+    }
+
+    #[test]
+    fn test_sla_escalation() {
+        let source = r#"flowchart TD
+    Start --> A[created = parse_date(input)]
+    A --> B{now_ms() - created > 3 * 24 * 60 * 60 * 1000?}
+    B -->|Yes| C[println 'escalate']
+    B -->|No| D[println 'ok']
+    C --> End
+    D --> End
+"#;
+        let (stdout, _) =
+            run_with_clock(source, vec!["2024-03-10"]).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["escalate"]);
+
+        let (stdout, _) = run_with_clock(source, vec!["2024-03-14T12:00:00Z"])
+            .expect("Should execute successfully");
+        assert_eq!(stdout, vec!["ok"]);
+    }
+
+    #[test]
+    fn test_sleep_advances_fake_clock() {
+        let source = r#"flowchart TD
+    Start --> A[t = now_ms(); sleep 2500]
+    A --> B[println now_ms() - t]
+    B --> End
+"#;
+        let (stdout, clock) = run_with_clock(source, vec![]).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["2500"]);
+        assert_eq!(clock.now_ms(), NOW_MS + 2500);
+    }
+
+    #[test]
+    fn test_sleep_negative_duration() {
+        let source = r#"flowchart TD
+    Start --> A[sleep -1]
+    A --> End
+"#;
+        let err = run_with_clock(source, vec![]).unwrap_err();
+        assert!(
+            err.contains("Invalid argument to sleep"),
+            "Unexpected error: {}",
+            err
+        );
+    }
+
+    #[test]
+    fn test_parse_date_invalid() {
+        let source = r#"flowchart TD
+    Start --> A[println parse_date('2024-02-30')]
+    A --> End
+"#;
+        let err = run_with_clock(source, vec![]).unwrap_err();
+        assert!(
+            err.contains("'2024-02-30' is not a valid date"),
+            "Unexpected error: {}",
+            err
+        );
+    }
+}
+
+// =============================================================================
+// Output event tests
+// =============================================================================
+
+mod output_events {
+    use super::*;
+    use merx::runtime::{OutputEvent, OutputStream, OutputWriter, Value};
+
+    /// Attributes each output line to the node that produced it.
+    struct NodeAttributingWriter {
+        lines: Vec<(String, OutputStream, Value)>,
+    }
+
+    impl OutputWriter for NodeAttributingWriter {
+        fn write_stdout(&mut self, _s: &str) -> Result<(), RuntimeError> {
+            Ok(())
+        }
+
+        fn write_stdout_no_newline(&mut self, _s: &str) -> Result<(), RuntimeError> {
+            Ok(())
+        }
+
+        fn write_stderr(&mut self, _s: &str) -> Result<(), RuntimeError> {
+            Ok(())
+        }
+
+        fn on_output(&mut self, event: OutputEvent<'_>) -> Result<(), RuntimeError> {
+            self.lines
+                .push((event.node_id.to_string(), event.stream, event.value.clone()));
+            Ok(())
+        }
+    }
+
+    #[test]
+    fn test_output_attributed_to_nodes() {
+        let source = r#"flowchart TD
+    Start --> Greet[println 'hello'; x = 42]
+    Greet --> Check{x > 40?}
+    Check -->|Yes| Warn[error 'too big']
+    Check -->|No| End
+    Warn --> Show[println x]
+    Show --> End
+"#;
+        let flowchart = parser::parse(source).unwrap();
+        let writer = NodeAttributingWriter { lines: Vec::new() };
+        let mut interpreter =
+            Interpreter::with_io(flowchart, VecInputReader::default(), writer).unwrap();
+        interpreter.run().unwrap();
+
+        let lines = interpreter.into_output_writer().lines;
+        assert_eq!(
+            lines,
+            vec![
+                (
+                    "Greet".to_string(),
+                    OutputStream::Stdout,
+                    Value::from("hello")
+                ),
+                (
+                    "Warn".to_string(),
+                    OutputStream::Stderr,
+                    Value::from("too big")
+                ),
+                ("Show".to_string(), OutputStream::Stdout, Value::Int(42)),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_default_adapter_formats_values() {
+        let source = r#"flowchart TD
+    Start --> A[print 1; println true; error 'e']
+    A --> End
+"#;
+        let (stdout, stderr) = run_flowchart(source).expect("Should execute successfully");
+        assert_eq!(stdout, vec!["1", "true"]);
+        assert_eq!(stderr, vec!["e"]);
+    }
+}
+
+// =============================================================================
+// run_str tests
+// =============================================================================
+
+mod run_str_api {
+    use merx::codes::ErrorCode;
+    use merx::i18n::{Lang, Localize};
+    use merx::runtime::OutputStream;
+    use merx::{RunError, run_str};
+
+    #[test]
+    fn test_run_str_success() {
+        let result = run_str(
+            r#"flowchart TD
+    Start --> A[print 'Name: '; name = input]
+    A --> B[println 'Hello, ' + name + '!']
+    B --> End
+"#,
+            &["merx"],
+        );
+        assert!(result.is_ok(), "{:?}", result.error);
+        assert_eq!(result.stdout, "Name: Hello, merx!\n");
+        assert_eq!(result.stderr, "");
+        assert_eq!(result.exit_code, 0);
+    }
+
+    #[test]
+    fn test_run_str_interleaved_output() {
+        let result = run_str(
+            r#"flowchart TD
+    Start --> A[println 'one'; error 'two'; println 'three']
+    A --> End
+"#,
+            &[],
+        );
+        let order: Vec<(OutputStream, &str)> = result
+            .output
+            .iter()
+            .map(|c| (c.stream, c.text.as_str()))
+            .collect();
+        assert_eq!(
+            order,
+            vec![
+                (OutputStream::Stdout, "one"),
+                (OutputStream::Stderr, "two"),
+                (OutputStream::Stdout, "three"),
+            ]
+        );
+        assert_eq!(result.stdout, "one\nthree\n");
+        assert_eq!(result.stderr, "two\n");
+    }
+
+    #[test]
+    fn test_run_str_exit_code() {
+        let result = run_str(
+            r#"flowchart TD
+    Start --> A[println 'failing']
+    A -->|exit 3| End
+"#,
+            &[],
+        );
+        assert!(result.is_ok());
+        assert_eq!(result.exit_code, 3);
+    }
+
+    #[test]
+    fn test_run_str_runtime_error_keeps_output() {
+        let result = run_str(
+            r#"flowchart TD
+    Start --> A[println 'before'; println 1 / 0]
+    A --> End
+"#,
+            &[],
+        );
+        assert_eq!(result.stdout, "before\n");
+        assert_eq!(result.exit_code, 1);
+        assert!(matches!(result.error, Some(RunError::Runtime(_))));
+        assert_eq!(
+            result.error.as_ref().map(RunError::code),
+            Some(ErrorCode::DivisionByZero)
+        );
+        assert_eq!(
+            result.error.unwrap().to_string(),
+            "Runtime error: Division by zero"
+        );
+    }
+
+    #[test]
+    fn test_run_str_analysis_error() {
+        let result = run_str("flowchart TD\n", &[]);
+        assert_eq!(result.exit_code, 2);
+        assert!(matches!(result.error, Some(RunError::Analysis(_))));
+        assert_eq!(
+            result.error.as_ref().map(RunError::code),
+            Some(ErrorCode::MissingStart)
+        );
+        assert!(result.output.is_empty());
+    }
+
+    #[test]
+    fn test_run_str_errors_in_japanese() {
+        let result = run_str("flowchart TD\n", &[]);
+        assert_eq!(
+            result.error.unwrap().in_lang(Lang::Ja).to_string(),
+            "検証エラー: 'Start' ノードがありません"
+        );
+
+        let result = run_str(
+            "flowchart TD\n    Start --> A[x = 1 / 0]\n    A --> End\n",
+            &[],
+        );
+        assert_eq!(
+            result.error.unwrap().in_lang(Lang::Ja).to_string(),
+            "実行時エラー: ゼロ除算"
+        );
+    }
+
+    #[test]
+    fn test_run_str_input_exhausted() {
+        let result = run_str(
+            r#"flowchart TD
+    Start --> A[x = input]
+    A --> End
+"#,
+            &[],
+        );
+        assert_eq!(result.exit_code, 1);
+        assert!(result.error.unwrap().to_string().contains("No more input"));
+    }
+}
@@ -42,6 +42,7 @@ export default withMermaid(
             },
             { text: "Operators", link: "/guide/operators" },
             { text: "Control Flow", link: "/guide/control-flow" },
+            { text: "Error Codes", link: "/guide/error-codes" },
           ],
         },
         {